@@ -0,0 +1,397 @@
+// Package admin serves debugging endpoints for a ring over HTTP, so routing
+// can be inspected in production without shelling into the process.
+//
+// Responses are JSON and include the ring's epoch, its version (see
+// hashring.HashRing.Version), so answers can be matched with the topology they
+// were computed from.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// ExplainResponse is the response to GET /explain.
+type ExplainResponse struct {
+	hashring.Explanation
+	Epoch uint64 `json:"epoch"` // the ring version the explanation was computed at
+}
+
+// LookupResponse is the response to GET /lookup.
+type LookupResponse struct {
+	Key     string   `json:"key"`
+	Servers []string `json:"servers"` // the key's replicas, in preference order
+	Epoch   uint64   `json:"epoch"`
+}
+
+// StatsResponse is the response to GET /stats.
+type StatsResponse struct {
+	Servers   int                `json:"servers"`
+	Ownership map[string]float64 `json:"ownership"` // fraction of the hash space each server owns
+	CV        float64            `json:"cv"`        // coefficient of variation of ownership, in percent
+	Lookups   uint64             `json:"lookups"`   // keys resolved since the ring was created
+	Epoch     uint64             `json:"epoch"`
+}
+
+// RangesResponse is the response to GET /ranges/{server}.
+type RangesResponse struct {
+	Server string               `json:"server"`
+	Ranges []hashring.HashRange `json:"ranges"` // in position order
+	Epoch  uint64               `json:"epoch"`
+}
+
+// TokensResponse is the response to GET /tokens.
+type TokensResponse struct {
+	Tokens []hashring.Token `json:"tokens"` // every virtual node, in position order
+	Epoch  uint64           `json:"epoch"`
+}
+
+// HealthResponse is the response to GET /healthz.
+type HealthResponse struct {
+	Healthy  bool     `json:"healthy"`
+	Servers  int      `json:"servers"`            // active servers; joining, draining, and down ones aren't counted
+	CV       float64  `json:"cv"`                 // coefficient of variation of the active servers' shares, in percent
+	MaxShare float64  `json:"max_share"`          // the largest fraction of the hash space any active server answers for
+	Failures []string `json:"failures,omitempty"` // the thresholds breached, if any
+	Epoch    uint64   `json:"epoch"`
+}
+
+// HealthThresholds are the limits GET /healthz checks the ring's balance
+// against. Zero values aren't checked.
+//
+// Only active servers count toward MinServers, MaxCV, and MaxShare. An inactive
+// server's ranges count toward the next active server clockwise, which takes
+// its writes, so a ring with its servers draining or down is unhealthy even
+// though its layout is balanced.
+type HealthThresholds struct {
+	MinServers int     // fewest active servers
+	MaxCV      float64 // largest coefficient of variation of ownership, in percent
+	MaxShare   float64 // largest fraction of the hash space one active server may answer for, e.g. 0.4
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithHealthThresholds sets the limits GET /healthz reports the ring unhealthy
+// past. By default the ring is healthy as long as it has a server.
+func WithHealthThresholds(t HealthThresholds) Option {
+	return func(s *Server) {
+		s.health = t
+	}
+}
+
+// Server serves a ring's admin endpoints.
+type Server struct {
+	ring   *hashring.HashRing
+	health HealthThresholds
+}
+
+// New creates an admin server for ring.
+//
+// Example:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/admin/", http.StripPrefix("/admin", admin.New(ring).Handler()))
+//
+//	// curl localhost:8080/admin/explain?key=user:42
+func New(ring *hashring.HashRing, opts ...Option) *Server {
+	s := &Server{ring: ring, health: HealthThresholds{MinServers: 1}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Handler returns the HTTP handler for the admin endpoints:
+//
+//	GET /explain?key=...   how the key is routed (see hashring.HashRing.Explain)
+//	GET /healthz           200 if the ring's balance is within its thresholds, 503 otherwise (see WithHealthThresholds)
+//	GET /lookup?key=...&n= the key's n replicas, 1 by default (see hashring.HashRing.GetReplicas)
+//	GET /profile           sampled lookup timings and allocations (see hashring.WithLookupProfiling)
+//	GET /ranges/{server}   the ranges of positions the server owns
+//	GET /snapshot          the ring's full state (see hashring.HashRing.Snapshot)
+//	GET /stats             ownership shares, their CV, and the lookup count
+//	GET /tokens            every virtual node with its owner and index (see hashring.HashRing.Tokens)
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /explain", s.explain)
+	mux.HandleFunc("GET /healthz", s.healthz)
+	mux.HandleFunc("GET /lookup", s.lookup)
+	mux.HandleFunc("GET /profile", s.profile)
+	mux.HandleFunc("GET /ranges/{server}", s.ranges)
+	mux.HandleFunc("GET /snapshot", s.snapshot)
+	mux.HandleFunc("GET /stats", s.stats)
+	mux.HandleFunc("GET /tokens", s.tokens)
+	return mux
+}
+
+// snapshot serves the ring's snapshot, which carries its own version, so
+// tools can rebuild the live ring with hashring.Restore.
+func (s *Server) snapshot(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.ring.Snapshot())
+}
+
+func (s *Server) explain(w http.ResponseWriter, r *http.Request) {
+	if !r.URL.Query().Has("key") {
+		http.Error(w, "missing key parameter", http.StatusBadRequest)
+		return
+	}
+
+	var exp hashring.Explanation
+	epoch, err := s.atEpoch(func() (err error) {
+		exp, err = s.ring.Explain(r.URL.Query().Get("key"))
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, ExplainResponse{Explanation: exp, Epoch: epoch})
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if !query.Has("key") {
+		http.Error(w, "missing key parameter", http.StatusBadRequest)
+		return
+	}
+
+	n := 1
+	if query.Has("n") {
+		var err error
+		if n, err = strconv.Atoi(query.Get("n")); err != nil || n <= 0 {
+			http.Error(w, fmt.Sprintf("invalid n parameter %q: must be a positive integer", query.Get("n")), http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp := LookupResponse{Key: query.Get("key")}
+	epoch, err := s.atEpoch(func() (err error) {
+		resp.Servers, err = s.ring.GetReplicas(resp.Key, n)
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	resp.Epoch = epoch
+	writeJSON(w, resp)
+}
+
+// profile serves the ring's lookup profile, which is empty unless the ring
+// was created with hashring.WithLookupProfiling.
+func (s *Server) profile(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.ring.LookupProfile())
+}
+
+func (s *Server) stats(w http.ResponseWriter, _ *http.Request) {
+	var resp StatsResponse
+	epoch, err := s.atEpoch(func() error {
+		resp.Ownership = s.ring.OwnershipShare()
+		resp.Servers = len(resp.Ownership)
+		resp.CV = s.ring.OwnershipCV()
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	resp.Epoch = epoch
+
+	resp.Lookups = s.ring.Lookups()
+	writeJSON(w, resp)
+}
+
+func (s *Server) tokens(w http.ResponseWriter, _ *http.Request) {
+	var resp TokensResponse
+	epoch, err := s.atEpoch(func() error {
+		resp.Tokens = s.ring.Tokens()
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	resp.Epoch = epoch
+
+	writeJSON(w, resp)
+}
+
+func (s *Server) healthz(w http.ResponseWriter, _ *http.Request) {
+	var resp HealthResponse
+	epoch, err := s.atEpoch(func() error {
+		shares := s.activeShares()
+		resp.Servers = len(shares)
+		resp.CV = ownershipCV(shares)
+		resp.MaxShare = 0
+		for _, share := range shares {
+			resp.MaxShare = max(resp.MaxShare, share)
+		}
+
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	resp.Epoch = epoch
+
+	t := s.health
+	if t.MinServers > 0 && resp.Servers < t.MinServers {
+		resp.Failures = append(resp.Failures, fmt.Sprintf("%d servers, fewer than %d", resp.Servers, t.MinServers))
+	}
+
+	if t.MaxCV > 0 && resp.CV > t.MaxCV {
+		resp.Failures = append(resp.Failures, fmt.Sprintf("ownership CV %.2f%% exceeds %.2f%%", resp.CV, t.MaxCV))
+	}
+
+	if t.MaxShare > 0 && resp.MaxShare > t.MaxShare {
+		resp.Failures = append(resp.Failures, fmt.Sprintf("a server owns %.2f%% of the ring, more than %.2f%%", resp.MaxShare*100, t.MaxShare*100))
+	}
+
+	resp.Healthy = len(resp.Failures) == 0
+	if !resp.Healthy {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	writeJSON(w, resp)
+}
+
+func (s *Server) ranges(w http.ResponseWriter, r *http.Request) {
+	resp := RangesResponse{Server: r.PathValue("server"), Ranges: []hashring.HashRange{}}
+	epoch, err := s.atEpoch(func() error {
+		if _, ok := s.ring.GetServerInfo(resp.Server); !ok {
+			return fmt.Errorf("server %s not found", resp.Server)
+		}
+
+		resp.Ranges = resp.Ranges[:0]
+		for rng, owner := range s.ring.OwnershipRanges() {
+			if owner == resp.Server {
+				resp.Ranges = append(resp.Ranges, rng)
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, errUnsettled) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resp.Epoch = epoch
+	writeJSON(w, resp)
+}
+
+// activeShares returns the fraction of the hash space each active server
+// answers for: the ranges it owns, plus those of the inactive servers before
+// it counter-clockwise.
+func (s *Server) activeShares() map[string]float64 {
+	type owned struct {
+		size   uint64
+		server string
+	}
+
+	// Collected first, since the ring can't be queried while it's iterated
+	var ranges []owned
+	for r, server := range s.ring.OwnershipRanges() {
+		ranges = append(ranges, owned{size: r.End - r.Start + 1, server: server})
+	}
+
+	active := make(map[string]bool)
+	for _, r := range ranges {
+		if _, ok := active[r.server]; !ok {
+			state, _ := s.ring.State(r.server)
+			active[r.server] = state == hashring.StateActive
+		}
+	}
+
+	shares := make(map[string]float64)
+	start := slices.IndexFunc(ranges, func(r owned) bool { return active[r.server] })
+	if start < 0 {
+		return shares
+	}
+
+	// Walk clockwise from the first active range, so inactive ranges at the
+	// end wrap around to it
+	var pending uint64
+	for i := range ranges {
+		r := ranges[(start+1+i)%len(ranges)]
+		if !active[r.server] {
+			pending += r.size
+			continue
+		}
+
+		shares[r.server] += float64(r.size+pending) / (1 << 32)
+		pending = 0
+	}
+
+	return shares
+}
+
+// ownershipCV returns the coefficient of variation of shares, in percent, as
+// hashring.OwnershipCV computes it over every server.
+func ownershipCV(shares map[string]float64) float64 {
+	if len(shares) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, share := range shares {
+		total += share
+	}
+	mean := total / float64(len(shares))
+
+	var variance float64
+	for _, share := range shares {
+		diff := share - mean
+		variance += diff * diff
+	}
+
+	variance /= float64(len(shares))
+	return math.Sqrt(variance) / mean * 100
+}
+
+// maxEpochAttempts is how many times atEpoch runs fn before giving up on a
+// ring whose topology keeps changing.
+const maxEpochAttempts = 10
+
+// errUnsettled is returned by atEpoch when it gives up.
+var errUnsettled = fmt.Errorf("ring topology changed during each of %d attempts", maxEpochAttempts)
+
+// atEpoch runs fn, retrying if the topology changed while it ran, so its
+// answer always agrees with the returned epoch. It gives up with an error if
+// the topology changed during every attempt.
+func (s *Server) atEpoch(fn func() error) (uint64, error) {
+	for range maxEpochAttempts {
+		epoch := s.ring.Version()
+		if err := fn(); err != nil {
+			return 0, err
+		}
+
+		if s.ring.Version() == epoch {
+			return epoch, nil
+		}
+	}
+
+	return 0, errUnsettled
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,326 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	ring := hashring.New(50)
+	srv := httptest.NewServer(New(ring).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/explain?key=user:42")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	resp, err = http.Get(srv.URL + "/explain?key=user:42")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var got ExplainResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+
+	want, err := ring.Explain("user:42")
+	require.NoError(t, err)
+	require.Equal(t, ExplainResponse{Explanation: want, Epoch: ring.Version()}, got)
+
+	resp, err = http.Get(srv.URL + "/explain")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(srv.URL+"/explain?key=user:42", "text/plain", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestSnapshot(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServerWithInfo(hashring.ServerInfo{Name: "server2", Zone: "a"}))
+
+	srv := httptest.NewServer(New(ring).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/snapshot")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var snap hashring.Snapshot
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&snap))
+
+	restored, err := hashring.Restore(snap)
+	require.NoError(t, err)
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+	require.Equal(t, ring.Version(), restored.Version())
+}
+
+func TestLookup(t *testing.T) {
+	ring := hashring.New(50)
+	srv := httptest.NewServer(New(ring).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/lookup?key=user:42")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	for _, server := range []string{"server1", "server2", "server3"} {
+		require.NoError(t, ring.AddServer(server))
+	}
+
+	resp, err = http.Get(srv.URL + "/lookup?key=user:42&n=2")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got LookupResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+
+	want, err := ring.GetReplicas("user:42", 2)
+	require.NoError(t, err)
+	require.Equal(t, LookupResponse{Key: "user:42", Servers: want, Epoch: ring.Version()}, got)
+
+	for _, query := range []string{"", "?key=user:42&n=0", "?key=user:42&n=two"} {
+		resp, err := http.Get(srv.URL + "/lookup" + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestStats(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	_, _ = ring.GetServer("user:42")
+
+	srv := httptest.NewServer(New(ring).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stats")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got StatsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	require.Equal(t, StatsResponse{
+		Servers:   2,
+		Ownership: ring.OwnershipShare(),
+		CV:        ring.OwnershipCV(),
+		Lookups:   1,
+		Epoch:     ring.Version(),
+	}, got)
+}
+
+func TestTokens(t *testing.T) {
+	ring := hashring.New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	srv := httptest.NewServer(New(ring).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/tokens")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got TokensResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	require.Equal(t, TokensResponse{Tokens: ring.Tokens(), Epoch: ring.Version()}, got)
+	require.Len(t, got.Tokens, 20)
+}
+
+func TestRanges(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	srv := httptest.NewServer(New(ring).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ranges/server1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got RangesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	require.Equal(t, "server1", got.Server)
+	require.Equal(t, ring.Version(), got.Epoch)
+	require.NotEmpty(t, got.Ranges)
+
+	var owned float64
+	for _, r := range got.Ranges {
+		owned += float64(r.End-r.Start+1) / (1 << 32)
+	}
+	require.InDelta(t, ring.OwnershipShare()["server1"], owned, 1e-9)
+
+	resp, err = http.Get(srv.URL + "/ranges/server9")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestHealthz(t *testing.T) {
+	ring := hashring.New(50)
+	get := func(srv *httptest.Server) (int, HealthResponse) {
+		resp, err := http.Get(srv.URL + "/healthz")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var got HealthResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		return resp.StatusCode, got
+	}
+
+	// an empty ring is unhealthy by default
+	srv := httptest.NewServer(New(ring).Handler())
+	defer srv.Close()
+
+	code, got := get(srv)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.False(t, got.Healthy)
+	require.Equal(t, []string{"0 servers, fewer than 1"}, got.Failures)
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	code, got = get(srv)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, got.Healthy)
+	require.Equal(t, 2, got.Servers)
+	require.Equal(t, ring.Version(), got.Epoch)
+
+	strict := httptest.NewServer(New(ring, WithHealthThresholds(HealthThresholds{
+		MinServers: 3,
+		MaxCV:      0.001,
+		MaxShare:   0.5,
+	})).Handler())
+	defer strict.Close()
+
+	code, got = get(strict)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.False(t, got.Healthy)
+	require.Len(t, got.Failures, 3)
+	require.Contains(t, got.Failures[0], "2 servers, fewer than 3")
+	require.Contains(t, got.Failures[1], "ownership CV")
+	require.Contains(t, got.Failures[2], "more than 50.00%")
+}
+
+func TestHealthzInactiveServers(t *testing.T) {
+	ring := hashring.New(50, hashring.WithPlacement(hashring.PlacementEvenlySpaced))
+	for _, server := range []string{"server1", "server2", "server3", "server4"} {
+		require.NoError(t, ring.AddServer(server))
+	}
+
+	srv := httptest.NewServer(New(ring, WithHealthThresholds(HealthThresholds{
+		MinServers: 2,
+		MaxShare:   0.6,
+	})).Handler())
+	defer srv.Close()
+
+	get := func() (int, HealthResponse) {
+		resp, err := http.Get(srv.URL + "/healthz")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var got HealthResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+		return resp.StatusCode, got
+	}
+
+	code, got := get()
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 4, got.Servers)
+	require.InDelta(t, 0.25, got.MaxShare, 1e-6)
+	require.InDelta(t, 0, got.CV, 1e-6)
+
+	// A down server's share counts toward the server taking its writes, and
+	// the CV is over the shares of the active servers: 1/2, 1/4, and 1/4
+	require.NoError(t, ring.MarkDown("server1"))
+	code, got = get()
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 3, got.Servers)
+	require.InDelta(t, 0.5, got.MaxShare, 1e-6)
+	require.InDelta(t, 100/math.Sqrt(8), got.CV, 1e-6)
+
+	// Down and draining servers don't count, though the layout is balanced
+	require.NoError(t, ring.SetState("server2", hashring.StateDraining))
+	require.NoError(t, ring.MarkDown("server3"))
+	code, got = get()
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, 1, got.Servers)
+	require.InDelta(t, 1, got.MaxShare, 1e-6)
+	require.Len(t, got.Failures, 2)
+	require.Contains(t, got.Failures[0], "1 servers, fewer than 2")
+
+	require.NoError(t, ring.SetState("server4", hashring.StateDraining))
+	code, got = get()
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.False(t, got.Healthy)
+	require.Zero(t, got.Servers)
+	require.Zero(t, got.MaxShare)
+}
+
+func TestAtEpochGivesUp(t *testing.T) {
+	ring := hashring.New(10)
+	s := New(ring)
+
+	// The topology changes during every attempt
+	attempts := 0
+	_, err := s.atEpoch(func() error {
+		attempts++
+		return ring.AddServer(fmt.Sprintf("server%d", attempts))
+	})
+	require.ErrorIs(t, err, errUnsettled)
+	require.Equal(t, maxEpochAttempts, attempts)
+
+	// It settles once changes stop
+	epoch, err := s.atEpoch(func() error {
+		attempts++
+		if attempts < maxEpochAttempts+3 {
+			return ring.AddServer(fmt.Sprintf("server%d", attempts))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, ring.Version(), epoch)
+}
+
+func TestProfile(t *testing.T) {
+	ring := hashring.New(50, hashring.WithLookupProfiling(1, 8))
+	require.NoError(t, ring.AddServer("server1"))
+	_, _ = ring.GetServer("user:42")
+	_, _ = ring.GetServer("user:43")
+
+	srv := httptest.NewServer(New(ring).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/profile")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got hashring.LookupProfile
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	require.Equal(t, 1, got.Rate)
+	require.Len(t, got.Samples, 2)
+}
@@ -0,0 +1,238 @@
+// Package assigner assigns the partitions of a stream or queue to consumers
+// using a consistent hash ring, as a lightweight alternative to Kafka
+// consumer-group rebalancing for custom queues.
+//
+// A single Coordinator tracks the consumers in the group. Each consumer is a
+// server on its ring and each partition a key, so when a consumer joins or
+// leaves only the partitions adjacent to it change hands; the rest keep
+// consuming without interruption.
+//
+// Every change bumps the group's generation, and each partition records the
+// generation it was last assigned at. Consumers send the generation they were
+// given with every commit, and the coordinator rejects commits from consumers
+// that no longer own a partition, or that own it under a newer generation
+// than they know of (see Coordinator.Validate). A consumer that hasn't yet
+// learned it lost a partition may still read from it, but can't commit, so
+// the partition's progress is only ever made by its current owner.
+package assigner
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultVirtualNodes is the number of virtual nodes placed for each consumer.
+const DefaultVirtualNodes = 150
+
+var (
+	// ErrNotOwner is returned by Validate for consumers acting on partitions
+	// they aren't assigned.
+	ErrNotOwner = errors.New("partition not assigned to consumer")
+	// ErrStaleGeneration is returned by Validate for requests made with an
+	// outdated assignment.
+	ErrStaleGeneration = errors.New("stale generation")
+)
+
+// Option configures a Coordinator.
+type Option func(*Coordinator)
+
+// WithVirtualNodes sets the number of virtual nodes placed for each consumer.
+// More give a more even split of the partitions.
+func WithVirtualNodes(n int) Option {
+	return func(c *Coordinator) {
+		c.vnodes = n
+	}
+}
+
+// Assignment is a consumer's share of the partitions.
+type Assignment struct {
+	Consumer   string `json:"consumer"`
+	Generation uint64 `json:"generation"`
+	Partitions []int  `json:"partitions"` // sorted
+}
+
+// Coordinator assigns partitions to the consumers in a group.
+//
+// It is safe for concurrent use.
+type Coordinator struct {
+	vnodes     int
+	partitions int
+
+	mu         sync.Mutex
+	ring       *hashring.HashRing
+	generation uint64
+	owners     []string // consumer of each partition, empty when there's none
+	assignedAt []uint64 // generation each partition was last assigned at
+}
+
+// NewCoordinator creates a coordinator for a group consuming partitions 0
+// through partitions-1.
+//
+// Example:
+//
+//	coord, err := assigner.NewCoordinator(12)
+//	assignment, err := coord.Join("consumer-a")
+//	for _, p := range assignment.Partitions {
+//		go consume(p, assignment.Generation)
+//	}
+func NewCoordinator(partitions int, opts ...Option) (*Coordinator, error) {
+	if partitions <= 0 {
+		return nil, fmt.Errorf("partition count must be positive, got %d", partitions)
+	}
+
+	c := &Coordinator{
+		vnodes:     DefaultVirtualNodes,
+		partitions: partitions,
+		owners:     make([]string, partitions),
+		assignedAt: make([]uint64, partitions),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// CRC32, the default hasher, clusters short numeric keys like partition
+	// numbers, so a few consumers would get most partitions
+	c.ring = hashring.New(c.vnodes, hashring.WithHasher(hashring.Murmur3))
+	return c, nil
+}
+
+// Join adds a consumer to the group and returns its assignment. Joining again
+// returns the consumer's current assignment without a rebalance.
+func (c *Coordinator) Join(consumer string) (Assignment, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if slices.Contains(c.ring.GetServers(), consumer) {
+		return c.assignment(consumer), nil
+	}
+
+	if err := c.ring.AddServer(consumer); err != nil {
+		return Assignment{}, err
+	}
+
+	c.reassign()
+	return c.assignment(consumer), nil
+}
+
+// Leave removes a consumer from the group, e.g. when it shuts down or stops
+// heartbeating. Its partitions move to the remaining consumers.
+func (c *Coordinator) Leave(consumer string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.ring.RemoveServer(consumer); err != nil {
+		return err
+	}
+
+	c.reassign()
+	return nil
+}
+
+// Assignment returns a consumer's current assignment. Consumers poll it, or
+// are told to after a rebalance, to learn about partitions they gained or
+// lost.
+func (c *Coordinator) Assignment(consumer string) Assignment {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.assignment(consumer)
+}
+
+// Assignments returns every consumer's assignment, sorted by consumer.
+func (c *Coordinator) Assignments() []Assignment {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var assignments []Assignment
+	for _, consumer := range c.ring.GetServers() {
+		assignments = append(assignments, c.assignment(consumer))
+	}
+
+	return assignments
+}
+
+// Generation returns the group's generation, which increases every time
+// consumers join or leave.
+func (c *Coordinator) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// Owner returns the consumer a partition is assigned to and the generation it
+// was assigned at. The consumer is empty if the group is empty.
+func (c *Coordinator) Owner(partition int) (string, uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if partition < 0 || partition >= c.partitions {
+		return "", 0, fmt.Errorf("partition %d out of range [0, %d)", partition, c.partitions)
+	}
+
+	return c.owners[partition], c.assignedAt[partition], nil
+}
+
+// Validate checks that consumer may act on partition, e.g. commit an offset,
+// with an assignment from the given generation: it must own the partition and
+// have been given it at or after the generation it was last assigned at.
+// Consumers holding an assignment from before a partition moved away and back
+// are rejected too, since another consumer may have owned it in between.
+//
+// Example:
+//
+//	if err := coord.Validate(req.Consumer, req.Partition, req.Generation); err != nil {
+//		return err // the consumer should fetch its assignment again
+//	}
+//	offsets.Commit(req.Partition, req.Offset)
+func (c *Coordinator) Validate(consumer string, partition int, generation uint64) error {
+	owner, assignedAt, err := c.Owner(partition)
+	if err != nil {
+		return err
+	}
+
+	if owner != consumer {
+		return fmt.Errorf("%w: partition %d is assigned to %q, not %q", ErrNotOwner, partition, owner, consumer)
+	}
+
+	if generation < assignedAt {
+		return fmt.Errorf("%w: partition %d was reassigned at generation %d, after %d", ErrStaleGeneration, partition, assignedAt, generation)
+	}
+
+	return nil
+}
+
+// reassign bumps the generation and recomputes the owner of each partition,
+// recording the generation of those that moved. The caller must hold c.mu.
+func (c *Coordinator) reassign() {
+	c.generation++
+	for p := range c.partitions {
+		owner, _, err := c.ring.Claim(strconv.Itoa(p))
+		if err != nil {
+			owner = ""
+		}
+
+		if owner != c.owners[p] {
+			c.owners[p] = owner
+			c.assignedAt[p] = c.generation
+		}
+	}
+}
+
+// assignment returns consumer's assignment. The caller must hold c.mu.
+func (c *Coordinator) assignment(consumer string) Assignment {
+	a := Assignment{Consumer: consumer, Generation: c.generation}
+	for p, owner := range c.owners {
+		if owner == consumer && consumer != "" {
+			a.Partitions = append(a.Partitions, p)
+		}
+	}
+
+	return a
+}
@@ -0,0 +1,109 @@
+package assigner
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// owners returns the owner of each partition.
+func owners(t *testing.T, c *Coordinator) []string {
+	t.Helper()
+
+	owners := make([]string, c.partitions)
+	for p := range owners {
+		owner, _, err := c.Owner(p)
+		require.NoError(t, err)
+		owners[p] = owner
+	}
+
+	return owners
+}
+
+func TestCoordinator(t *testing.T) {
+	_, err := NewCoordinator(0)
+	require.Error(t, err)
+
+	coord, err := NewCoordinator(64)
+	require.NoError(t, err)
+
+	a, err := coord.Join("consumer1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), a.Generation)
+	require.Len(t, a.Partitions, 64, "The only consumer should get every partition")
+
+	for i := 2; i <= 4; i++ {
+		_, err := coord.Join(fmt.Sprintf("consumer%d", i))
+		require.NoError(t, err)
+	}
+
+	// every partition is assigned exactly once
+	total := 0
+	for _, a := range coord.Assignments() {
+		require.NotEmpty(t, a.Partitions, a.Consumer)
+		require.Equal(t, uint64(4), a.Generation)
+		total += len(a.Partitions)
+	}
+	require.Equal(t, 64, total)
+
+	// joining again doesn't rebalance
+	again, err := coord.Join("consumer2")
+	require.NoError(t, err)
+	require.Equal(t, coord.Assignment("consumer2"), again)
+	require.Equal(t, uint64(4), coord.Generation())
+
+	// leaving only moves the leaver's partitions
+	before := owners(t, coord)
+	require.NoError(t, coord.Leave("consumer3"))
+	for p, owner := range owners(t, coord) {
+		if before[p] != "consumer3" {
+			require.Equal(t, before[p], owner, "partition %d moved", p)
+		}
+		require.NotEqual(t, "consumer3", owner)
+	}
+
+	require.Error(t, coord.Leave("consumer3"))
+	require.Empty(t, coord.Assignment("consumer3").Partitions)
+	_, _, err = coord.Owner(64)
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	coord, err := NewCoordinator(16)
+	require.NoError(t, err)
+
+	first, err := coord.Join("consumer1")
+	require.NoError(t, err)
+	_, err = coord.Join("consumer2")
+	require.NoError(t, err)
+
+	for p := range 16 {
+		owner, assignedAt, err := coord.Owner(p)
+		require.NoError(t, err)
+
+		if owner == "consumer1" {
+			// kept through the rebalance, so the older generation still works
+			require.NoError(t, coord.Validate("consumer1", p, first.Generation))
+			require.Equal(t, first.Generation, assignedAt)
+			continue
+		}
+
+		// moved to consumer2, so consumer1 is fenced out
+		require.ErrorIs(t, coord.Validate("consumer1", p, first.Generation), ErrNotOwner)
+		require.NoError(t, coord.Validate("consumer2", p, coord.Generation()))
+	}
+
+	// consumer1 gets everything back, but its first assignment is stale for the
+	// partitions consumer2 owned in between
+	require.NoError(t, coord.Leave("consumer2"))
+	stale := 0
+	for p := range 16 {
+		if err := coord.Validate("consumer1", p, first.Generation); err != nil {
+			require.ErrorIs(t, err, ErrStaleGeneration)
+			stale++
+		}
+		require.NoError(t, coord.Validate("consumer1", p, coord.Generation()))
+	}
+	require.Positive(t, stale)
+}
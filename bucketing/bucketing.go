@@ -0,0 +1,170 @@
+// Package bucketing assigns identifiers, such as user IDs, to weighted
+// buckets, such as the arms of an A/B experiment, using a consistent hash
+// ring.
+//
+// Each bucket is placed on the ring with virtual nodes in proportion to its
+// weight, so a bucket's share of identifiers tracks its share of the total
+// weight. Changing one bucket's weight only adds or removes that bucket's own
+// virtual nodes, so only identifiers moving into or out of that bucket change
+// assignment: growing a treatment from 10% to 20% keeps everyone already in it
+// and takes the newcomers from the other buckets.
+package bucketing
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"sync"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultResolution is the number of virtual nodes per unit of weight.
+const DefaultResolution = 100
+
+// Bucket is a named bucket and its weight, relative to the other buckets'.
+type Bucket struct {
+	Name   string
+	Weight float64
+}
+
+// Option configures a Bucketer.
+type Option func(*Bucketer)
+
+// WithSalt sets a salt prepended to identifiers before hashing, so separate
+// experiments bucket the same identifiers independently.
+func WithSalt(salt string) Option {
+	return func(b *Bucketer) {
+		b.salt = salt
+	}
+}
+
+// WithResolution sets the number of virtual nodes placed per unit of weight.
+// Higher resolutions make shares track weights more closely at the cost of
+// memory; with weights given as percentages, the default places 10,000
+// virtual nodes, which keeps shares within about half a point of their
+// weights.
+func WithResolution(n int) Option {
+	return func(b *Bucketer) {
+		b.resolution = n
+	}
+}
+
+// Bucketer assigns identifiers to buckets.
+//
+// It is safe for concurrent use.
+type Bucketer struct {
+	salt       string
+	resolution int
+	ring       *hashring.HashRing
+
+	mu      sync.Mutex
+	weights map[string]float64
+}
+
+// New creates a bucketer with the given buckets. Weights are relative, e.g.
+// 90 and 10 for a 90/10 split; buckets with zero weight get no identifiers
+// until their weight is raised.
+//
+// Returns an error if a name is repeated, a weight is negative, or no bucket
+// has a positive weight.
+//
+// Example:
+//
+//	b, err := bucketing.New([]bucketing.Bucket{
+//		{Name: "control", Weight: 90},
+//		{Name: "treatment", Weight: 10},
+//	}, bucketing.WithSalt("checkout-redesign"))
+//	if err != nil {
+//		return err
+//	}
+//
+//	if b.Bucket(userID) == "treatment" {
+//		renderNewCheckout(w)
+//	}
+func New(buckets []Bucket, opts ...Option) (*Bucketer, error) {
+	b := &Bucketer{
+		resolution: DefaultResolution,
+		weights:    make(map[string]float64, len(buckets)),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.ring = hashring.New(b.resolution, hashring.WithHasher(hashring.Murmur3))
+	for _, bkt := range buckets {
+		if _, ok := b.weights[bkt.Name]; ok {
+			return nil, fmt.Errorf("duplicate bucket %s", bkt.Name)
+		}
+
+		if err := b.setWeight(bkt.Name, bkt.Weight); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.ring.Size() == 0 {
+		return nil, fmt.Errorf("no bucket has a positive weight")
+	}
+
+	return b, nil
+}
+
+// Bucket returns the bucket id is assigned to.
+func (b *Bucketer) Bucket(id string) string {
+	// The ring is never empty, so this can't fail
+	bucket, _ := b.ring.GetServer(b.salt + id)
+	return bucket
+}
+
+// SetWeight changes a bucket's weight, adding the bucket if it's new. Only
+// identifiers moving into or out of the bucket change assignment.
+//
+// Returns an error if the weight is negative or it would leave no bucket with
+// a positive weight.
+func (b *Bucketer) SetWeight(name string, weight float64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if weight == 0 && b.weights[name] > 0 && b.ring.Size() == 1 {
+		return fmt.Errorf("bucket %s: can't remove the last bucket with a positive weight", name)
+	}
+
+	return b.setWeight(name, weight)
+}
+
+// Weights returns the weight of each bucket.
+func (b *Bucketer) Weights() map[string]float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return maps.Clone(b.weights)
+}
+
+// setWeight places the bucket on the ring for weight. Buckets with zero
+// weight are kept off the ring; since a bucket's virtual nodes are derived
+// from its name, re-adding one restores its old positions. The caller must
+// hold b.mu, except during New.
+func (b *Bucketer) setWeight(name string, weight float64) error {
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return fmt.Errorf("bucket %s: invalid weight %v", name, weight)
+	}
+
+	prev := b.weights[name]
+	var err error
+	switch {
+	case weight == 0 && prev > 0:
+		err = b.ring.RemoveServer(name)
+	case weight > 0 && prev == 0:
+		err = b.ring.AddServerWithInfo(hashring.ServerInfo{Name: name, Weight: weight})
+	case weight > 0:
+		err = b.ring.SetWeight(name, weight)
+	}
+
+	if err != nil {
+		return fmt.Errorf("bucket %s: %w", name, err)
+	}
+
+	b.weights[name] = weight
+	return nil
+}
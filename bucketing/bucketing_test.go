@@ -0,0 +1,98 @@
+package bucketing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// shares returns the fraction of n identifiers assigned to each bucket, and
+// each identifier's bucket.
+func shares(b *Bucketer, n int) (map[string]float64, []string) {
+	counts := make(map[string]float64)
+	assigned := make([]string, n)
+	for i := range n {
+		assigned[i] = b.Bucket(fmt.Sprintf("user%d", i))
+		counts[assigned[i]]++
+	}
+
+	for name := range counts {
+		counts[name] /= float64(n)
+	}
+
+	return counts, assigned
+}
+
+func TestBucketer(t *testing.T) {
+	b, err := New([]Bucket{{Name: "control", Weight: 90}, {Name: "treatment", Weight: 10}})
+	require.NoError(t, err)
+
+	got, before := shares(b, 100000)
+	require.InDelta(t, 0.9, got["control"], 0.015)
+	require.InDelta(t, 0.1, got["treatment"], 0.015)
+
+	// growing the treatment keeps everyone already in it
+	require.NoError(t, b.SetWeight("treatment", 20))
+	got, after := shares(b, 100000)
+	require.InDelta(t, 90.0/110, got["control"], 0.015)
+	for i := range before {
+		if before[i] == "treatment" {
+			require.Equal(t, "treatment", after[i])
+		}
+	}
+
+	// and shrinking it back restores the original assignments
+	require.NoError(t, b.SetWeight("treatment", 10))
+	_, restored := shares(b, 100000)
+	require.Equal(t, before, restored)
+
+	require.Equal(t, map[string]float64{"control": 90, "treatment": 10}, b.Weights())
+}
+
+func TestBucketerZeroWeight(t *testing.T) {
+	b, err := New([]Bucket{{Name: "a", Weight: 1}, {Name: "b"}})
+	require.NoError(t, err)
+
+	got, before := shares(b, 1000)
+	require.Equal(t, map[string]float64{"a": 1}, got)
+
+	require.NoError(t, b.SetWeight("b", 1))
+	require.NoError(t, b.SetWeight("c", 1))
+	got, _ = shares(b, 1000)
+	require.Len(t, got, 3)
+
+	require.NoError(t, b.SetWeight("b", 0))
+	require.NoError(t, b.SetWeight("c", 0))
+	_, after := shares(b, 1000)
+	require.Equal(t, before, after)
+
+	require.Error(t, b.SetWeight("a", 0))
+	require.Error(t, b.SetWeight("a", -1))
+}
+
+func TestBucketerSalt(t *testing.T) {
+	buckets := []Bucket{{Name: "a", Weight: 50}, {Name: "b", Weight: 50}}
+	exp1, err := New(buckets, WithSalt("exp1"))
+	require.NoError(t, err)
+	exp2, err := New(buckets, WithSalt("exp2"))
+	require.NoError(t, err)
+
+	_, assigned1 := shares(exp1, 1000)
+	_, assigned2 := shares(exp2, 1000)
+	require.NotEqual(t, assigned1, assigned2)
+}
+
+func TestNewErrors(t *testing.T) {
+	_, err := New(nil)
+	require.Error(t, err)
+
+	_, err = New([]Bucket{{Name: "a"}})
+	require.Error(t, err)
+
+	_, err = New([]Bucket{{Name: "a", Weight: 1}, {Name: "a", Weight: 2}})
+	require.Error(t, err)
+
+	_, err = New([]Bucket{{Name: "a", Weight: -1}})
+	require.Error(t, err)
+}
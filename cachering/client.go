@@ -0,0 +1,237 @@
+// Package cachering is a distributed cache client that spreads keys across
+// cache nodes using a consistent hash ring.
+//
+// The client is transport agnostic: connections to nodes are created by a
+// Dialer, so any cache protocol can be plugged in. Each node gets its own
+// connection pool, every operation is bounded by a timeout, and when a node is
+// removed from the ring its keys are routed to the remaining nodes and its
+// pool is closed.
+package cachering
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+const (
+	// DefaultTimeout bounds each cache operation.
+	DefaultTimeout = 100 * time.Millisecond
+
+	// DefaultMaxIdle is the number of idle connections kept per node.
+	DefaultMaxIdle = 4
+)
+
+// ErrNotFound is returned by Get when a key isn't in the cache. Conn
+// implementations must return it (or wrap it) for cache misses.
+var ErrNotFound = errors.New("cachering: key not found")
+
+// Conn is a connection to a single cache node.
+//
+// A Conn is only used by one goroutine at a time. Any error other than
+// ErrNotFound is assumed to leave the connection unusable, and it's closed.
+type Conn interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Close() error
+}
+
+// Dialer opens a connection to a cache node.
+type Dialer func(ctx context.Context, node string) (Conn, error)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithTimeout sets the timeout for each operation, including dialing. Zero
+// disables the timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithMaxIdle sets the number of idle connections kept per node.
+func WithMaxIdle(n int) Option {
+	return func(c *Client) {
+		c.maxIdle = max(n, 0)
+	}
+}
+
+// WithFallback makes Get try up to n of the next nodes clockwise around the
+// ring when the owner fails (a miss isn't a failure). This helps when values
+// are replicated to successors, or to ride out a node outage during a
+// rebalance.
+func WithFallback(n int) Option {
+	return func(c *Client) {
+		c.fallback = max(n, 0)
+	}
+}
+
+// Client routes cache operations to the nodes in a hash ring.
+//
+// It is safe for concurrent use.
+type Client struct {
+	ring     *hashring.HashRing
+	dial     Dialer
+	timeout  time.Duration
+	maxIdle  int
+	fallback int
+
+	mu      sync.Mutex
+	version uint64
+	pools   map[string]*pool
+}
+
+// New creates a cache client over the nodes in ring, connecting to them with
+// dial.
+//
+// Example:
+//
+//	ring := hashring.New(150)
+//	ring.AddServer("cache-1:7000")
+//	ring.AddServer("cache-2:7000")
+//
+//	cache := cachering.New(ring, dialMyProtocol, cachering.WithTimeout(50*time.Millisecond))
+//	defer cache.Close()
+//
+//	if err := cache.Set(ctx, "user:42", data, time.Hour); err != nil {
+//		log.Printf("cache set failed: %v", err)
+//	}
+func New(ring *hashring.HashRing, dial Dialer, opts ...Option) *Client {
+	c := &Client{
+		ring:    ring,
+		dial:    dial,
+		timeout: DefaultTimeout,
+		maxIdle: DefaultMaxIdle,
+		pools:   make(map[string]*pool),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Get returns the value of key from the node that owns it, or ErrNotFound.
+//
+// With WithFallback, nodes following the owner are tried when it fails.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	get := func(ctx context.Context, conn Conn) (err error) {
+		value, err = conn.Get(ctx, key)
+		return err
+	}
+
+	if c.fallback == 0 {
+		err := c.do(ctx, key, get)
+		return value, err
+	}
+
+	var (
+		version = c.ring.Version()
+		missed  bool
+	)
+
+	_, err := c.ring.GetWithFallback(key, c.fallback, func(node string) error {
+		err := c.doNode(ctx, c.pool(node, version), get)
+		if errors.Is(err, ErrNotFound) {
+			missed = true
+			return nil
+		}
+
+		return err
+	})
+
+	if err == nil && missed {
+		err = ErrNotFound
+	}
+
+	return value, err
+}
+
+// Set stores value under key on the node that owns it. A zero ttl means the
+// value doesn't expire.
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.do(ctx, key, func(ctx context.Context, conn Conn) error {
+		return conn.Set(ctx, key, value, ttl)
+	})
+}
+
+// Delete removes key from the node that owns it.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	return c.do(ctx, key, func(ctx context.Context, conn Conn) error {
+		return conn.Delete(ctx, key)
+	})
+}
+
+// Close closes every pooled connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for node, p := range c.pools {
+		errs = append(errs, p.close())
+		delete(c.pools, node)
+	}
+
+	return errors.Join(errs...)
+}
+
+// do runs fn with a connection to the node that owns key.
+func (c *Client) do(ctx context.Context, key string, fn func(context.Context, Conn) error) error {
+	node, version, err := c.ring.GetServerVersioned(key)
+	if err != nil {
+		return err
+	}
+
+	return c.doNode(ctx, c.pool(node, version), fn)
+}
+
+// doNode runs fn with a connection from p, bounded by the client's timeout.
+func (c *Client) doNode(ctx context.Context, p *pool, fn func(context.Context, Conn) error) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	conn, err := p.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(ctx, conn)
+	p.put(conn, err)
+	return err
+}
+
+// pool returns the (cached) pool for node. When the ring has changed since the
+// last call, pools for nodes that left the ring are closed.
+func (c *Client) pool(node string, version uint64) *pool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version != c.version {
+		c.version = version
+		for n, p := range c.pools {
+			if _, ok := c.ring.GetServerInfo(n); !ok {
+				_ = p.close()
+				delete(c.pools, n)
+			}
+		}
+	}
+
+	p, ok := c.pools[node]
+	if !ok {
+		p = newPool(node, c.dial, c.maxIdle)
+		c.pools[node] = p
+	}
+
+	return p
+}
@@ -0,0 +1,258 @@
+package cachering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode is an in-memory cache node.
+type fakeNode struct {
+	mu     sync.Mutex
+	items  map[string][]byte
+	dials  int
+	open   int
+	broken bool // operations fail
+	slow   bool // operations block until the context is done
+}
+
+type fakeConn struct {
+	node *fakeNode
+}
+
+func (c *fakeConn) op(ctx context.Context, fn func()) error {
+	n := c.node
+	n.mu.Lock()
+	broken, slow := n.broken, n.slow
+	n.mu.Unlock()
+
+	if slow {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	if broken {
+		return errors.New("connection reset")
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	fn()
+	return nil
+}
+
+func (c *fakeConn) Get(ctx context.Context, key string) ([]byte, error) {
+	var (
+		value []byte
+		ok    bool
+	)
+
+	if err := c.op(ctx, func() { value, ok = c.node.items[key] }); err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return value, nil
+}
+
+func (c *fakeConn) Set(ctx context.Context, key string, value []byte, _ time.Duration) error {
+	return c.op(ctx, func() { c.node.items[key] = value })
+}
+
+func (c *fakeConn) Delete(ctx context.Context, key string) error {
+	return c.op(ctx, func() { delete(c.node.items, key) })
+}
+
+func (c *fakeConn) Close() error {
+	c.node.mu.Lock()
+	defer c.node.mu.Unlock()
+	c.node.open--
+	return nil
+}
+
+func setup(t *testing.T, names ...string) (*hashring.HashRing, map[string]*fakeNode, Dialer) {
+	t.Helper()
+
+	ring := hashring.New(50)
+	nodes := make(map[string]*fakeNode)
+	for _, name := range names {
+		nodes[name] = &fakeNode{items: make(map[string][]byte)}
+		require.NoError(t, ring.AddServer(name))
+	}
+
+	dial := func(_ context.Context, name string) (Conn, error) {
+		node, ok := nodes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown node %s", name)
+		}
+
+		node.mu.Lock()
+		defer node.mu.Unlock()
+		node.dials++
+		node.open++
+		return &fakeConn{node: node}, nil
+	}
+
+	return ring, nodes, dial
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	ring, nodes, dial := setup(t, "cache-1", "cache-2", "cache-3")
+
+	c := New(ring, dial)
+	defer func() { require.NoError(t, c.Close()) }()
+
+	for i := range 20 {
+		key := fmt.Sprintf("user:%d", i)
+		require.NoError(t, c.Set(ctx, key, []byte(key), time.Minute))
+
+		// The value lives only on the owning node
+		owner, err := ring.GetServer(key)
+		require.NoError(t, err)
+
+		for name, node := range nodes {
+			_, ok := node.items[key]
+			require.Equal(t, name == owner, ok, "key %s on %s", key, name)
+		}
+
+		value, err := c.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, key, string(value))
+	}
+
+	require.NoError(t, c.Delete(ctx, "user:0"))
+	_, err := c.Get(ctx, "user:0")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Connections are reused
+	for _, node := range nodes {
+		require.Equal(t, 1, node.dials)
+	}
+
+	_, err = New(hashring.New(10), dial).Get(ctx, "key")
+	require.Error(t, err)
+}
+
+func TestClientBrokenConnections(t *testing.T) {
+	ctx := context.Background()
+	ring, nodes, dial := setup(t, "cache-1")
+
+	c := New(ring, dial)
+	defer func() { require.NoError(t, c.Close()) }()
+
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), 0))
+
+	nodes["cache-1"].broken = true
+	require.Error(t, c.Set(ctx, "key", []byte("value"), 0))
+	require.Zero(t, nodes["cache-1"].open, "failed connections should be closed")
+
+	nodes["cache-1"].broken = false
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), 0))
+	require.Equal(t, 2, nodes["cache-1"].dials)
+}
+
+func TestClientTimeout(t *testing.T) {
+	ctx := context.Background()
+	ring, nodes, dial := setup(t, "cache-1")
+	nodes["cache-1"].slow = true
+
+	c := New(ring, dial, WithTimeout(10*time.Millisecond))
+	defer func() { require.NoError(t, c.Close()) }()
+
+	_, err := c.Get(ctx, "key")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestClientNodeRemoval(t *testing.T) {
+	ctx := context.Background()
+	ring, nodes, dial := setup(t, "cache-1", "cache-2")
+
+	c := New(ring, dial)
+	defer func() { require.NoError(t, c.Close()) }()
+
+	owner, err := ring.GetServer("key")
+	require.NoError(t, err)
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), 0))
+
+	// Keys owned by the removed node are re-routed and its pool is closed
+	require.NoError(t, ring.RemoveServer(owner))
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), 0))
+	require.Zero(t, nodes[owner].open)
+
+	for name, node := range nodes {
+		if name != owner {
+			require.Contains(t, node.items, "key")
+		}
+	}
+}
+
+func TestPoolMaxIdle(t *testing.T) {
+	ctx := context.Background()
+	_, nodes, dial := setup(t, "cache-1")
+
+	p := newPool("cache-1", dial, 1)
+
+	conn1, err := p.get(ctx)
+	require.NoError(t, err)
+	conn2, err := p.get(ctx)
+	require.NoError(t, err)
+
+	p.put(conn1, nil)
+	p.put(conn2, ErrNotFound)
+	require.Equal(t, 1, nodes["cache-1"].open, "connections beyond the idle limit should be closed")
+
+	require.NoError(t, p.close())
+	require.Zero(t, nodes["cache-1"].open)
+
+	conn, err := p.get(ctx)
+	require.NoError(t, err)
+	p.put(conn, nil)
+	require.Zero(t, nodes["cache-1"].open, "connections returned to a closed pool should be closed")
+}
+
+func TestClientFallback(t *testing.T) {
+	ctx := context.Background()
+	ring, nodes, dial := setup(t, "cache-1", "cache-2", "cache-3")
+
+	replicas, err := ring.GetReplicas("key", 3)
+	require.NoError(t, err)
+
+	// The value is replicated to the owner's successor
+	nodes[replicas[1]].items["key"] = []byte("value")
+	nodes[replicas[0]].broken = true
+
+	c := New(ring, dial)
+	defer func() { require.NoError(t, c.Close()) }()
+
+	_, err = c.Get(ctx, "key")
+	require.Error(t, err, "the owner's failure should be returned without fallback")
+
+	c = New(ring, dial, WithFallback(1))
+	defer func() { require.NoError(t, c.Close()) }()
+
+	value, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, "value", string(value))
+
+	// Misses don't fall back
+	nodes[replicas[0]].broken = false
+	_, err = c.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Every candidate failing returns their errors
+	nodes[replicas[0]].broken = true
+	nodes[replicas[1]].broken = true
+	_, err = c.Get(ctx, "key")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,72 @@
+package cachering
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// pool holds idle connections to a single node.
+type pool struct {
+	node string
+	dial Dialer
+
+	mu     sync.Mutex
+	idle   []Conn
+	max    int
+	closed bool
+}
+
+func newPool(node string, dial Dialer, maxIdle int) *pool {
+	return &pool{node: node, dial: dial, max: maxIdle}
+}
+
+// get returns an idle connection, or dials a new one.
+func (p *pool) get(ctx context.Context) (Conn, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.mu.Unlock()
+
+	return p.dial(ctx, p.node)
+}
+
+// put returns conn to the pool after an operation that returned err. The
+// connection is closed instead if it failed, the pool is full, or the pool is
+// closed.
+func (p *pool) put(conn Conn, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		_ = conn.Close()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(p.idle) >= p.max {
+		_ = conn.Close()
+		return
+	}
+
+	p.idle = append(p.idle, conn)
+}
+
+// close closes every idle connection. Connections in use are closed when
+// they're returned.
+func (p *pool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var errs []error
+	for _, conn := range p.idle {
+		errs = append(errs, conn.Close())
+	}
+
+	p.idle = nil
+	p.closed = true
+	return errors.Join(errs...)
+}
@@ -75,10 +75,9 @@ func main() {
 	fmt.Println("\n--- Adding a new server (server-D) ---")
 
 	// Track which keys move
-	oldMapping := make(map[string]string)
-	for _, key := range keys {
-		server, _ := ring.GetServer(key)
-		oldMapping[key] = server
+	before, err := hashring.Restore(ring.Snapshot())
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	if err := ring.AddServer("server-D"); err != nil {
@@ -86,18 +85,24 @@ func main() {
 	}
 	fmt.Println("  ✓ Added server-D")
 
-	moved := 0
-	for _, key := range keys {
-		newServer, _ := ring.GetServer(key)
-		if newServer != oldMapping[key] {
-			moved++
-		}
-	}
-
-	fmt.Printf("\nKeys that moved: %d out of %d (%.2f%%)\n", moved, len(keys), float64(moved)/float64(len(keys))*100)
+	report := hashring.MovedKeys(before, ring, keys)
+	fmt.Printf("\nKeys that moved: %d out of %d (%.2f%%)\n", report.Moved(), report.Total, report.Fraction()*100)
 	expectedMove := float64(len(keys)) / float64(len(servers)+1)
 	fmt.Printf("Expected keys to move: ~%.0f (%.2f%%)\n", expectedMove, expectedMove/float64(len(keys))*100)
 
+	// Every moved key should have gone to server-D, not between existing servers
+	violations, err := hashring.VerifyMonotonic(before, keys, func(r *hashring.HashRing) error {
+		return r.AddServer("server-D")
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(violations) == 0 {
+		fmt.Println("  ✓ Only keys moving to server-D moved")
+	} else {
+		fmt.Printf("  ⚠ %d keys moved between existing servers\n", len(violations))
+	}
+
 	// Show new distribution
 	fmt.Println("\nNew key distribution:")
 	distribution = ring.GetDistribution(keys)
@@ -110,10 +115,9 @@ func main() {
 	fmt.Println("\n--- Removing a server (server-B) ---")
 
 	// Track which keys move
-	oldMapping = make(map[string]string)
-	for _, key := range keys {
-		server, _ := ring.GetServer(key)
-		oldMapping[key] = server
+	before, err = hashring.Restore(ring.Snapshot())
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	if err := ring.RemoveServer("server-B"); err != nil {
@@ -121,19 +125,10 @@ func main() {
 	}
 	fmt.Println("  ✓ Removed server-B")
 
-	moved = 0
-	movedTo := make(map[string]int)
-	for _, key := range keys {
-		newServer, _ := ring.GetServer(key)
-		if newServer != oldMapping[key] {
-			moved++
-			movedTo[newServer]++
-		}
-	}
-
-	fmt.Printf("\nKeys that moved: %d out of %d (%.2f%%)\n", moved, len(keys), float64(moved)/float64(len(keys))*100)
+	report = hashring.MovedKeys(before, ring, keys)
+	fmt.Printf("\nKeys that moved: %d out of %d (%.2f%%)\n", report.Moved(), report.Total, report.Fraction()*100)
 	fmt.Println("\nKeys moved to:")
-	for server, count := range movedTo {
+	for server, count := range report.MovedTo() {
 		fmt.Printf("  %s: %d keys\n", server, count)
 	}
 
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/pseudomuto/hashlab/topology"
+)
+
+// runAnalyze implements "hashlab analyze".
+func runAnalyze(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("analyze [flags]", "Reports how a sample of real keys distributes across the servers in a topology\nfile, so analysis uses production key shapes rather than synthetic ones.", stderr)
+
+	path := fs.String("topology", "", "topology file to analyze (required)")
+	keys := keyFlags(fs, "-")
+	format := fs.String("format", "text", "output format: text or csv")
+	sortBy := fs.String("sort", "name", "order of servers in text output: name or count")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case fs.NArg() > 0:
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	case *path == "":
+		return errors.New("-topology is required")
+	case *format != "text" && *format != "csv":
+		return fmt.Errorf("unknown format %q", *format)
+	case *sortBy != "name" && *sortBy != "count":
+		return fmt.Errorf("unknown sort order %q", *sortBy)
+	}
+
+	topo, err := topology.Load(*path)
+	if err != nil {
+		return err
+	}
+
+	ring, err := topo.Ring()
+	if err != nil {
+		return fmt.Errorf("%s: %w", *path, err)
+	}
+
+	sample, err := keys.read(stdin)
+	if err != nil {
+		return err
+	}
+
+	metrics := ring.AnalyzePerformance(sample)
+	if *format == "csv" {
+		return metrics.WriteCSV(stdout)
+	}
+
+	var opts []hashring.PrintOption
+	if *sortBy == "count" {
+		opts = append(opts, hashring.SortByCount())
+	}
+
+	fmt.Fprintf(stdout, "Topology: %s (%d servers)\n", *path, ring.Size())
+	metrics.Fprint(stdout, opts...)
+	return nil
+}
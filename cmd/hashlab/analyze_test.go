@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFile writes data to name in a temporary directory, returning its path.
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestAnalyze(t *testing.T) {
+	topo := writeFile(t, "ring.yaml", "servers: [{name: cache-1}, {name: cache-2}]\n")
+
+	var out bytes.Buffer
+	stdin := strings.NewReader("user:1\nuser:2\n\nuser:3\nuser:1\n")
+	require.NoError(t, run([]string{"analyze", "-topology", topo}, stdin, &out, io.Discard))
+	require.Contains(t, out.String(), "(2 servers)")
+	require.Contains(t, out.String(), "Total Keys: 4")
+	require.Contains(t, out.String(), "Virtual Nodes: 150 per server")
+	require.Regexp(t, `(?s)cache-1: .*cache-2: `, out.String())
+
+	out.Reset()
+	stdin = strings.NewReader("user:1\nuser:2\nuser:3\nuser:4\nuser:5\n")
+	require.NoError(t, run([]string{"analyze", "-topology", topo, "-sort", "count"}, stdin, &out, io.Discard))
+	_, dist, _ := strings.Cut(out.String(), "Key Distribution:\n")
+	first, second, _ := strings.Cut(dist, "\n")
+	require.GreaterOrEqual(t, keyCount(t, first), keyCount(t, second))
+
+	keys := writeFile(t, "keys.jsonl", `{"key": "user:1"}`+"\n"+`"user:2"`+"\n")
+	out.Reset()
+	require.NoError(t, run([]string{"analyze", "-topology", topo, "-keys", keys, "-format", "csv"}, nil, &out, io.Discard))
+	require.True(t, strings.HasPrefix(out.String(), "server,keys,percent,expected_percent,delta\n"))
+	require.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 3)
+}
+
+func TestAnalyzeInvalid(t *testing.T) {
+	topo := writeFile(t, "ring.yaml", "servers: [{name: cache-1}]\n")
+	for _, args := range [][]string{
+		{},
+		{"-topology", filepath.Join(t.TempDir(), "missing.yaml")},
+		{"-topology", topo, "-format", "xml"},
+		{"-topology", topo, "-sort", "size"},
+		{"-topology", topo, "-input", "csv"},
+	} {
+		err := run(append([]string{"analyze"}, args...), strings.NewReader("key\n"), io.Discard, io.Discard)
+		require.Error(t, err, args)
+	}
+
+	// no keys at all
+	err := run([]string{"analyze", "-topology", topo}, strings.NewReader("\n"), io.Discard, io.Discard)
+	require.ErrorContains(t, err, "no keys")
+}
+
+func TestReadKeys(t *testing.T) {
+	keys, err := readKeys(strings.NewReader("a\r\nb c\n\n"), "lines", "key")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b c"}, keys)
+
+	keys, err = readKeys(strings.NewReader(`{"id": "x", "n": 1}`+"\n"+`"y"`), "jsonl", "id")
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "y"}, keys)
+
+	_, err = readKeys(strings.NewReader(`"ok"`+"\n"+`{"id": 1}`), "jsonl", "id")
+	require.ErrorContains(t, err, `line 2: no string field "id"`)
+	_, err = readKeys(strings.NewReader("[1]"), "jsonl", "key")
+	require.ErrorContains(t, err, "expected a string or an object")
+}
+
+// keyCount returns the key count on a line of a report's key distribution.
+func keyCount(t *testing.T, line string) int {
+	t.Helper()
+
+	var server string
+	var count int
+	_, err := fmt.Sscanf(strings.TrimSpace(line), "%s %d keys", &server, &count)
+	require.NoError(t, err, line)
+	return count
+}
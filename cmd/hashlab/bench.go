@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// benchOps are the operations bench can measure, in the order they're run.
+var benchOps = []string{"lookup", "add", "remove"}
+
+// benchConfig is the configuration of a bench run.
+type benchConfig struct {
+	keys        countFlag
+	servers     int
+	vnodes      int
+	concurrency int
+	hasher      hashring.Hasher
+}
+
+// benchResult is the result of benchmarking one operation.
+type benchResult struct {
+	op string
+	testing.BenchmarkResult
+}
+
+// runBench implements "hashlab bench".
+func runBench(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("bench [flags]", "Benchmarks ring lookups and membership changes, printing results as a table or\nin the format benchstat reads.", stderr)
+
+	var cfg benchConfig
+	cfg.keys = 100_000
+	fs.Var(&cfg.keys, "keys", "number of distinct keys to look up, e.g. 100k or 1M")
+	fs.IntVar(&cfg.servers, "servers", 10, "number of servers in the ring")
+	fs.IntVar(&cfg.vnodes, "vnodes", 150, "number of virtual nodes per server")
+	fs.IntVar(&cfg.concurrency, "concurrency", 1, "number of goroutines looking up keys at once")
+	hasher := hasherFlag(fs)
+	ops := fs.String("ops", strings.Join(benchOps, ","), "comma-separated operations to benchmark: "+strings.Join(benchOps, ", "))
+	benchtime := fs.Duration("benchtime", time.Second, "how long to run each benchmark")
+	count := fs.Int("count", 1, "number of times to run each benchmark, for benchstat")
+	format := fs.String("format", "text", "output format: text or benchstat")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	switch {
+	case cfg.keys <= 0:
+		return fmt.Errorf("-keys must be positive, got %d", cfg.keys)
+	case cfg.servers <= 0:
+		return fmt.Errorf("-servers must be positive, got %d", cfg.servers)
+	case cfg.vnodes <= 0:
+		return fmt.Errorf("-vnodes must be positive, got %d", cfg.vnodes)
+	case cfg.concurrency <= 0:
+		return fmt.Errorf("-concurrency must be positive, got %d", cfg.concurrency)
+	case *benchtime <= 0:
+		return fmt.Errorf("-benchtime must be positive, got %s", *benchtime)
+	case *count <= 0:
+		return fmt.Errorf("-count must be positive, got %d", *count)
+	case *format != "text" && *format != "benchstat":
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var err error
+	if cfg.hasher, err = lookupHasher(*hasher); err != nil {
+		return err
+	}
+
+	selected := strings.Split(*ops, ",")
+	for _, op := range selected {
+		if !slices.Contains(benchOps, op) {
+			return fmt.Errorf("unknown operation %q", op)
+		}
+	}
+
+	if err := setBenchtime(*benchtime); err != nil {
+		return err
+	}
+
+	var results []benchResult
+	for _, op := range benchOps {
+		if !slices.Contains(selected, op) {
+			continue
+		}
+
+		for range *count {
+			results = append(results, benchResult{op: op, BenchmarkResult: cfg.run(op)})
+		}
+	}
+
+	if *format == "benchstat" {
+		return writeBenchstat(stdout, cfg, results)
+	}
+
+	return writeBenchText(stdout, cfg, results)
+}
+
+// setBenchtime sets how long testing.Benchmark runs each benchmark, which is
+// only configurable through the testing package's flags.
+func setBenchtime(d time.Duration) error {
+	if flag.Lookup("test.benchtime") == nil {
+		testing.Init()
+	}
+
+	return flag.Set("test.benchtime", d.String())
+}
+
+// run benchmarks op.
+func (cfg benchConfig) run(op string) testing.BenchmarkResult {
+	switch op {
+	case "add":
+		return testing.Benchmark(cfg.benchAdd)
+	case "remove":
+		return testing.Benchmark(cfg.benchRemove)
+	default:
+		return testing.Benchmark(cfg.benchLookup)
+	}
+}
+
+// ring returns a ring with the configured servers.
+func (cfg benchConfig) ring() *hashring.HashRing {
+	ring := hashring.New(cfg.vnodes, hashring.WithHasher(cfg.hasher))
+	for i := range cfg.servers {
+		if err := ring.AddServer(fmt.Sprintf("server-%d", i)); err != nil {
+			panic(err)
+		}
+	}
+
+	return ring
+}
+
+// benchLookup measures GetServer, split across the configured number of
+// goroutines.
+func (cfg benchConfig) benchLookup(b *testing.B) {
+	ring := cfg.ring()
+	keys := make([]string, cfg.keys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var wg sync.WaitGroup
+	per := (b.N + cfg.concurrency - 1) / cfg.concurrency
+	for w := range cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w * per; i < min(b.N, (w+1)*per); i++ {
+				_, _ = ring.GetServer(keys[i%len(keys)])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// benchAdd measures adding a server to the ring, removing it again outside
+// the timer.
+func (cfg benchConfig) benchAdd(b *testing.B) {
+	ring := cfg.ring()
+	b.ReportAllocs()
+
+	for b.Loop() {
+		if err := ring.AddServer("extra"); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		_ = ring.RemoveServer("extra")
+		b.StartTimer()
+	}
+}
+
+// benchRemove measures removing a server from the ring, adding it back
+// outside the timer.
+func (cfg benchConfig) benchRemove(b *testing.B) {
+	ring := cfg.ring()
+	b.ReportAllocs()
+
+	for b.Loop() {
+		b.StopTimer()
+		_ = ring.AddServer("extra")
+		b.StartTimer()
+
+		if err := ring.RemoveServer("extra"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// writeBenchText writes results as a table.
+func writeBenchText(w io.Writer, cfg benchConfig, results []benchResult) error {
+	fmt.Fprintf(w, "servers=%d vnodes=%d keys=%d concurrency=%d hasher=%s\n\n",
+		cfg.servers, cfg.vnodes, cfg.keys, cfg.concurrency, cfg.hasher.Name())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\titerations\tns/op\tops/s\tB/op\tallocs/op\t")
+	for _, r := range results {
+		nsPerOp := float64(r.T.Nanoseconds()) / float64(r.N)
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%.0f\t%d\t%d\t\n",
+			r.op, r.N, nsPerOp, 1e9/nsPerOp, r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+
+	return tw.Flush()
+}
+
+// writeBenchstat writes results in the Go benchmark format read by benchstat.
+func writeBenchstat(w io.Writer, cfg benchConfig, results []benchResult) error {
+	fmt.Fprintf(w, "goos: %s\ngoarch: %s\npkg: github.com/pseudomuto/hashlab/hashring\n", runtime.GOOS, runtime.GOARCH)
+	for _, r := range results {
+		name := fmt.Sprintf("Benchmark%s%s/servers=%d/vnodes=%d/hasher=%s",
+			strings.ToUpper(r.op[:1]), r.op[1:], cfg.servers, cfg.vnodes, cfg.hasher.Name())
+		if r.op == "lookup" {
+			name += fmt.Sprintf("/keys=%d/concurrency=%d", cfg.keys, cfg.concurrency)
+		}
+
+		if _, err := fmt.Fprintf(w, "%s-%d\t%s\t%s\n", name, runtime.GOMAXPROCS(0), r.String(), r.MemString()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBench(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"bench", "-benchtime", "10ms", "-servers", "3", "-vnodes", "10", "-keys", "100"}, nil, &out, io.Discard)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "servers=3 vnodes=10 keys=100 concurrency=1 hasher=crc32-ieee")
+	for _, op := range benchOps {
+		require.Contains(t, out.String(), op)
+	}
+}
+
+func TestBenchBenchstat(t *testing.T) {
+	var out bytes.Buffer
+	args := []string{"bench", "-benchtime", "10ms", "-format", "benchstat", "-ops", "lookup", "-count", "2", "-concurrency", "2", "-hasher", "xxhash64"}
+	require.NoError(t, run(args, nil, &out, io.Discard))
+
+	var lines []string
+	for line := range strings.Lines(out.String()) {
+		if strings.HasPrefix(line, "Benchmark") {
+			lines = append(lines, line)
+		}
+	}
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasPrefix(lines[0], "BenchmarkLookup/servers=10/vnodes=150/hasher=xxhash64/keys=100000/concurrency=2-"))
+	require.Contains(t, lines[0], "ns/op")
+	require.Contains(t, lines[0], "allocs/op")
+}
+
+func TestBenchInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"-servers", "0"},
+		{"-hasher", "md5"},
+		{"-ops", "lookup,scan"},
+		{"-format", "csv"},
+		{"extra"},
+	} {
+		require.Error(t, run(append([]string{"bench"}, args...), nil, io.Discard, io.Discard), args)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stderr bytes.Buffer
+	require.ErrorContains(t, run([]string{"frobnicate"}, nil, io.Discard, &stderr), `unknown command "frobnicate"`)
+	require.Contains(t, stderr.String(), "bench")
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/pseudomuto/hashlab/topology"
+)
+
+// hashSpace is the number of positions on a ring.
+const hashSpace = 1 << 32
+
+// runDiff implements "hashlab diff".
+func runDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("diff [flags] old.yaml new.yaml", "Compares two topology files, printing the servers added, removed, and changed,\neach server's change in ownership, and how many keys would move, so topology\nedits can be reviewed before they're applied.", stderr)
+	keys := keyFlags(fs, "")
+
+	paths, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) != 2 {
+		fs.Usage()
+		return errors.New("diff needs an old and a new topology file")
+	}
+
+	before, beforeRing, err := loadRing(paths[0])
+	if err != nil {
+		return err
+	}
+
+	after, afterRing, err := loadRing(paths[1])
+	if err != nil {
+		return err
+	}
+
+	old, new := serversByName(before), serversByName(after)
+	var added, removed, changed []string
+	for _, name := range slices.Sorted(maps.Keys(new)) {
+		if server, ok := old[name]; !ok {
+			added = append(added, name)
+		} else if !reflect.DeepEqual(server, new[name]) {
+			changed = append(changed, name)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(old)) {
+		if _, ok := new[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	fmt.Fprintf(stdout, "--- %s\n+++ %s\n\n", paths[0], paths[1])
+	writeNames(stdout, "Added", added)
+	writeNames(stdout, "Removed", removed)
+	writeNames(stdout, "Changed", changed)
+	if len(added)+len(removed)+len(changed) == 0 {
+		fmt.Fprintln(stdout, "No server changes")
+	}
+
+	// Ownership of the hash space, which keys follow when they hash evenly
+	was, now := beforeRing.OwnershipShare(), afterRing.OwnershipShare()
+	fmt.Fprintln(stdout, "\nOwnership:")
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tBEFORE\tAFTER\tDELTA")
+	names := slices.Concat(slices.Collect(maps.Keys(old)), added)
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%.2f%%\t%.2f%%\t%+.2f\n", name, was[name]*100, now[name]*100, (now[name]-was[name])*100)
+	}
+	_ = tw.Flush()
+
+	var moved uint64
+	for _, move := range hashring.DiffRanges(beforeRing, afterRing) {
+		moved += move.Range.End - move.Range.Start + 1
+	}
+	fmt.Fprintf(stdout, "\nEstimated keys moved: %.2f%% of the hash space\n", float64(moved)/hashSpace*100)
+
+	if keys.path == "" {
+		return nil
+	}
+
+	sample, err := keys.read(stdin)
+	if err != nil {
+		return err
+	}
+
+	report := hashring.MovedKeys(beforeRing, afterRing, sample)
+	fmt.Fprintf(stdout, "Sampled keys moved: %d of %d (%.2f%%)\n", report.Moved(), report.Total, report.Fraction()*100)
+	if report.Moved() == 0 {
+		return nil
+	}
+
+	from, to := report.MovedFrom(), report.MovedTo()
+	tw = tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nSERVER\tLOST\tGAINED")
+	for _, name := range names {
+		if from[name] > 0 || to[name] > 0 {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", name, from[name], to[name])
+		}
+	}
+
+	return tw.Flush()
+}
+
+// loadRing loads the topology file at path and builds its ring.
+func loadRing(path string) (*topology.Topology, *hashring.HashRing, error) {
+	topo, err := topology.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ring, err := topo.Ring()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return topo, ring, nil
+}
+
+// serversByName indexes a topology's servers by name.
+func serversByName(topo *topology.Topology) map[string]topology.Server {
+	servers := make(map[string]topology.Server, len(topo.Servers))
+	for _, server := range topo.Servers {
+		servers[server.Name] = server
+	}
+
+	return servers
+}
+
+// writeNames writes a labelled list of server names, if there are any.
+func writeNames(w io.Writer, label string, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s (%d):\n", label, len(names))
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", name)
+	}
+}
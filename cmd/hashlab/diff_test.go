@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	old := writeFile(t, "old.yaml", "servers: [{name: a}, {name: b}, {name: c}]\n")
+	new := writeFile(t, "new.yaml", "servers: [{name: a, zone: z1}, {name: b}, {name: d}]\n")
+
+	var out bytes.Buffer
+	keys := strings.NewReader(strings.Repeat("user:1\nuser:2\nuser:3\nuser:4\n", 2))
+	require.NoError(t, run([]string{"diff", old, new, "-keys", "-"}, keys, &out, io.Discard))
+
+	for _, want := range []string{
+		"Added (1):\n  d\n",
+		"Removed (1):\n  c\n",
+		"Changed (1):\n  a\n",
+		"Estimated keys moved:",
+		"Sampled keys moved:",
+	} {
+		require.Contains(t, out.String(), want)
+	}
+
+	// nothing moves between identical topologies
+	out.Reset()
+	require.NoError(t, run([]string{"diff", old, old}, nil, &out, io.Discard))
+	require.Contains(t, out.String(), "No server changes")
+	require.Contains(t, out.String(), "Estimated keys moved: 0.00% of the hash space")
+	require.NotContains(t, out.String(), "Sampled")
+}
+
+func TestDiffInvalid(t *testing.T) {
+	old := writeFile(t, "old.yaml", "servers: [{name: a}]\n")
+	require.ErrorContains(t, run([]string{"diff", old}, nil, io.Discard, io.Discard), "needs an old and a new topology file")
+	require.Error(t, run([]string{"diff", old, old + ".missing"}, nil, io.Discard, io.Discard))
+}
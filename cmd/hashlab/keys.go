@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxKeyLine is the longest line a key file may have.
+const maxKeyLine = 1 << 20
+
+// keySource is where a command reads sampled keys from, set by its -keys,
+// -input, and -field flags.
+type keySource struct {
+	path  string // file to read, or "-" for stdin
+	input string // lines or jsonl, inferred from path if empty
+	field string // the key's field in JSONL objects
+}
+
+// keyFlags registers the -keys, -input, and -field flags on fs, reading keys
+// from def by default.
+func keyFlags(fs *flag.FlagSet, def string) *keySource {
+	src := &keySource{}
+	fs.StringVar(&src.path, "keys", def, `file of sampled keys, or "-" for stdin`)
+	fs.StringVar(&src.input, "input", "", "key file format: lines (one key per line) or jsonl (default jsonl for .jsonl and .ndjson files, lines otherwise)")
+	fs.StringVar(&src.field, "field", "key", "field holding the key in JSONL objects")
+
+	return src
+}
+
+// read reads the keys, taking stdin for "-". Keys are returned in the order
+// they appear, repeats included.
+func (src *keySource) read(stdin io.Reader) ([]string, error) {
+	input := src.input
+	if input == "" {
+		switch filepath.Ext(src.path) {
+		case ".jsonl", ".ndjson":
+			input = "jsonl"
+		default:
+			input = "lines"
+		}
+	}
+
+	if input != "lines" && input != "jsonl" {
+		return nil, fmt.Errorf("unknown key file format %q", input)
+	}
+
+	r, name := stdin, "stdin"
+	if src.path != "-" {
+		f, err := os.Open(src.path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		r, name = f, src.path
+	}
+
+	keys, err := readKeys(r, input, src.field)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no keys", name)
+	}
+
+	return keys, nil
+}
+
+// readKeys reads keys from r, one per line, skipping blank lines. In jsonl
+// input, each line is a JSON string or an object with the key in field.
+func readKeys(r io.Reader, input, field string) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxKeyLine)
+
+	var keys []string
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if input == "lines" {
+			keys = append(keys, line)
+			continue
+		}
+
+		key, err := jsonKey([]byte(line), field)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, scanner.Err()
+}
+
+// jsonKey returns the key in a JSONL line: the line itself if it's a string,
+// or its field if it's an object.
+func jsonKey(line []byte, field string) (string, error) {
+	var v any
+	if err := json.Unmarshal(line, &v); err != nil {
+		return "", err
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case map[string]any:
+		key, ok := v[field].(string)
+		if !ok {
+			return "", fmt.Errorf("no string field %q", field)
+		}
+
+		return key, nil
+	default:
+		return "", errors.New("expected a string or an object")
+	}
+}
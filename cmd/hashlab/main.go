@@ -0,0 +1,181 @@
+// Command hashlab is a toolbox for measuring and experimenting with hash
+// rings from the command line.
+//
+// Usage:
+//
+//	hashlab <command> [flags]
+//
+// Run "hashlab <command> -h" for a command's flags.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// hashers are the built-in hashers by name, for the -hasher flag.
+var hashers = map[string]hashring.Hasher{
+	hashring.CRC32.Name():    hashring.CRC32,
+	hashring.CRC32C.Name():   hashring.CRC32C,
+	hashring.FNV1a.Name():    hashring.FNV1a,
+	hashring.XXHash64.Name(): hashring.XXHash64,
+	hashring.Murmur3.Name():  hashring.Murmur3,
+}
+
+// command is a hashlab subcommand.
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) error
+}
+
+// commands are the subcommands by name.
+var commands = map[string]command{
+	"add-server":    {summary: "add servers to the ring in the state file", run: runAddServer},
+	"analyze":       {summary: "report how sampled keys distribute across a topology file", run: runAnalyze},
+	"bench":         {summary: "benchmark ring lookups and membership changes", run: runBench},
+	"diff":          {summary: "compare two topology files and estimate the keys moved", run: runDiff},
+	"lookup":        {summary: "print the servers keys map to in the ring in the state file", run: runLookup},
+	"remove-server": {summary: "remove servers from the ring in the state file", run: runRemoveServer},
+	"simulate":      {summary: "simulate scaling a ring and report the keys moved", run: runSimulate},
+	"viz":           {summary: "draw a ring from a topology file or admin endpoint", run: runViz},
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	default:
+		fmt.Fprintln(os.Stderr, "hashlab:", err)
+		os.Exit(2)
+	}
+}
+
+// run runs the subcommand named by args[0] with the rest of args.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return errors.New("no command given")
+		}
+
+		return flag.ErrHelp
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage(stderr)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	return cmd.run(args[1:], stdin, stdout, stderr)
+}
+
+// usage writes the list of commands to w.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: hashlab <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 4, ' ', 0)
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, commands[name].summary)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "hashlab <command> -h" for a command's flags.`)
+}
+
+// newFlagSet returns a flag set for the subcommand with the given usage line,
+// e.g. "bench [flags]", that reports errors instead of exiting and writes its
+// usage to stderr.
+func newFlagSet(usage, synopsis string, stderr io.Writer) *flag.FlagSet {
+	name, _, _ := strings.Cut(usage, " ")
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: hashlab %s\n\n%s\n\nFlags:\n", usage, synopsis)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parseInterspersed parses flags in args that may come before, between, or
+// after positional arguments, returning the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if args = fs.Args(); len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// hasherFlag registers a -hasher flag on fs choosing one of the built-in
+// hashers, returning the name it's set to.
+func hasherFlag(fs *flag.FlagSet) *string {
+	names := slices.Sorted(maps.Keys(hashers))
+	return fs.String("hasher", hashring.CRC32.Name(), "hash function: "+strings.Join(names, ", "))
+}
+
+// lookupHasher returns the built-in hasher with the given name.
+func lookupHasher(name string) (hashring.Hasher, error) {
+	hasher, ok := hashers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown hasher %q", name)
+	}
+
+	return hasher, nil
+}
+
+// countFlag is an int flag that accepts k, M, and G suffixes, e.g. 1M for a
+// million keys.
+type countFlag int
+
+func (c *countFlag) String() string {
+	return strconv.Itoa(int(*c))
+}
+
+func (c *countFlag) Set(s string) error {
+	mult := 1
+	switch {
+	case strings.HasSuffix(s, "k"), strings.HasSuffix(s, "K"):
+		mult = 1_000
+	case strings.HasSuffix(s, "M"):
+		mult = 1_000_000
+	case strings.HasSuffix(s, "G"):
+		mult = 1_000_000_000
+	}
+
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.Atoi(strings.ReplaceAll(s, "_", ""))
+	if err != nil {
+		return errors.New("must be a whole number, optionally with a k, M, or G suffix")
+	}
+
+	*c = countFlag(n * mult)
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// scenarios are the changes simulate can make to a ring, by name.
+var scenarios = []string{"scale-up", "scale-down"}
+
+// runSimulate implements "hashlab simulate".
+func runSimulate(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("simulate [flags]", "Simulates a change to a ring of synthetic servers and reports the keys it moves,\nownership before and after, and imbalance, for capacity planning.", stderr)
+
+	scenario := fs.String("scenario", "scale-up", "change to simulate: "+strings.Join(scenarios, ", "))
+	servers := fs.Int("servers", 10, "number of servers before the change")
+	add := fs.Int("add", 1, "number of servers to add when scaling up")
+	remove := fs.Int("remove", 1, "number of servers to remove when scaling down")
+	vnodes := fs.Int("vnodes", 150, "number of virtual nodes per server")
+	keys := countFlag(100_000)
+	fs.Var(&keys, "keys", "number of synthetic keys to place, e.g. 100k or 1M")
+	hasher := hasherFlag(fs)
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	switch {
+	case *servers <= 0:
+		return fmt.Errorf("-servers must be positive, got %d", *servers)
+	case *vnodes <= 0:
+		return fmt.Errorf("-vnodes must be positive, got %d", *vnodes)
+	case keys <= 0:
+		return fmt.Errorf("-keys must be positive, got %d", keys)
+	}
+
+	var after int
+	switch *scenario {
+	case "scale-up":
+		if *add <= 0 {
+			return fmt.Errorf("-add must be positive, got %d", *add)
+		}
+
+		after = *servers + *add
+	case "scale-down":
+		if *remove <= 0 || *remove >= *servers {
+			return fmt.Errorf("-remove must be between 1 and %d, got %d", *servers-1, *remove)
+		}
+
+		after = *servers - *remove
+	default:
+		return fmt.Errorf("unknown scenario %q", *scenario)
+	}
+
+	h, err := lookupHasher(*hasher)
+	if err != nil {
+		return err
+	}
+
+	// Scaling down removes the servers scaling up would have added, so both
+	// compare the same two topologies
+	small, large := ringOf(min(*servers, after), *vnodes, h), ringOf(max(*servers, after), *vnodes, h)
+	before, next := small, large
+	if after < *servers {
+		before, next = large, small
+	}
+
+	sample := make([]string, keys)
+	for i := range sample {
+		sample[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	moved := hashring.MovedKeys(before, next, sample)
+	was, now := before.AnalyzePerformance(sample), next.AnalyzePerformance(sample)
+
+	// With perfect balance, scaling between a and b servers moves |a-b|/max(a,b)
+	// of the keys
+	ideal := float64(max(*servers, after)-min(*servers, after)) / float64(max(*servers, after))
+
+	fmt.Fprintf(stdout, "Scenario: %s from %d to %d servers (%d vnodes, %s, %d keys)\n\n",
+		*scenario, *servers, after, *vnodes, h.Name(), keys)
+	fmt.Fprintf(stdout, "Keys moved: %d (%.2f%%, ideal %.2f%%)\n\n", moved.Moved(), moved.Fraction()*100, ideal*100)
+
+	hashring.CompareDistributions(was.Distribution, now.Distribution).Fprint(stdout)
+
+	fmt.Fprintln(stdout)
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "IMBALANCE\tBEFORE\tAFTER")
+	fmt.Fprintf(tw, "CV\t%.2f%%\t%.2f%%\n", was.DistributionCV, now.DistributionCV)
+	fmt.Fprintf(tw, "Max share\t%.2f%%\t%.2f%%\n", was.MaxShare, now.MaxShare)
+	fmt.Fprintf(tw, "Max/min load\t%.2fx\t%.2fx\n", was.MaxMinRatio, now.MaxMinRatio)
+	fmt.Fprintf(tw, "Gini\t%.3f\t%.3f\n", was.Gini, now.Gini)
+
+	return tw.Flush()
+}
+
+// ringOf returns a ring with n servers named server-1 to server-n.
+func ringOf(n, vnodes int, hasher hashring.Hasher) *hashring.HashRing {
+	ring := hashring.New(vnodes, hashring.WithHasher(hasher))
+	for i := range n {
+		if err := ring.AddServer(fmt.Sprintf("server-%d", i+1)); err != nil {
+			panic(err)
+		}
+	}
+
+	return ring
+}
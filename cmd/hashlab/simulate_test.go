@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulate(t *testing.T) {
+	var out bytes.Buffer
+	err := run([]string{"simulate", "-scenario", "scale-up", "-servers", "4", "-add", "1", "-keys", "10k"}, nil, &out, io.Discard)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Scenario: scale-up from 4 to 5 servers (150 vnodes, crc32-ieee, 10000 keys)")
+	require.Contains(t, out.String(), "ideal 20.00%")
+	require.Contains(t, out.String(), "server-5")
+	require.Contains(t, out.String(), "IMBALANCE")
+
+	out.Reset()
+	err = run([]string{"simulate", "-scenario", "scale-down", "-servers", "4", "-remove", "2", "-keys", "1000"}, nil, &out, io.Discard)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "from 4 to 2 servers")
+	require.Contains(t, out.String(), "ideal 50.00%")
+}
+
+func TestSimulateInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"-scenario", "meltdown"},
+		{"-scenario", "scale-down", "-servers", "2", "-remove", "2"},
+		{"-add", "0"},
+		{"-keys", "lots"},
+	} {
+		require.Error(t, run(append([]string{"simulate"}, args...), nil, io.Discard, io.Discard), args)
+	}
+}
+
+func TestCountFlag(t *testing.T) {
+	for in, want := range map[string]int{"42": 42, "10k": 10_000, "1M": 1_000_000, "2G": 2_000_000_000, "1_000": 1000} {
+		var c countFlag
+		require.NoError(t, c.Set(in))
+		require.Equal(t, countFlag(want), c, in)
+	}
+
+	var c countFlag
+	require.Error(t, c.Set("1.5M"))
+}
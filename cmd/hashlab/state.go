@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// stateEnv is the environment variable that overrides the default state file.
+const stateEnv = "HASHLAB_STATE"
+
+// ringState is the ring kept in a state file between invocations, set by the
+// -state, -vnodes, and -hasher flags. The ring's settings only apply when the
+// state file doesn't exist yet; after that they come from the file.
+type ringState struct {
+	path   string
+	vnodes int
+	hasher *string
+}
+
+// stateFlags registers the -state, -vnodes, and -hasher flags on fs.
+func stateFlags(fs *flag.FlagSet) *ringState {
+	st := &ringState{}
+	fs.StringVar(&st.path, "state", defaultStatePath(), "state file holding the ring between commands, $"+stateEnv+" if set")
+	fs.IntVar(&st.vnodes, "vnodes", 150, "number of virtual nodes per server, if the state file is new")
+	st.hasher = hasherFlag(fs)
+	fs.Lookup("hasher").Usage = strings.Replace(fs.Lookup("hasher").Usage, ":", " if the state file is new:", 1)
+
+	return st
+}
+
+// defaultStatePath returns the state file used when -state isn't given.
+func defaultStatePath() string {
+	if path := os.Getenv(stateEnv); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".hashlab", "ring.json")
+	}
+
+	return filepath.Join(home, ".hashlab", "ring.json")
+}
+
+// load returns the ring in the state file, or a new, empty ring if there's no
+// state file yet.
+func (st *ringState) load() (*hashring.HashRing, error) {
+	data, err := os.ReadFile(st.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if st.vnodes <= 0 {
+			return nil, fmt.Errorf("-vnodes must be positive, got %d", st.vnodes)
+		}
+
+		hasher, err := lookupHasher(*st.hasher)
+		if err != nil {
+			return nil, err
+		}
+
+		return hashring.New(st.vnodes, hashring.WithHasher(hasher)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snap hashring.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("%s: %w", st.path, err)
+	}
+
+	ring, err := hashring.Restore(snap)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", st.path, err)
+	}
+
+	return ring, nil
+}
+
+// save writes ring to the state file, replacing it atomically so a failed
+// write never leaves a truncated file behind.
+func (st *ringState) save(ring *hashring.HashRing) error {
+	data, err := json.MarshalIndent(ring.Snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(st.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, ".ring-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), st.path)
+}
+
+// runAddServer implements "hashlab add-server".
+func runAddServer(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("add-server [flags] name...", "Adds servers to the ring in the state file, creating it if needed.", stderr)
+	st := stateFlags(fs)
+	zone := fs.String("zone", "", "zone the servers are in")
+	tags := fs.String("tags", "", "comma-separated tags for the servers")
+	weight := fs.Float64("weight", 0, "relative capacity of the servers (default 1)")
+
+	names, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		fs.Usage()
+		return errors.New("add-server needs at least one server name")
+	}
+
+	ring, err := st.load()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		info := hashring.ServerInfo{Name: name, Zone: *zone, Weight: *weight}
+		if *tags != "" {
+			info.Tags = strings.Split(*tags, ",")
+		}
+
+		if err := ring.AddServerWithInfo(info); err != nil {
+			return err
+		}
+	}
+
+	if err := st.save(ring); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Added %s (%d servers, version %d)\n", strings.Join(names, ", "), ring.Size(), ring.Version())
+	return nil
+}
+
+// runRemoveServer implements "hashlab remove-server".
+func runRemoveServer(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("remove-server [flags] name...", "Removes servers from the ring in the state file.", stderr)
+	st := stateFlags(fs)
+
+	names, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		fs.Usage()
+		return errors.New("remove-server needs at least one server name")
+	}
+
+	ring, err := st.load()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := ring.RemoveServer(name); err != nil {
+			return err
+		}
+	}
+
+	if err := st.save(ring); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Removed %s (%d servers, version %d)\n", strings.Join(names, ", "), ring.Size(), ring.Version())
+	return nil
+}
+
+// runLookup implements "hashlab lookup".
+func runLookup(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("lookup [flags] key...", "Prints the server each key maps to in the ring in the state file.", stderr)
+	st := stateFlags(fs)
+	n := fs.Int("n", 1, "number of replicas to print per key")
+
+	keys, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(keys) == 0:
+		fs.Usage()
+		return errors.New("lookup needs at least one key")
+	case *n <= 0:
+		return fmt.Errorf("-n must be positive, got %d", *n)
+	}
+
+	ring, err := st.load()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		servers, err := ring.GetReplicas(key, *n)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\n", key, strings.Join(servers, ", "))
+	}
+
+	return tw.Flush()
+}
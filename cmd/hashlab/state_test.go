@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateCommands(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "nested", "ring.json")
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"add-server", "a", "b", "c", "-state", state, "-vnodes", "50", "-zone", "z1"}, nil, &out, io.Discard))
+	require.Equal(t, "Added a, b, c (3 servers, version 3)\n", out.String())
+
+	// the ring's settings come from the state file once it exists
+	out.Reset()
+	require.NoError(t, run([]string{"add-server", "d", "-state", state, "-vnodes", "10"}, nil, &out, io.Discard))
+	require.Equal(t, "Added d (4 servers, version 4)\n", out.String())
+
+	st := &ringState{path: state}
+	ring, err := st.load()
+	require.NoError(t, err)
+	require.Equal(t, 50, ring.Snapshot().VirtualNodes)
+	info, ok := ring.GetServerInfo("a")
+	require.True(t, ok)
+	require.Equal(t, "z1", info.Zone)
+
+	out.Reset()
+	require.NoError(t, run([]string{"remove-server", "-state", state, "d"}, nil, &out, io.Discard))
+	require.Equal(t, "Removed d (3 servers, version 5)\n", out.String())
+
+	want, err := ring.GetServer("user:1")
+	require.NoError(t, err)
+
+	out.Reset()
+	require.NoError(t, run([]string{"lookup", "-state", state, "user:1"}, nil, &out, io.Discard))
+	require.Equal(t, "user:1  "+want+"\n", out.String())
+
+	out.Reset()
+	require.NoError(t, run([]string{"lookup", "-state", state, "-n", "3", "user:1"}, nil, &out, io.Discard))
+	require.Regexp(t, `^user:1  [abc], [abc], [abc]\n$`, out.String())
+}
+
+func TestStateCommandsInvalid(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "ring.json")
+	require.ErrorContains(t, run([]string{"add-server", "-state", state}, nil, io.Discard, io.Discard), "needs at least one server name")
+	require.ErrorContains(t, run([]string{"lookup", "-state", state}, nil, io.Discard, io.Discard), "needs at least one key")
+	require.Error(t, run([]string{"lookup", "-state", state, "user:1"}, nil, io.Discard, io.Discard))
+	require.Error(t, run([]string{"remove-server", "-state", state, "a"}, nil, io.Discard, io.Discard))
+	require.ErrorContains(t, run([]string{"add-server", "-state", state, "-hasher", "md5", "a"}, nil, io.Discard, io.Discard), `unknown hasher "md5"`)
+
+	// a failed command leaves the state file untouched
+	require.NoError(t, run([]string{"add-server", "-state", state, "a"}, nil, io.Discard, io.Discard))
+	require.Error(t, run([]string{"add-server", "-state", state, "b", "a"}, nil, io.Discard, io.Discard))
+	ring, err := (&ringState{path: state}).load()
+	require.NoError(t, err)
+	require.Equal(t, 1, ring.Size())
+}
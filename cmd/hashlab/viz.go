@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// vizFormats are the formats viz can render, by file extension.
+var vizFormats = map[string]string{".txt": "ascii", ".svg": "svg", ".dot": "dot", ".gv": "dot"}
+
+// palette are the colors servers are drawn in, in order of name.
+var palette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948",
+	"#b07aa1", "#ff9da7", "#9c755f", "#bab0ac", "#86bcb6", "#d37295",
+}
+
+// symbols are the characters servers are drawn with in ASCII, in order of
+// name. Servers past the last symbol are drawn as '?'.
+const symbols = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// segment is a range of positions owned by one server.
+type segment struct {
+	r      hashring.HashRange
+	server string
+}
+
+// ringView is what viz draws: a ring's ownership ranges and its servers in
+// order of name.
+type ringView struct {
+	segments []segment
+	servers  []string
+	shares   map[string]float64
+	vnodes   int
+}
+
+// runViz implements "hashlab viz".
+func runViz(args []string, _ io.Reader, stdout, stderr io.Writer) error {
+	fs := newFlagSet("viz [flags]", "Draws the ownership of a ring from a topology file or a live admin endpoint\n(see the admin package) as ASCII, SVG, or a Graphviz graph.", stderr)
+
+	path := fs.String("topology", "", "topology file to draw")
+	url := fs.String("admin", "", "base URL of a live admin endpoint to draw, e.g. http://host:8080/admin")
+	format := fs.String("format", "", "output format: ascii, svg, or dot (default from -o's extension, ascii otherwise)")
+	output := fs.String("o", "", "file to write to (default stdout)")
+	width := fs.Int("width", 72, "width of the ASCII ring in columns")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case fs.NArg() > 0:
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	case (*path == "") == (*url == ""):
+		return errors.New("exactly one of -topology and -admin is required")
+	case *width < 10:
+		return fmt.Errorf("-width must be at least 10, got %d", *width)
+	}
+
+	if *format == "" {
+		*format = "ascii"
+		if f, ok := vizFormats[filepath.Ext(*output)]; ok {
+			*format = f
+		}
+	}
+
+	render, ok := map[string]func(ringView, io.Writer) error{
+		"ascii": func(v ringView, w io.Writer) error { return v.ascii(w, *width) },
+		"svg":   ringView.svg,
+		"dot":   ringView.dot,
+	}[*format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var ring *hashring.HashRing
+	var err error
+	if *path != "" {
+		_, ring, err = loadRing(*path)
+	} else {
+		ring, err = fetchRing(*url)
+	}
+	if err != nil {
+		return err
+	}
+
+	if ring.Size() == 0 {
+		return errors.New("the ring has no servers")
+	}
+
+	view := viewOf(ring)
+	if *output == "" {
+		return render(view, stdout)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	if err := render(view, f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// fetchRing rebuilds the ring served by the admin endpoint at base.
+func fetchRing(base string) (*hashring.HashRing, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(strings.TrimSuffix(base, "/") + "/snapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching snapshot: %s", resp.Status)
+	}
+
+	var snap hashring.Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	return hashring.Restore(snap)
+}
+
+// viewOf captures what viz draws of ring.
+func viewOf(ring *hashring.HashRing) ringView {
+	v := ringView{shares: ring.OwnershipShare()}
+	for r, server := range ring.OwnershipRanges() {
+		v.segments = append(v.segments, segment{r: r, server: server})
+	}
+
+	for range ring.VNodes() {
+		v.vnodes++
+	}
+
+	v.servers = slices.Sorted(maps.Keys(v.shares))
+	return v
+}
+
+// index returns the position of server in name order, which picks its color
+// and symbol.
+func (v ringView) index(server string) int {
+	i, _ := slices.BinarySearch(v.servers, server)
+	return i
+}
+
+// symbol returns the character server is drawn with in ASCII.
+func (v ringView) symbol(server string) byte {
+	if i := v.index(server); i < len(symbols) {
+		return symbols[i]
+	}
+
+	return '?'
+}
+
+// color returns the color server is drawn in.
+func (v ringView) color(server string) string {
+	return palette[v.index(server)%len(palette)]
+}
+
+// ascii draws the ring unrolled into a bar of width columns, each showing the
+// server that owns most of its positions, followed by a legend.
+func (v ringView) ascii(w io.Writer, width int) error {
+	bar := make([]byte, width)
+	seg := 0
+	for col := range width {
+		lo := uint64(col) * hashSpace / uint64(width)
+		hi := uint64(col+1)*hashSpace/uint64(width) - 1
+
+		owned := make(map[string]uint64)
+		for seg < len(v.segments) && v.segments[seg].r.Start <= hi {
+			s := v.segments[seg]
+			owned[s.server] += min(s.r.End, hi) - max(s.r.Start, lo) + 1
+			if s.r.End > hi {
+				break
+			}
+			seg++
+		}
+
+		var most string
+		for _, server := range v.servers {
+			if owned[server] > owned[most] {
+				most = server
+			}
+		}
+		bar[col] = v.symbol(most)
+	}
+
+	fmt.Fprintf(w, "Ring: %d servers, %d virtual nodes\n\n", len(v.servers), v.vnodes)
+	fmt.Fprintf(w, "0%*d\n", width+1, uint64(hashSpace-1))
+	fmt.Fprintf(w, "|%s|\n\n", bar)
+	for _, server := range v.servers {
+		fmt.Fprintf(w, "%c  %-*s %6.2f%%\n", v.symbol(server), longest(v.servers), server, v.shares[server]*100)
+	}
+
+	return nil
+}
+
+// svg draws the ring as a circle of arcs, one per ownership range, starting at
+// the top and running clockwise, with a legend beside it.
+func (v ringView) svg(w io.Writer) error {
+	const (
+		size   = 600
+		center = size / 2
+		radius = 220
+		stroke = 48
+	)
+
+	legendX := size + 20
+	height := max(size, 40+len(v.servers)*24)
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="14">`+"\n",
+		legendX+260, height, legendX+260, height)
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="middle">%d servers, %d virtual nodes</text>`+"\n", center, center, len(v.servers), v.vnodes)
+
+	point := func(pos uint64) (float64, float64) {
+		angle := float64(pos)/hashSpace*2*math.Pi - math.Pi/2
+		return center + radius*math.Cos(angle), center + radius*math.Sin(angle)
+	}
+
+	for _, s := range v.segments {
+		x0, y0 := point(s.r.Start)
+		x1, y1 := point(s.r.End + 1)
+		large := 0
+		if s.r.End-s.r.Start >= hashSpace/2 {
+			large = 1
+		}
+
+		// A single range covering the whole ring can't be drawn as one arc
+		if s.r.Start == 0 && s.r.End == hashSpace-1 {
+			fmt.Fprintf(w, `<circle cx="%d" cy="%d" r="%d" fill="none" stroke="%s" stroke-width="%d"><title>%s</title></circle>`+"\n",
+				center, center, radius, v.color(s.server), stroke, html.EscapeString(s.server))
+			continue
+		}
+
+		fmt.Fprintf(w, `<path d="M %.2f %.2f A %d %d 0 %d 1 %.2f %.2f" fill="none" stroke="%s" stroke-width="%d"><title>%s %d-%d</title></path>`+"\n",
+			x0, y0, radius, radius, large, x1, y1, v.color(s.server), stroke, html.EscapeString(s.server), s.r.Start, s.r.End)
+	}
+
+	for i, server := range v.servers {
+		y := 40 + i*24
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="16" height="16" fill="%s"/>`+"\n", legendX, y-13, v.color(server))
+		fmt.Fprintf(w, `<text x="%d" y="%d">%s (%.2f%%)</text>`+"\n", legendX+24, y, html.EscapeString(server), v.shares[server]*100)
+	}
+
+	_, err := fmt.Fprintln(w, "</svg>")
+	return err
+}
+
+// dot draws the ring as a Graphviz graph: a cycle of ownership ranges in ring
+// order, laid out as a circle by circo.
+func (v ringView) dot(w io.Writer) error {
+	fmt.Fprintln(w, "digraph ring {")
+	fmt.Fprintln(w, "  layout=circo;")
+	fmt.Fprintln(w, `  node [shape=box, style=filled, fontname="sans-serif"];`)
+	for i, s := range v.segments {
+		fmt.Fprintf(w, "  r%d [label=%q, fillcolor=%q];\n", i, fmt.Sprintf("%s\n%d-%d", s.server, s.r.Start, s.r.End), v.color(s.server))
+	}
+
+	for i := range v.segments {
+		fmt.Fprintf(w, "  r%d -> r%d;\n", i, (i+1)%len(v.segments))
+	}
+
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// longest returns the length of the longest of names.
+func longest(names []string) int {
+	n := 0
+	for _, name := range names {
+		n = max(n, len(name))
+	}
+
+	return n
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pseudomuto/hashlab/admin"
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVizTopology(t *testing.T) {
+	path := writeFile(t, "ring.yaml", "servers: [{name: a}, {name: b}, {name: c}]\n")
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"viz", "-topology", path, "-width", "40"}, nil, &out, io.Discard))
+	require.Contains(t, out.String(), "Ring: 3 servers, 450 virtual nodes")
+	require.Contains(t, out.String(), "A  a ")
+	require.Contains(t, out.String(), "C  c ")
+
+	lines := strings.Split(out.String(), "\n")
+	require.Regexp(t, `^\|[ABC]{40}\|$`, lines[3])
+
+	// the format follows -o's extension
+	svg := filepath.Join(t.TempDir(), "ring.svg")
+	require.NoError(t, run([]string{"viz", "-topology", path, "-o", svg}, nil, io.Discard, io.Discard))
+	data, err := os.ReadFile(svg)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(data), "<svg "))
+	require.Contains(t, string(data), "<path ")
+	require.True(t, strings.HasSuffix(string(data), "</svg>\n"))
+
+	out.Reset()
+	require.NoError(t, run([]string{"viz", "-topology", path, "-format", "dot"}, nil, &out, io.Discard))
+	require.True(t, strings.HasPrefix(out.String(), "digraph ring {\n  layout=circo;\n"))
+	require.Contains(t, out.String(), "r0 -> r1;")
+}
+
+func TestVizAdmin(t *testing.T) {
+	ring := hashring.New(10)
+	require.NoError(t, ring.AddServer("only"))
+
+	srv := httptest.NewServer(admin.New(ring).Handler())
+	defer srv.Close()
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"viz", "-admin", srv.URL, "-format", "svg"}, nil, &out, io.Discard))
+	require.Contains(t, out.String(), "1 servers, 10 virtual nodes")
+	require.Contains(t, out.String(), "<circle ")
+	require.Contains(t, out.String(), "only (100.00%)")
+}
+
+func TestVizInvalid(t *testing.T) {
+	path := writeFile(t, "ring.yaml", "servers: [{name: a}]\n")
+	require.ErrorContains(t, run([]string{"viz"}, nil, io.Discard, io.Discard), "exactly one of -topology and -admin")
+	require.ErrorContains(t, run([]string{"viz", "-topology", path, "-format", "png"}, nil, io.Discard, io.Discard), `unknown format "png"`)
+	require.ErrorContains(t, run([]string{"viz", "-topology", path, "-width", "5"}, nil, io.Discard, io.Discard), "-width must be at least 10")
+}
@@ -41,23 +41,16 @@ func main() {
 		ring.AddServer(fmt.Sprintf("server-%d", i))
 	}
 
-	chMap := make(map[string]string)
-	for i := range numKeys {
-		key := fmt.Sprintf("key-%d", i)
-		server, _ := ring.GetServer(key)
-		chMap[key] = server
+	keys := make([]string, numKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
 	}
 
+	before, _ := hashring.Restore(ring.Snapshot())
+
 	// Add a server with consistent hashing
 	ring.AddServer("server-3")
-	chMoved := 0
-	for i := range numKeys {
-		key := fmt.Sprintf("key-%d", i)
-		newServer, _ := ring.GetServer(key)
-		if newServer != chMap[key] {
-			chMoved++
-		}
-	}
+	chMoved := hashring.MovedKeys(before, ring, keys).Moved()
 
 	fmt.Printf("When adding 1 server to %d servers:\n", numServers)
 	fmt.Printf("  Modulo hashing: %d keys moved (%.0f%%)\n",
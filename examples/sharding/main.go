@@ -31,24 +31,15 @@ func main() {
 	// Show what happens when we add a new shard
 	fmt.Println("\nAdding a new shard (db-shard-5)...")
 
-	beforeMap := make(map[string]string)
-	for userID := 1; userID <= 100; userID++ {
-		key := fmt.Sprintf("user:%d", userID)
-		shard, _ := ring.GetServer(key)
-		beforeMap[key] = shard
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%d", i+1)
 	}
 
+	before, _ := hashring.Restore(ring.Snapshot())
 	ring.AddServer("db-shard-5")
 
-	moved := 0
-	for userID := 1; userID <= 100; userID++ {
-		key := fmt.Sprintf("user:%d", userID)
-		newShard, _ := ring.GetServer(key)
-		if newShard != beforeMap[key] {
-			moved++
-		}
-	}
-
-	fmt.Printf("Users that need to be migrated: %d out of 100 (%.0f%%)\n",
-		moved, float64(moved))
+	report := hashring.MovedKeys(before, ring, keys)
+	fmt.Printf("Users that need to be migrated: %d out of %d (%.0f%%)\n",
+		report.Moved(), report.Total, report.Fraction()*100)
 }
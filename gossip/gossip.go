@@ -0,0 +1,291 @@
+// Package gossip lets processes that each hold a copy of a ring converge on
+// the same topology without a central coordinator.
+//
+// Every node serves its ring's digest and snapshot over HTTP (see
+// Node.Handler) and periodically runs an anti-entropy round with a random
+// peer: the two compare digests and the node with the older topology adopts
+// the other's with hashring.HashRing.Apply. Topologies are ordered by version,
+// with ties broken by checksum, so concurrent changes on different nodes
+// resolve the same way everywhere: the last writer wins.
+//
+// This is meant for small clusters that change membership rarely. A change
+// made on one node reaches the others in O(log n) rounds, but concurrent
+// changes on two nodes keep only one of them.
+package gossip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultInterval is how often a Node syncs with a peer.
+const DefaultInterval = time.Second
+
+// DefaultMaxSnapshotSize is the largest snapshot, in bytes, a Node accepts
+// from a peer.
+const DefaultMaxSnapshotSize = 16 << 20
+
+// Digest summarizes a ring's topology.
+type Digest struct {
+	Version  uint64 `json:"version"`
+	Checksum uint64 `json:"checksum"`
+}
+
+// Newer reports whether d describes a newer topology than other: it has a
+// higher version, or the same version and a higher checksum.
+func (d Digest) Newer(other Digest) bool {
+	if d.Version != other.Version {
+		return d.Version > other.Version
+	}
+
+	return d.Checksum > other.Checksum
+}
+
+// digestOf returns the digest of a snapshot.
+func digestOf(s hashring.Snapshot) Digest {
+	return Digest{Version: s.Version, Checksum: s.Checksum}
+}
+
+// ringDigest returns the ring's digest, without building a snapshot.
+func ringDigest(ring *hashring.HashRing) Digest {
+	checksum, version := ring.ChecksumVersioned()
+	return Digest{Version: version, Checksum: checksum}
+}
+
+// Option configures a Node.
+type Option func(*Node)
+
+// WithInterval sets how often Run syncs with a peer.
+func WithInterval(d time.Duration) Option {
+	return func(n *Node) {
+		n.interval = d
+	}
+}
+
+// WithHTTPClient sets the client used to reach peers. The default is a client
+// with a 5 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(n *Node) {
+		n.client = client
+	}
+}
+
+// WithMaxSnapshotSize sets the largest snapshot, in bytes, the node accepts
+// when a peer pushes one. Larger requests are rejected.
+func WithMaxSnapshotSize(n int64) Option {
+	return func(node *Node) {
+		node.maxSnapshot = n
+	}
+}
+
+// WithErrorHandler sets a function that's called when a round fails, e.g.
+// because a peer is unreachable. By default errors are ignored, since the
+// next round retries.
+func WithErrorHandler(fn func(error)) Option {
+	return func(n *Node) {
+		n.onError = fn
+	}
+}
+
+// Node keeps a ring in sync with its peers.
+type Node struct {
+	ring     *hashring.HashRing
+	peers    []string
+	interval time.Duration
+	client   *http.Client
+	onError  func(error)
+
+	maxSnapshot int64
+
+	mu sync.Mutex // serializes adopting snapshots
+}
+
+// NewNode creates a node that syncs ring with peers, given as the base URLs
+// their handlers are served at.
+//
+// Example:
+//
+//	node := gossip.NewNode(ring, []string{"http://10.0.0.2:7946/gossip", "http://10.0.0.3:7946/gossip"})
+//	http.Handle("/gossip/", http.StripPrefix("/gossip", node.Handler()))
+//	go node.Run(ctx)
+func NewNode(ring *hashring.HashRing, peers []string, opts ...Option) *Node {
+	n := &Node{
+		ring:     ring,
+		peers:    peers,
+		interval: DefaultInterval,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		onError:  func(error) {},
+
+		maxSnapshot: DefaultMaxSnapshotSize,
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+// Handler returns the handler peers sync with. It serves:
+//
+//   - GET /digest: the ring's Digest
+//   - GET /snapshot: the ring's hashring.Snapshot
+//   - POST /snapshot: a peer's snapshot, which is applied if it's newer and
+//     no larger than the limit set by WithMaxSnapshotSize
+func (n *Node) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /digest", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, ringDigest(n.ring))
+	})
+
+	mux.HandleFunc("GET /snapshot", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, n.ring.Snapshot())
+	})
+
+	mux.HandleFunc("POST /snapshot", func(w http.ResponseWriter, r *http.Request) {
+		var snap hashring.Snapshot
+		body := http.MaxBytesReader(w, r.Body, n.maxSnapshot)
+		if err := json.NewDecoder(body).Decode(&snap); err != nil {
+			code := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				code = http.StatusRequestEntityTooLarge
+			}
+
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		if err := n.adopt(snap); err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		writeJSON(w, ringDigest(n.ring))
+	})
+
+	return mux
+}
+
+// Run syncs with a random peer every interval until ctx is done, returning
+// ctx's error. Failed rounds are reported to the error handler.
+func (n *Node) Run(ctx context.Context) error {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if len(n.peers) == 0 {
+				continue
+			}
+
+			peer := n.peers[rand.IntN(len(n.peers))]
+			if err := n.Sync(ctx, peer); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				n.onError(err)
+			}
+		}
+	}
+}
+
+// Sync runs one anti-entropy round with peer: if the peer's topology is newer
+// the node adopts it, and if the node's is newer it's pushed to the peer.
+func (n *Node) Sync(ctx context.Context, peer string) error {
+	var remote Digest
+	if err := n.call(ctx, http.MethodGet, peer, "/digest", nil, &remote); err != nil {
+		return err
+	}
+
+	local := ringDigest(n.ring)
+	switch {
+	case remote.Newer(local):
+		var snap hashring.Snapshot
+		if err := n.call(ctx, http.MethodGet, peer, "/snapshot", nil, &snap); err != nil {
+			return err
+		}
+
+		if err := n.adopt(snap); err != nil {
+			return fmt.Errorf("gossip: applying snapshot from %s: %w", peer, err)
+		}
+	case local.Newer(remote):
+		return n.call(ctx, http.MethodPost, peer, "/snapshot", n.ring.Snapshot(), &remote)
+	}
+
+	return nil
+}
+
+// adopt applies snap to the ring if it's newer.
+func (n *Node) adopt(snap hashring.Snapshot) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !digestOf(snap).Newer(ringDigest(n.ring)) {
+		return nil
+	}
+
+	return n.ring.Apply(snap)
+}
+
+// call sends a request to a peer's handler, encoding in as the body if it
+// isn't nil and decoding the response into out.
+func (n *Node) call(ctx context.Context, method, peer, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	url := strings.TrimSuffix(peer, "/") + path
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("gossip: %w", err)
+	}
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gossip: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("gossip: %s %s: %s: %s", method, url, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("gossip: decoding response from %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// writeJSON writes v as the response body.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
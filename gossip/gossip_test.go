@@ -0,0 +1,203 @@
+package gossip
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// cluster starts n nodes with their own rings, each knowing every other node
+// as a peer.
+func cluster(t *testing.T, n int, opts ...Option) ([]*Node, []string) {
+	t.Helper()
+
+	nodes := make([]*Node, n)
+	urls := make([]string, n)
+	for i := range n {
+		i := i
+		srv := httptest.NewServer(nil)
+		t.Cleanup(srv.Close)
+		urls[i] = srv.URL
+
+		ring := hashring.New(50)
+		require.NoError(t, ring.AddServer("server1"))
+		nodes[i] = NewNode(ring, nil, opts...)
+		srv.Config.Handler = nodes[i].Handler()
+	}
+
+	for i, node := range nodes {
+		for j, url := range urls {
+			if i != j {
+				node.peers = append(node.peers, url)
+			}
+		}
+	}
+
+	return nodes, urls
+}
+
+func TestDigestNewer(t *testing.T) {
+	require.True(t, Digest{Version: 2}.Newer(Digest{Version: 1, Checksum: 9}))
+	require.False(t, Digest{Version: 1, Checksum: 9}.Newer(Digest{Version: 2}))
+	require.True(t, Digest{Version: 1, Checksum: 9}.Newer(Digest{Version: 1, Checksum: 8}))
+	require.False(t, Digest{Version: 1, Checksum: 9}.Newer(Digest{Version: 1, Checksum: 9}))
+}
+
+func TestSync(t *testing.T) {
+	nodes, urls := cluster(t, 2)
+	a, b := nodes[0].ring, nodes[1].ring
+	ctx := context.Background()
+
+	// b pulls a's newer topology
+	require.NoError(t, a.AddServer("server2"))
+	require.NoError(t, nodes[1].Sync(ctx, urls[0]))
+	require.Equal(t, a.Checksum(), b.Checksum())
+	require.Equal(t, a.Version(), b.Version())
+	require.Equal(t, hashring.ChangeSync, b.History()[len(b.History())-1].Type)
+
+	// a pushes its newer topology to b
+	require.NoError(t, a.RemoveServer("server1"))
+	require.NoError(t, nodes[0].Sync(ctx, urls[1]))
+	require.Equal(t, []string{"server2"}, b.GetServers())
+
+	// nothing to do once they agree
+	version := b.Version()
+	require.NoError(t, nodes[0].Sync(ctx, urls[1]))
+	require.Equal(t, version, b.Version())
+}
+
+func TestSyncConcurrentChanges(t *testing.T) {
+	nodes, urls := cluster(t, 2)
+	a, b := nodes[0].ring, nodes[1].ring
+	ctx := context.Background()
+
+	// Both change at the same version; the higher checksum wins on both
+	require.NoError(t, a.AddServer("server2"))
+	require.NoError(t, b.AddServer("server3"))
+	winner := max(a.Checksum(), b.Checksum())
+
+	require.NoError(t, nodes[0].Sync(ctx, urls[1]))
+	require.NoError(t, nodes[1].Sync(ctx, urls[0]))
+	require.Equal(t, winner, a.Checksum())
+	require.Equal(t, winner, b.Checksum())
+	require.Equal(t, a.Version(), b.Version())
+}
+
+func TestRun(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+
+	nodes, _ := cluster(t, 4, WithInterval(5*time.Millisecond), WithErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, len(nodes))
+	for _, node := range nodes {
+		go func() { done <- node.Run(ctx) }()
+	}
+
+	require.NoError(t, nodes[2].ring.AddServer("server2"))
+	require.NoError(t, nodes[2].ring.AddServer("server3"))
+	want := nodes[2].ring.Checksum()
+
+	require.Eventually(t, func() bool {
+		for _, node := range nodes {
+			if node.ring.Checksum() != want {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 5*time.Millisecond)
+
+	cancel()
+	for range nodes {
+		require.ErrorIs(t, <-done, context.Canceled)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Empty(t, errs)
+}
+
+func TestRunReportsErrors(t *testing.T) {
+	errs := make(chan error, 1)
+	ring := hashring.New(50)
+	node := NewNode(ring, []string{"http://127.0.0.1:1"}, WithInterval(time.Millisecond), WithErrorHandler(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = node.Run(ctx) }()
+
+	select {
+	case err := <-errs:
+		require.ErrorContains(t, err, "127.0.0.1:1")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Expected an error for an unreachable peer")
+	}
+}
+
+func TestSyncErrors(t *testing.T) {
+	nodes, urls := cluster(t, 1)
+	ctx := context.Background()
+
+	require.Error(t, nodes[0].Sync(ctx, "http://127.0.0.1:1"))
+	require.Error(t, nodes[0].Sync(ctx, urls[0]+"/missing"))
+
+	// Rings that place servers differently can't sync
+	other := NewNode(hashring.New(100), nil)
+	require.NoError(t, other.ring.AddServer("server1"))
+	require.NoError(t, other.ring.AddServer("server2"))
+	otherSrv := httptest.NewServer(other.Handler())
+	defer otherSrv.Close()
+
+	err := nodes[0].Sync(ctx, otherSrv.URL)
+	require.ErrorContains(t, err, fmt.Sprintf("snapshot has %d virtual nodes", 100))
+}
+
+func TestHandlerLimitsSnapshotSize(t *testing.T) {
+	nodes, urls := cluster(t, 1, WithMaxSnapshotSize(1024))
+
+	resp, err := http.Get(urls[0] + "/digest")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var digest Digest
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&digest))
+	require.Equal(t, digestOf(nodes[0].ring.Snapshot()), digest)
+
+	// A newer snapshot too large to accept is rejected unread
+	ring := hashring.New(50)
+	for i := range 100 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	data, err := json.Marshal(ring.Snapshot())
+	require.NoError(t, err)
+	require.Greater(t, len(data), 1024)
+
+	resp, err = http.Post(urls[0]+"/snapshot", "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	require.Equal(t, []string{"server1"}, nodes[0].ring.GetServers())
+}
@@ -0,0 +1,159 @@
+// Package grpcring integrates the consistent hash ring with gRPC.
+//
+// It provides a load balancer that routes each RPC to the backend owning a
+// routing key carried in the call's metadata, giving sticky routing without a
+// sidecar, and a resolver that publishes the ring's membership as the
+// connection's addresses so clients follow topology changes.
+package grpcring
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// Name is the name the default balancer is registered under. Select it
+	// with a service config such as:
+	//
+	//	{"loadBalancingConfig": [{"hashlab_ring": {}}]}
+	Name = "hashlab_ring"
+
+	// DefaultMetadataKey is the metadata field the default balancer routes by.
+	DefaultMetadataKey = "x-hashlab-key"
+
+	// DefaultVirtualNodes is the number of virtual nodes per backend used by
+	// the default balancer.
+	DefaultVirtualNodes = 150
+)
+
+func init() {
+	balancer.Register(NewBuilder())
+}
+
+// Option configures a balancer builder.
+type Option func(*pickerBuilder)
+
+// WithName sets the name the balancer is registered under.
+func WithName(name string) Option {
+	return func(b *pickerBuilder) {
+		b.name = name
+	}
+}
+
+// WithMetadataKey sets the metadata field the balancer routes by.
+func WithMetadataKey(key string) Option {
+	return func(b *pickerBuilder) {
+		b.key = key
+	}
+}
+
+// WithVirtualNodes sets the number of virtual nodes per backend.
+func WithVirtualNodes(n int) Option {
+	return func(b *pickerBuilder) {
+		b.vnodes = n
+	}
+}
+
+// NewBuilder creates a balancer builder that hashes a per-RPC metadata field
+// onto a ring of the connection's ready backends.
+//
+// RPCs without the metadata field are spread round-robin. The default builder
+// (Name, DefaultMetadataKey) is registered automatically when this package is
+// imported; register additional builders for other keys:
+//
+//	balancer.Register(grpcring.NewBuilder(
+//		grpcring.WithName("tenant_ring"),
+//		grpcring.WithMetadataKey("x-tenant-id"),
+//	))
+func NewBuilder(opts ...Option) balancer.Builder {
+	b := &pickerBuilder{
+		name:   Name,
+		key:    DefaultMetadataKey,
+		vnodes: DefaultVirtualNodes,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return base.NewBalancerBuilder(b.name, b, base.Config{HealthCheck: true})
+}
+
+// WithRoutingKey returns a context whose outgoing metadata routes RPCs made
+// with it by key, using DefaultMetadataKey.
+//
+// Example:
+//
+//	ctx = grpcring.WithRoutingKey(ctx, userID)
+//	resp, err := client.GetProfile(ctx, req)
+func WithRoutingKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, DefaultMetadataKey, key)
+}
+
+type pickerBuilder struct {
+	name   string
+	key    string
+	vnodes int
+}
+
+// Build creates a picker from the ready backends. It's called by gRPC every
+// time the set of ready backends changes.
+func (b *pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	p := &picker{
+		key:      b.key,
+		ring:     hashring.New(b.vnodes),
+		subConns: make(map[string]balancer.SubConn, len(info.ReadySCs)),
+	}
+
+	for sc, sci := range info.ReadySCs {
+		addr := sci.Address.Addr
+		if _, ok := p.subConns[addr]; ok {
+			continue
+		}
+
+		p.subConns[addr] = sc
+		_ = p.ring.AddServer(addr)
+	}
+
+	p.order = p.ring.GetServers()
+	sort.Strings(p.order)
+	return p
+}
+
+type picker struct {
+	key      string
+	ring     *hashring.HashRing
+	subConns map[string]balancer.SubConn
+	order    []string // sorted addresses for round-robin fallback
+	next     atomic.Uint64
+}
+
+// Pick selects the backend for a single RPC.
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	var addr string
+	if md, ok := metadata.FromOutgoingContext(info.Ctx); ok {
+		if values := md.Get(p.key); len(values) > 0 && values[0] != "" {
+			server, err := p.ring.GetServer(values[0])
+			if err != nil {
+				return balancer.PickResult{}, balancer.ErrNoSubConnAvailable
+			}
+			addr = server
+		}
+	}
+
+	if addr == "" {
+		addr = p.order[p.next.Add(1)%uint64(len(p.order))]
+	}
+
+	return balancer.PickResult{SubConn: p.subConns[addr]}, nil
+}
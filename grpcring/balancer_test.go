@@ -0,0 +1,126 @@
+package grpcring
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+const (
+	serverHeader = "x-served-by"
+	defaultWait  = 5 * time.Second
+	tick         = 10 * time.Millisecond
+)
+
+// startBackends starts n gRPC health servers that report their address in a
+// response header.
+func startBackends(t *testing.T, n int) []string {
+	t.Helper()
+
+	addrs := make([]string, n)
+	for i := range n {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		addr := lis.Addr().String()
+		srv := grpc.NewServer(grpc.UnaryInterceptor(func(
+			ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+		) (any, error) {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(serverHeader, addr))
+			return handler(ctx, req)
+		}))
+		healthpb.RegisterHealthServer(srv, health.NewServer())
+
+		go func() { _ = srv.Serve(lis) }()
+		t.Cleanup(srv.Stop)
+		addrs[i] = addr
+	}
+
+	return addrs
+}
+
+func dial(t *testing.T, addrs []string) healthpb.HealthClient {
+	t.Helper()
+
+	state := resolver.State{}
+	for _, addr := range addrs {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+	}
+
+	r := manual.NewBuilderWithScheme("test")
+	r.InitialState(state)
+
+	conn, err := grpc.NewClient(r.Scheme()+":///backends",
+		grpc.WithResolvers(r),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig": [{%q: {}}]}`, Name)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func servedBy(t *testing.T, ctx context.Context, client healthpb.HealthClient) string {
+	t.Helper()
+
+	var header metadata.MD
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header), grpc.WaitForReady(true))
+	require.NoError(t, err)
+	require.Len(t, header.Get(serverHeader), 1)
+	return header.Get(serverHeader)[0]
+}
+
+func TestBalancerRoutesByKey(t *testing.T) {
+	addrs := startBackends(t, 3)
+	client := dial(t, addrs)
+
+	ring := hashring.New(DefaultVirtualNodes)
+	for _, addr := range addrs {
+		require.NoError(t, ring.AddServer(addr))
+	}
+
+	// Wait for every backend to become ready so the picker sees all of them
+	require.Eventually(t, func() bool {
+		seen := make(map[string]bool)
+		for range 10 {
+			seen[servedBy(t, t.Context(), client)] = true
+		}
+		return len(seen) == len(addrs)
+	}, defaultWait, tick)
+
+	for i := range 20 {
+		key := fmt.Sprintf("user-%d", i)
+		expected, err := ring.GetServer(key)
+		require.NoError(t, err)
+
+		for range 3 {
+			require.Equal(t, expected, servedBy(t, WithRoutingKey(t.Context(), key), client))
+		}
+	}
+}
+
+func TestBalancerRoundRobinWithoutKey(t *testing.T) {
+	addrs := startBackends(t, 2)
+	client := dial(t, addrs)
+
+	require.Eventually(t, func() bool {
+		seen := make(map[string]bool)
+		for range 4 {
+			seen[servedBy(t, t.Context(), client)] = true
+		}
+		return len(seen) == len(addrs)
+	}, defaultWait, tick)
+}
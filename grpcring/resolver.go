@@ -0,0 +1,198 @@
+package grpcring
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/resolver"
+)
+
+const (
+	// Scheme is the default URI scheme handled by resolvers created with
+	// NewResolverBuilder, e.g. "hashlab:///cache".
+	Scheme = "hashlab"
+
+	// DefaultPollInterval is how often resolvers check the ring for changes.
+	DefaultPollInterval = time.Second
+)
+
+type attrKey int
+
+const (
+	zoneKey attrKey = iota
+	tagsKey
+	weightKey
+)
+
+// ResolverOption configures a resolver builder.
+type ResolverOption func(*resolverBuilder)
+
+// WithScheme sets the URI scheme the resolver is registered for.
+func WithScheme(scheme string) ResolverOption {
+	return func(b *resolverBuilder) {
+		b.scheme = scheme
+	}
+}
+
+// WithPollInterval sets how often the resolver checks the ring for changes.
+func WithPollInterval(d time.Duration) ResolverOption {
+	return func(b *resolverBuilder) {
+		b.interval = d
+	}
+}
+
+// NewResolverBuilder creates a gRPC resolver that publishes the ring's
+// membership as the connection's addresses.
+//
+// Each server name is used as an address and carries its zone, tags, and
+// weight as attributes (see Zone, Tags, and Weight). The resolver watches the
+// ring's version and pushes a new address list whenever the topology changes,
+// keeping gRPC clients in sync with whatever feeds the ring (discovery, admin
+// APIs, etc.).
+//
+// Example:
+//
+//	conn, err := grpc.NewClient("hashlab:///search",
+//		grpc.WithResolvers(grpcring.NewResolverBuilder(ring)),
+//		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"hashlab_ring": {}}]}`),
+//		grpc.WithTransportCredentials(insecure.NewCredentials()),
+//	)
+func NewResolverBuilder(ring *hashring.HashRing, opts ...ResolverOption) resolver.Builder {
+	b := &resolverBuilder{
+		ring:     ring,
+		scheme:   Scheme,
+		interval: DefaultPollInterval,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Zone returns the zone attribute of an address published by the resolver.
+func Zone(addr resolver.Address) string {
+	zone, _ := addr.Attributes.Value(zoneKey).(string)
+	return zone
+}
+
+// Tags returns the tags attribute of an address published by the resolver.
+func Tags(addr resolver.Address) []string {
+	tags, _ := addr.Attributes.Value(tagsKey).(tagList)
+	return slices.Clone(tags)
+}
+
+// Weight returns the weight attribute of an address published by the
+// resolver, which is 1 for servers without an explicit weight.
+func Weight(addr resolver.Address) float64 {
+	if weight, ok := addr.Attributes.Value(weightKey).(float64); ok && weight > 0 {
+		return weight
+	}
+
+	return 1
+}
+
+type resolverBuilder struct {
+	ring     *hashring.HashRing
+	scheme   string
+	interval time.Duration
+}
+
+// Build starts a resolver for the target. The target's path isn't used since
+// the builder is bound to a single ring.
+func (b *resolverBuilder) Build(_ resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &ringResolver{
+		ring:    b.ring,
+		cc:      cc,
+		resolve: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	r.update()
+
+	r.wg.Add(1)
+	go r.watch(b.interval)
+	return r, nil
+}
+
+// Scheme returns the URI scheme handled by the builder.
+func (b *resolverBuilder) Scheme() string {
+	return b.scheme
+}
+
+type ringResolver struct {
+	ring    *hashring.HashRing
+	cc      resolver.ClientConn
+	resolve chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+	version uint64
+}
+
+// ResolveNow triggers an immediate re-resolution.
+func (r *ringResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.resolve <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops watching the ring.
+func (r *ringResolver) Close() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+func (r *ringResolver) watch(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-r.resolve:
+			r.update()
+		case <-ticker.C:
+			if r.ring.Version() != r.version {
+				r.update()
+			}
+		}
+	}
+}
+
+// update publishes the ring's current membership.
+func (r *ringResolver) update() {
+	snap := r.ring.Snapshot()
+	r.version = snap.Version
+
+	addrs := make([]resolver.Address, 0, len(snap.Servers))
+	for _, info := range snap.Servers {
+		addrs = append(addrs, resolver.Address{
+			Addr: info.Name,
+			Attributes: attributes.New(zoneKey, info.Zone).
+				WithValue(tagsKey, tagList(info.Tags)).
+				WithValue(weightKey, info.Weight),
+		})
+	}
+
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		r.cc.ReportError(err)
+	}
+}
+
+// tagList wraps tags so attribute comparisons (which require comparable values
+// or an Equal method) work.
+type tagList []string
+
+// Equal reports whether both tag lists hold the same tags in the same order.
+func (t tagList) Equal(o any) bool {
+	other, ok := o.(tagList)
+	return ok && slices.Equal(t, other)
+}
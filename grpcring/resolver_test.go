@@ -0,0 +1,96 @@
+package grpcring
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+// fakeClientConn records the states published by a resolver.
+type fakeClientConn struct {
+	resolver.ClientConn
+
+	mu     sync.Mutex
+	states []resolver.State
+}
+
+func (f *fakeClientConn) UpdateState(s resolver.State) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states = append(f.states, s)
+	return nil
+}
+
+func (f *fakeClientConn) ReportError(error) {}
+
+func (f *fakeClientConn) ParseServiceConfig(string) *serviceconfig.ParseResult { return nil }
+
+func (f *fakeClientConn) last() (resolver.State, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.states[len(f.states)-1], len(f.states)
+}
+
+func addrNames(s resolver.State) []string {
+	names := make([]string, len(s.Addresses))
+	for i, addr := range s.Addresses {
+		names[i] = addr.Addr
+	}
+	return names
+}
+
+func TestResolverPublishesMembership(t *testing.T) {
+	ring := hashring.New(10)
+	require.NoError(t, ring.AddServerWithInfo(hashring.ServerInfo{Name: "10.0.0.1:9000", Zone: "us-east-1a", Tags: []string{"ssd"}, Weight: 2}))
+	require.NoError(t, ring.AddServer("10.0.0.2:9000"))
+
+	b := NewResolverBuilder(ring, WithPollInterval(time.Millisecond))
+	require.Equal(t, Scheme, b.Scheme())
+
+	cc := &fakeClientConn{}
+	r, err := b.Build(resolver.Target{}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	state, _ := cc.last()
+	require.Equal(t, []string{"10.0.0.1:9000", "10.0.0.2:9000"}, addrNames(state))
+	require.Equal(t, "us-east-1a", Zone(state.Addresses[0]))
+	require.Equal(t, []string{"ssd"}, Tags(state.Addresses[0]))
+	require.Equal(t, 2.0, Weight(state.Addresses[0]))
+	require.Equal(t, 1.0, Weight(state.Addresses[1]))
+	require.Empty(t, Zone(state.Addresses[1]))
+
+	// Topology changes are picked up
+	require.NoError(t, ring.RemoveServer("10.0.0.1:9000"))
+	require.NoError(t, ring.AddServer("10.0.0.3:9000"))
+
+	require.Eventually(t, func() bool {
+		state, _ := cc.last()
+		return len(state.Addresses) == 2 && state.Addresses[1].Addr == "10.0.0.3:9000"
+	}, defaultWait, tick)
+}
+
+func TestResolverResolveNow(t *testing.T) {
+	ring := hashring.New(10)
+	require.NoError(t, ring.AddServer("10.0.0.1:9000"))
+
+	b := NewResolverBuilder(ring, WithScheme("ring"), WithPollInterval(time.Hour))
+	require.Equal(t, "ring", b.Scheme())
+
+	cc := &fakeClientConn{}
+	r, err := b.Build(resolver.Target{}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	_, n := cc.last()
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	require.Eventually(t, func() bool {
+		_, count := cc.last()
+		return count > n
+	}, defaultWait, tick)
+}
@@ -0,0 +1,69 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxRecommendedVNodes is the largest virtual node count RecommendVNodes will
+// return. Beyond this, the memory and AddServer cost of a ring outweigh the
+// small improvements in distribution.
+const MaxRecommendedVNodes = 4096
+
+// advisorNamings are the server naming schemes RecommendVNodes simulates, so
+// the recommendation doesn't depend on how a particular set of names hashes.
+var advisorNamings = []string{"server-%d", "10.0.%d.1:6379", "node%d.example.com"}
+
+// RecommendVNodes returns the smallest virtual node count that gives a ring of
+// numServers servers a distribution CV (in percent, as reported by
+// AnalyzePerformance) of at most targetCV.
+//
+// The recommendation is empirical: rings are built for several server naming
+// schemes and the CV of the share of the hash space each server owns is
+// averaged across them. This is the CV a large, uniformly hashed key set
+// converges to. Counts up to MaxRecommendedVNodes are tried in steps of about
+// 25%. CV doesn't fall smoothly with the count, and eventually levels off, so
+// if targetCV can't be reached the count with the lowest CV is returned.
+//
+// This builds many rings, so it's meant for capacity planning rather than
+// being called at startup.
+//
+// Example:
+//
+//	vnodes := hashring.RecommendVNodes(len(servers), 10) // CV <= 10%
+//	ring := hashring.New(vnodes)
+func RecommendVNodes(numServers int, targetCV float64) int {
+	if numServers <= 1 {
+		return 1
+	}
+
+	best, bestCV := 1, math.Inf(1)
+	for vnodes := 1; vnodes <= MaxRecommendedVNodes; vnodes = max(vnodes+1, vnodes*5/4) {
+		cv := simulateCV(numServers, vnodes)
+		if cv <= targetCV {
+			return vnodes
+		}
+
+		if cv < bestCV {
+			best, bestCV = vnodes, cv
+		}
+	}
+
+	return best
+}
+
+// simulateCV returns the ownership CV, in percent, of a ring with numServers
+// servers and vnodes virtual nodes each, averaged over advisorNamings.
+func simulateCV(numServers, vnodes int) float64 {
+	var total float64
+	for _, naming := range advisorNamings {
+		ring := New(vnodes)
+		for i := range numServers {
+			_ = ring.AddServer(fmt.Sprintf(naming, i))
+		}
+
+		total += ring.OwnershipCV()
+	}
+
+	return total / float64(len(advisorNamings))
+}
@@ -0,0 +1,27 @@
+package hashring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecommendVNodes(t *testing.T) {
+	require.Equal(t, 1, RecommendVNodes(0, 5))
+	require.Equal(t, 1, RecommendVNodes(1, 5))
+
+	vnodes := RecommendVNodes(5, 20)
+	require.Greater(t, vnodes, 1)
+	require.LessOrEqual(t, simulateCV(5, vnodes), 20.0)
+	for v := 1; v < vnodes; v = max(v+1, v*5/4) {
+		require.Greater(t, simulateCV(5, v), 20.0, "%d vnodes should be the smallest count meeting the target", vnodes)
+	}
+
+	// Tighter targets need more virtual nodes
+	require.Greater(t, RecommendVNodes(5, 10), vnodes)
+
+	// Unreachable targets get the best count found
+	best := RecommendVNodes(5, 0)
+	require.LessOrEqual(t, best, MaxRecommendedVNodes)
+	require.LessOrEqual(t, simulateCV(5, best), simulateCV(5, vnodes))
+}
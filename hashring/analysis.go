@@ -0,0 +1,55 @@
+package hashring
+
+// cancelCheckInterval is how many keys context-aware analyses look up between
+// checks for cancellation.
+const cancelCheckInterval = 1024
+
+// AnalysisOption configures a call to GetDistribution or AnalyzePerformance.
+type AnalysisOption func(*analysisConfig)
+
+type analysisConfig struct {
+	progress func(done, total int)
+}
+
+// WithProgress calls fn as keys are analyzed, with the number of keys done so
+// far and the total, so long analyses can drive a progress bar or log a
+// heartbeat. It's called about once per percent of the keys, and always once
+// all of them are done.
+//
+// fn runs while the ring is read-locked, so it must not modify the ring.
+//
+// Example:
+//
+//	metrics := ring.AnalyzePerformance(keys, hashring.WithProgress(func(done, total int) {
+//		fmt.Fprintf(os.Stderr, "\ranalyzed %d/%d keys", done, total)
+//	}))
+func WithProgress(fn func(done, total int)) AnalysisOption {
+	return func(c *analysisConfig) {
+		c.progress = fn
+	}
+}
+
+// newAnalysisConfig applies opts to the default configuration.
+func newAnalysisConfig(opts []AnalysisOption) analysisConfig {
+	var cfg analysisConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// reporter returns a function to call after each of total keys is done, which
+// calls the progress callback, if there is one, every percent and at the end.
+func (c analysisConfig) reporter(total int) func(done int) {
+	if c.progress == nil {
+		return func(int) {}
+	}
+
+	every := max(total/100, 1)
+	return func(done int) {
+		if done%every == 0 || done == total {
+			c.progress(done, total)
+		}
+	}
+}
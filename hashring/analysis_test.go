@@ -0,0 +1,84 @@
+package hashring
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithProgress(t *testing.T) {
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	keys := make([]string, 250)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	var calls [][2]int
+	progress := WithProgress(func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	})
+
+	// Reported every percent (2 keys here) and at the end
+	require.Equal(t, ring.GetDistribution(keys), ring.GetDistribution(keys, progress))
+	require.Len(t, calls, 125)
+	require.Equal(t, [2]int{2, 250}, calls[0])
+	require.Equal(t, [2]int{250, 250}, calls[len(calls)-1])
+
+	calls = nil
+	metrics := ring.AnalyzePerformance(keys[:7], progress)
+	require.Equal(t, 7, metrics.TotalKeys)
+	require.Equal(t, [][2]int{{1, 7}, {2, 7}, {3, 7}, {4, 7}, {5, 7}, {6, 7}, {7, 7}}, calls)
+
+	calls = nil
+	ring.GetDistribution(nil, progress)
+	require.Empty(t, calls)
+}
+
+func TestAnalysisContext(t *testing.T) {
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	keys := make([]string, 5000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	dist, err := ring.GetDistributionContext(context.Background(), keys)
+	require.NoError(t, err)
+	require.Equal(t, ring.GetDistribution(keys), dist)
+
+	metrics, err := ring.AnalyzePerformanceContext(context.Background(), keys)
+	require.NoError(t, err)
+	require.Equal(t, len(keys), metrics.TotalKeys)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dist, err = ring.GetDistributionContext(ctx, keys)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, dist)
+
+	metrics, err = ring.AnalyzePerformanceContext(ctx, keys)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, metrics.TotalKeys)
+
+	// Analyses stop partway when the context is canceled while they run
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var done int
+	_, err = ring.GetDistributionContext(ctx, keys, WithProgress(func(n, _ int) {
+		done = n
+		if n >= 2000 {
+			cancel()
+		}
+	}))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, done, len(keys))
+}
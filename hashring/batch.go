@@ -0,0 +1,222 @@
+package hashring
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WithWriteBatching queues topology changes and applies them in batches on a
+// single goroutine, taking the ring's write lock once per batch rather than
+// once per change. Changes made within window of the first queued one are
+// applied together; changes queued while a batch is being applied join the
+// next one.
+//
+// Every method that changes the ring's topology is queued: AddServer,
+// AddServerWithInfo, AddServerWithTokens, RemoveServer, RenameServer,
+// ReplaceServer, SetServerInfo, SetServers, SetState (and the methods built on
+// it, such as MarkDown), SetWeight (and StepWeight's steps), Rebalance,
+// Rollback, Apply, Pin, Unpin, SetCanary, and ClearCanary, as well as changes
+// made through a RingSet.
+//
+// Consecutive membership changes (adding servers without tokens and removing
+// servers) are placed together: the ring's virtual nodes are laid out once for
+// the run rather than after each change, and OnMove subscribers are sent the
+// run's combined moves. Other changes need the current layout, so the run is
+// placed before they're applied.
+//
+// This keeps lookups fast during churn storms, such as a fleet of serverless
+// workers registering at once, where per-change locking and placement would
+// repeatedly stall readers. Each call still blocks until its change is applied
+// and returns its own error, so callers see the same results as without
+// batching, only later. A zero window batches only the changes that queue up
+// while a batch is being applied.
+//
+// Example:
+//
+//	ring := hashring.New(150, hashring.WithWriteBatching(5*time.Millisecond))
+func WithWriteBatching(window time.Duration) Option {
+	return func(h *HashRing) {
+		h.writes = &writeBatcher{window: max(window, 0)}
+	}
+}
+
+// writeBatcher collects queued changes for a ring. It has its own lock so
+// changes can be queued while a batch holds h.mu.
+type writeBatcher struct {
+	mu       sync.Mutex
+	window   time.Duration
+	pending  []write // changes waiting to be applied, oldest first
+	applying bool    // whether a goroutine is applying batches
+}
+
+// write is a queued change. apply runs with h.mu held for writing, and its
+// result is sent on done.
+type write struct {
+	apply      func() error
+	membership bool // only adds or removes servers, so placement can wait
+	done       chan error
+}
+
+// write applies fn with h.mu held for writing, either directly or, when write
+// batching is enabled, as part of the next batch. It returns fn's error.
+func (h *HashRing) write(fn func() error) error {
+	return h.enqueue(fn, false)
+}
+
+// writeMembership is write for changes that only add or remove servers, whose
+// placement a batch defers until the run of them ends (see applyMembership).
+func (h *HashRing) writeMembership(fn func() error) error {
+	return h.enqueue(fn, true)
+}
+
+// enqueue applies fn as write describes.
+func (h *HashRing) enqueue(fn func() error, membership bool) error {
+	if h.writes == nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		return fn()
+	}
+
+	done := make(chan error, 1)
+
+	b := h.writes
+	b.mu.Lock()
+	b.pending = append(b.pending, write{apply: fn, membership: membership, done: done})
+	if !b.applying {
+		b.applying = true
+		go h.applyWrites()
+	}
+	b.mu.Unlock()
+
+	return <-done
+}
+
+// applyWrites applies queued changes in batches until the queue is empty.
+func (h *HashRing) applyWrites() {
+	b := h.writes
+	for {
+		if b.window > 0 {
+			time.Sleep(b.window)
+		}
+
+		b.mu.Lock()
+		batch := b.pending
+		b.pending = nil
+		if len(batch) == 0 {
+			b.applying = false
+			b.mu.Unlock()
+			return
+		}
+		b.mu.Unlock()
+
+		h.mu.Lock()
+		h.metrics.Histogram("write_batch_size", float64(len(batch)))
+		for len(batch) > 0 {
+			// Take the next run of membership changes, or the next other change
+			n := 1
+			for batch[0].membership && n < len(batch) && batch[n].membership {
+				n++
+			}
+
+			if batch[0].membership {
+				h.applyMembership(batch[:n])
+			} else {
+				batch[0].done <- batch[0].apply()
+			}
+			batch = batch[n:]
+		}
+		h.mu.Unlock()
+	}
+}
+
+// applyMembership applies a run of membership changes, placing the servers'
+// virtual nodes once at the end rather than after each change. If they can't
+// all be placed (see place), the run is undone and replayed one change at a
+// time, so each gets the result it would have had without batching. The
+// caller must hold h.mu for writing.
+func (h *HashRing) applyMembership(run []write) {
+	saved := h.saveMembership()
+	errs := make([]error, len(run))
+
+	h.deferring = true
+	for i, w := range run {
+		errs[i] = w.apply()
+	}
+	h.deferring = false
+
+	if err := h.settle(); err != nil {
+		h.restoreMembership(saved)
+		for i, w := range run {
+			errs[i] = w.apply()
+		}
+	}
+
+	// Callers can't look their change up until h.mu is released, by which
+	// time it's placed
+	for i, w := range run {
+		w.done <- errs[i]
+	}
+}
+
+// settle places every server's virtual nodes after membership changes were
+// applied without placing them, and sends OnMove subscribers their combined
+// moves. The caller must hold h.mu for writing.
+func (h *HashRing) settle() error {
+	if !h.stale {
+		return nil
+	}
+
+	h.stale = false
+	if h.evenlySpaced() {
+		h.placeEvenly()
+	} else if err := h.rebuild(); err != nil {
+		return err
+	}
+
+	h.notifyMoves()
+	return nil
+}
+
+// membership is the state a run of membership changes can modify, saved so a
+// run can be undone. The virtual nodes aren't included since the run leaves
+// them alone until it's placed.
+type membership struct {
+	names      []string
+	ids        map[string]int32
+	servers    map[string]ServerInfo
+	normalized map[string]string
+	pins       map[string]string
+	canary     Canary
+	downFrom   map[string]ServerState
+	version    uint64
+	history    []TopologyChange
+}
+
+// saveMembership returns the state a run of membership changes can modify.
+// The caller must hold h.mu.
+func (h *HashRing) saveMembership() membership {
+	return membership{
+		names:      slices.Clone(h.names),
+		ids:        maps.Clone(h.ids),
+		servers:    maps.Clone(h.servers),
+		normalized: maps.Clone(h.normalized),
+		pins:       maps.Clone(h.pins),
+		canary:     h.canary,
+		downFrom:   maps.Clone(h.downFrom),
+		version:    h.version,
+		history:    slices.Clone(h.history),
+	}
+}
+
+// restoreMembership undoes the membership changes made since m was saved. The
+// caller must hold h.mu for writing.
+func (h *HashRing) restoreMembership(m membership) {
+	h.names, h.ids, h.servers, h.normalized = m.names, m.ids, m.servers, m.normalized
+	h.pins, h.canary, h.downFrom = m.pins, m.canary, m.downFrom
+	h.version, h.history = m.version, m.history
+	h.indexServers()
+	h.stale = false
+}
@@ -0,0 +1,171 @@
+package hashring
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithWriteBatching(t *testing.T) {
+	sink := &recordingSink{}
+	ring := New(50, WithWriteBatching(20*time.Millisecond), WithMetricsSink(sink))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ring.AddServer(fmt.Sprintf("worker%d", i))
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.Len(t, ring.GetServers(), 20)
+	require.Equal(t, uint64(20), ring.Version())
+
+	// Changes made within the window share a lock acquisition
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	var batches int
+	for _, m := range sink.metrics {
+		if strings.HasPrefix(m, "histogram write_batch_size") {
+			batches++
+		}
+	}
+	require.Less(t, batches, 20)
+
+	// The ring matches one built without batching
+	want := New(50)
+	for i := range 20 {
+		require.NoError(t, want.AddServer(fmt.Sprintf("worker%d", i)))
+	}
+	require.Equal(t, want.Checksum(), ring.Checksum())
+}
+
+func TestWithWriteBatchingErrors(t *testing.T) {
+	ring := New(50, WithWriteBatching(0))
+	require.NoError(t, ring.AddServer("server1"))
+	require.Error(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServerWithTokens("server2", []uint64{1, 2, 3}))
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.Error(t, ring.RemoveServer("server1"))
+
+	// Each change is applied before the call returns
+	server, err := ring.GetServer("key")
+	require.NoError(t, err)
+	require.Equal(t, "server2", server)
+}
+
+func TestWithWriteBatchingSetWeight(t *testing.T) {
+	sink := &recordingSink{}
+	ring := New(50, WithWriteBatching(0), WithMetricsSink(sink))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	// Weight changes are queued like membership changes
+	require.NoError(t, ring.SetWeight("server1", 2))
+	require.Error(t, ring.SetWeight("server3", 2))
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	var batches int
+	for _, m := range sink.metrics {
+		if strings.HasPrefix(m, "histogram write_batch_size") {
+			batches++
+		}
+	}
+	require.Equal(t, 4, batches)
+}
+
+func TestWithWriteBatchingMixedChanges(t *testing.T) {
+	build := func(opts ...Option) *HashRing {
+		ring := New(50, opts...)
+		for i := range 5 {
+			require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+		}
+		return ring
+	}
+
+	var moves [][]RangeMove
+	var mu sync.Mutex
+	ring := build(WithWriteBatching(20*time.Millisecond), WithMaxVNodes(50*12))
+	ring.OnMove(func(m []RangeMove) {
+		mu.Lock()
+		defer mu.Unlock()
+		moves = append(moves, m)
+	})
+
+	// Changes that commute, so the batch's order doesn't matter
+	changes := []func(r *HashRing) error{
+		func(r *HashRing) error { return r.RemoveServer("server0") },
+		func(r *HashRing) error { return r.RemoveServer("server1") },
+		func(r *HashRing) error { return r.AddServerWithTokens("tokens", []uint64{1, 2, 3}) },
+		func(r *HashRing) error { return r.SetWeight("server2", 2) },
+	}
+	for i := range 8 {
+		changes = append(changes, func(r *HashRing) error { return r.AddServer(fmt.Sprintf("worker%d", i)) })
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(changes))
+	for i, change := range changes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = change(ring)
+		}()
+	}
+	wg.Wait()
+
+	// The vnode limit holds although placement was deferred: 5 servers, one
+	// of them doubled, plus 8 workers would need 14*50
+	var failed int
+	for _, err := range errs {
+		if err != nil {
+			require.ErrorContains(t, err, "would exceed")
+			failed++
+		}
+	}
+	require.Positive(t, failed)
+
+	// The ring matches one built without batching
+	want := build()
+	for _, server := range want.GetServers() {
+		if _, ok := ring.GetServerInfo(server); !ok {
+			require.NoError(t, want.RemoveServer(server))
+		}
+	}
+	for _, server := range ring.GetServers() {
+		info, _ := ring.GetServerInfo(server)
+		if _, ok := want.GetServerInfo(server); !ok {
+			require.NoError(t, want.AddServerWithInfo(info))
+		}
+	}
+	if info, _ := ring.GetServerInfo("server2"); info.Weight == 2 {
+		require.NoError(t, want.SetWeight("server2", 2))
+	}
+	require.Equal(t, want.Checksum(), ring.Checksum())
+	require.Equal(t, want.Tokens(), ring.Tokens())
+	require.Equal(t, want.Collisions(), ring.Collisions())
+
+	// Moves were reported per run of membership changes, not per change
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(moves) > 0
+	}, time.Second, time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Less(t, len(moves), len(changes)-failed)
+}
@@ -0,0 +1,142 @@
+package hashring
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive failures that trip a
+	// server's circuit breaker.
+	DefaultFailureThreshold = 5
+
+	// DefaultBreakerCooldown is how long a tripped server is excluded from
+	// lookups before a probe request is let through.
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// WithCircuitBreaker sets the number of consecutive failures that trip a
+// server's circuit breaker, and how long it stays open before a probe is let
+// through. A threshold of zero disables circuit breaking.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(h *HashRing) {
+		h.breakers.threshold = max(threshold, 0)
+		h.breakers.cooldown = cooldown
+	}
+}
+
+// breakers tracks a circuit breaker per server. It has its own lock so that
+// lookups holding h.mu for reading can still update breaker state.
+type breakers struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     map[string]*breaker
+}
+
+// breaker is open when failures reaches the threshold. Once until passes it's
+// half-open: a single lookup is allowed through as a probe, and the next
+// probe isn't allowed until another cooldown has passed.
+type breaker struct {
+	failures int
+	until    time.Time
+}
+
+// ReportFailure records a failed request to server. Once the server fails
+// repeatedly (see WithCircuitBreaker), its breaker trips and lookups skip it,
+// routing its keys to the next server clockwise, as though it had been
+// removed. After the cooldown a single probe lookup is routed to it; reporting
+// success for the probe closes the breaker, while another failure re-opens it.
+//
+// Circuit breaking is driven entirely by the failures callers report and
+// doesn't change the ring's topology, version, or checksum. It complements
+// active health checks rather than replacing them.
+//
+// This operation is thread-safe.
+//
+// Example:
+//
+//	server, _ := ring.GetServer(key)
+//	if err := call(server); err != nil {
+//		ring.ReportFailure(server)
+//	} else {
+//		ring.ReportSuccess(server)
+//	}
+func (h *HashRing) ReportFailure(server string) {
+	b := &h.breakers
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold == 0 {
+		return
+	}
+
+	if b.state == nil {
+		b.state = make(map[string]*breaker)
+	}
+
+	s, ok := b.state[server]
+	if !ok {
+		s = &breaker{}
+		b.state[server] = s
+	}
+
+	now := h.now()
+	open := s.failures >= b.threshold && now.Before(s.until)
+
+	s.failures++
+	if s.failures >= b.threshold {
+		s.until = now.Add(b.cooldown)
+		if !open {
+			h.metrics.Counter("breaker_trips", 1, Label{Name: "server", Value: server})
+		}
+	}
+}
+
+// ReportSuccess records a successful request to server, closing its breaker.
+//
+// This operation is thread-safe.
+func (h *HashRing) ReportSuccess(server string) {
+	h.breakers.reset(server)
+}
+
+// Tripped reports whether server's breaker is open, excluding it from lookups.
+// Servers whose cooldown has passed aren't considered tripped.
+//
+// This operation is thread-safe.
+func (h *HashRing) Tripped(server string) bool {
+	b := &h.breakers
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.state[server]
+	return ok && s.failures >= b.threshold && h.now().Before(s.until)
+}
+
+// allow reports whether lookups may route to server, letting a probe through
+// when its breaker is half-open.
+func (h *HashRing) allow(server string) bool {
+	b := &h.breakers
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.state[server]
+	if !ok || s.failures < b.threshold {
+		return true
+	}
+
+	now := h.now()
+	if now.Before(s.until) {
+		return false
+	}
+
+	s.until = now.Add(b.cooldown)
+	return true
+}
+
+// reset closes server's breaker.
+func (b *breakers) reset(server string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.state, server)
+}
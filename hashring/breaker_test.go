@@ -0,0 +1,128 @@
+package hashring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+
+	ring := New(50, WithCircuitBreaker(3, time.Minute))
+	ring.now = func() time.Time { return now }
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	replicas, err := ring.GetReplicas("key", 3)
+	require.NoError(t, err)
+	owner := replicas[0]
+	version, checksum := ring.Version(), ring.Checksum()
+
+	// Failures below the threshold don't trip the breaker
+	ring.ReportFailure(owner)
+	ring.ReportFailure(owner)
+	require.False(t, ring.Tripped(owner))
+	requireServer(t, ring, "key", owner)
+
+	// A success resets the failure count
+	ring.ReportSuccess(owner)
+	ring.ReportFailure(owner)
+	ring.ReportFailure(owner)
+	require.False(t, ring.Tripped(owner))
+
+	// Tripping excludes the server from lookups
+	ring.ReportFailure(owner)
+	require.True(t, ring.Tripped(owner))
+	requireServer(t, ring, "key", replicas[1])
+
+	got, err := ring.GetReplicas("key", 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{replicas[1], replicas[2], owner}, got, "tripped servers should be tried last")
+
+	require.Equal(t, version, ring.Version(), "breakers shouldn't change the topology")
+	require.Equal(t, checksum, ring.Checksum())
+
+	// After the cooldown a single probe is let through
+	now = now.Add(time.Minute)
+	require.False(t, ring.Tripped(owner))
+	requireServer(t, ring, "key", owner)
+	requireServer(t, ring, "key", replicas[1])
+
+	// A failed probe re-opens the breaker
+	ring.ReportFailure(owner)
+	require.True(t, ring.Tripped(owner))
+	requireServer(t, ring, "key", replicas[1])
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	requireServer(t, ring, "key", owner)
+	ring.ReportSuccess(owner)
+	requireServer(t, ring, "key", owner)
+	requireServer(t, ring, "key", owner)
+}
+
+func TestCircuitBreakerAllTripped(t *testing.T) {
+	ring := New(50, WithCircuitBreaker(1, time.Minute))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	owner, err := ring.GetServer("key")
+	require.NoError(t, err)
+
+	ring.ReportFailure("server1")
+	ring.ReportFailure("server2")
+
+	// Lookups fail open rather than returning nothing
+	requireServer(t, ring, "key", owner)
+}
+
+func TestCircuitBreakerPins(t *testing.T) {
+	ring := New(50, WithCircuitBreaker(1, time.Minute))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.Pin("key", "server1"))
+
+	ring.ReportFailure("server1")
+	requireServer(t, ring, "key", "server2")
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	ring := New(50, WithCircuitBreaker(0, time.Minute))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	owner, err := ring.GetServer("key")
+	require.NoError(t, err)
+
+	for range 10 {
+		ring.ReportFailure(owner)
+	}
+
+	require.False(t, ring.Tripped(owner))
+	requireServer(t, ring, "key", owner)
+}
+
+func TestCircuitBreakerRemoveServer(t *testing.T) {
+	ring := New(50, WithCircuitBreaker(1, time.Minute))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	ring.ReportFailure("server1")
+	require.True(t, ring.Tripped("server1"))
+
+	// Re-adding a server starts with a closed breaker
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.NoError(t, ring.AddServer("server1"))
+	require.False(t, ring.Tripped("server1"))
+}
+
+func requireServer(t *testing.T, ring *HashRing, key, expected string) {
+	t.Helper()
+
+	server, err := ring.GetServer(key)
+	require.NoError(t, err)
+	require.Equal(t, expected, server)
+}
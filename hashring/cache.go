@@ -0,0 +1,105 @@
+package hashring
+
+import (
+	"container/list"
+	"sync"
+)
+
+// WithLookupCache caches up to size key to server resolutions, evicting the
+// least recently used entry when full. The cache is cleared whenever the
+// ring's version changes, so it never returns a stale owner.
+//
+// This helps workloads that look up the same keys repeatedly, where hashing
+// the key and searching the ring is measurable. Circuit breakers (see
+// ReportFailure) are still consulted on every lookup.
+//
+// Example:
+//
+//	ring := hashring.New(150, hashring.WithLookupCache(10_000))
+func WithLookupCache(size int) Option {
+	return func(h *HashRing) {
+		if size > 0 {
+			h.cache = newLookupCache(size)
+		}
+	}
+}
+
+// lookupCache is a bounded LRU cache of key to server. It has its own lock
+// so lookups holding h.mu for reading can update it.
+type lookupCache struct {
+	mu      sync.Mutex
+	size    int
+	version uint64
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key    string
+	server string
+}
+
+func newLookupCache(size int) *lookupCache {
+	return &lookupCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns the cached server for key, if it was cached at version.
+func (c *lookupCache) get(key string, version uint64) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version != version {
+		c.reset(version)
+		return "", false
+	}
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).server, true
+}
+
+// put caches server for key at version.
+func (c *lookupCache) put(key, server string, version uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version != version {
+		c.reset(version)
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).server = server
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, server: server})
+}
+
+// len returns the number of cached entries.
+func (c *lookupCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// reset clears the cache for a new version. The caller must hold c.mu.
+func (c *lookupCache) reset(version uint64) {
+	c.version = version
+	c.order.Init()
+	clear(c.entries)
+}
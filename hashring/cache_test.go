@@ -0,0 +1,83 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCache(t *testing.T) {
+	cached := New(50, WithLookupCache(8))
+	plain := New(50)
+	for _, ring := range []*HashRing{cached, plain} {
+		require.NoError(t, ring.AddServer("server1"))
+		require.NoError(t, ring.AddServer("server2"))
+		require.NoError(t, ring.AddServer("server3"))
+	}
+
+	requireSame := func() {
+		t.Helper()
+		for i := range 20 {
+			key := fmt.Sprintf("key%d", i)
+			want, err := plain.GetServer(key)
+			require.NoError(t, err)
+			requireServer(t, cached, key, want)
+		}
+	}
+
+	// Lookups are cached, up to the size limit
+	requireSame()
+	requireSame()
+	require.Equal(t, 8, cached.cache.len())
+
+	// Topology changes invalidate the cache
+	for _, ring := range []*HashRing{cached, plain} {
+		require.NoError(t, ring.RemoveServer("server2"))
+	}
+	requireSame()
+
+	for _, ring := range []*HashRing{cached, plain} {
+		require.NoError(t, ring.Pin("key1", "server3"))
+	}
+	requireSame()
+	requireServer(t, cached, "key1", "server3")
+}
+
+func TestLookupCacheBreakers(t *testing.T) {
+	ring := New(50, WithLookupCache(8), WithCircuitBreaker(1, time.Minute))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	replicas, err := ring.GetReplicas("key", 2)
+	require.NoError(t, err)
+	requireServer(t, ring, "key", replicas[0])
+
+	// A cached owner is still skipped when its breaker is open
+	ring.ReportFailure(replicas[0])
+	requireServer(t, ring, "key", replicas[1])
+}
+
+func TestLookupCacheEviction(t *testing.T) {
+	c := newLookupCache(2)
+	c.put("a", "server1", 1)
+	c.put("b", "server2", 1)
+
+	_, ok := c.get("a", 1)
+	require.True(t, ok)
+
+	// b is the least recently used, so it's evicted
+	c.put("c", "server3", 1)
+	_, ok = c.get("b", 1)
+	require.False(t, ok)
+
+	server, ok := c.get("a", 1)
+	require.True(t, ok)
+	require.Equal(t, "server1", server)
+
+	// A new version clears the cache
+	_, ok = c.get("a", 2)
+	require.False(t, ok)
+	require.Zero(t, c.len())
+}
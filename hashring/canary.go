@@ -0,0 +1,174 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// canarySegments is the number of evenly spaced segments of the hash space a
+// canary's share is split across, so that it takes a little of every server's
+// keys rather than one contiguous run. It divides the hash space exactly.
+const canarySegments = 64
+
+// canaryWidth is the size of each segment of the hash space.
+const canaryWidth = (maxHash + 1) / canarySegments
+
+// Canary is a server receiving a fixed share of the keyspace (see SetCanary).
+type Canary struct {
+	Server  string  `json:"server"`
+	Percent float64 `json:"percent"` // share of the keyspace, between 0 and 100
+
+	// Offset is where the canary's ranges start, derived from the server's
+	// name when it became the canary and kept if the server is renamed.
+	Offset uint64 `json:"offset"`
+}
+
+// SetCanary routes percent of the keyspace to server, e.g. one running a new
+// release, while every other key follows normal placement.
+//
+// The canaried keys are a stable subset determined by the server's name:
+// raising the percentage only adds keys, and lowering it only returns some to
+// their owners, so a rollout can be grown gradually without moving keys back
+// and forth. The share is taken evenly from across the ring, so every server
+// gives up about the same fraction of its keys. CanaryRanges reports exactly
+// which positions are canaried. Pins take precedence over the canary.
+//
+// The canary must be in the ring, where it also owns its normal share of keys;
+// add it with a low weight (see AddServerWithInfo) to route it little beyond
+// its canary share. Only one canary is supported at a time, so setting one
+// replaces any other. Setting a canary changes routing, so it's recorded in
+// the history as ChangeCanary and OnMove subscribers are told which ranges
+// moved to or from it. It's cleared when the server is removed from the ring.
+//
+// Returns an error if the server isn't in the ring or percent isn't in
+// (0, 100].
+//
+// Example:
+//
+//	ring.AddServerWithInfo(hashring.ServerInfo{Name: "api-v2", Weight: 0.01})
+//	for _, percent := range []float64{1, 5, 25, 100} {
+//		if err := ring.SetCanary("api-v2", percent); err != nil {
+//			return err
+//		}
+//		time.Sleep(10 * time.Minute) // watch the error rate
+//	}
+func (h *HashRing) SetCanary(server string, percent float64) error {
+	if !(percent > 0 && percent <= 100) {
+		return fmt.Errorf("invalid canary percentage %v", percent)
+	}
+
+	return h.write(func() error {
+		if !h.hasServer(server) {
+			return fmt.Errorf("server %s does not exist", server)
+		}
+
+		h.canary = Canary{Server: server, Percent: percent, Offset: h.hashKey(server)}
+		h.recordChange(ChangeCanary, server)
+		return nil
+	})
+}
+
+// ClearCanary stops routing keys to the canary, returning them to normal
+// placement. Like SetCanary, it's recorded in the history as ChangeCanary.
+//
+// Returns an error if no canary is set.
+func (h *HashRing) ClearCanary() error {
+	return h.write(func() error {
+		server := h.canary.Server
+		if server == "" {
+			return errors.New("no canary is set")
+		}
+
+		h.canary = Canary{}
+		h.recordChange(ChangeCanary, server)
+		return nil
+	})
+}
+
+// Canary returns the current canary, if one is set.
+func (h *HashRing) Canary() (Canary, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.canary, h.canary.Server != ""
+}
+
+// CanaryRanges returns the ranges of positions routed to the canary, sorted
+// by start, or nil if no canary is set. Keys whose hash (see Hash) falls in a
+// range go to the canary unless they're pinned.
+//
+// Example:
+//
+//	for _, r := range ring.CanaryRanges() {
+//		fmt.Printf("%#08x-%#08x\n", r.Start, r.End)
+//	}
+func (h *HashRing) CanaryRanges() []HashRange {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.canaryRanges()
+}
+
+// canaryRanges returns the ranges of positions routed to the canary, sorted by
+// start. The caller must hold h.mu.
+func (h *HashRing) canaryRanges() []HashRange {
+	if h.canary.Server == "" {
+		return nil
+	}
+
+	offset, length := h.canarySpan()
+	if length == canaryWidth {
+		return []HashRange{{Start: 0, End: maxHash}}
+	}
+
+	// The segments are evenly spaced across the whole hash space, so starting
+	// from the lowest keeps them in order. Only the last can wrap around the
+	// top, in which case it's split at the ends.
+	first := offset % canaryWidth
+	var ranges []HashRange
+	for i := range uint64(canarySegments) {
+		start := first + i*canaryWidth
+		if end := start + length - 1; end <= maxHash {
+			ranges = append(ranges, HashRange{Start: start, End: end})
+		} else {
+			ranges = append([]HashRange{{Start: 0, End: end - maxHash - 1}}, ranges...)
+			ranges = append(ranges, HashRange{Start: start, End: maxHash})
+		}
+	}
+
+	return ranges
+}
+
+// canarySpan returns where the canary's segments start and how many positions
+// at the start of each are canaried. The caller must hold h.mu and ensure a
+// canary is set.
+func (h *HashRing) canarySpan() (offset, length uint64) {
+	length = uint64(math.Round(h.canary.Percent / 100 * canaryWidth))
+	return h.canary.Offset, max(length, 1)
+}
+
+// canaried reports whether keys at hash are routed to the canary. The caller
+// must hold h.mu.
+func (h *HashRing) canaried(hash uint64) bool {
+	if h.canary.Server == "" {
+		return false
+	}
+
+	offset, length := h.canarySpan()
+	return ((hash-offset)&maxHash)%canaryWidth < length
+}
+
+// override returns the server key is routed to regardless of the virtual
+// nodes, its pinned server or the canary, if any. The caller must hold h.mu.
+func (h *HashRing) override(key string) (string, bool) {
+	if server, ok := h.pinned(key); ok {
+		return server, true
+	}
+
+	if h.canary.Server != "" && h.canaried(h.hashKey(h.routingKey(key))) {
+		return h.canary.Server, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,236 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// canariedKeys returns which of n keys the ring routes to server.
+func canariedKeys(t *testing.T, ring *HashRing, server string, n int) []bool {
+	t.Helper()
+
+	keys := make([]bool, n)
+	for i := range keys {
+		owner, err := ring.GetServer(fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+		keys[i] = owner == server
+	}
+
+	return keys
+}
+
+func TestSetCanary(t *testing.T) {
+	ring := New(50)
+	for i := range 4 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "canary", Weight: 0.02}))
+
+	require.Error(t, ring.SetCanary("unknown", 10))
+	require.Error(t, ring.SetCanary("canary", 0))
+	require.Error(t, ring.SetCanary("canary", 101))
+	require.Error(t, ring.ClearCanary())
+	_, ok := ring.Canary()
+	require.False(t, ok)
+
+	before := ring.Snapshot()
+	version := ring.Version()
+	require.NoError(t, ring.SetCanary("canary", 10))
+	require.Greater(t, ring.Version(), version)
+	require.NotEqual(t, before.Checksum, ring.Checksum())
+
+	canary, ok := ring.Canary()
+	require.True(t, ok)
+	require.Equal(t, Canary{Server: "canary", Percent: 10, Offset: ring.Hash("canary")}, canary)
+
+	const n = 20000
+	prev := canariedKeys(t, ring, "canary", n)
+	count := 0
+	for _, on := range prev {
+		if on {
+			count++
+		}
+	}
+	require.InDelta(t, n/10, count, n/100)
+
+	// growing the canary only adds keys, and other keys don't move
+	old, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.SetCanary("canary", 25))
+	for i, on := range canariedKeys(t, ring, "canary", n) {
+		key := fmt.Sprintf("key%d", i)
+		if prev[i] {
+			require.True(t, on, key)
+		}
+		if !on {
+			before, err := old.GetServer(key)
+			require.NoError(t, err)
+			after, err := ring.GetServer(key)
+			require.NoError(t, err)
+			require.Equal(t, before, after, key)
+		}
+	}
+
+	// pins win
+	require.NoError(t, ring.SetCanary("canary", 100))
+	require.NoError(t, ring.Pin("key1", "server1"))
+	server, err := ring.GetServer("key1")
+	require.NoError(t, err)
+	require.Equal(t, "server1", server)
+	server, err = ring.GetServer("key2")
+	require.NoError(t, err)
+	require.Equal(t, "canary", server)
+
+	// clearing restores normal placement
+	require.NoError(t, ring.Unpin("key1"))
+	require.NoError(t, ring.ClearCanary())
+	require.Equal(t, before.Checksum, ring.Checksum())
+
+	// and removing the canary clears it
+	require.NoError(t, ring.SetCanary("canary", 5))
+	require.NoError(t, ring.RemoveServer("canary"))
+	_, ok = ring.Canary()
+	require.False(t, ok)
+}
+
+func TestCanaryRanges(t *testing.T) {
+	ring := New(50)
+	require.Nil(t, ring.CanaryRanges())
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("canary"))
+
+	for _, percent := range []float64{0.001, 1, 10, 50, 99.9, 100} {
+		require.NoError(t, ring.SetCanary("canary", percent))
+		ranges := ring.CanaryRanges()
+
+		// the ranges are sorted, disjoint, and cover the canary's share
+		var size uint64
+		for i, r := range ranges {
+			require.LessOrEqual(t, r.Start, r.End)
+			if i > 0 {
+				require.Greater(t, r.Start, ranges[i-1].End)
+			}
+			size += r.End - r.Start + 1
+		}
+		require.InDelta(t, percent/100, float64(size)/(maxHash+1), 0.0001, "at %v%%", percent)
+
+		// and match routing
+		for i := range 1000 {
+			key := fmt.Sprintf("key%d", i)
+			res, err := ring.Lookup(key)
+			require.NoError(t, err)
+
+			in := false
+			for _, r := range ranges {
+				in = in || r.Contains(res.Hash)
+			}
+			require.Equal(t, in, res.Canary, key)
+			if in {
+				require.Equal(t, "canary", res.Server)
+			}
+
+			exp, err := ring.Explain(key)
+			require.NoError(t, err)
+			require.Equal(t, in, exp.Canary)
+			require.Equal(t, res.Server, exp.Server)
+		}
+	}
+}
+
+func TestCanarySnapshot(t *testing.T) {
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("canary"))
+	require.NoError(t, ring.SetCanary("canary", 20))
+
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	canary, ok := restored.Canary()
+	require.True(t, ok)
+	require.Equal(t, Canary{Server: "canary", Percent: 20, Offset: ring.Hash("canary")}, canary)
+	require.Equal(t, ring.CanaryRanges(), restored.CanaryRanges())
+
+	frozen := ring.Freeze()
+	for i := range 100 {
+		key := fmt.Sprintf("key%d", i)
+		want, err := ring.GetServer(key)
+		require.NoError(t, err)
+		got, err := frozen.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, want, got, key)
+	}
+
+	other := New(50)
+	require.NoError(t, other.Apply(ring.Snapshot()))
+	canary, ok = other.Canary()
+	require.True(t, ok)
+	require.Equal(t, "canary", canary.Server)
+
+	// renaming the canary keeps its keys
+	ranges := ring.CanaryRanges()
+	require.NoError(t, ring.RenameServer("canary", "canary-2"))
+	canary, _ = ring.Canary()
+	require.Equal(t, "canary-2", canary.Server)
+	require.Equal(t, ranges, ring.CanaryRanges())
+
+	snap := ring.Snapshot()
+	snap.Canary.Server = "unknown"
+	snap.Checksum = 0
+	_, err = Restore(snap)
+	require.Error(t, err)
+}
+
+func TestCanaryMoves(t *testing.T) {
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "canary", Weight: 0.1}))
+
+	ch := make(chan []RangeMove, 10)
+	defer ring.OnMove(func(moves []RangeMove) { ch <- moves })()
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.SetCanary("canary", 10))
+	set := ring.Version()
+
+	// The canaried ranges move to the canary, and the diff accounts for
+	// every key that changed server
+	moves := receive(t, ch)
+	require.NotEmpty(t, moves)
+	require.Equal(t, DiffRanges(before, ring), moves)
+	for _, move := range moves {
+		require.Equal(t, "canary", move.To)
+	}
+
+	for i := range 2000 {
+		key := fmt.Sprintf("key%d", i)
+		from, _ := before.GetServer(key)
+		to, _ := ring.GetServer(key)
+
+		hash := ring.Hash(key)
+		moved := false
+		for _, move := range moves {
+			moved = moved || move.Range.Contains(hash)
+		}
+		require.Equal(t, from != to, moved, key)
+	}
+
+	// Clearing it moves them back
+	require.NoError(t, ring.ClearCanary())
+	for _, move := range receive(t, ch) {
+		require.Equal(t, "canary", move.From)
+	}
+
+	history := ring.History()
+	require.Equal(t, ChangeCanary, history[len(history)-1].Type)
+	require.Equal(t, ChangeCanary, history[len(history)-2].Type)
+	require.Equal(t, "canary", history[len(history)-2].Server)
+
+	// Canary versions are in the history, so they can be rolled back to
+	require.NoError(t, ring.AddServer("server3"))
+	require.NoError(t, ring.Rollback(set))
+	require.Equal(t, []string{"canary", "server1", "server2"}, ring.GetServers())
+}
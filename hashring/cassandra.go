@@ -0,0 +1,392 @@
+package hashring
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// Murmur3Token returns the token Cassandra's Murmur3Partitioner assigns a
+// partition key: the first half of its 128-bit MurmurHash3 (x64), as a signed
+// integer.
+//
+// key must be the partition key serialized as Cassandra does, e.g. a text
+// column's UTF-8 bytes or an int column's 4 big-endian bytes. Composite keys
+// serialize each component as a 2-byte big-endian length, the component, and
+// a zero byte.
+//
+// Example:
+//
+//	// SELECT token(id) FROM users WHERE id = 1, with an int id
+//	token := hashring.Murmur3Token([]byte{0, 0, 0, 1}) // -4069959284402364209
+func Murmur3Token(key []byte) int64 {
+	token := int64(cassandraMurmur3(key))
+	if token == math.MinInt64 {
+		// The minimum token is reserved, so Cassandra maps it to the maximum
+		return math.MaxInt64
+	}
+
+	return token
+}
+
+// Murmur3InitialTokens returns evenly spaced tokens for a Murmur3Partitioner
+// cluster of nodes single-token nodes, suitable for their initial_token
+// settings: the ith node gets -2^63 + i*(2^64/nodes).
+//
+// Returns nil if nodes isn't positive.
+//
+// Example:
+//
+//	tokens := hashring.Murmur3InitialTokens(4)
+//	// [-9223372036854775808 -4611686018427387904 0 4611686018427387904]
+func Murmur3InitialTokens(nodes int) []int64 {
+	if nodes <= 0 {
+		return nil
+	}
+
+	if nodes == 1 {
+		return []int64{math.MinInt64}
+	}
+
+	// 2^64 / nodes, without overflowing
+	step, _ := bits.Div64(1, 0, uint64(nodes))
+
+	tokens := make([]int64, nodes)
+	for i := range tokens {
+		tokens[i] = int64(uint64(i)*step + 1<<63)
+	}
+
+	return tokens
+}
+
+// CassandraNode is a node in a CassandraRing.
+type CassandraNode struct {
+	Name       string
+	Datacenter string  // used by ReplicasByDatacenter
+	Rack       string  // used by ReplicasByDatacenter
+	Tokens     []int64 // the node's tokens, as listed by nodetool ring
+}
+
+// CassandraRange is a range of tokens owned by a node. Like Cassandra's token
+// ranges, Start is exclusive and End is inclusive, and the range wraps around
+// the end of the token space when Start isn't before End.
+type CassandraRange struct {
+	Start int64  `json:"start"`
+	End   int64  `json:"end"`
+	Owner string `json:"owner"` // the node whose token is End
+}
+
+// Contains reports whether token is in the range.
+func (r CassandraRange) Contains(token int64) bool {
+	if r.Start < r.End {
+		return token > r.Start && token <= r.End
+	}
+
+	return token > r.Start || token <= r.End
+}
+
+// CassandraRing reproduces the token ring of a Cassandra cluster using the
+// Murmur3Partitioner, so clients can tell which node owns a partition key and
+// which nodes hold its replicas, e.g. to send requests straight to a replica.
+//
+// A Cassandra token space is 64 bits and signed, so it can't be represented by
+// a HashRing; CassandraRing is a separate, immutable Router instead.
+type CassandraRing struct {
+	tokens []cassandraToken // sorted
+	nodes  map[string]CassandraNode
+	racks  map[string]int // datacenter -> number of racks in it
+	sizes  map[string]int // datacenter -> number of nodes in it
+}
+
+// cassandraToken is a token on a CassandraRing.
+type cassandraToken struct {
+	token int64
+	node  string
+}
+
+// NewCassandraRing builds the ring of nodes, as nodetool ring shows it.
+//
+// Returns an error if no nodes are given, a node has no name or tokens or is
+// given twice, or two nodes share a token.
+//
+// Example:
+//
+//	tokens := hashring.Murmur3InitialTokens(3)
+//	ring, err := hashring.NewCassandraRing([]hashring.CassandraNode{
+//		{Name: "10.0.0.1", Datacenter: "dc1", Rack: "rack1", Tokens: tokens[:1]},
+//		{Name: "10.0.0.2", Datacenter: "dc1", Rack: "rack2", Tokens: tokens[1:2]},
+//		{Name: "10.0.0.3", Datacenter: "dc1", Rack: "rack3", Tokens: tokens[2:]},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	replicas := ring.Replicas(hashring.Murmur3Token([]byte("user-42")), 3)
+func NewCassandraRing(nodes []CassandraNode) (*CassandraRing, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("no nodes given")
+	}
+
+	r := &CassandraRing{
+		nodes: make(map[string]CassandraNode, len(nodes)),
+		racks: make(map[string]int),
+		sizes: make(map[string]int),
+	}
+	owners := make(map[int64]string)
+	racks := make(map[[2]string]bool)
+	for _, node := range nodes {
+		if node.Name == "" {
+			return nil, errors.New("node name cannot be empty")
+		}
+
+		if _, ok := r.nodes[node.Name]; ok {
+			return nil, fmt.Errorf("node %s given twice", node.Name)
+		}
+
+		if len(node.Tokens) == 0 {
+			return nil, fmt.Errorf("node %s: no tokens given", node.Name)
+		}
+
+		for _, token := range node.Tokens {
+			if owner, ok := owners[token]; ok {
+				return nil, fmt.Errorf("node %s: token %d is already owned by %s", node.Name, token, owner)
+			}
+			owners[token] = node.Name
+			r.tokens = append(r.tokens, cassandraToken{token: token, node: node.Name})
+		}
+
+		node.Tokens = slices.Clone(node.Tokens)
+		r.nodes[node.Name] = node
+		r.sizes[node.Datacenter]++
+		if !racks[[2]string{node.Datacenter, node.Rack}] {
+			racks[[2]string{node.Datacenter, node.Rack}] = true
+			r.racks[node.Datacenter]++
+		}
+	}
+
+	slices.SortFunc(r.tokens, func(a, b cassandraToken) int {
+		return cmp.Compare(a.token, b.token)
+	})
+
+	return r, nil
+}
+
+// GetServer returns the node owning key's token, its primary replica. key is
+// the serialized partition key (see Murmur3Token).
+func (r *CassandraRing) GetServer(key string) (string, error) {
+	return r.tokens[r.search(Murmur3Token([]byte(key)))].node, nil
+}
+
+// search returns the index of the first token at or after token, wrapping
+// around to the first.
+func (r *CassandraRing) search(token int64) int {
+	i, _ := slices.BinarySearchFunc(r.tokens, token, func(t cassandraToken, token int64) int {
+		return cmp.Compare(t.token, token)
+	})
+
+	return i % len(r.tokens)
+}
+
+// Range returns the token range containing token.
+func (r *CassandraRing) Range(token int64) CassandraRange {
+	i := r.search(token)
+	prev := r.tokens[(i+len(r.tokens)-1)%len(r.tokens)]
+	return CassandraRange{Start: prev.token, End: r.tokens[i].token, Owner: r.tokens[i].node}
+}
+
+// Ranges returns the token ranges node is the primary replica for, in token
+// order, or nil if there's no such node.
+func (r *CassandraRing) Ranges(node string) []CassandraRange {
+	var ranges []CassandraRange
+	for i, t := range r.tokens {
+		if t.node == node {
+			prev := r.tokens[(i+len(r.tokens)-1)%len(r.tokens)]
+			ranges = append(ranges, CassandraRange{Start: prev.token, End: t.token, Owner: node})
+		}
+	}
+
+	return ranges
+}
+
+// Replicas returns the nodes holding token's replicas under SimpleStrategy
+// with replication factor rf: the owner of its range, then the next distinct
+// nodes clockwise. Fewer are returned if there are fewer than rf nodes.
+//
+// Example:
+//
+//	replicas := ring.Replicas(hashring.Murmur3Token(key), 3)
+func (r *CassandraRing) Replicas(token int64, rf int) []string {
+	rf = max(min(rf, len(r.nodes)), 0)
+	replicas := make([]string, 0, rf)
+	seen := make(map[string]bool, rf)
+	for n, i := 0, r.search(token); n < len(r.tokens) && len(replicas) < rf; n, i = n+1, (i+1)%len(r.tokens) {
+		if node := r.tokens[i].node; !seen[node] {
+			seen[node] = true
+			replicas = append(replicas, node)
+		}
+	}
+
+	return replicas
+}
+
+// ReplicasByDatacenter returns the nodes holding token's replicas under
+// NetworkTopologyStrategy, with rf giving the replication factor of each
+// datacenter.
+//
+// As in Cassandra, each datacenter's replicas are the first of its nodes
+// clockwise from token, skipping nodes on racks that already hold a replica
+// until every rack in the datacenter does. Replicas are listed in the order
+// they're found. Datacenters not in rf get no replicas.
+//
+// Example:
+//
+//	replicas := ring.ReplicasByDatacenter(hashring.Murmur3Token(key), map[string]int{"us-east": 3, "eu-west": 2})
+func (r *CassandraRing) ReplicasByDatacenter(token int64, rf map[string]int) []string {
+	type datacenter struct {
+		want     int
+		replicas map[string]bool
+		racks    map[string]bool
+		skipped  []string
+	}
+
+	dcs := make(map[string]*datacenter, len(rf))
+	pending := 0
+	for name, n := range rf {
+		if n = min(n, r.sizes[name]); n > 0 {
+			dcs[name] = &datacenter{want: n, replicas: make(map[string]bool), racks: make(map[string]bool)}
+			pending += n
+		}
+	}
+
+	var replicas []string
+	add := func(dc *datacenter, node string) {
+		dc.replicas[node] = true
+		replicas = append(replicas, node)
+		pending--
+	}
+
+	for n, i := 0, r.search(token); n < len(r.tokens) && pending > 0; n, i = n+1, (i+1)%len(r.tokens) {
+		node := r.nodes[r.tokens[i].node]
+		dc := dcs[node.Datacenter]
+		if dc == nil || len(dc.replicas) == dc.want || dc.replicas[node.Name] {
+			continue
+		}
+
+		switch {
+		case len(dc.racks) == r.racks[node.Datacenter]:
+			// Every rack has a replica, so any node will do
+			add(dc, node.Name)
+		case dc.racks[node.Rack]:
+			if !slices.Contains(dc.skipped, node.Name) {
+				dc.skipped = append(dc.skipped, node.Name)
+			}
+		default:
+			add(dc, node.Name)
+			dc.racks[node.Rack] = true
+
+			// Once every rack has a replica, the nodes skipped for sharing
+			// a rack are next in line
+			if len(dc.racks) == r.racks[node.Datacenter] {
+				for _, skipped := range dc.skipped {
+					if len(dc.replicas) == dc.want {
+						break
+					}
+					add(dc, skipped)
+				}
+			}
+		}
+	}
+
+	return replicas
+}
+
+// CassandraBuilder returns a RouterBuilder that builds CassandraRings with
+// evenly spaced tokens (see Murmur3InitialTokens), a node per server in the
+// order given, to compare a single-token cluster's placement with a ring's
+// using VerifyConsistency.
+func CassandraBuilder() RouterBuilder {
+	return func(servers []ServerInfo) (Router, error) {
+		tokens := Murmur3InitialTokens(len(servers))
+		nodes := make([]CassandraNode, len(servers))
+		for i, server := range servers {
+			nodes[i] = CassandraNode{Name: server.Name, Datacenter: server.Zone, Tokens: tokens[i : i+1]}
+		}
+
+		return NewCassandraRing(nodes)
+	}
+}
+
+// cassandraMurmur3 returns the first half of the 128-bit MurmurHash3 (x64) of
+// data with a zero seed, as Cassandra computes it. Cassandra's port sign
+// extends the trailing bytes, so its hash differs from the reference one for
+// keys whose length isn't a multiple of 16 and whose trailing bytes have the
+// high bit set.
+func cassandraMurmur3(data []byte) uint64 {
+	const (
+		c1 = 0x87c37b91114253d5
+		c2 = 0x4cf5ad432745937f
+	)
+
+	mixK1 := func(k uint64) uint64 {
+		k *= c1
+		k = bits.RotateLeft64(k, 31)
+		return k * c2
+	}
+	mixK2 := func(k uint64) uint64 {
+		k *= c2
+		k = bits.RotateLeft64(k, 33)
+		return k * c1
+	}
+	fmix := func(k uint64) uint64 {
+		k ^= k >> 33
+		k *= 0xff51afd7ed558ccd
+		k ^= k >> 33
+		k *= 0xc4ceb9fe1a85ec53
+		k ^= k >> 33
+		return k
+	}
+
+	n := len(data)
+	var h1, h2 uint64
+	for ; len(data) >= 16; data = data[16:] {
+		h1 ^= mixK1(binary.LittleEndian.Uint64(data))
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		h2 ^= mixK2(binary.LittleEndian.Uint64(data[8:]))
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	// Java bytes are signed, so each is sign extended before shifting
+	signed := func(b byte) uint64 { return uint64(int64(int8(b))) }
+
+	var k1, k2 uint64
+	for i := len(data) - 1; i >= 8; i-- {
+		k2 ^= signed(data[i]) << (8 * (i - 8))
+	}
+	if len(data) > 8 {
+		h2 ^= mixK2(k2)
+	}
+
+	for i := min(len(data), 8) - 1; i >= 0; i-- {
+		k1 ^= signed(data[i]) << (8 * i)
+	}
+	if len(data) > 0 {
+		h1 ^= mixK1(k1)
+	}
+
+	h1 ^= uint64(n)
+	h2 ^= uint64(n)
+	h1 += h2
+	h2 += h1
+	h1 = fmix(h1)
+	h2 = fmix(h2)
+	h1 += h2
+	return h1
+}
@@ -0,0 +1,112 @@
+package hashring
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ Router = (*CassandraRing)(nil)
+
+func TestMurmur3Token(t *testing.T) {
+	// SELECT token(1) with an int partition key
+	require.Equal(t, int64(-4069959284402364209), Murmur3Token([]byte{0, 0, 0, 1}))
+
+	// Trailing bytes with the high bit set are sign extended, like Java's
+	require.NotEqual(t, Murmur3Token([]byte{0x80}), Murmur3Token([]byte{0x7f}))
+	for n := range 40 {
+		key := make([]byte, n)
+		for i := range key {
+			key[i] = byte(i * 37)
+		}
+		require.NotEqual(t, int64(math.MinInt64), Murmur3Token(key))
+	}
+}
+
+func TestMurmur3InitialTokens(t *testing.T) {
+	require.Nil(t, Murmur3InitialTokens(0))
+	require.Equal(t, []int64{math.MinInt64}, Murmur3InitialTokens(1))
+	require.Equal(t, []int64{math.MinInt64, -4611686018427387904, 0, 4611686018427387904}, Murmur3InitialTokens(4))
+	require.Equal(t, []int64{math.MinInt64, -3074457345618258603, 3074457345618258602}, Murmur3InitialTokens(3))
+}
+
+func TestCassandraRing(t *testing.T) {
+	ring, err := NewCassandraRing([]CassandraNode{
+		{Name: "n1", Tokens: []int64{-100, 100}},
+		{Name: "n2", Tokens: []int64{0}},
+		{Name: "n3", Tokens: []int64{50, 200}},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, CassandraRange{Start: -100, End: 0, Owner: "n2"}, ring.Range(-50))
+	require.Equal(t, CassandraRange{Start: -100, End: 0, Owner: "n2"}, ring.Range(0))
+	require.Equal(t, CassandraRange{Start: 200, End: -100, Owner: "n1"}, ring.Range(201))
+	require.Equal(t, CassandraRange{Start: 200, End: -100, Owner: "n1"}, ring.Range(math.MinInt64))
+	require.True(t, ring.Range(201).Contains(-1000))
+	require.False(t, ring.Range(201).Contains(0))
+
+	require.Equal(t, []CassandraRange{{Start: 200, End: -100, Owner: "n1"}, {Start: 50, End: 100, Owner: "n1"}}, ring.Ranges("n1"))
+	require.Nil(t, ring.Ranges("n4"))
+
+	require.Equal(t, []string{"n3", "n1", "n2"}, ring.Replicas(20, 3))
+	require.Equal(t, []string{"n3", "n1"}, ring.Replicas(150, 2))
+	require.Equal(t, []string{"n1", "n2", "n3"}, ring.Replicas(150000, 5))
+	require.Empty(t, ring.Replicas(0, -1))
+
+	key := "user-42"
+	server, err := ring.GetServer(key)
+	require.NoError(t, err)
+	require.Equal(t, ring.Range(Murmur3Token([]byte(key))).Owner, server)
+
+	_, err = NewCassandraRing([]CassandraNode{{Name: "n1", Tokens: []int64{1}}, {Name: "n2", Tokens: []int64{1}}})
+	require.ErrorContains(t, err, "already owned by n1")
+
+	_, err = NewCassandraRing([]CassandraNode{{Name: "n1"}})
+	require.ErrorContains(t, err, "no tokens")
+
+	_, err = NewCassandraRing(nil)
+	require.Error(t, err)
+}
+
+func TestCassandraRingReplicasByDatacenter(t *testing.T) {
+	ring, err := NewCassandraRing([]CassandraNode{
+		{Name: "a1", Datacenter: "dc1", Rack: "r1", Tokens: []int64{0}},
+		{Name: "a2", Datacenter: "dc1", Rack: "r1", Tokens: []int64{10}},
+		{Name: "b1", Datacenter: "dc2", Rack: "r1", Tokens: []int64{20}},
+		{Name: "a3", Datacenter: "dc1", Rack: "r2", Tokens: []int64{30}},
+		{Name: "b2", Datacenter: "dc2", Rack: "r1", Tokens: []int64{40}},
+	})
+	require.NoError(t, err)
+
+	// a2 shares a rack with a1, so it's skipped until r2 has a replica
+	require.Equal(t, []string{"a1", "a3", "a2"}, ring.ReplicasByDatacenter(-5, map[string]int{"dc1": 3}))
+	require.Equal(t, []string{"a1", "a3"}, ring.ReplicasByDatacenter(-5, map[string]int{"dc1": 2}))
+
+	// Replication factors are capped by the datacenter's size
+	require.Equal(t, []string{"a1", "b1", "a3", "a2", "b2"}, ring.ReplicasByDatacenter(-5, map[string]int{"dc1": 5, "dc2": 5}))
+	require.Equal(t, []string{"b2", "b1"}, ring.ReplicasByDatacenter(35, map[string]int{"dc2": 2}))
+	require.Empty(t, ring.ReplicasByDatacenter(0, map[string]int{"dc3": 1}))
+}
+
+func TestCassandraBuilder(t *testing.T) {
+	servers := []ServerInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	keys := make([]string, 4000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	build := CassandraBuilder()
+	router, err := build(servers)
+	require.NoError(t, err)
+
+	counts := make(map[string]int)
+	for _, key := range keys {
+		server, _ := router.GetServer(key)
+		counts[server]++
+	}
+	for _, server := range servers {
+		require.InDelta(t, 1000, counts[server.Name], 150)
+	}
+}
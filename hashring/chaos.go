@@ -0,0 +1,290 @@
+package hashring
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// maxViolations is the most violations a ChaosReport lists.
+const maxViolations = 50
+
+// ChaosConfig configures Chaos. Zero values use the defaults.
+type ChaosConfig struct {
+	Workers     int     // goroutines performing operations at once, GOMAXPROCS by default
+	Operations  int     // total operations across all workers, 10,000 by default
+	Servers     int     // size of the pool of servers added and removed, 8 by default
+	LookupRatio float64 // fraction of operations that are lookups, 0.8 by default
+	Seed        uint64  // seeds the random operations, so a failing run's mix can be repeated
+}
+
+// ChaosReport is the outcome of a Chaos run.
+type ChaosReport struct {
+	Adds       int
+	Removes    int
+	Lookups    int
+	Violations []string // broken invariants, empty if the ring held up (at most 50 are listed)
+}
+
+// Chaos hammers ring with random concurrent lookups and membership changes,
+// checking that:
+//
+//   - no operation panics
+//   - every lookup returns a server that was in the ring at the version it
+//     was made at
+//   - the ring's internal state stays consistent: virtual nodes sorted by
+//     position, each owned by a member
+//   - membership ends up matching the changes made
+//
+// It's meant for tests, ideally run with the race detector, to harden the
+// ring before automating topology changes. Changes are made to servers named
+// chaos-0, chaos-1, and so on, which must not already be in the ring, and are
+// serialized with each other (but not with lookups) so each version's
+// membership is known; other goroutines must not change the ring while Chaos
+// runs. At least one chaos server is always in the ring, so lookups never
+// fail for lack of servers.
+//
+// Example:
+//
+//	func TestRingChaos(t *testing.T) {
+//		ring := hashring.New(50, hashring.WithLookupCache(100))
+//		report := hashring.Chaos(ring, hashring.ChaosConfig{Seed: 42})
+//		require.Empty(t, report.Violations)
+//	}
+func Chaos(ring *HashRing, cfg ChaosConfig) ChaosReport {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	if cfg.Operations <= 0 {
+		cfg.Operations = 10_000
+	}
+	if cfg.Servers <= 0 {
+		cfg.Servers = 8
+	}
+	if cfg.LookupRatio <= 0 {
+		cfg.LookupRatio = 0.8
+	}
+
+	c := &chaos{
+		ring:    ring,
+		pool:    make([]string, cfg.Servers),
+		members: make(map[string]bool),
+		seen:    make(map[uint64][]string),
+	}
+	for i := range c.pool {
+		c.pool[i] = fmt.Sprintf("chaos-%d", i)
+	}
+
+	c.mu.Lock()
+	c.add(c.pool[0])
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for w := range cfg.Workers {
+		ops := cfg.Operations / cfg.Workers
+		if w < cfg.Operations%cfg.Workers {
+			ops++
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rng := rand.New(rand.NewPCG(cfg.Seed, uint64(w)))
+			for range ops {
+				if rng.Float64() < cfg.LookupRatio {
+					c.lookup(fmt.Sprintf("key-%d", rng.IntN(1_000_000)))
+				} else {
+					c.change(c.pool[rng.IntN(len(c.pool))])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.verify()
+	return c.report
+}
+
+// chaos is the state of a Chaos run.
+type chaos struct {
+	ring *HashRing
+	pool []string
+
+	mu      sync.Mutex          // serializes changes, and guards the fields below
+	members map[string]bool     // chaos servers in the ring
+	seen    map[uint64][]string // ring version -> its sorted servers
+	lookups []chaosLookup
+	report  ChaosReport
+}
+
+// chaosLookup is a lookup's result, checked once the run is over.
+type chaosLookup struct {
+	key     string
+	server  string
+	version uint64
+}
+
+// violate records a broken invariant. The caller must hold c.mu.
+func (c *chaos) violate(format string, args ...any) {
+	if len(c.report.Violations) < maxViolations {
+		c.report.Violations = append(c.report.Violations, fmt.Sprintf(format, args...))
+	}
+}
+
+// guard runs fn, recording a panic as a violation. The caller must hold c.mu.
+func (c *chaos) guard(op string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.violate("%s panicked: %v", op, r)
+		}
+	}()
+
+	fn()
+}
+
+// lookup looks up key, keeping the result to check against the membership at
+// its version.
+func (c *chaos) lookup(key string) {
+	var (
+		server   string
+		version  uint64
+		err      error
+		panicked any
+	)
+
+	func() {
+		defer func() { panicked = recover() }()
+		server, version, err = c.ring.GetServerVersioned(key)
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.report.Lookups++
+	switch {
+	case panicked != nil:
+		c.violate("lookup %s panicked: %v", key, panicked)
+	case err != nil:
+		c.violate("lookup %s failed: %v", key, err)
+	default:
+		c.lookups = append(c.lookups, chaosLookup{key: key, server: server, version: version})
+	}
+}
+
+// change adds server to the ring, or removes it if it's a member and isn't
+// the last chaos server.
+func (c *chaos) change(server string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case !c.members[server]:
+		c.add(server)
+	case len(c.members) > 1:
+		c.remove(server)
+	}
+}
+
+// add adds server to the ring. The caller must hold c.mu.
+func (c *chaos) add(server string) {
+	c.report.Adds++
+	c.guard("add "+server, func() {
+		if err := c.ring.AddServer(server); err != nil {
+			c.violate("add %s failed: %v", server, err)
+			return
+		}
+
+		c.members[server] = true
+	})
+	c.record()
+}
+
+// remove removes server from the ring. The caller must hold c.mu.
+func (c *chaos) remove(server string) {
+	c.report.Removes++
+	c.guard("remove "+server, func() {
+		if err := c.ring.RemoveServer(server); err != nil {
+			c.violate("remove %s failed: %v", server, err)
+			return
+		}
+
+		delete(c.members, server)
+	})
+	c.record()
+}
+
+// record notes the ring's membership at its current version. The caller must
+// hold c.mu.
+func (c *chaos) record() {
+	c.guard("record membership", func() {
+		c.seen[c.ring.Version()] = c.ring.GetServers()
+	})
+}
+
+// verify checks the lookups made against the membership at their versions,
+// and the ring's final state.
+func (c *chaos) verify() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, l := range c.lookups {
+		servers, ok := c.seen[l.version]
+		if !ok {
+			c.violate("lookup %s saw version %d, which no change produced", l.key, l.version)
+			continue
+		}
+
+		if _, found := slices.BinarySearch(servers, l.server); !found {
+			c.violate("lookup %s returned %s, which wasn't in the ring at version %d", l.key, l.server, l.version)
+		}
+	}
+
+	c.guard("check invariants", func() {
+		for _, problem := range c.ring.checkInvariants() {
+			c.violate("%s", problem)
+		}
+	})
+
+	for _, server := range c.pool {
+		if _, ok := c.ring.GetServerInfo(server); ok != c.members[server] {
+			c.violate("%s in ring: %t, expected %t", server, ok, c.members[server])
+		}
+	}
+}
+
+// checkInvariants returns the ways the ring's internal state is inconsistent,
+// if any.
+func (h *HashRing) checkInvariants() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var problems []string
+	for i, v := range h.entries {
+		if i > 0 && v.hash <= h.entries[i-1].hash {
+			problems = append(problems, fmt.Sprintf("virtual node %d at %d isn't after the previous one at %d", i, v.hash, h.entries[i-1].hash))
+		}
+
+		if v.server < 0 || int(v.server) >= len(h.names) {
+			problems = append(problems, fmt.Sprintf("virtual node %d has unknown owner %d", i, v.server))
+		}
+	}
+
+	if len(h.names) != len(h.servers) || len(h.ids) != len(h.servers) {
+		problems = append(problems, fmt.Sprintf("server table has %d names and %d ids for %d servers", len(h.names), len(h.ids), len(h.servers)))
+	}
+
+	for i, name := range h.names {
+		if !h.hasServer(name) {
+			problems = append(problems, fmt.Sprintf("server table has %s, which isn't in the ring", name))
+		}
+
+		if id, ok := h.ids[name]; !ok || int(id) != i {
+			problems = append(problems, fmt.Sprintf("server %s is at %d in the server table, but its id is %d", name, i, id))
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,54 @@
+package hashring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaos(t *testing.T) {
+	for name, ring := range map[string]*HashRing{
+		"plain":  New(20),
+		"cached": New(20, WithLookupCache(64)),
+		"evenly": New(20, WithPlacement(PlacementEvenlySpaced)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, ring.AddServer("static"))
+
+			report := Chaos(ring, ChaosConfig{Workers: 4, Operations: 2000, Seed: 1})
+			require.Empty(t, report.Violations)
+			require.Positive(t, report.Lookups)
+			require.Positive(t, report.Removes)
+
+			// Removing the last chaos server is skipped, so not every
+			// operation is counted
+			require.LessOrEqual(t, report.Adds+report.Removes+report.Lookups, 2001)
+
+			// Servers that were already in the ring are left alone
+			_, ok := ring.GetServerInfo("static")
+			require.True(t, ok)
+		})
+	}
+}
+
+func TestChaosViolations(t *testing.T) {
+	ring := New(20)
+	require.NoError(t, ring.AddServer("chaos-0"))
+
+	report := Chaos(ring, ChaosConfig{Operations: 10})
+	require.Contains(t, report.Violations, "add chaos-0 failed: server chaos-0 already exists")
+}
+
+func TestCheckInvariants(t *testing.T) {
+	ring := New(20)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.Empty(t, ring.checkInvariants())
+
+	ring.entries[0], ring.entries[1] = ring.entries[1], ring.entries[0]
+	ring.ids["server1"], ring.ids["server2"] = ring.ids["server2"], ring.ids["server1"]
+	problems := ring.checkInvariants()
+	require.Len(t, problems, 3)
+	require.Contains(t, problems[0], "virtual node 1")
+	require.Contains(t, problems[1], "server server1 is at 0 in the server table, but its id is 1")
+}
@@ -0,0 +1,155 @@
+package hashring
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"maps"
+	"math"
+	"slices"
+)
+
+// Checksum returns a deterministic digest of the ring's topology.
+//
+// The digest covers the ring's topology: the set of servers with their
+// metadata, tokens, and weights, the number of virtual nodes per server, pins,
+// the canary, hash tag delimiters, the vnode placement mode, and the hasher
+// (see WithHasher). Key and tenant extractors are functions, so they aren't
+// covered (see WithKeyExtractor and WithTenantExtractor).
+//
+// Two rings that return the same checksum and use equivalent extractors will
+// route every key identically, regardless of the order in which servers were
+// added. This makes it a cheap way for distributed clients to verify they
+// agree on the ring, and to detect split-brain configurations.
+//
+// This operation is thread-safe.
+//
+// Example:
+//
+//	if local.Checksum() != remoteChecksum {
+//		log.Printf("ring mismatch: refreshing topology")
+//	}
+func (h *HashRing) Checksum() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.checksum()
+}
+
+// ChecksumVersioned returns the ring's checksum along with its version, read
+// atomically, so the pair describes a single topology without the cost of a
+// Snapshot.
+//
+// Example:
+//
+//	checksum, version := ring.ChecksumVersioned()
+//	fmt.Printf("v%d %016x\n", version, checksum)
+func (h *HashRing) ChecksumVersioned() (uint64, uint64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.checksum(), h.version
+}
+
+// checksum computes the topology digest. The caller must hold h.mu.
+func (h *HashRing) checksum() uint64 {
+	servers := h.serverInfos()
+
+	d := fnv.New64a()
+	writeString(d, h.hasher.Name())
+	if h.placement != PlacementHashed {
+		// omitted for hashed placement so checksums from before placement
+		// modes existed stay valid
+		writeString(d, string(h.placement))
+	}
+	writeString(d, h.tagOpen)
+	writeString(d, h.tagClose)
+	writeUint64(d, uint64(h.vnodes))
+	writeUint64(d, uint64(len(servers)))
+	for _, server := range servers {
+		writeServer(d, server)
+	}
+
+	pins := slices.Sorted(maps.Keys(h.pins))
+	writeUint64(d, uint64(len(pins)))
+	for _, keyOrPrefix := range pins {
+		writeString(d, keyOrPrefix)
+		writeString(d, h.pins[keyOrPrefix])
+	}
+
+	// omitted without a canary so checksums from before canaries existed stay
+	// valid
+	if h.canary.Server != "" {
+		writeString(d, "canary")
+		writeString(d, h.canary.Server)
+		writeUint64(d, math.Float64bits(h.canary.Percent))
+		writeUint64(d, h.canary.Offset)
+	}
+
+	return d.Sum64()
+}
+
+// writeServer writes the fields of server covered by the checksum to d.
+func writeServer(d hash.Hash64, server ServerInfo) {
+	writeString(d, server.Name)
+	writeString(d, server.Zone)
+	writeUint64(d, uint64(len(server.Tags)))
+	for _, tag := range slices.Sorted(slices.Values(server.Tags)) {
+		writeString(d, tag)
+	}
+
+	// omitted for servers without tokens so checksums from before tokens
+	// existed stay valid
+	if len(server.Tokens) > 0 {
+		writeString(d, "tokens")
+		writeUint64(d, uint64(len(server.Tokens)))
+		for _, token := range server.Tokens {
+			writeUint64(d, token)
+		}
+	}
+
+	// likewise for weights, tenants, and capacity
+	if server.Weight != 0 {
+		writeString(d, "weight")
+		writeUint64(d, math.Float64bits(server.Weight))
+	}
+
+	if server.VNodes != 0 {
+		writeString(d, "vnodes")
+		writeUint64(d, uint64(server.VNodes))
+	}
+
+	if len(server.Tenants) > 0 {
+		writeString(d, "tenants")
+		writeUint64(d, uint64(len(server.Tenants)))
+		for _, tenant := range slices.Sorted(slices.Values(server.Tenants)) {
+			writeString(d, tenant)
+		}
+	}
+
+	if server.State != "" {
+		writeString(d, "state")
+		writeString(d, string(server.State))
+	}
+
+	if !server.Capacity.IsZero() {
+		writeString(d, "capacity")
+		for _, v := range []float64{server.Capacity.CPU, server.Capacity.Memory, server.Capacity.Disk, server.Capacity.Score} {
+			writeUint64(d, math.Float64bits(v))
+		}
+	}
+}
+
+// writeUint64 writes v to d in big-endian order.
+func writeUint64(d hash.Hash64, v uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	_, _ = d.Write(buf[:])
+}
+
+// writeString writes a length-prefixed s to d so that adjacent strings can't
+// be confused with each other (e.g. "ab"+"c" vs "a"+"bc").
+func writeString(d hash.Hash64, s string) {
+	writeUint64(d, uint64(len(s)))
+	_, _ = d.Write([]byte(s))
+}
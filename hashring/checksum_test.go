@@ -0,0 +1,63 @@
+package hashring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	ring1 := New(150)
+	require.NoError(t, ring1.AddServer("server1"))
+	require.NoError(t, ring1.AddServer("server2"))
+	require.NoError(t, ring1.AddServer("server3"))
+
+	// Same membership added in a different order
+	ring2 := New(150)
+	require.NoError(t, ring2.AddServer("server3"))
+	require.NoError(t, ring2.AddServer("server1"))
+	require.NoError(t, ring2.AddServer("server2"))
+
+	require.Equal(t, ring1.Checksum(), ring2.Checksum(), "Checksum should not depend on insertion order")
+
+	// Changing membership changes the checksum
+	before := ring2.Checksum()
+	require.NoError(t, ring2.RemoveServer("server2"))
+	require.NotEqual(t, before, ring2.Checksum(), "Checksum should change when membership changes")
+
+	// Restoring membership restores the checksum
+	require.NoError(t, ring2.AddServer("server2"))
+	require.Equal(t, before, ring2.Checksum())
+
+	// Virtual node count is part of the topology
+	ring3 := New(100)
+	require.NoError(t, ring3.AddServer("server1"))
+	require.NoError(t, ring3.AddServer("server2"))
+	require.NoError(t, ring3.AddServer("server3"))
+	require.NotEqual(t, ring1.Checksum(), ring3.Checksum(), "Checksum should include virtual node count")
+}
+
+func TestChecksumVersioned(t *testing.T) {
+	ring := New(10)
+	checksum, version := ring.ChecksumVersioned()
+	require.Equal(t, ring.Checksum(), checksum)
+	require.Zero(t, version)
+
+	require.NoError(t, ring.AddServer("server1"))
+	checksum, version = ring.ChecksumVersioned()
+	snap := ring.Snapshot()
+	require.Equal(t, snap.Checksum, checksum)
+	require.Equal(t, snap.Version, version)
+}
+
+func TestChecksumAmbiguousNames(t *testing.T) {
+	ring1 := New(10)
+	require.NoError(t, ring1.AddServer("ab"))
+	require.NoError(t, ring1.AddServer("c"))
+
+	ring2 := New(10)
+	require.NoError(t, ring2.AddServer("a"))
+	require.NoError(t, ring2.AddServer("bc"))
+
+	require.NotEqual(t, ring1.Checksum(), ring2.Checksum())
+}
@@ -0,0 +1,84 @@
+package hashring
+
+import (
+	"errors"
+	"sync"
+)
+
+// Claim returns the server that owns key along with a fencing token: the ring
+// version the ownership was decided at, which increases with every topology
+// change.
+//
+// Storage systems can use the token to reject writes from stale owners: a
+// server passes the token along with each write, and storage rejects writes
+// carrying a lower token than one it's already accepted for the key (see
+// FenceGuard). After a rebalance, the new owner claims the key with a higher
+// token, so writes from the old owner, which still holds the old token, are
+// rejected.
+//
+// Unlike GetServer, Claim ignores circuit breakers: a claim belongs to the
+// key's owner, not to a server standing in for it. Since claims guard writes,
+// the owner is the server that would be written to: joining servers can own
+// keys and draining ones can't (see SetState).
+//
+// Returns an error if the hash ring is empty or no server is eligible for the
+// key (see ServerInfo.Tenants).
+//
+// Example:
+//
+//	owner, fence, err := ring.Claim("order:42")
+//	if err != nil {
+//		return err
+//	}
+//	if owner == self {
+//		err = store.Write(ctx, "order:42", value, fence)
+//	}
+func (h *HashRing) Claim(key string) (string, uint64, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.entries) == 0 {
+		return "", 0, errors.New("hash ring is empty")
+	}
+
+	h.lookups.Add(1)
+	owner := h.resolve(key, AccessWrite)
+	if owner == "" {
+		return "", 0, errNoEligibleServer(key)
+	}
+
+	return owner, h.version, nil
+}
+
+// FenceGuard is the storage side of Claim: it remembers the highest fencing
+// token seen for each key and rejects lower ones.
+//
+// The zero value is ready to use. It's safe for concurrent use.
+type FenceGuard struct {
+	mu     sync.Mutex
+	fences map[string]uint64 // highest token accepted, by key
+}
+
+// Accept reports whether a write to key carrying fence may proceed, i.e.
+// fence is at least the highest token accepted for key, and records it.
+//
+// Example:
+//
+//	if !guard.Accept(key, fence) {
+//		return ErrStaleOwner
+//	}
+func (g *FenceGuard) Accept(key string, fence uint64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if fence < g.fences[key] {
+		return false
+	}
+
+	if g.fences == nil {
+		g.fences = make(map[string]uint64)
+	}
+
+	g.fences[key] = fence
+	return true
+}
@@ -0,0 +1,45 @@
+package hashring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaim(t *testing.T) {
+	ring := New(50, WithCircuitBreaker(1, time.Hour))
+	_, _, err := ring.Claim("key")
+	require.Error(t, err)
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	owner, fence, err := ring.Claim("key")
+	require.NoError(t, err)
+	require.Equal(t, ring.Version(), fence)
+
+	// open breakers don't move claims
+	ring.ReportFailure(owner)
+	server, err := ring.GetServer("key")
+	require.NoError(t, err)
+	require.NotEqual(t, owner, server)
+
+	claimed, _, err := ring.Claim("key")
+	require.NoError(t, err)
+	require.Equal(t, owner, claimed)
+
+	// the new owner's claim fences out the old one
+	require.NoError(t, ring.Pin("key", server))
+	newOwner, newFence, err := ring.Claim("key")
+	require.NoError(t, err)
+	require.Equal(t, server, newOwner)
+	require.Greater(t, newFence, fence)
+
+	var guard FenceGuard
+	require.True(t, guard.Accept("key", fence))
+	require.True(t, guard.Accept("key", newFence))
+	require.False(t, guard.Accept("key", fence), "Expected the stale owner's write to be rejected")
+	require.True(t, guard.Accept("key", newFence))
+	require.True(t, guard.Accept("other", fence))
+}
@@ -0,0 +1,224 @@
+package hashring
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// maxProbes bounds how many times a colliding virtual node is re-hashed in
+// search of a free position. Any reasonable hasher finds one within a few
+// probes; one that doesn't would otherwise loop forever.
+const maxProbes = 100
+
+// Collisions returns the number of virtual nodes that collided with another
+// virtual node and were moved to a different position.
+//
+// Two virtual nodes hashing to the same position can't both own it. Rather
+// than letting one silently overwrite the other (skewing ownership), the
+// collision is resolved deterministically: servers with explicit tokens keep
+// their positions, otherwise the server whose name sorts first keeps the
+// position, and the other virtual node is re-hashed with a salt until it finds
+// a free position. The resulting ring doesn't depend on the order servers were
+// added in. A virtual node that still collides after 100 probes, which only a
+// degenerate hasher causes, fails the change that placed it.
+//
+// This operation is thread-safe.
+func (h *HashRing) Collisions() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.collisions
+}
+
+// place puts a server's virtual nodes on the ring, re-probing hashed vnodes
+// past occupied positions. It returns the number of collisions, or an error,
+// leaving the ring unchanged, if a vnode is still colliding after maxProbes.
+// The caller must hold h.mu.
+func (h *HashRing) place(info ServerInfo) (int, error) {
+	id := h.ids[info.Name]
+	positions := h.positionsOf(info)
+	added := unprobed(id, positions)
+
+	sortEntries(added)
+	if !overlaps(h.entries, added) {
+		h.entries = merge(h.entries, added)
+		return 0, nil
+	}
+
+	// Collisions are rare, so re-probe one vnode at a time only when needed
+	placed := h.entries
+	own := make(map[uint64]bool, len(added))
+	occupied := func(pos uint64) bool {
+		_, ok := find(placed, pos)
+		return ok || own[pos]
+	}
+
+	collisions := 0
+	buf := make([]byte, 0, len(info.Name)+2*maxIntLen)
+	added = added[:0]
+	for i, pos := range positions {
+		for probe := 1; occupied(pos); probe++ {
+			if probe > maxProbes {
+				return 0, fmt.Errorf("server %s: virtual node %d still collides after %d probes", info.Name, i, maxProbes)
+			}
+
+			buf = vnodeKey(buf, info.Name, i, probe)
+			pos = h.hashBytes(buf)
+			collisions++
+		}
+
+		own[pos] = true
+		added = append(added, vnode{hash: pos, server: id, index: int32(i)})
+	}
+
+	sortEntries(added)
+	h.entries = merge(h.entries, added)
+	h.collisions += collisions
+	return collisions, nil
+}
+
+// placeServer puts a newly added or resized server's virtual nodes on the
+// ring, rebuilding it if anything collides so the layout doesn't depend on
+// the order of changes. The caller must hold h.mu.
+func (h *HashRing) placeServer(info ServerInfo) error {
+	if h.collisions == 0 {
+		collisions, err := h.place(info)
+		if err != nil || collisions == 0 {
+			return err
+		}
+	}
+
+	return h.rebuild()
+}
+
+// positionsOf returns info's virtual node positions before collisions are
+// resolved: its tokens, or its hashed positions. The caller must hold h.mu.
+func (h *HashRing) positionsOf(info ServerInfo) []uint64 {
+	if len(info.Tokens) > 0 {
+		return info.Tokens
+	}
+
+	return h.positions(info.Name, h.vnodesFor(info))
+}
+
+// unprobed returns a server's virtual nodes at positions, in index order.
+func unprobed(id int32, positions []uint64) []vnode {
+	vnodes := make([]vnode, 0, len(positions))
+	for i, pos := range positions {
+		vnodes = append(vnodes, vnode{hash: pos, server: id, index: int32(i)})
+	}
+
+	return vnodes
+}
+
+// overlaps reports whether any position appears twice across a and b, which
+// must both be sorted.
+func overlaps(a, b []vnode) bool {
+	for i := 1; i < len(b); i++ {
+		if b[i].hash == b[i-1].hash {
+			return true
+		}
+	}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].hash < b[j].hash:
+			i++
+		case a[i].hash > b[j].hash:
+			j++
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+// merge returns the sorted union of a and b, which must both be sorted.
+func merge(a, b []vnode) []vnode {
+	merged := make([]vnode, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0].hash <= b[0].hash {
+			merged = append(merged, a[0])
+			a = a[1:]
+		} else {
+			merged = append(merged, b[0])
+			b = b[1:]
+		}
+	}
+
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// maxIntLen is the longest decimal representation of an int, with a separator.
+const maxIntLen = 21
+
+// vnodeKey writes the key hashed to place a virtual node into buf (reusing its
+// storage) and returns it: "server#i", or "server#i#probe" when re-probing
+// after a collision.
+func vnodeKey(buf []byte, server string, i, probe int) []byte {
+	buf = append(buf[:0], server...)
+	buf = append(buf, '#')
+	buf = strconv.AppendInt(buf, int64(i), 10)
+	if probe > 0 {
+		buf = append(buf, '#')
+		buf = strconv.AppendInt(buf, int64(probe), 10)
+	}
+
+	return buf
+}
+
+// rebuild places every server's virtual nodes from scratch, resolving
+// collisions in favour of servers with tokens and then by server name. If a
+// virtual node can't be placed (see place), it returns the error and leaves
+// the virtual nodes as they were. The caller must hold h.mu.
+func (h *HashRing) rebuild() error {
+	entries, collisions := h.entries, h.collisions
+	h.collisions = 0
+
+	servers := h.serverList()
+	slices.SortStableFunc(servers, func(a, b string) int {
+		// servers with tokens first
+		return min(len(h.servers[b].Tokens), 1) - min(len(h.servers[a].Tokens), 1)
+	})
+
+	// Usually nothing collides and everything can be sorted at once
+	var all []vnode
+	for _, server := range servers {
+		all = append(all, unprobed(h.ids[server], h.positionsOf(h.servers[server]))...)
+	}
+
+	sortEntries(all)
+	if !overlaps(nil, all) {
+		h.entries = all
+		return nil
+	}
+
+	// place merges into a new slice, so entries is left intact
+	h.entries = nil
+	for _, server := range servers {
+		if _, err := h.place(h.servers[server]); err != nil {
+			h.entries, h.collisions = entries, collisions
+			return err
+		}
+	}
+
+	return nil
+}
+
+// find returns the index of the virtual node at pos in entries, which must be
+// sorted.
+func find(entries []vnode, pos uint64) (int, bool) {
+	return slices.BinarySearchFunc(entries, pos, func(v vnode, pos uint64) int {
+		return cmp.Compare(v.hash, pos)
+	})
+}
+
+// sortEntries sorts virtual nodes by position.
+func sortEntries(entries []vnode) {
+	slices.SortFunc(entries, func(a, b vnode) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+}
@@ -0,0 +1,130 @@
+package hashring
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// "coddbwb#0" and "lwp#0" have the same CRC32.
+const collidingHash = 2693389033
+
+func TestCollisions(t *testing.T) {
+	require.Equal(t, uint32(collidingHash), crc32.ChecksumIEEE([]byte("coddbwb#0")))
+	require.Equal(t, uint32(collidingHash), crc32.ChecksumIEEE([]byte("lwp#0")))
+
+	ring1 := New(1)
+	require.NoError(t, ring1.AddServer("lwp"))
+	require.NoError(t, ring1.AddServer("coddbwb"))
+
+	ring2 := New(1)
+	require.NoError(t, ring2.AddServer("coddbwb"))
+	require.NoError(t, ring2.AddServer("lwp"))
+
+	// Both servers keep a virtual node, and the name sorting first wins the
+	// contested position regardless of insertion order
+	for _, ring := range []*HashRing{ring1, ring2} {
+		require.Len(t, positions(ring), 2)
+		require.Equal(t, "coddbwb", ownerAt(ring, collidingHash))
+		require.Equal(t, 1, ring.Collisions())
+		require.Equal(t, 1, ring.AnalyzePerformance([]string{"key"}).Collisions)
+	}
+
+	require.Equal(t, positions(ring1), positions(ring2))
+	require.Equal(t, ring1.layout(), ring2.layout())
+
+	// Removing the winner lets the displaced vnode take its position back
+	require.NoError(t, ring1.RemoveServer("coddbwb"))
+	require.Equal(t, []uint64{collidingHash}, positions(ring1))
+	require.Equal(t, "lwp", ownerAt(ring1, collidingHash))
+	require.Zero(t, ring1.Collisions())
+
+	require.NoError(t, ring2.RemoveServer("lwp"))
+	require.Equal(t, []uint64{collidingHash}, positions(ring2))
+	require.Equal(t, "coddbwb", ownerAt(ring2, collidingHash))
+	require.Zero(t, ring2.Collisions())
+}
+
+func TestCollisionsWithTokens(t *testing.T) {
+	ring := New(1)
+	require.NoError(t, ring.AddServerWithTokens("tokens", []uint64{collidingHash}))
+	require.NoError(t, ring.AddServer("coddbwb"))
+
+	// Explicit tokens always keep their position
+	require.Equal(t, "tokens", ownerAt(ring, collidingHash))
+	require.Len(t, positions(ring), 2)
+	require.Equal(t, 1, ring.Collisions())
+}
+
+func TestVNodeKey(t *testing.T) {
+	var buf []byte
+	require.Equal(t, "server#0", string(vnodeKey(buf, "server", 0, 0)))
+	require.Equal(t, "server#149", string(vnodeKey(buf, "server", 149, 0)))
+	require.Equal(t, "server#3#2", string(vnodeKey(buf, "server", 3, 2)))
+
+	// Placement matches hashing the formatted key, so existing rings keep their layout
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server"))
+	for i := range 10 {
+		require.Equal(t, "server", ownerAt(ring, ring.hashKey(fmt.Sprintf("server#%d", i))))
+	}
+}
+
+func TestCollisionsExhaustProbes(t *testing.T) {
+	constant := WithHasher(NewHasher("constant", func([]byte) uint32 { return 1 }))
+
+	ring := New(1, constant)
+	require.NoError(t, ring.AddServer("a"))
+
+	// Every probe lands on a's position, so b can't be placed
+	err := ring.AddServer("b")
+	require.ErrorContains(t, err, "still collides after 100 probes")
+	require.Equal(t, []string{"a"}, ring.GetServers())
+	require.Len(t, ring.Tokens(), 1)
+	require.Equal(t, uint64(1), ring.Version())
+
+	// Nor can a second virtual node of a
+	require.ErrorContains(t, ring.SetWeight("a", 2), "still collides")
+	info, _ := ring.GetServerInfo("a")
+	require.Zero(t, info.Weight)
+	require.Len(t, ring.Tokens(), 1)
+
+	server, err := ring.GetServer("key")
+	require.NoError(t, err)
+	require.Equal(t, "a", server)
+}
+
+func TestCollisionsExhaustProbesBatched(t *testing.T) {
+	ring := New(1, WithHasher(NewHasher("constant", func([]byte) uint32 { return 1 })), WithWriteBatching(20*time.Millisecond))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ring.AddServer(fmt.Sprintf("server%d", i))
+		}()
+	}
+	wg.Wait()
+
+	// The batch can't be placed, so it's replayed one change at a time: the
+	// first add succeeds and the others fail, as without batching
+	var added []string
+	for i, err := range errs {
+		if err == nil {
+			added = append(added, fmt.Sprintf("server%d", i))
+			continue
+		}
+		require.ErrorContains(t, err, "still collides")
+	}
+	require.Len(t, added, 1)
+	require.Equal(t, added, ring.GetServers())
+	require.Len(t, ring.Tokens(), 1)
+	require.Equal(t, uint64(1), ring.Version())
+	require.Len(t, ring.History(), 1)
+}
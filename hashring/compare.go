@@ -0,0 +1,194 @@
+package hashring
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// MetricDelta is the change in a metric between two runs.
+type MetricDelta struct {
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Delta  float64 `json:"delta"`  // After - Before
+	Change float64 `json:"change"` // Delta as a percentage of Before, 0 if Before is 0
+}
+
+// newMetricDelta returns the change from before to after.
+func newMetricDelta(before, after float64) MetricDelta {
+	d := MetricDelta{Before: before, After: after}
+	if before == after {
+		// Unchanged, even if infinite
+		return d
+	}
+
+	d.Delta = after - before
+	if before != 0 && !math.IsInf(before, 0) {
+		d.Change = d.Delta / math.Abs(before) * 100
+	}
+
+	return d
+}
+
+// MarshalJSON implements json.Marshaler, encoding values JSON can't represent,
+// such as an infinite MaxMinRatio, as null.
+func (d MetricDelta) MarshalJSON() ([]byte, error) {
+	finite := func(f float64) *float64 {
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil
+		}
+
+		return &f
+	}
+
+	return json.Marshal(struct {
+		Before *float64 `json:"before"`
+		After  *float64 `json:"after"`
+		Delta  *float64 `json:"delta"`
+		Change *float64 `json:"change"`
+	}{finite(d.Before), finite(d.After), finite(d.Delta), finite(d.Change)})
+}
+
+// ComparisonReport compares two performance analyses, such as those taken
+// before and after a scaling event or a change to the ring's settings.
+// Lower is better for every metric except the counts.
+type ComparisonReport struct {
+	TotalKeys      MetricDelta      `json:"total_keys"`
+	Servers        MetricDelta      `json:"servers"`
+	VirtualNodes   MetricDelta      `json:"virtual_nodes"`
+	AvgLatency     MetricDelta      `json:"avg_latency_ns"` // in nanoseconds
+	DistributionCV MetricDelta      `json:"distribution_cv"`
+	Gini           MetricDelta      `json:"gini"`
+	MaxMinRatio    MetricDelta      `json:"max_min_ratio"`
+	MaxShare       MetricDelta      `json:"max_share"`
+	Collisions     MetricDelta      `json:"collisions"`
+	Distribution   DistributionDiff `json:"distribution"`
+}
+
+// CompareRuns compares the analyses before and after, e.g. for a scaling
+// runbook or a CI job that fails when balance or latency regresses. The
+// report can be printed with Print or encoded as JSON.
+//
+// Example:
+//
+//	before := ring.AnalyzePerformance(keys)
+//	ring.AddServer("server-4")
+//	report := hashring.CompareRuns(before, ring.AnalyzePerformance(keys))
+//	report.Print()
+//	if regressions := report.Regressions(10); len(regressions) > 0 {
+//		log.Fatalf("balance regressed: %v", regressions)
+//	}
+func CompareRuns(before, after PerformanceMetrics) ComparisonReport {
+	return ComparisonReport{
+		TotalKeys:      newMetricDelta(float64(before.TotalKeys), float64(after.TotalKeys)),
+		Servers:        newMetricDelta(float64(before.Servers), float64(after.Servers)),
+		VirtualNodes:   newMetricDelta(float64(before.VirtualNodes), float64(after.VirtualNodes)),
+		AvgLatency:     newMetricDelta(float64(before.AvgLatency), float64(after.AvgLatency)),
+		DistributionCV: newMetricDelta(before.DistributionCV, after.DistributionCV),
+		Gini:           newMetricDelta(before.Gini, after.Gini),
+		MaxMinRatio:    newMetricDelta(before.MaxMinRatio, after.MaxMinRatio),
+		MaxShare:       newMetricDelta(before.MaxShare, after.MaxShare),
+		Collisions:     newMetricDelta(float64(before.Collisions), float64(after.Collisions)),
+		Distribution:   CompareDistributions(before.Distribution, after.Distribution),
+	}
+}
+
+// Regressions returns the names of the latency and balance metrics that grew
+// by more than tolerance percent, in the order Fprint lists them. A metric
+// that becomes infinite, such as MaxMinRatio when a server is left without
+// keys, always counts as a regression.
+func (r ComparisonReport) Regressions(tolerance float64) []string {
+	var regressions []string
+	for _, m := range r.metrics() {
+		if !m.lowerIsBetter {
+			continue
+		}
+
+		d := m.delta
+		if (math.IsInf(d.After, 1) && !math.IsInf(d.Before, 1)) || d.Change > tolerance {
+			regressions = append(regressions, m.name)
+		}
+	}
+
+	return regressions
+}
+
+// Print writes the report to stdout. See Fprint.
+func (r ComparisonReport) Print() {
+	r.Fprint(os.Stdout)
+}
+
+// Fprint writes a formatted report to w: each metric's value before and
+// after, its change, and the change in the key distribution.
+//
+// Example output:
+//
+//	=== Run Comparison ===
+//	METRIC           BEFORE  AFTER   DELTA   CHANGE
+//	Total Keys       10000   10000   +0      +0.0%
+//	Servers          3       4       +1      +33.3%
+//	Virtual Nodes    150     150     +0      +0.0%
+//	Avg Latency      125ns   131ns   +6ns    +4.8%
+//	Distribution CV  3.45    4.10    +0.65   +18.8%
+//	Gini             0.004   0.012   +0.008  +200.0%
+//	Max/Min Load     1.01    1.04    +0.03   +3.0%
+//	Max Share        33.40   25.90   -7.50   -22.5%
+//	Collisions       0       0       +0      +0.0%
+//
+//	=== Distribution Diff ===
+//	...
+func (r ComparisonReport) Fprint(w io.Writer) {
+	fmt.Fprintln(w, "=== Run Comparison ===")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tBEFORE\tAFTER\tDELTA\tCHANGE")
+	for _, m := range r.metrics() {
+		d := m.delta
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%+.1f%%\n", m.name, m.format(d.Before), m.format(d.After), m.formatDelta(d.Delta), d.Change)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	r.Distribution.Fprint(w)
+}
+
+// comparedMetric is a row of a ComparisonReport.
+type comparedMetric struct {
+	name          string
+	delta         MetricDelta
+	lowerIsBetter bool
+	format        func(float64) string
+}
+
+// formatDelta formats a change in the metric with its sign.
+func (m comparedMetric) formatDelta(d float64) string {
+	if d < 0 {
+		return m.format(d)
+	}
+
+	return "+" + m.format(d)
+}
+
+// metrics returns the report's metrics in the order they're printed.
+func (r ComparisonReport) metrics() []comparedMetric {
+	count := func(f float64) string { return fmt.Sprintf("%.0f", f) }
+	latency := func(f float64) string { return time.Duration(f).String() }
+	precise := func(f float64) string { return fmt.Sprintf("%.3f", f) }
+	short := func(f float64) string { return fmt.Sprintf("%.2f", f) }
+
+	return []comparedMetric{
+		{"Total Keys", r.TotalKeys, false, count},
+		{"Servers", r.Servers, false, count},
+		{"Virtual Nodes", r.VirtualNodes, false, count},
+		{"Avg Latency", r.AvgLatency, true, latency},
+		{"Distribution CV", r.DistributionCV, true, short},
+		{"Gini", r.Gini, true, precise},
+		{"Max/Min Load", r.MaxMinRatio, true, short},
+		{"Max Share", r.MaxShare, true, short},
+		{"Collisions", r.Collisions, true, count},
+	}
+}
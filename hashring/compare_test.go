@@ -0,0 +1,90 @@
+package hashring
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareRuns(t *testing.T) {
+	before := PerformanceMetrics{
+		TotalKeys:      100,
+		Servers:        2,
+		VirtualNodes:   50,
+		AvgLatency:     100 * time.Nanosecond,
+		DistributionCV: 4,
+		Gini:           0.02,
+		MaxMinRatio:    1.1,
+		MaxShare:       52,
+		Distribution:   map[string]int{"a": 52, "b": 48},
+	}
+	after := before
+	after.Servers = 3
+	after.AvgLatency = 150 * time.Nanosecond
+	after.DistributionCV = 4.2
+	after.MaxShare = 40
+	after.Distribution = map[string]int{"a": 40, "b": 30, "c": 30}
+
+	report := CompareRuns(before, after)
+	require.Equal(t, MetricDelta{Before: 2, After: 3, Delta: 1, Change: 50}, report.Servers)
+	require.Equal(t, MetricDelta{Before: 100, After: 150, Delta: 50, Change: 50}, report.AvgLatency)
+	require.InDelta(t, 5, report.DistributionCV.Change, 1e-9)
+	require.InDelta(t, -12, report.MaxShare.Delta, 1e-9)
+	require.Equal(t, MetricDelta{Before: 50, After: 50}, report.VirtualNodes)
+	require.Equal(t, 30, report.Distribution.Moved)
+
+	require.Equal(t, []string{"Avg Latency", "Distribution CV"}, report.Regressions(1))
+	require.Equal(t, []string{"Avg Latency"}, report.Regressions(10))
+	require.Empty(t, report.Regressions(50))
+
+	var buf bytes.Buffer
+	report.Fprint(&buf)
+	require.Contains(t, buf.String(), "=== Run Comparison ===\n")
+	require.Regexp(t, `Avg Latency\s+100ns\s+150ns\s+\+50ns\s+\+50\.0%`, buf.String())
+	require.Regexp(t, `Max Share\s+52\.00\s+40\.00\s+-12\.00\s+-23\.1%`, buf.String())
+	require.Contains(t, buf.String(), "=== Distribution Diff ===\n")
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"avg_latency_ns":{"before":100,"after":150,"delta":50,"change":50}`)
+}
+
+func TestCompareRunsInfinite(t *testing.T) {
+	before := PerformanceMetrics{MaxMinRatio: 1.2}
+	after := PerformanceMetrics{MaxMinRatio: math.Inf(1)}
+
+	// A server losing all its keys is a regression, however lenient the gate
+	report := CompareRuns(before, after)
+	require.Equal(t, []string{"Max/Min Load"}, report.Regressions(math.MaxFloat64))
+
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"max_min_ratio":{"before":1.2,"after":null,"delta":null,"change":null}`)
+
+	// Staying infinite isn't a change
+	report = CompareRuns(after, after)
+	require.Equal(t, MetricDelta{Before: math.Inf(1), After: math.Inf(1)}, report.MaxMinRatio)
+	require.Empty(t, report.Regressions(0))
+}
+
+func TestCompareRunsAnalysis(t *testing.T) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	before := ring.AnalyzePerformance(keys)
+
+	require.NoError(t, ring.AddServer("server3"))
+	report := CompareRuns(before, ring.AnalyzePerformance(keys))
+	require.Equal(t, MetricDelta{Before: 2, After: 3, Delta: 1, Change: 50}, report.Servers)
+	require.Equal(t, report.Distribution.Moved, report.Distribution.Servers[2].After)
+}
@@ -0,0 +1,122 @@
+package hashring
+
+// Router routes keys to servers. HashRing and FrozenRing implement it, and
+// RouterFunc adapts any other implementation, such as another service's
+// hashing scheme ported for comparison.
+type Router interface {
+	GetServer(key string) (string, error)
+}
+
+// RouterFunc adapts a function to Router.
+type RouterFunc func(key string) (string, error)
+
+// GetServer implements Router.
+func (f RouterFunc) GetServer(key string) (string, error) {
+	return f(key)
+}
+
+// RouterBuilder builds a Router over a membership, so two implementations can
+// be given identical servers.
+type RouterBuilder func(servers []ServerInfo) (Router, error)
+
+// RingBuilder returns a RouterBuilder that builds rings with New(vnodes,
+// opts...) and SetServers.
+//
+// Example:
+//
+//	crc := hashring.RingBuilder(150)
+//	xx := hashring.RingBuilder(150, hashring.WithHasher(hashring.XXHash64))
+func RingBuilder(vnodes int, opts ...Option) RouterBuilder {
+	return func(servers []ServerInfo) (Router, error) {
+		ring := New(vnodes, opts...)
+		if err := ring.SetServers(servers); err != nil {
+			return nil, err
+		}
+
+		return ring, nil
+	}
+}
+
+// Divergence is a key two routers send to different servers. A server is empty
+// when its router failed to route the key.
+type Divergence struct {
+	Key string `json:"key"`
+	A   string `json:"a"`
+	B   string `json:"b"`
+}
+
+// ConsistencyReport summarizes how two routers' mappings of a set of keys
+// differ.
+type ConsistencyReport struct {
+	Total     int          `json:"total"`     // number of keys checked
+	Divergent []Divergence `json:"divergent"` // keys routed differently, in the order given
+}
+
+// Agreement returns the fraction of keys both routers sent to the same
+// server, between 0 and 1.
+func (r ConsistencyReport) Agreement() float64 {
+	if r.Total == 0 {
+		return 1
+	}
+
+	return 1 - float64(len(r.Divergent))/float64(r.Total)
+}
+
+// Flows returns how many divergent keys went to each pair of servers:
+// Flows()[a][b] is the number of keys router A sent to a and router B sent to
+// b. Skews concentrated on a few servers usually point at a difference in
+// weighting or naming rather than hashing.
+func (r ConsistencyReport) Flows() map[string]map[string]int {
+	flows := make(map[string]map[string]int)
+	for _, d := range r.Divergent {
+		if flows[d.A] == nil {
+			flows[d.A] = make(map[string]int)
+		}
+		flows[d.A][d.B]++
+	}
+
+	return flows
+}
+
+// VerifyConsistency builds two routers over the same servers and reports the
+// keys they route differently. Use it when migrating between hashing schemes,
+// e.g. to check a port of another service's routing against the original, or
+// to measure how many keys a hasher change would remap.
+//
+// Returns an error if either router can't be built.
+//
+// Example:
+//
+//	report, err := hashring.VerifyConsistency(servers, keys,
+//		hashring.RingBuilder(150),
+//		func(servers []hashring.ServerInfo) (hashring.Router, error) {
+//			return legacyRouter(servers), nil
+//		},
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Printf("%.2f%% of keys agree\n", report.Agreement()*100)
+func VerifyConsistency(servers []ServerInfo, keys []string, a, b RouterBuilder) (ConsistencyReport, error) {
+	routerA, err := a(cloneInfos(servers))
+	if err != nil {
+		return ConsistencyReport{}, err
+	}
+
+	routerB, err := b(cloneInfos(servers))
+	if err != nil {
+		return ConsistencyReport{}, err
+	}
+
+	report := ConsistencyReport{Total: len(keys)}
+	for _, key := range keys {
+		// A failed lookup counts as routing to no server
+		serverA, _ := routerA.GetServer(key)
+		serverB, _ := routerB.GetServer(key)
+		if serverA != serverB {
+			report.Divergent = append(report.Divergent, Divergence{Key: key, A: serverA, B: serverB})
+		}
+	}
+
+	return report, nil
+}
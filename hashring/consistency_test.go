@@ -0,0 +1,72 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ Router = (*HashRing)(nil)
+	_ Router = (*FrozenRing)(nil)
+)
+
+func TestVerifyConsistency(t *testing.T) {
+	servers := []ServerInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	// Identical configurations always agree
+	report, err := VerifyConsistency(servers, keys, RingBuilder(50), RingBuilder(50))
+	require.NoError(t, err)
+	require.Equal(t, 1000, report.Total)
+	require.Empty(t, report.Divergent)
+	require.Equal(t, 1.0, report.Agreement())
+
+	// Different hashers route about two thirds of keys differently with
+	// three servers
+	report, err = VerifyConsistency(servers, keys, RingBuilder(50), RingBuilder(50, WithHasher(XXHash64)))
+	require.NoError(t, err)
+	require.InDelta(t, 1.0/3, report.Agreement(), 0.1)
+
+	moved := 0
+	for a, row := range report.Flows() {
+		for b, n := range row {
+			require.NotEqual(t, a, b)
+			moved += n
+		}
+	}
+	require.Equal(t, len(report.Divergent), moved)
+
+	// A reference implementation can be anything that routes keys
+	modulo := func(servers []ServerInfo) (Router, error) {
+		return RouterFunc(func(key string) (string, error) {
+			return servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(servers))].Name, nil
+		}), nil
+	}
+
+	report, err = VerifyConsistency(servers, keys, modulo, modulo)
+	require.NoError(t, err)
+	require.Empty(t, report.Divergent)
+
+	failing := func([]ServerInfo) (Router, error) {
+		return RouterFunc(func(string) (string, error) { return "", errors.New("down") }), nil
+	}
+	report, err = VerifyConsistency(servers, keys[:2], modulo, failing)
+	require.NoError(t, err)
+	require.Len(t, report.Divergent, 2)
+	require.Empty(t, report.Divergent[0].B)
+}
+
+func TestVerifyConsistencyErrors(t *testing.T) {
+	duplicate := []ServerInfo{{Name: "a"}, {Name: "a"}}
+	_, err := VerifyConsistency(duplicate, nil, RingBuilder(50), RingBuilder(50))
+	require.Error(t, err)
+
+	require.Equal(t, 1.0, ConsistencyReport{}.Agreement())
+}
@@ -0,0 +1,215 @@
+package hashring
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+)
+
+// DigestDepth is the number of levels below the root of a MembershipDigest.
+// Servers are spread over its 2^DigestDepth leaves by the hash of their name.
+const DigestDepth = 10
+
+// MembershipDigest is a Merkle tree over a ring's servers, used to find which
+// servers differ between two views of a ring without exchanging them all (see
+// DiffDigest).
+//
+// Each leaf hashes the servers whose names fall into it, and each node above
+// hashes its two children, so two rings with the same membership have the same
+// Root. Unlike Checksum, it only covers servers: pins and ring options aren't
+// included.
+type MembershipDigest struct {
+	levels  [][]uint64     // levels[d] holds the 2^d node hashes at depth d
+	buckets [][]ServerInfo // servers by leaf, sorted by name
+}
+
+// DigestSource answers the queries MembershipDigest.Diff makes of the other
+// side of a comparison. A MembershipDigest is one; a peer's digest reached over
+// the network is another.
+type DigestSource interface {
+	// Nodes returns the hashes of the nodes at the given indexes of a level,
+	// where level 0 is the root and DigestDepth the leaves.
+	Nodes(level int, indexes []int) ([]uint64, error)
+	// Servers returns the servers in the given leaves.
+	Servers(leaves []int) ([]ServerInfo, error)
+}
+
+// DiffDigest returns a Merkle digest of the ring's membership.
+//
+// Comparing digests with Diff walks down from the root, only descending into
+// nodes whose hashes differ, so finding k differing servers exchanges
+// O(k log n) hashes plus the servers in the differing leaves, rather than every
+// server. This is useful for reconciling large rings between processes.
+//
+// This operation is thread-safe.
+//
+// Example:
+//
+//	changed, err := ring.DiffDigest().Diff(peerDigest)
+func (h *HashRing) DiffDigest() *MembershipDigest {
+	h.mu.RLock()
+	servers := h.serverInfos()
+	h.mu.RUnlock()
+
+	leaves := 1 << DigestDepth
+	d := &MembershipDigest{
+		levels:  make([][]uint64, DigestDepth+1),
+		buckets: make([][]ServerInfo, leaves),
+	}
+
+	// serverInfos is sorted by name, so each bucket is too
+	for _, server := range servers {
+		leaf := digestLeaf(server.Name)
+		d.buckets[leaf] = append(d.buckets[leaf], server)
+	}
+
+	d.levels[DigestDepth] = make([]uint64, leaves)
+	for i, bucket := range d.buckets {
+		h := fnv.New64a()
+		for _, server := range bucket {
+			writeUint64(h, serverHash(server))
+		}
+
+		d.levels[DigestDepth][i] = h.Sum64()
+	}
+
+	for level := DigestDepth - 1; level >= 0; level-- {
+		below := d.levels[level+1]
+		d.levels[level] = make([]uint64, 1<<level)
+		for i := range d.levels[level] {
+			h := fnv.New64a()
+			writeUint64(h, below[2*i])
+			writeUint64(h, below[2*i+1])
+			d.levels[level][i] = h.Sum64()
+		}
+	}
+
+	return d
+}
+
+// Root returns the hash of the whole membership.
+func (d *MembershipDigest) Root() uint64 {
+	return d.levels[0][0]
+}
+
+// Nodes returns the hashes of the nodes at the given indexes of a level, where
+// level 0 is the root and DigestDepth the leaves.
+func (d *MembershipDigest) Nodes(level int, indexes []int) ([]uint64, error) {
+	if level < 0 || level > DigestDepth {
+		return nil, fmt.Errorf("digest level %d out of range [0, %d]", level, DigestDepth)
+	}
+
+	hashes := make([]uint64, len(indexes))
+	for i, index := range indexes {
+		if index < 0 || index >= len(d.levels[level]) {
+			return nil, fmt.Errorf("digest node %d out of range at level %d", index, level)
+		}
+
+		hashes[i] = d.levels[level][index]
+	}
+
+	return hashes, nil
+}
+
+// Servers returns the servers in the given leaves.
+func (d *MembershipDigest) Servers(leaves []int) ([]ServerInfo, error) {
+	var servers []ServerInfo
+	for _, leaf := range leaves {
+		if leaf < 0 || leaf >= len(d.buckets) {
+			return nil, fmt.Errorf("digest leaf %d out of range", leaf)
+		}
+
+		servers = append(servers, cloneInfos(d.buckets[leaf])...)
+	}
+
+	return servers, nil
+}
+
+// Diff returns the sorted names of the servers that differ between d and
+// other: those only one side has, and those whose info differs.
+//
+// Example:
+//
+//	changed, err := local.Diff(remote)
+//	if err != nil {
+//		return err
+//	}
+//	for _, server := range changed {
+//		log.Printf("%s differs from the peer's ring", server)
+//	}
+func (d *MembershipDigest) Diff(other DigestSource) ([]string, error) {
+	indexes := []int{0}
+	for level := 0; level <= DigestDepth; level++ {
+		if level > 0 {
+			children := make([]int, 0, 2*len(indexes))
+			for _, i := range indexes {
+				children = append(children, 2*i, 2*i+1)
+			}
+			indexes = children
+		}
+
+		remote, err := other.Nodes(level, indexes)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(remote) != len(indexes) {
+			return nil, fmt.Errorf("digest returned %d hashes for %d nodes", len(remote), len(indexes))
+		}
+
+		differing := indexes[:0]
+		for i, index := range indexes {
+			if d.levels[level][index] != remote[i] {
+				differing = append(differing, index)
+			}
+		}
+
+		indexes = differing
+		if len(indexes) == 0 {
+			return nil, nil
+		}
+	}
+
+	remote, err := other.Servers(indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	local, _ := d.Servers(indexes)
+	hashes := make(map[string]uint64, len(local))
+	for _, server := range local {
+		hashes[server.Name] = serverHash(server)
+	}
+
+	var names []string
+	for _, server := range remote {
+		if hash, ok := hashes[server.Name]; !ok || hash != serverHash(server) {
+			names = append(names, server.Name)
+		}
+
+		delete(hashes, server.Name)
+	}
+
+	for name := range hashes {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// digestLeaf returns the leaf of a MembershipDigest a server falls into.
+func digestLeaf(name string) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	// FNV's low bits vary far more than its high bits between similar names
+	return int(h.Sum64() & (1<<DigestDepth - 1))
+}
+
+// serverHash returns the hash of the fields of server covered by the
+// checksum.
+func serverHash(server ServerInfo) uint64 {
+	h := fnv.New64a()
+	writeServer(h, server)
+	return h.Sum64()
+}
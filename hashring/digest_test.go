@@ -0,0 +1,69 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingSource counts the hashes and servers a DigestSource returns.
+type countingSource struct {
+	DigestSource
+	hashes, servers int
+}
+
+func (c *countingSource) Nodes(level int, indexes []int) ([]uint64, error) {
+	hashes, err := c.DigestSource.Nodes(level, indexes)
+	c.hashes += len(hashes)
+	return hashes, err
+}
+
+func (c *countingSource) Servers(leaves []int) ([]ServerInfo, error) {
+	servers, err := c.DigestSource.Servers(leaves)
+	c.servers += len(servers)
+	return servers, err
+}
+
+func TestDiffDigest(t *testing.T) {
+	a, b := New(10), New(10)
+	for i := range 1000 {
+		require.NoError(t, a.AddServer(fmt.Sprintf("server%d", i)))
+		require.NoError(t, b.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	// the digest doesn't depend on the order servers were added in
+	c := New(10)
+	for i := 999; i >= 0; i-- {
+		require.NoError(t, c.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	require.Equal(t, a.DiffDigest().Root(), c.DiffDigest().Root())
+
+	diff, err := a.DiffDigest().Diff(b.DiffDigest())
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	require.NoError(t, a.AddServer("server1000"))
+	require.NoError(t, b.RemoveServer("server7"))
+	require.NoError(t, b.SetWeight("server42", 2))
+
+	remote := &countingSource{DigestSource: b.DiffDigest()}
+	diff, err = a.DiffDigest().Diff(remote)
+	require.NoError(t, err)
+	require.Equal(t, []string{"server1000", "server42", "server7"}, diff)
+
+	// far less than the 1000 servers in the ring
+	require.Less(t, remote.hashes, 2*3*(DigestDepth+1))
+	require.Less(t, remote.servers, 10)
+}
+
+func TestDiffDigestErrors(t *testing.T) {
+	d := New(10).DiffDigest()
+
+	_, err := d.Nodes(DigestDepth+1, []int{0})
+	require.Error(t, err)
+	_, err = d.Nodes(1, []int{2})
+	require.Error(t, err)
+	_, err = d.Servers([]int{1 << DigestDepth})
+	require.Error(t, err)
+}
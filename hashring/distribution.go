@@ -0,0 +1,170 @@
+package hashring
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// ServerDelta is the change in a server's key count between two distributions.
+type ServerDelta struct {
+	Server string `json:"server"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+	Delta  int    `json:"delta"` // After - Before
+}
+
+// DistributionDiff compares two key distributions, such as those returned by
+// GetDistribution before and after a scaling event.
+type DistributionDiff struct {
+	// Servers holds the change for every server in either distribution, sorted
+	// by name.
+	Servers []ServerDelta `json:"servers"`
+
+	// Flows is the net flow of keys between servers: Flows[from][to] is the
+	// number of keys from gives to to. Since distributions only contain counts,
+	// this is the smallest set of transfers that explains the deltas rather
+	// than the keys that actually moved (see MovedKeys for those).
+	Flows map[string]map[string]int `json:"flows"`
+
+	// Moved is the total number of keys in Flows.
+	Moved int `json:"moved"`
+}
+
+// CompareDistributions compares the distributions a (before) and b (after).
+// Servers missing from a distribution are treated as having no keys.
+//
+// Example:
+//
+//	before := ring.GetDistribution(keys)
+//	ring.AddServer("server-4")
+//	diff := hashring.CompareDistributions(before, ring.GetDistribution(keys))
+//	diff.Print()
+func CompareDistributions(a, b map[string]int) DistributionDiff {
+	names := make([]string, 0, len(a)+len(b))
+	for server := range a {
+		names = append(names, server)
+	}
+
+	for server := range b {
+		if _, ok := a[server]; !ok {
+			names = append(names, server)
+		}
+	}
+
+	slices.Sort(names)
+
+	diff := DistributionDiff{
+		Servers: make([]ServerDelta, len(names)),
+		Flows:   make(map[string]map[string]int),
+	}
+
+	var losers, gainers []ServerDelta
+	for i, server := range names {
+		d := ServerDelta{Server: server, Before: a[server], After: b[server]}
+		d.Delta = d.After - d.Before
+		diff.Servers[i] = d
+
+		switch {
+		case d.Delta < 0:
+			losers = append(losers, d)
+		case d.Delta > 0:
+			gainers = append(gainers, d)
+		}
+	}
+
+	// Match losers with gainers in name order until every delta is accounted
+	// for. Each step settles at least one server, so there are at most
+	// len(losers)+len(gainers) transfers.
+	for len(losers) > 0 && len(gainers) > 0 {
+		from, to := &losers[0], &gainers[0]
+		n := min(-from.Delta, to.Delta)
+
+		if diff.Flows[from.Server] == nil {
+			diff.Flows[from.Server] = make(map[string]int)
+		}
+
+		diff.Flows[from.Server][to.Server] += n
+		diff.Moved += n
+		from.Delta += n
+		to.Delta -= n
+
+		if from.Delta == 0 {
+			losers = losers[1:]
+		}
+
+		if to.Delta == 0 {
+			gainers = gainers[1:]
+		}
+	}
+
+	return diff
+}
+
+// Print writes the report to stdout. See Fprint.
+func (d DistributionDiff) Print() {
+	d.Fprint(os.Stdout)
+}
+
+// Fprint writes a formatted report of the diff to w: the per-server change,
+// followed by the net flow matrix (rows give keys to columns) when any keys
+// moved.
+//
+// Example output:
+//
+//	=== Distribution Diff ===
+//	SERVER    BEFORE  AFTER  DELTA
+//	server-1  3342    2507   -835
+//	server-2  3321    2490   -831
+//	server-3  3337    2503   -834
+//	server-4  0       2500   +2500
+//
+//	Net Flow (2500 keys):
+//	FROM \ TO  server-4
+//	server-1   835
+//	server-2   831
+//	server-3   834
+func (d DistributionDiff) Fprint(w io.Writer) {
+	fmt.Fprintln(w, "=== Distribution Diff ===")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVER\tBEFORE\tAFTER\tDELTA")
+	for _, s := range d.Servers {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%+d\n", s.Server, s.Before, s.After, s.Delta)
+	}
+	tw.Flush()
+
+	if d.Moved == 0 {
+		return
+	}
+
+	var from, to []string
+	for _, s := range d.Servers {
+		if _, ok := d.Flows[s.Server]; ok {
+			from = append(from, s.Server)
+		}
+
+		if s.Delta > 0 {
+			to = append(to, s.Server)
+		}
+	}
+
+	fmt.Fprintf(w, "\nNet Flow (%d keys):\n", d.Moved)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "FROM \\ TO\t%s\n", strings.Join(to, "\t"))
+	for _, src := range from {
+		row := make([]string, len(to))
+		for i, dst := range to {
+			row[i] = "-"
+			if n := d.Flows[src][dst]; n > 0 {
+				row[i] = fmt.Sprint(n)
+			}
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\n", src, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
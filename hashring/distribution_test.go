@@ -0,0 +1,83 @@
+package hashring
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareDistributions(t *testing.T) {
+	diff := CompareDistributions(
+		map[string]int{"a": 30, "b": 30, "c": 40},
+		map[string]int{"a": 20, "c": 35, "d": 25, "e": 20},
+	)
+
+	require.Equal(t, []ServerDelta{
+		{Server: "a", Before: 30, After: 20, Delta: -10},
+		{Server: "b", Before: 30, After: 0, Delta: -30},
+		{Server: "c", Before: 40, After: 35, Delta: -5},
+		{Server: "d", Before: 0, After: 25, Delta: 25},
+		{Server: "e", Before: 0, After: 20, Delta: 20},
+	}, diff.Servers)
+
+	require.Equal(t, 45, diff.Moved)
+	require.Equal(t, map[string]map[string]int{
+		"a": {"d": 10},
+		"b": {"d": 15, "e": 15},
+		"c": {"e": 5},
+	}, diff.Flows)
+
+	// No change means no flows
+	same := map[string]int{"a": 1, "b": 2}
+	diff = CompareDistributions(same, same)
+	require.Zero(t, diff.Moved)
+	require.Empty(t, diff.Flows)
+}
+
+func TestCompareDistributionsRing(t *testing.T) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	before := ring.GetDistribution(keys)
+
+	require.NoError(t, ring.AddServer("server3"))
+	after := ring.GetDistribution(keys)
+
+	// Adding a server only moves keys to it, so the net flow is exact
+	diff := CompareDistributions(before, after)
+	require.Equal(t, after["server3"], diff.Moved)
+	for from, flows := range diff.Flows {
+		require.Equal(t, map[string]int{"server3": before[from] - after[from]}, flows)
+	}
+}
+
+func TestDistributionDiffFprint(t *testing.T) {
+	var buf bytes.Buffer
+	CompareDistributions(
+		map[string]int{"a": 30, "b": 30},
+		map[string]int{"a": 20, "b": 20, "c": 20},
+	).Fprint(&buf)
+
+	require.Equal(t, `=== Distribution Diff ===
+SERVER  BEFORE  AFTER  DELTA
+a       30      20     -10
+b       30      20     -10
+c       0       20     +20
+
+Net Flow (20 keys):
+FROM \ TO  c
+a          10
+b          10
+`, buf.String())
+
+	buf.Reset()
+	CompareDistributions(map[string]int{"a": 1}, map[string]int{"a": 1}).Fprint(&buf)
+	require.NotContains(t, buf.String(), "Net Flow")
+}
@@ -0,0 +1,203 @@
+package hashring
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Severity indicates how serious a Finding is.
+type Severity string
+
+const (
+	// SeverityInfo findings are worth knowing about but don't need action.
+	SeverityInfo Severity = "info"
+	// SeverityWarning findings degrade balance or availability.
+	SeverityWarning Severity = "warning"
+	// SeverityError findings prevent the ring from routing.
+	SeverityError Severity = "error"
+)
+
+// rank orders severities from least to most serious.
+func (s Severity) rank() int {
+	switch s {
+	case SeverityWarning:
+		return 1
+	case SeverityError:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Finding codes reported by Doctor. They're stable, so tooling can match on
+// them.
+const (
+	FindingEmptyRing          = "empty-ring"
+	FindingCollisions         = "vnode-collisions"
+	FindingLowVNodes          = "low-vnodes"
+	FindingAnomalousOwnership = "anomalous-ownership"
+	FindingSingleZone         = "single-zone"
+	FindingMissingZone        = "missing-zone"
+	FindingTrippedBreaker     = "tripped-breaker"
+)
+
+// OwnershipAnomalyThreshold is how far, relative to its target, a server's
+// ownership share can be before Doctor reports it.
+const OwnershipAnomalyThreshold = 0.25
+
+// Finding is a single diagnostic reported by Doctor.
+type Finding struct {
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Server   string   `json:"server,omitempty"` // the server concerned, if any
+}
+
+// String returns the finding as "severity [code] message".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s [%s] %s", f.Severity, f.Code, f.Message)
+}
+
+// Doctor checks the ring for problems and returns its findings, most severe
+// first. A healthy ring has none.
+//
+// The checks cover an empty ring, virtual node collisions, too few virtual
+// nodes for the number of servers, servers owning much more or less than their
+// weighted share (see OwnershipAnomalyThreshold), zone layouts that keep
+// replicas from spanning zones, and tripped circuit breakers. Each finding has
+// a stable code so CI can gate on ring health.
+//
+// Example:
+//
+//	for _, f := range ring.Doctor() {
+//		if f.Severity != hashring.SeverityInfo {
+//			log.Fatal(f)
+//		}
+//	}
+func (h *HashRing) Doctor() []Finding {
+	h.mu.RLock()
+	findings := h.diagnose()
+	servers := h.serverList()
+	h.mu.RUnlock()
+
+	// Breakers have their own lock
+	for _, server := range servers {
+		if h.Tripped(server) {
+			findings = append(findings, Finding{
+				Code:     FindingTrippedBreaker,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("server %s's circuit breaker is open", server),
+				Server:   server,
+			})
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b Finding) int {
+		return cmp.Or(
+			cmp.Compare(b.Severity.rank(), a.Severity.rank()),
+			cmp.Compare(a.Code, b.Code),
+			cmp.Compare(a.Server, b.Server),
+		)
+	})
+
+	return findings
+}
+
+// diagnose runs the checks that need the ring's state. The caller must hold
+// h.mu.
+func (h *HashRing) diagnose() []Finding {
+	if len(h.servers) == 0 {
+		return []Finding{{
+			Code:     FindingEmptyRing,
+			Severity: SeverityError,
+			Message:  "the ring has no servers, so every lookup fails",
+		}}
+	}
+
+	var findings []Finding
+	if h.collisions > 0 {
+		findings = append(findings, Finding{
+			Code:     FindingCollisions,
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("%d virtual nodes were moved after hash collisions", h.collisions),
+		})
+	}
+
+	if want := minVNodes(len(h.servers)); !h.evenlySpaced() && h.vnodes < want {
+		findings = append(findings, Finding{
+			Code:     FindingLowVNodes,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("%d virtual nodes per server is low for %d servers; use at least %d (see RecommendVNodes)", h.vnodes, len(h.servers), want),
+		})
+	}
+
+	if len(h.servers) > 1 {
+		shares, targets := h.shares(), h.targets()
+		for _, server := range h.serverList() {
+			deviation := (shares[server] - targets[server]) / targets[server]
+			if math.Abs(deviation) > OwnershipAnomalyThreshold {
+				findings = append(findings, Finding{
+					Code:     FindingAnomalousOwnership,
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("server %s owns %.1f%% of the ring but should own %.1f%% (see Rebalance)", server, shares[server]*100, targets[server]*100),
+					Server:   server,
+				})
+			}
+		}
+	}
+
+	return append(findings, h.diagnoseZones()...)
+}
+
+// diagnoseZones checks that replicas can span zones. The caller must hold
+// h.mu.
+func (h *HashRing) diagnoseZones() []Finding {
+	zones := make(map[string]bool)
+	var unzoned []string
+	for _, server := range h.serverList() {
+		if zone := h.servers[server].Zone; zone != "" {
+			zones[zone] = true
+		} else {
+			unzoned = append(unzoned, server)
+		}
+	}
+
+	if len(zones) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	if len(zones) == 1 && len(h.servers) > 1 {
+		findings = append(findings, Finding{
+			Code:     FindingSingleZone,
+			Severity: SeverityWarning,
+			Message:  "every zoned server is in the same zone, so no replica survives losing it",
+		})
+	}
+
+	for _, server := range unzoned {
+		findings = append(findings, Finding{
+			Code:     FindingMissingZone,
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("server %s has no zone while others do, so views filtering by zone never select it", server),
+			Server:   server,
+		})
+	}
+
+	return findings
+}
+
+// minVNodes returns the fewest virtual nodes per server that gives n servers a
+// reasonable distribution, following the guidance on New.
+func minVNodes(n int) int {
+	switch {
+	case n <= 10:
+		return 100
+	case n <= 50:
+		return 50
+	default:
+		return 20
+	}
+}
@@ -0,0 +1,91 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// codes returns the codes of findings, in order.
+func codes(findings []Finding) []string {
+	var codes []string
+	for _, f := range findings {
+		codes = append(codes, f.Code)
+	}
+
+	return codes
+}
+
+func TestDoctor(t *testing.T) {
+	require.Equal(t, []Finding{{
+		Code:     FindingEmptyRing,
+		Severity: SeverityError,
+		Message:  "the ring has no servers, so every lookup fails",
+	}}, New(150).Doctor())
+
+	ring := New(200)
+	for i := range 3 {
+		require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: fmt.Sprintf("server-%d", i), Zone: fmt.Sprintf("zone-%d", i)}))
+	}
+
+	_, err := ring.Rebalance(0.05)
+	require.NoError(t, err)
+	require.Empty(t, ring.Doctor(), "a healthy ring has no findings")
+}
+
+func TestDoctorFindings(t *testing.T) {
+	ring := New(5, WithCircuitBreaker(1, time.Minute))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server-1", Zone: "us-east-1a"}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server-2", Zone: "us-east-1a"}))
+	require.NoError(t, ring.AddServer("server-3"))
+	ring.ReportFailure("server-2")
+
+	findings := ring.Doctor()
+	for i := 1; i < len(findings); i++ {
+		require.GreaterOrEqual(t, findings[i-1].Severity.rank(), findings[i].Severity.rank(), "most severe first")
+	}
+
+	require.Contains(t, codes(findings), FindingLowVNodes)
+	require.Contains(t, codes(findings), FindingSingleZone)
+	require.Contains(t, findings, Finding{
+		Code:     FindingMissingZone,
+		Severity: SeverityInfo,
+		Message:  "server server-3 has no zone while others do, so views filtering by zone never select it",
+		Server:   "server-3",
+	})
+	require.Contains(t, findings, Finding{
+		Code:     FindingTrippedBreaker,
+		Severity: SeverityWarning,
+		Message:  "server server-2's circuit breaker is open",
+		Server:   "server-2",
+	})
+}
+
+func TestDoctorOwnership(t *testing.T) {
+	ring := New(100)
+	require.NoError(t, ring.AddServer("server-1"))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server-2", VNodes: 20}))
+
+	var anomalous []string
+	for _, f := range ring.Doctor() {
+		if f.Code == FindingAnomalousOwnership {
+			anomalous = append(anomalous, f.Server)
+		}
+	}
+
+	require.Equal(t, []string{"server-1", "server-2"}, anomalous)
+}
+
+func TestDoctorCollisions(t *testing.T) {
+	ring := New(1)
+	require.NoError(t, ring.AddServer("coddbwb"))
+	require.NoError(t, ring.AddServer("lwp"))
+	require.Contains(t, codes(ring.Doctor()), FindingCollisions)
+}
+
+func TestFindingString(t *testing.T) {
+	f := Finding{Code: FindingEmptyRing, Severity: SeverityError, Message: "no servers"}
+	require.Equal(t, "error [empty-ring] no servers", f.String())
+}
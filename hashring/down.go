@@ -0,0 +1,63 @@
+package hashring
+
+import "fmt"
+
+// MarkDown takes a server out of every lookup without removing its virtual
+// nodes, e.g. while a health check fails. Its keys go to the next server
+// clockwise until it's marked up, when they return to it, so a brief outage
+// moves only the down server's keys, and only while it's down, rather than
+// rebalancing the ring on removal and again on re-adding.
+//
+// MarkDown is SetState(server, StateDown) but succeeds without a change if the
+// server is already down, so health checks can call it on every failure.
+//
+// Returns an error if the server doesn't exist or a transition hook vetoes
+// the change (see AddTransitionHook).
+//
+// Example:
+//
+//	if !healthy(server) {
+//		_ = ring.MarkDown(server)
+//	} else {
+//		_ = ring.MarkUp(server)
+//	}
+func (h *HashRing) MarkDown(server string) error {
+	state, err := h.stateFor(server)
+	if err != nil {
+		return err
+	}
+
+	if state == StateDown {
+		return nil
+	}
+
+	return h.SetState(server, StateDown)
+}
+
+// MarkUp returns a server marked down to the state it was in before, e.g.
+// joining or draining, or to StateActive if that isn't known because it was
+// restored from a snapshot while down. It succeeds without a change if the
+// server isn't down.
+//
+// Returns an error if the server doesn't exist or a transition hook vetoes
+// the change (see AddTransitionHook).
+func (h *HashRing) MarkUp(server string) error {
+	h.mu.RLock()
+	info, ok := h.servers[server]
+	prior, known := h.downFrom[server]
+	h.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("server %s does not exist", server)
+	}
+
+	if stateOf(info) != StateDown {
+		return nil
+	}
+
+	if !known {
+		prior = StateActive
+	}
+
+	return h.SetState(server, prior)
+}
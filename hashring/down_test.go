@@ -0,0 +1,78 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkDown(t *testing.T) {
+	ring := New(50)
+	for i := range 4 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	owners := make(map[string]string)
+	for i := range 1000 {
+		key := fmt.Sprintf("key%d", i)
+		owners[key], _ = ring.GetServer(key)
+	}
+	checksum := ring.Checksum()
+
+	// only the down server's keys move, and they all come back
+	require.NoError(t, ring.MarkDown("server1"))
+	require.NoError(t, ring.MarkDown("server1"))
+	require.Equal(t, 4, ring.Size())
+	for key, owner := range owners {
+		server, err := ring.GetServer(key)
+		require.NoError(t, err)
+		if owner == "server1" {
+			require.NotEqual(t, "server1", server)
+		} else {
+			require.Equal(t, owner, server, key)
+		}
+	}
+
+	version := ring.Version()
+	require.NoError(t, ring.MarkUp("server1"))
+	require.NoError(t, ring.MarkUp("server1"))
+	require.Equal(t, version+1, ring.Version())
+	require.Equal(t, checksum, ring.Checksum())
+	for key, owner := range owners {
+		server, err := ring.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, owner, server, key)
+	}
+
+	require.ErrorContains(t, ring.MarkDown("missing"), "does not exist")
+	require.ErrorContains(t, ring.MarkUp("missing"), "does not exist")
+}
+
+func TestMarkUpRestoresState(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server1", State: StateDraining}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server2", State: StateJoining}))
+
+	for _, server := range []string{"server1", "server2"} {
+		require.NoError(t, ring.MarkDown(server))
+	}
+
+	// the prior state follows a rename
+	require.NoError(t, ring.RenameServer("server2", "server3"))
+
+	require.NoError(t, ring.MarkUp("server1"))
+	require.NoError(t, ring.MarkUp("server3"))
+	state, _ := ring.State("server1")
+	require.Equal(t, StateDraining, state)
+	state, _ = ring.State("server3")
+	require.Equal(t, StateJoining, state)
+
+	// servers restored while down come back active
+	require.NoError(t, ring.MarkDown("server1"))
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, restored.MarkUp("server1"))
+	state, _ = restored.State("server1")
+	require.Equal(t, StateActive, state)
+}
@@ -0,0 +1,205 @@
+package hashring
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Envoy's default ring_hash_lb_config sizes.
+const (
+	envoyMinRingSize = 1024
+	envoyMaxRingSize = 8 * 1024 * 1024
+)
+
+// EnvoyConfig mirrors the ring_hash_lb_config of an Envoy cluster using the
+// RING_HASH load balancer. Zero values use Envoy's defaults.
+type EnvoyConfig struct {
+	MinRingSize uint64 // minimum_ring_size, 1024 by default
+	MaxRingSize uint64 // maximum_ring_size, 8M by default
+}
+
+// EnvoyRing reproduces the ring built by Envoy's RING_HASH load balancer with
+// the default XX_HASH hash function, so services can predict which backend an
+// Envoy edge sends a key to, or check that their own routing agrees with it.
+//
+// Envoy's ring lives in a 64-bit hash space, so it can't be represented by a
+// HashRing; EnvoyRing is a separate, immutable Router instead. Backends are
+// hashed by name, which must be the key Envoy hashes them by: the endpoint's
+// address ("10.0.0.1:8080"), its hostname with use_hostname_for_hashing, or
+// its hash_key metadata. Backends must be given in the order Envoy sees them
+// (the order of the cluster's endpoints), since it affects how hashes are
+// shared out when the ring can't give every backend a whole number of them.
+//
+// Only the Name and Weight of each backend are used. Weights are relative, and
+// backends without one weigh 1, like endpoints without a load_balancing_weight.
+type EnvoyRing struct {
+	entries []envoyEntry // sorted by hash
+}
+
+// envoyEntry is a position on an EnvoyRing.
+type envoyEntry struct {
+	hash uint64
+	host string
+}
+
+// NewEnvoyRing builds the ring Envoy would for backends with cfg.
+//
+// As in Envoy, backends get hashes in proportion to their weight, scaled so
+// the lightest backend gets at least cfg.MinRingSize times its share, but the
+// ring never has more than cfg.MaxRingSize hashes.
+//
+// Returns an error if no backends are given, a backend has no name or is given
+// twice, or cfg.MinRingSize is greater than cfg.MaxRingSize.
+//
+// Example:
+//
+//	ring, err := hashring.NewEnvoyRing([]hashring.ServerInfo{
+//		{Name: "10.0.0.1:8080"},
+//		{Name: "10.0.0.2:8080", Weight: 2},
+//	}, hashring.EnvoyConfig{MinRingSize: 4096})
+//	if err != nil {
+//		return err
+//	}
+//	backend, err := ring.GetServer(r.Header.Get("x-user-id"))
+func NewEnvoyRing(backends []ServerInfo, cfg EnvoyConfig) (*EnvoyRing, error) {
+	if cfg.MinRingSize == 0 {
+		cfg.MinRingSize = envoyMinRingSize
+	}
+	if cfg.MaxRingSize == 0 {
+		cfg.MaxRingSize = envoyMaxRingSize
+	}
+
+	if cfg.MinRingSize > cfg.MaxRingSize {
+		return nil, fmt.Errorf("minimum ring size %d is greater than maximum ring size %d", cfg.MinRingSize, cfg.MaxRingSize)
+	}
+
+	if len(backends) == 0 {
+		return nil, errors.New("no backends given")
+	}
+
+	weights := make([]float64, len(backends))
+	seen := make(map[string]bool, len(backends))
+	var total float64
+	for i, backend := range backends {
+		if backend.Name == "" {
+			return nil, errors.New("backend name cannot be empty")
+		}
+
+		if seen[backend.Name] {
+			return nil, fmt.Errorf("backend %s given twice", backend.Name)
+		}
+		seen[backend.Name] = true
+
+		weights[i] = backend.Weight
+		if weights[i] <= 0 {
+			weights[i] = 1
+		}
+		total += weights[i]
+	}
+
+	// Normalize the weights, then scale them so the lightest backend gets a
+	// whole number of hashes, within the maximum ring size.
+	minWeight := 1.0
+	for i := range weights {
+		weights[i] /= total
+		minWeight = min(minWeight, weights[i])
+	}
+
+	scale := min(math.Ceil(minWeight*float64(cfg.MinRingSize))/minWeight, float64(cfg.MaxRingSize))
+
+	// Running totals share out fractional hashes the same way Envoy does: a
+	// backend gets hashes until the ring reaches its cumulative target.
+	r := &EnvoyRing{entries: make([]envoyEntry, 0, int(math.Ceil(scale)))}
+	var current, target float64
+	for i, backend := range backends {
+		target += scale * weights[i]
+		for n := 0; current < target; n++ {
+			key := backend.Name + "_" + strconv.Itoa(n)
+			r.entries = append(r.entries, envoyEntry{hash: xxhash64([]byte(key), 0), host: backend.Name})
+			current++
+		}
+	}
+
+	slices.SortStableFunc(r.entries, func(a, b envoyEntry) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+
+	return r, nil
+}
+
+// EnvoyBuilder returns a RouterBuilder that builds EnvoyRings with cfg, to
+// compare Envoy's routing with a ring's using VerifyConsistency.
+//
+// Example:
+//
+//	report, err := hashring.VerifyConsistency(backends, keys,
+//		hashring.EnvoyBuilder(hashring.EnvoyConfig{}),
+//		hashring.RingBuilder(150, hashring.WithHasher(hashring.XXHash64)),
+//	)
+func EnvoyBuilder(cfg EnvoyConfig) RouterBuilder {
+	return func(servers []ServerInfo) (Router, error) {
+		return NewEnvoyRing(servers, cfg)
+	}
+}
+
+// GetServer returns the backend Envoy routes key to when a hash policy hashes
+// it on its own, such as a header or cookie hash policy with the key as the
+// value. Other hash policies can be reproduced with GetServerForHash.
+func (r *EnvoyRing) GetServer(key string) (string, error) {
+	return r.GetServerForHash(xxhash64([]byte(key), 0)), nil
+}
+
+// GetServerForHash returns the backend Envoy routes a request with the given
+// 64-bit hash to: that of the first ring entry at or after hash, wrapping
+// around to the first entry.
+func (r *EnvoyRing) GetServerForHash(hash uint64) string {
+	// Envoy's binary search, ported from ketama, kept verbatim so edge cases
+	// land on the same entry
+	low, high := 0, len(r.entries)
+	for {
+		mid := (low + high) / 2
+		if mid == len(r.entries) {
+			return r.entries[0].host
+		}
+
+		var prev uint64
+		if mid > 0 {
+			prev = r.entries[mid-1].hash
+		}
+
+		if hash <= r.entries[mid].hash && hash > prev {
+			return r.entries[mid].host
+		}
+
+		if r.entries[mid].hash < hash {
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+
+		if low > high {
+			return r.entries[0].host
+		}
+	}
+}
+
+// Size returns the number of hashes on the ring, Envoy's ring_hash_size.
+func (r *EnvoyRing) Size() int {
+	return len(r.entries)
+}
+
+// Hashes returns the number of hashes each backend has on the ring. Envoy
+// reports the smallest and largest of these as min_hashes_per_host and
+// max_hashes_per_host.
+func (r *EnvoyRing) Hashes() map[string]int {
+	hashes := make(map[string]int)
+	for _, e := range r.entries {
+		hashes[e.host]++
+	}
+
+	return hashes
+}
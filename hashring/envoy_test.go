@@ -0,0 +1,101 @@
+package hashring
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ Router = (*EnvoyRing)(nil)
+
+func TestNewEnvoyRing(t *testing.T) {
+	backends := func(weights ...float64) []ServerInfo {
+		infos := make([]ServerInfo, len(weights))
+		for i, w := range weights {
+			infos[i] = ServerInfo{Name: fmt.Sprintf("10.0.0.%d:80", i+1), Weight: w}
+		}
+		return infos
+	}
+
+	// Equal weights: the lightest backend's share of the minimum size is
+	// rounded up to a whole number of hashes
+	ring, err := NewEnvoyRing(backends(0, 0, 0), EnvoyConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 1026, ring.Size())
+	require.Equal(t, map[string]int{"10.0.0.1:80": 342, "10.0.0.2:80": 342, "10.0.0.3:80": 342}, ring.Hashes())
+
+	ring, err = NewEnvoyRing(backends(1, 3), EnvoyConfig{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"10.0.0.1:80": 256, "10.0.0.2:80": 768}, ring.Hashes())
+
+	// Envoy's own example: four backends on a ring of six get 2, 1, 2, 1
+	// hashes
+	ring, err = NewEnvoyRing(backends(1, 1, 1, 1), EnvoyConfig{MinRingSize: 6, MaxRingSize: 6})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"10.0.0.1:80": 2, "10.0.0.2:80": 1, "10.0.0.3:80": 2, "10.0.0.4:80": 1}, ring.Hashes())
+
+	_, err = NewEnvoyRing(backends(1), EnvoyConfig{MinRingSize: 10, MaxRingSize: 5})
+	require.ErrorContains(t, err, "greater than maximum ring size")
+
+	_, err = NewEnvoyRing(nil, EnvoyConfig{})
+	require.Error(t, err)
+
+	_, err = NewEnvoyRing([]ServerInfo{{Name: "a"}, {Name: "a"}}, EnvoyConfig{})
+	require.ErrorContains(t, err, "given twice")
+
+	_, err = NewEnvoyRing([]ServerInfo{{}}, EnvoyConfig{})
+	require.Error(t, err)
+}
+
+func TestEnvoyRingGetServer(t *testing.T) {
+	ring, err := NewEnvoyRing([]ServerInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}}, EnvoyConfig{MinRingSize: 64})
+	require.NoError(t, err)
+	require.True(t, sort.SliceIsSorted(ring.entries, func(i, j int) bool { return ring.entries[i].hash < ring.entries[j].hash }))
+
+	// Each hash goes to the first entry at or after it, wrapping around
+	successor := func(hash uint64) string {
+		i := sort.Search(len(ring.entries), func(i int) bool { return ring.entries[i].hash >= hash })
+		return ring.entries[i%len(ring.entries)].host
+	}
+
+	for i := range 1000 {
+		key := fmt.Sprintf("key%d", i)
+		server, err := ring.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, successor(xxhash64([]byte(key), 0)), server, key)
+	}
+
+	for _, e := range ring.entries {
+		require.Equal(t, e.host, ring.GetServerForHash(e.hash))
+		require.Equal(t, successor(e.hash+1), ring.GetServerForHash(e.hash+1))
+	}
+	require.Equal(t, ring.entries[0].host, ring.GetServerForHash(0))
+	require.Equal(t, ring.entries[0].host, ring.GetServerForHash(^uint64(0)))
+
+	// Backends are hashed by name and vnode index
+	require.Equal(t, "a", ring.GetServerForHash(xxhash64([]byte("a_0"), 0)))
+}
+
+func TestEnvoyBuilder(t *testing.T) {
+	servers := []ServerInfo{{Name: "a"}, {Name: "b", Weight: 2}, {Name: "c"}}
+	keys := make([]string, 4000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	report, err := VerifyConsistency(servers, keys, EnvoyBuilder(EnvoyConfig{}), EnvoyBuilder(EnvoyConfig{}))
+	require.NoError(t, err)
+	require.Empty(t, report.Divergent)
+
+	// Weights are honored
+	ring, err := NewEnvoyRing(servers, EnvoyConfig{})
+	require.NoError(t, err)
+	counts := make(map[string]int)
+	for _, key := range keys {
+		server, _ := ring.GetServer(key)
+		counts[server]++
+	}
+	require.InDelta(t, 2000, counts["b"], 300)
+}
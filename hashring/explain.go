@@ -0,0 +1,154 @@
+package hashring
+
+import "errors"
+
+// ExplainNeighbors is the number of virtual nodes on each side of the matched
+// one that Explain reports.
+const ExplainNeighbors = 3
+
+// Explanation describes step by step how a key was routed, as returned by
+// Explain.
+type Explanation struct {
+	Key        string `json:"key"`
+	RoutingKey string `json:"routingKey"` // the part of the key that was hashed (see WithHashTags)
+	Hash       uint64 `json:"hash"`       // the key's position on the ring
+
+	// Path is the binary search for the first virtual node clockwise from
+	// Hash, in the order the virtual nodes were probed.
+	Path []SearchStep `json:"path"`
+
+	// Wrapped reports that Hash is past the last virtual node, so the search
+	// wrapped around to the first one.
+	Wrapped bool `json:"wrapped"`
+
+	Index int    `json:"index"` // the matched virtual node's index in position order
+	VNode uint64 `json:"vnode"` // the matched virtual node's position
+	Owner string `json:"owner"` // the matched virtual node's server
+
+	// Nearby are the virtual nodes around the matched one, in ring order,
+	// including the matched one.
+	Nearby []NearbyVNode `json:"nearby"`
+
+	// Pin is the longest pin matching the key, if any, which overrides Owner.
+	Pin string `json:"pin,omitempty"`
+
+	// Canary reports that Hash is in the canary's ranges, so the key goes to
+	// the canary unless it's pinned (see SetCanary).
+	Canary bool `json:"canary,omitempty"`
+
+	// Tenant is the key's tenant, if it has dedicated servers. Only servers
+	// eligible for the key are routed to (see ServerInfo.Tenants), so Server
+	// may be further clockwise than Owner.
+	Tenant string `json:"tenant,omitempty"`
+
+	// Server is the server the key is read from: the pinned server, the
+	// canary, or Owner, unless isolation or server states rule them out. It's
+	// empty if no server is eligible for the key. Circuit breakers aren't
+	// consulted, as with Lookup.
+	Server string `json:"server"`
+}
+
+// SearchStep is a virtual node probed by the binary search in Explain.
+type SearchStep struct {
+	Low       int    `json:"low"`       // the lowest index still in range
+	High      int    `json:"high"`      // one past the highest index still in range
+	Index     int    `json:"index"`     // the probed index, halfway between them
+	VNode     uint64 `json:"vnode"`     // the probed virtual node's position
+	Server    string `json:"server"`    // the probed virtual node's server
+	Clockwise bool   `json:"clockwise"` // VNode >= the key's hash, so the search continued below Index
+}
+
+// NearbyVNode is a virtual node near the one a key matched.
+type NearbyVNode struct {
+	Index   int    `json:"index"`
+	VNode   uint64 `json:"vnode"`
+	Server  string `json:"server"`
+	Matched bool   `json:"matched"` // this is the virtual node the key matched
+}
+
+// Explain describes how key is routed: its hash, the binary search for its
+// virtual node, the virtual nodes around it, and any pin or canary overriding
+// it. It answers "why did key X go to server Y" without a debugger.
+//
+// Returns an error if the hash ring is empty.
+//
+// Example:
+//
+//	exp, err := ring.Explain("user:42")
+//	if err != nil {
+//		return err
+//	}
+//	for _, n := range exp.Nearby {
+//		fmt.Printf("%5d %#016x %s\n", n.Index, n.VNode, n.Server)
+//	}
+func (h *HashRing) Explain(key string) (Explanation, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.entries) == 0 {
+		return Explanation{}, errors.New("hash ring is empty")
+	}
+
+	exp := Explanation{Key: key, RoutingKey: h.routingKey(key)}
+	exp.Hash = h.hashKey(exp.RoutingKey)
+
+	// The same search as search, recording each probe
+	lo, hi := 0, len(h.entries)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		step := SearchStep{
+			Low:       lo,
+			High:      hi,
+			Index:     mid,
+			VNode:     h.entries[mid].hash,
+			Server:    h.owner(mid),
+			Clockwise: h.entries[mid].hash >= exp.Hash,
+		}
+		exp.Path = append(exp.Path, step)
+
+		if step.Clockwise {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	if lo == len(h.entries) {
+		exp.Wrapped = true
+		lo = 0
+	}
+
+	exp.Index = lo
+	exp.VNode = h.entries[lo].hash
+	exp.Owner = h.owner(lo)
+	exp.Nearby = h.nearby(lo, ExplainNeighbors)
+
+	exp.Pin, _ = h.matchPin(key)
+	exp.Canary = h.canaried(exp.Hash)
+	exp.Tenant = h.tenant(key)
+	exp.Server = h.locate(key, AccessRead)
+
+	h.lookups.Add(1)
+	return exp, nil
+}
+
+// nearby returns the virtual nodes within n positions of index i, wrapping
+// around the ring, without repeating any. The caller must hold h.mu.
+func (h *HashRing) nearby(i, n int) []NearbyVNode {
+	total := len(h.entries)
+	from := -min(n, (total-1)/2)
+	to := min(n, total-1+from)
+
+	vnodes := make([]NearbyVNode, 0, to-from+1)
+	for off := from; off <= to; off++ {
+		j := ((i+off)%total + total) % total
+		vnodes = append(vnodes, NearbyVNode{
+			Index:   j,
+			VNode:   h.entries[j].hash,
+			Server:  h.owner(j),
+			Matched: off == 0,
+		})
+	}
+
+	return vnodes
+}
@@ -0,0 +1,84 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	ring := New(50, WithHashTags("{", "}"))
+	_, err := ring.Explain("key")
+	require.Error(t, err)
+
+	for i := range 3 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	for i := range 100 {
+		key := fmt.Sprintf("{user%d}:profile", i)
+		exp, err := ring.Explain(key)
+		require.NoError(t, err)
+
+		// agrees with Lookup
+		res, err := ring.Lookup(key)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("user%d", i), exp.RoutingKey)
+		require.Equal(t, res.Hash, exp.Hash)
+		require.Equal(t, res.Index, exp.Index)
+		require.Equal(t, res.VNode, exp.VNode)
+		require.Equal(t, res.Server, exp.Server)
+		require.Equal(t, exp.Owner, exp.Server)
+		require.Empty(t, exp.Pin)
+
+		// a binary search over 150 virtual nodes
+		require.NotEmpty(t, exp.Path)
+		require.LessOrEqual(t, len(exp.Path), 8)
+		last := exp.Path[len(exp.Path)-1]
+		if exp.Wrapped {
+			require.Zero(t, exp.Index)
+			require.Less(t, last.VNode, exp.Hash)
+		} else {
+			require.GreaterOrEqual(t, exp.VNode, exp.Hash)
+		}
+
+		require.Len(t, exp.Nearby, 2*ExplainNeighbors+1)
+		require.True(t, exp.Nearby[ExplainNeighbors].Matched)
+		require.Equal(t, exp.Index, exp.Nearby[ExplainNeighbors].Index)
+	}
+
+	require.NoError(t, ring.Pin("{user1}", "server0"))
+	exp, err := ring.Explain("{user1}:profile")
+	require.NoError(t, err)
+	require.Equal(t, "{user1}", exp.Pin)
+	require.Equal(t, "server0", exp.Server)
+}
+
+func TestExplainWrapped(t *testing.T) {
+	ring := New(1)
+	require.NoError(t, ring.AddServerWithTokens("server1", []uint64{100}))
+	require.NoError(t, ring.AddServerWithTokens("server2", []uint64{200}))
+
+	// find a key hashing past the last token
+	key := ""
+	for i := 0; key == ""; i++ {
+		if k := fmt.Sprintf("key%d", i); ring.Hash(k) > 200 {
+			key = k
+		}
+	}
+
+	exp, err := ring.Explain(key)
+	require.NoError(t, err)
+	require.True(t, exp.Wrapped)
+	require.Equal(t, 0, exp.Index)
+	require.Equal(t, "server1", exp.Server)
+	require.Len(t, exp.Path, 1)
+	require.False(t, exp.Path[0].Clockwise)
+
+	// neighbors don't repeat on small rings
+	require.Equal(t, []NearbyVNode{
+		{Index: 0, VNode: 100, Server: "server1", Matched: true},
+		{Index: 1, VNode: 200, Server: "server2"},
+	}, exp.Nearby)
+}
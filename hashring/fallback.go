@@ -0,0 +1,41 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GetWithFallback calls try with the key's owner and, while it fails, with up
+// to n of the next distinct servers clockwise around the ring.
+//
+// It returns the server for which try succeeded. If every attempt fails, the
+// errors from all attempts are returned, joined. The ring isn't locked while
+// try runs, so try may call back into the ring.
+//
+// Returns an error without calling try if the hash ring is empty.
+//
+// Example:
+//
+//	var value []byte
+//	server, err := ring.GetWithFallback("user:12345", 2, func(server string) (err error) {
+//		value, err = fetch(server, "user:12345")
+//		return err
+//	})
+func (h *HashRing) GetWithFallback(key string, n int, try func(server string) error) (string, error) {
+	candidates, err := h.GetReplicas(key, max(n, 0)+1)
+	if err != nil {
+		return "", err
+	}
+
+	var errs []error
+	for _, server := range candidates {
+		err := try(server)
+		if err == nil {
+			return server, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+	}
+
+	return "", errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package hashring
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetWithFallback(t *testing.T) {
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	replicas, err := ring.GetReplicas("key", 3)
+	require.NoError(t, err)
+
+	// The owner is used when it succeeds
+	var tried []string
+	server, err := ring.GetWithFallback("key", 2, func(server string) error {
+		tried = append(tried, server)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, replicas[0], server)
+	require.Equal(t, replicas[:1], tried)
+
+	// Failures fall through to the successors in ring order
+	errDown := errors.New("down")
+	tried = nil
+	server, err = ring.GetWithFallback("key", 2, func(server string) error {
+		tried = append(tried, server)
+		if server == replicas[2] {
+			return nil
+		}
+		return errDown
+	})
+	require.NoError(t, err)
+	require.Equal(t, replicas[2], server)
+	require.Equal(t, replicas, tried)
+
+	// Only n successors are tried
+	tried = nil
+	_, err = ring.GetWithFallback("key", 1, func(server string) error {
+		tried = append(tried, server)
+		return errDown
+	})
+	require.ErrorIs(t, err, errDown)
+	require.Equal(t, replicas[:2], tried)
+
+	_, err = New(10).GetWithFallback("key", 2, func(string) error { return nil })
+	require.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package hashring
+
+import (
+	"errors"
+	"maps"
+	"slices"
+)
+
+// FrozenRing is an immutable copy of a HashRing, optimized for reads.
+//
+// Since it never changes, lookups take no locks and can be made from any
+// number of goroutines, which suits request routers that receive a new ring
+// wholesale rather than mutating one. Lookups route exactly as the ring did
+// when it was frozen, except that circuit breakers, the lookup cache, and the
+// metrics sink aren't consulted.
+type FrozenRing struct {
+	ring     *HashRing // private copy, never modified or locked
+	servers  []string  // sorted server names
+	checksum uint64
+}
+
+// Freeze returns an immutable, lock-free copy of the ring. Later changes to
+// the ring don't affect the copy. This operation is thread-safe.
+//
+// Example:
+//
+//	var current atomic.Pointer[hashring.FrozenRing]
+//	current.Store(ring.Freeze())
+//
+//	// in the request path
+//	server, err := current.Load().GetServer(requestKey)
+func (h *HashRing) Freeze() *FrozenRing {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return &FrozenRing{
+		ring:     h.clone(),
+		servers:  h.serverList(),
+		checksum: h.checksum(),
+	}
+}
+
+// Thaw returns a new, mutable ring with the frozen ring's membership, version,
+// history, and options. Changes to it don't affect the frozen ring.
+//
+// Example:
+//
+//	next := frozen.Thaw()
+//	next.AddServer("server4")
+//	current.Store(next.Freeze())
+func (f *FrozenRing) Thaw() *HashRing {
+	return f.ring.clone()
+}
+
+// GetServer returns the server responsible for the given key.
+//
+// Returns an error if the ring is empty or no server is eligible for the key
+// (see ServerInfo.Tenants).
+func (f *FrozenRing) GetServer(key string) (string, error) {
+	h := f.ring
+	if len(h.entries) == 0 {
+		return "", errors.New("hash ring is empty")
+	}
+
+	server := h.locate(key, AccessRead)
+	if server == "" {
+		return "", errNoEligibleServer(key)
+	}
+
+	return server, nil
+}
+
+// GetReplicas returns up to n distinct servers for the given key, ordered by
+// preference, as HashRing.GetReplicas does.
+//
+// Returns an error if the ring is empty or no server is eligible for the key.
+func (f *FrozenRing) GetReplicas(key string, n int) ([]string, error) {
+	h := f.ring
+	if len(h.entries) == 0 {
+		return nil, errors.New("hash ring is empty")
+	}
+
+	n = min(n, len(h.servers))
+	accept := h.eligible(key, AccessRead)
+	replicas := make([]string, 0, n)
+	if server, ok := h.override(key); ok && n > 0 && (accept == nil || accept(server)) {
+		replicas = append(replicas, server)
+	}
+
+	h.walk(h.hashKey(h.routingKey(key)), func(server string) bool {
+		if (accept == nil || accept(server)) && !slices.Contains(replicas, server) {
+			replicas = append(replicas, server)
+		}
+
+		return len(replicas) < n
+	})
+
+	if n > 0 && len(replicas) == 0 {
+		return nil, errNoEligibleServer(key)
+	}
+
+	return replicas, nil
+}
+
+// GetServers returns a sorted list of the servers in the ring.
+func (f *FrozenRing) GetServers() []string {
+	return slices.Clone(f.servers)
+}
+
+// Size returns the number of servers in the ring.
+func (f *FrozenRing) Size() int {
+	return len(f.servers)
+}
+
+// Version returns the ring's version when it was frozen.
+func (f *FrozenRing) Version() uint64 {
+	return f.ring.version
+}
+
+// Checksum returns the ring's checksum when it was frozen (see
+// HashRing.Checksum).
+func (f *FrozenRing) Checksum() uint64 {
+	return f.checksum
+}
+
+// clone returns a copy of the ring with the same membership, version, history,
+// and options, but fresh circuit breakers, lookup cache, and lookup profile,
+// and without OnMove and OnStateChange subscriptions or transition hooks. The
+// caller must hold h.mu.
+func (h *HashRing) clone() *HashRing {
+	c := &HashRing{
+		entries:      slices.Clone(h.entries),
+		names:        slices.Clone(h.names),
+		ids:          maps.Clone(h.ids),
+		servers:      make(map[string]ServerInfo, len(h.servers)),
+		vnodes:       h.vnodes,
+		placement:    h.placement,
+		hasher:       h.hasher,
+		collisions:   h.collisions,
+		version:      h.version,
+		pins:         maps.Clone(h.pins),
+		canary:       h.canary,
+		dedicated:    make(map[string]map[string]bool),
+		restricted:   make(map[string]ServerState),
+		downFrom:     maps.Clone(h.downFrom),
+		tenantOf:     h.tenantOf,
+		tagOpen:      h.tagOpen,
+		tagClose:     h.tagClose,
+		extractor:    h.extractor,
+		weightPolicy: h.weightPolicy,
+		normalize:    h.normalize,
+		normalized:   maps.Clone(h.normalized),
+		maxServers:   h.maxServers,
+		maxVNodes:    h.maxVNodes,
+		actor:        h.actor,
+		history:      h.historyCopy(),
+		historyLimit: h.historyLimit,
+		now:          h.now,
+		breakers: breakers{
+			threshold: h.breakers.threshold,
+			cooldown:  h.breakers.cooldown,
+		},
+		metrics: h.metrics,
+	}
+
+	for server, info := range h.servers {
+		c.servers[server] = info.clone()
+	}
+	c.indexServers()
+
+	if h.cache != nil {
+		c.cache = newLookupCache(h.cache.size)
+	}
+
+	if h.profile != nil {
+		c.profile = &lookupProfiler{rate: h.profile.rate, samples: make([]LookupSample, 0, cap(h.profile.samples))}
+	}
+
+	if h.writes != nil {
+		c.writes = &writeBatcher{window: h.writes.window}
+	}
+
+	return c
+}
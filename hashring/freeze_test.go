@@ -0,0 +1,73 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreeze(t *testing.T) {
+	ring := New(100, WithHashTags("{", "}"))
+	for i := range 5 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	require.NoError(t, ring.Pin("tenant:acme:", "server3"))
+
+	frozen := ring.Freeze()
+	require.Equal(t, ring.GetServers(), frozen.GetServers())
+	require.Equal(t, 5, frozen.Size())
+	require.Equal(t, ring.Version(), frozen.Version())
+	require.Equal(t, ring.Checksum(), frozen.Checksum())
+
+	for i := range 1000 {
+		key := fmt.Sprintf("key%d", i)
+
+		want, err := ring.GetServer(key)
+		require.NoError(t, err)
+		got, err := frozen.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, want, got, "key %s", key)
+
+		wantReplicas, err := ring.GetReplicas(key, 3)
+		require.NoError(t, err)
+		gotReplicas, err := frozen.GetReplicas(key, 3)
+		require.NoError(t, err)
+		require.Equal(t, wantReplicas, gotReplicas, "key %s", key)
+	}
+
+	server, err := frozen.GetServer("tenant:acme:user1")
+	require.NoError(t, err)
+	require.Equal(t, "server3", server)
+
+	// Later changes don't affect the frozen ring
+	require.NoError(t, ring.RemoveServer("server0"))
+	require.Equal(t, 5, frozen.Size())
+	require.NotEqual(t, ring.Checksum(), frozen.Checksum())
+}
+
+func TestFreezeEmpty(t *testing.T) {
+	frozen := New(100).Freeze()
+	_, err := frozen.GetServer("key")
+	require.Error(t, err)
+	_, err = frozen.GetReplicas("key", 2)
+	require.Error(t, err)
+}
+
+func TestThaw(t *testing.T) {
+	ring := New(100, WithActor("deployer"))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	frozen := ring.Freeze()
+	thawed := frozen.Thaw()
+	require.Equal(t, ring.Checksum(), thawed.Checksum())
+	require.Equal(t, ring.Version(), thawed.Version())
+	require.Equal(t, ring.History(), thawed.History())
+
+	// The thawed ring is independent of the frozen one
+	require.NoError(t, thawed.AddServer("server3"))
+	require.Equal(t, uint64(3), thawed.Version())
+	require.Equal(t, []string{"server1", "server2"}, frozen.GetServers())
+	require.Equal(t, []string{"server1", "server2"}, frozen.Thaw().GetServers())
+}
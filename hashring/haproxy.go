@@ -0,0 +1,181 @@
+package hashring
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	// haproxyWeightScale is HAProxy's BE_WEIGHT_SCALE: nodes per unit of a
+	// server's weight.
+	haproxyWeightScale = 16
+
+	// haproxyWeightRange is HAProxy's SRV_EWGHT_RANGE, which spaces out the
+	// node keys of consecutive server ids.
+	haproxyWeightRange = 256 * haproxyWeightScale
+
+	// haproxyMaxWeight is the largest weight HAProxy accepts.
+	haproxyMaxWeight = 256
+)
+
+// HAProxyConfig mirrors the hashing settings of an HAProxy backend using
+// `hash-type consistent`.
+type HAProxyConfig struct {
+	// Avalanche applies HAProxy's avalanche function to key hashes, as
+	// `hash-type consistent avalanche` does. It spreads keys whose hashes are
+	// close, such as sequential ids, around the ring.
+	Avalanche bool
+}
+
+// HAProxyRing reproduces the tree HAProxy builds for a backend using
+// `hash-type consistent` with the default sdbm hash function and server ids
+// as hash keys (`hash-key id`), so services behind HAProxy can predict which
+// server it sends a key to.
+//
+// HAProxy places a server's nodes by its numeric id, not its name, so servers
+// must be given in the order they're declared in the backend, which is the
+// order HAProxy numbers them in from 1. Backends that set explicit ids with
+// `id` aren't supported. Only the Name and Weight of each server are used;
+// weights must be whole numbers up to 256, and servers without one weigh 1.
+// Backup servers aren't part of the tree, so they shouldn't be given.
+//
+// Unlike most rings, HAProxy sends each key to the node closest to its hash in
+// either direction. HAProxy skips servers that are down or over their
+// hash-balance-factor; HAProxyRing doesn't track either.
+type HAProxyRing struct {
+	nodes     []haproxyNode // sorted by key
+	avalanche bool
+}
+
+// haproxyNode is a node in an HAProxyRing.
+type haproxyNode struct {
+	key    uint32
+	server string
+}
+
+// NewHAProxyRing builds the tree HAProxy would for servers with cfg. Each
+// server gets 16 nodes per unit of weight.
+//
+// Returns an error if no servers are given, a server has no name or is given
+// twice, or a weight isn't a whole number up to 256.
+//
+// Example:
+//
+//	ring, err := hashring.NewHAProxyRing([]hashring.ServerInfo{
+//		{Name: "web1"},
+//		{Name: "web2", Weight: 2},
+//	}, hashring.HAProxyConfig{})
+//	if err != nil {
+//		return err
+//	}
+//	backend, err := ring.GetServer(r.URL.Path) // balance uri
+func NewHAProxyRing(servers []ServerInfo, cfg HAProxyConfig) (*HAProxyRing, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("no servers given")
+	}
+
+	r := &HAProxyRing{avalanche: cfg.Avalanche}
+	seen := make(map[string]bool, len(servers))
+	for i, server := range servers {
+		if server.Name == "" {
+			return nil, errors.New("server name cannot be empty")
+		}
+
+		if seen[server.Name] {
+			return nil, fmt.Errorf("server %s given twice", server.Name)
+		}
+		seen[server.Name] = true
+
+		weight := server.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		if weight != math.Trunc(weight) || weight > haproxyMaxWeight {
+			return nil, fmt.Errorf("server %s: HAProxy weights are whole numbers up to %d, got %g", server.Name, haproxyMaxWeight, weight)
+		}
+
+		id := uint32(i + 1)
+		for node := range uint32(weight) * haproxyWeightScale {
+			r.nodes = append(r.nodes, haproxyNode{key: haproxyFullHash(id*haproxyWeightRange + node), server: server.Name})
+		}
+	}
+
+	slices.SortStableFunc(r.nodes, func(a, b haproxyNode) int {
+		return cmp.Compare(a.key, b.key)
+	})
+
+	return r, nil
+}
+
+// HAProxyBuilder returns a RouterBuilder that builds HAProxyRings with cfg, to
+// compare HAProxy's routing with a ring's using VerifyConsistency.
+//
+// Example:
+//
+//	report, err := hashring.VerifyConsistency(servers, keys,
+//		hashring.HAProxyBuilder(hashring.HAProxyConfig{}),
+//		hashring.RingBuilder(150),
+//	)
+func HAProxyBuilder(cfg HAProxyConfig) RouterBuilder {
+	return func(servers []ServerInfo) (Router, error) {
+		return NewHAProxyRing(servers, cfg)
+	}
+}
+
+// GetServer returns the server HAProxy routes key to, where key is the sample
+// the backend balances on, such as the URI or a header's value.
+func (r *HAProxyRing) GetServer(key string) (string, error) {
+	hash := sdbm([]byte(key))
+	if r.avalanche {
+		hash = haproxyFullHash(hash)
+	}
+
+	return r.GetServerForHash(hash), nil
+}
+
+// GetServerForHash returns the server owning the node closest to hash. Of the
+// nodes on either side of hash, wrapping around, the one before wins ties.
+func (r *HAProxyRing) GetServerForHash(hash uint32) string {
+	next, _ := slices.BinarySearchFunc(r.nodes, hash, func(n haproxyNode, hash uint32) int {
+		return cmp.Compare(n.key, hash)
+	})
+	next %= len(r.nodes)
+	prev := (next + len(r.nodes) - 1) % len(r.nodes)
+
+	// Distances wrap around the 32-bit space, as they do in HAProxy
+	if hash-r.nodes[prev].key <= r.nodes[next].key-hash {
+		return r.nodes[prev].server
+	}
+
+	return r.nodes[next].server
+}
+
+// Size returns the number of nodes in the tree.
+func (r *HAProxyRing) Size() int {
+	return len(r.nodes)
+}
+
+// haproxyFullHash is HAProxy's full_hash: one of Bob Jenkins' full avalanche
+// integer hashes, spread over the 32-bit space by a large prime.
+func haproxyFullHash(a uint32) uint32 {
+	a = (a + 0x7ed55d16) + (a << 12)
+	a = (a ^ 0xc761c23c) ^ (a >> 19)
+	a = (a + 0x165667b1) + (a << 5)
+	a = (a + 0xd3a2646c) ^ (a << 9)
+	a = (a + 0xfd7046c5) + (a << 3)
+	a = (a ^ 0xb55a4f09) ^ (a >> 16)
+	return a * 3221225473
+}
+
+// sdbm computes the sdbm hash of data, HAProxy's default hash function.
+func sdbm(data []byte) uint32 {
+	var h uint32
+	for _, c := range data {
+		h = uint32(c) + (h << 6) + (h << 16) - h
+	}
+
+	return h
+}
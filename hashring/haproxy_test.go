@@ -0,0 +1,95 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ Router = (*HAProxyRing)(nil)
+
+func TestSDBM(t *testing.T) {
+	require.Equal(t, uint32(0), sdbm(nil))
+	require.Equal(t, uint32(97), sdbm([]byte("a")))
+	require.Equal(t, uint32(98+97<<6+97<<16-97), sdbm([]byte("ab")))
+}
+
+func TestNewHAProxyRing(t *testing.T) {
+	ring, err := NewHAProxyRing([]ServerInfo{{Name: "web1"}, {Name: "web2", Weight: 3}}, HAProxyConfig{})
+	require.NoError(t, err)
+	require.Equal(t, 4*16, ring.Size())
+
+	// Nodes are keyed by server id (from 1) and node index
+	owners := make(map[uint32]string)
+	for _, n := range ring.nodes {
+		owners[n.key] = n.server
+	}
+	require.Equal(t, "web1", owners[haproxyFullHash(1*4096)])
+	require.Equal(t, "web2", owners[haproxyFullHash(2*4096+47)])
+
+	_, err = NewHAProxyRing([]ServerInfo{{Name: "web1", Weight: 257}}, HAProxyConfig{})
+	require.ErrorContains(t, err, "up to 256")
+
+	_, err = NewHAProxyRing([]ServerInfo{{Name: "web1"}, {Name: "web1"}}, HAProxyConfig{})
+	require.ErrorContains(t, err, "given twice")
+
+	_, err = NewHAProxyRing(nil, HAProxyConfig{})
+	require.Error(t, err)
+}
+
+func TestHAProxyRingGetServer(t *testing.T) {
+	servers := []ServerInfo{{Name: "web1"}, {Name: "web2"}, {Name: "web3"}}
+	ring, err := NewHAProxyRing(servers, HAProxyConfig{})
+	require.NoError(t, err)
+
+	// Each hash goes to a server with a node closest to it in either
+	// direction
+	closest := func(hash uint32) map[string]bool {
+		best := ^uint32(0)
+		owners := make(map[string]bool)
+		for _, n := range ring.nodes {
+			d := min(hash-n.key, n.key-hash)
+			if d < best {
+				best, owners = d, make(map[string]bool)
+			}
+			if d == best {
+				owners[n.server] = true
+			}
+		}
+		return owners
+	}
+
+	for i := range 1000 {
+		hash := uint32(i) * 4294967 // spread over the hash space
+		require.True(t, closest(hash)[ring.GetServerForHash(hash)], "hash %d", hash)
+	}
+
+	// The node before wins a tie
+	a, b := ring.nodes[0], ring.nodes[1]
+	if (b.key-a.key)%2 == 0 && a.server != b.server {
+		require.Equal(t, a.server, ring.GetServerForHash(a.key+(b.key-a.key)/2))
+	}
+
+	server, err := ring.GetServer("/index.html")
+	require.NoError(t, err)
+	require.Equal(t, ring.GetServerForHash(sdbm([]byte("/index.html"))), server)
+
+	// Avalanche spreads sequential keys that sdbm would keep together
+	plain, err := NewHAProxyRing(servers, HAProxyConfig{})
+	require.NoError(t, err)
+	aval, err := NewHAProxyRing(servers, HAProxyConfig{Avalanche: true})
+	require.NoError(t, err)
+
+	plainSeen, avalSeen := make(map[string]bool), make(map[string]bool)
+	for i := range 10 {
+		key := fmt.Sprintf("%d", i)
+		s, _ := plain.GetServer(key)
+		plainSeen[s] = true
+		s, _ = aval.GetServer(key)
+		avalSeen[s] = true
+		require.Equal(t, aval.GetServerForHash(haproxyFullHash(sdbm([]byte(key)))), s)
+	}
+	require.Len(t, plainSeen, 1)
+	require.Greater(t, len(avalSeen), 1)
+}
@@ -0,0 +1,225 @@
+package hashring
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math/bits"
+)
+
+// Hasher maps bytes to a position in the ring's 32-bit hash space. It's used
+// for both keys and virtual nodes, so it must be deterministic and safe to
+// call concurrently.
+type Hasher interface {
+	// Name identifies the algorithm. It's part of the ring's checksum and
+	// snapshots, so rings using different hashers never appear identical.
+	Name() string
+
+	// Hash returns the position of data on the ring.
+	Hash(data []byte) uint32
+}
+
+// Built-in hashers. 64-bit hashes are folded into the ring's hash space by
+// xoring their halves.
+var (
+	// CRC32 is CRC-32 with the IEEE polynomial. It's the default.
+	CRC32 Hasher = NewHasher("crc32-ieee", crc32.ChecksumIEEE)
+
+	// CRC32C is CRC-32 with the Castagnoli polynomial, which is hardware
+	// accelerated on amd64 (SSE4.2) and arm64.
+	CRC32C Hasher = NewHasher("crc32c", func(data []byte) uint32 {
+		return crc32.Checksum(data, castagnoli)
+	})
+
+	// FNV1a is the 32-bit FNV-1a hash.
+	FNV1a Hasher = NewHasher("fnv1a-32", fnv1a32)
+
+	// XXHash64 is the 64-bit xxHash (XXH64) with a zero seed.
+	XXHash64 Hasher = NewHasher("xxhash64", func(data []byte) uint32 {
+		return fold(xxhash64(data, 0))
+	})
+
+	// Murmur3 is the 32-bit MurmurHash3 (x86_32) with a zero seed.
+	Murmur3 Hasher = NewHasher("murmur3-32", murmur3)
+)
+
+// hashers are the built-in hashers by name, used to restore snapshots (see
+// also HasherByName).
+var hashers = map[string]Hasher{
+	CRC32.Name():    CRC32,
+	CRC32C.Name():   CRC32C,
+	FNV1a.Name():    FNV1a,
+	XXHash64.Name(): XXHash64,
+	Murmur3.Name():  Murmur3,
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// NewHasher returns a Hasher with the given name that hashes with fn.
+//
+// Example:
+//
+//	hasher := hashring.NewHasher("adler32", adler32.Checksum)
+//	ring := hashring.New(150, hashring.WithHasher(hasher))
+func NewHasher(name string, fn func([]byte) uint32) Hasher {
+	return funcHasher{name: name, fn: fn}
+}
+
+type funcHasher struct {
+	name string
+	fn   func([]byte) uint32
+}
+
+func (f funcHasher) Name() string            { return f.name }
+func (f funcHasher) Hash(data []byte) uint32 { return f.fn(data) }
+
+// WithHasher sets the hash function used to place keys and virtual nodes. The
+// default is CRC32. Changing the hasher moves almost every key, so every client
+// of a ring must use the same one.
+//
+// Example:
+//
+//	ring := hashring.New(150, hashring.WithHasher(hashring.XXHash64))
+func WithHasher(hasher Hasher) Option {
+	return func(h *HashRing) {
+		if hasher != nil {
+			h.hasher = hasher
+		}
+	}
+}
+
+// fnv1a32 computes the 32-bit FNV-1a hash of data.
+func fnv1a32(data []byte) uint32 {
+	const (
+		offset = 2166136261
+		prime  = 16777619
+	)
+
+	h := uint32(offset)
+	for _, b := range data {
+		h ^= uint32(b)
+		h *= prime
+	}
+
+	return h
+}
+
+// fold xors the halves of a 64-bit hash into the ring's 32-bit hash space.
+func fold(h uint64) uint32 {
+	return uint32(h ^ h>>32)
+}
+
+// xxhash64 computes the 64-bit xxHash of data.
+func xxhash64(data []byte, seed uint64) uint64 {
+	const (
+		prime1 uint64 = 11400714785074694791
+		prime2 uint64 = 14029467366897019727
+		prime3 uint64 = 1609587929392839161
+		prime4 uint64 = 9650029242287828579
+		prime5 uint64 = 2870177450012600261
+	)
+
+	round := func(acc, input uint64) uint64 {
+		acc += input * prime2
+		acc = bits.RotateLeft64(acc, 31)
+		return acc * prime1
+	}
+
+	merge := func(acc, val uint64) uint64 {
+		acc ^= round(0, val)
+		return acc*prime1 + prime4
+	}
+
+	n := len(data)
+	var h uint64
+	if n >= 32 {
+		v1 := seed + prime1 + prime2
+		v2 := seed + prime2
+		v3 := seed
+		v4 := seed - prime1
+		for len(data) >= 32 {
+			v1 = round(v1, binary.LittleEndian.Uint64(data[0:]))
+			v2 = round(v2, binary.LittleEndian.Uint64(data[8:]))
+			v3 = round(v3, binary.LittleEndian.Uint64(data[16:]))
+			v4 = round(v4, binary.LittleEndian.Uint64(data[24:]))
+			data = data[32:]
+		}
+
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = merge(h, v1)
+		h = merge(h, v2)
+		h = merge(h, v3)
+		h = merge(h, v4)
+	} else {
+		h = seed + prime5
+	}
+
+	h += uint64(n)
+
+	for ; len(data) >= 8; data = data[8:] {
+		h ^= round(0, binary.LittleEndian.Uint64(data))
+		h = bits.RotateLeft64(h, 27)*prime1 + prime4
+	}
+
+	if len(data) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(data)) * prime1
+		h = bits.RotateLeft64(h, 23)*prime2 + prime3
+		data = data[4:]
+	}
+
+	for _, b := range data {
+		h ^= uint64(b) * prime5
+		h = bits.RotateLeft64(h, 11) * prime1
+	}
+
+	h ^= h >> 33
+	h *= prime2
+	h ^= h >> 29
+	h *= prime3
+	h ^= h >> 32
+	return h
+}
+
+// murmur3 computes the 32-bit MurmurHash3 (x86_32) of data with a zero seed.
+func murmur3(data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	n := len(data)
+	var h uint32
+	for ; len(data) >= 4; data = data[4:] {
+		k := binary.LittleEndian.Uint32(data)
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	switch len(data) {
+	case 3:
+		k ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(n)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
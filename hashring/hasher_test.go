@@ -0,0 +1,84 @@
+package hashring
+
+import (
+	"fmt"
+	"hash/adler32"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinHashers(t *testing.T) {
+	tests := []struct {
+		hasher Hasher
+		input  string
+		want   uint32
+	}{
+		{CRC32, "123456789", 0xcbf43926},
+		{CRC32C, "123456789", 0xe3069283},
+		{FNV1a, "", 0x811c9dc5},
+		{FNV1a, "a", 0xe40c292c},
+		{Murmur3, "", 0},
+		{Murmur3, "hello", 0x248bfa47},
+		{Murmur3, "The quick brown fox jumps over the lazy dog", 0x2e4ff723},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, tt.hasher.Hash([]byte(tt.input)), "%s(%q)", tt.hasher.Name(), tt.input)
+	}
+
+	// XXH64 reference values, including an input longer than a 32 byte stripe
+	xxh := map[string]uint64{
+		"":            0xef46db3751d8e999,
+		"a":           0xd24ec4f1a98c6e5b,
+		"abc":         0x44bc2cf5ad770999,
+		"hello world": 0x45ab6734b21e6968,
+		"0123456789abcdef0123456789abcdef0123456789abc": 0xfc9bc401c0e4cff3,
+	}
+	for input, want := range xxh {
+		require.Equal(t, want, xxhash64([]byte(input), 0), "xxhash64(%q)", input)
+		require.Equal(t, uint32(want^want>>32), XXHash64.Hash([]byte(input)))
+	}
+}
+
+func TestWithHasher(t *testing.T) {
+	for name, hasher := range hashers {
+		t.Run(name, func(t *testing.T) {
+			ring := New(150, WithHasher(hasher))
+			for i := range 5 {
+				require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+			}
+
+			server, err := ring.GetServer("key")
+			require.NoError(t, err)
+			require.Equal(t, ring.owner(ring.search(uint64(hasher.Hash([]byte("key"))))), server)
+
+			// Snapshots of rings using built-in hashers restore without options
+			restored, err := Restore(ring.Snapshot())
+			require.NoError(t, err)
+			require.Equal(t, ring.Checksum(), restored.Checksum())
+		})
+	}
+
+	// The hasher is part of the checksum
+	a, b := New(150), New(150, WithHasher(XXHash64))
+	require.NoError(t, a.AddServer("server1"))
+	require.NoError(t, b.AddServer("server1"))
+	require.NotEqual(t, a.Checksum(), b.Checksum())
+}
+
+func TestCustomHasher(t *testing.T) {
+	hasher := NewHasher("adler32", adler32.Checksum)
+	ring := New(150, WithHasher(hasher))
+	require.NoError(t, ring.AddServer("server1"))
+
+	snap := ring.Snapshot()
+	require.Equal(t, "adler32", snap.Hasher)
+
+	_, err := Restore(snap)
+	require.ErrorContains(t, err, "adler32")
+
+	restored, err := Restore(snap, WithHasher(hasher))
+	require.NoError(t, err)
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+}
@@ -1,12 +1,15 @@
 package hashring
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"hash/crc32"
+	"iter"
+	"math"
 	"slices"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,13 +19,60 @@ import (
 //
 // The ring is thread-safe and supports concurrent operations.
 type HashRing struct {
-	mu         sync.RWMutex
-	ring       map[uint32]string // hash position -> server name
-	serverKeys []uint32          // sorted hash positions
-	servers    map[string]bool   // set of server names
-	vnodes     int               // number of virtual nodes per server
+	mu           sync.RWMutex
+	entries      []vnode                    // virtual nodes sorted by position
+	names        []string                   // server table, indexed by vnode.server
+	ids          map[string]int32           // server name -> index in names
+	servers      map[string]ServerInfo      // server name -> metadata
+	vnodes       int                        // number of virtual nodes per server
+	placement    Placement                  // how virtual nodes are positioned (see WithPlacement)
+	hasher       Hasher                     // hashes keys and virtual nodes (see WithHasher)
+	collisions   int                        // virtual nodes displaced by a collision (see Collisions)
+	stale        bool                       // entries don't match servers until settle places them
+	deferring    bool                       // a write batch is applying a membership change (see settle)
+	version      uint64                     // bumped on every topology change
+	pins         map[string]string          // key or prefix -> pinned server
+	canary       Canary                     // keys routed to a canary, if Server is set (see SetCanary)
+	dedicated    map[string]map[string]bool // tenant -> its dedicated servers (see ServerInfo.Tenants)
+	restricted   map[string]ServerState     // server -> its state, for servers that aren't active (see SetState)
+	downFrom     map[string]ServerState     // server -> its state before it went down (see MarkUp)
+	tenantOf     func(string) string        // derives tenants from keys (see WithTenantExtractor)
+	tagOpen      string                     // hash tag opening delimiter (see WithHashTags)
+	tagClose     string                     // hash tag closing delimiter (see WithHashTags)
+	extractor    func(string) string        // derives routing keys (see WithKeyExtractor)
+	weightPolicy WeightPolicy               // derives weights from capacity (see WithWeightPolicy)
+	normalize    func(string) string        // normalizes names for comparison (see WithNameNormalizer)
+	normalized   map[string]string          // normalized name -> server name
+	maxServers   int                        // max servers, 0 for no limit (see WithMaxServers)
+	maxVNodes    int                        // max total virtual nodes, 0 for no limit (see WithMaxVNodes)
+
+	actor        string           // recorded as the author of topology changes
+	history      []TopologyChange // oldest first, bounded by historyLimit
+	historyLimit int              // max history entries, 0 disables history
+	now          func() time.Time // clock used for history timestamps
+
+	breakers  breakers              // per-server circuit breakers (see ReportFailure)
+	cache     *lookupCache          // optional key -> server cache (see WithLookupCache)
+	writes    *writeBatcher         // optional queue of topology changes (see WithWriteBatching)
+	lookups   atomic.Uint64         // keys resolved (see Lookups)
+	profile   *lookupProfiler       // optional lookup samples (see WithLookupProfiling)
+	metrics   MetricsSink           // receives emitted metrics (see WithMetricsSink)
+	moves     moveHub               // planned move subscribers (see OnMove)
+	states    notifier[StateChange] // state change subscribers (see OnStateChange)
+	hooks     transitionHooks       // vetoes state changes (see AddTransitionHook)
+	scheduler scheduler             // changes queued for later (see ScheduleAdd)
 }
 
+// vnode is a virtual node on the ring.
+type vnode struct {
+	hash   uint64 // position on the ring
+	server int32  // owner's index in the server table
+	index  int32  // index among the owner's virtual nodes
+}
+
+// Option configures optional behaviour of a HashRing.
+type Option func(*HashRing)
+
 // New creates a new hash ring with the specified number of virtual nodes per server.
 //
 // The virtualNodes parameter determines how many positions each physical server
@@ -38,18 +88,55 @@ type HashRing struct {
 //	ring.AddServer("server1")
 //	ring.AddServer("server2")
 //	server, _ := ring.GetServer("mykey")
-func New(virtualNodes int) *HashRing {
-	return &HashRing{
-		ring:       make(map[uint32]string),
-		serverKeys: make([]uint32, 0),
-		servers:    make(map[string]bool),
-		vnodes:     virtualNodes,
+//
+// Additional behaviour can be enabled by passing options:
+//
+//	ring := hashring.New(150, hashring.WithActor("deployer"))
+//
+// New panics if virtualNodes isn't positive, since such a ring could never
+// route a key.
+func New(virtualNodes int, opts ...Option) *HashRing {
+	if virtualNodes <= 0 {
+		panic(fmt.Sprintf("hashring: virtual node count must be positive, got %d", virtualNodes))
+	}
+
+	h := &HashRing{
+		ids:          make(map[string]int32),
+		servers:      make(map[string]ServerInfo),
+		pins:         make(map[string]string),
+		dedicated:    make(map[string]map[string]bool),
+		restricted:   make(map[string]ServerState),
+		downFrom:     make(map[string]ServerState),
+		vnodes:       virtualNodes,
+		placement:    PlacementHashed,
+		hasher:       CRC32,
+		weightPolicy: ScoreWeight,
+		normalize:    NormalizeName,
+		normalized:   make(map[string]string),
+		historyLimit: DefaultHistoryLimit,
+		now:          time.Now,
+		breakers: breakers{
+			threshold: DefaultFailureThreshold,
+			cooldown:  DefaultBreakerCooldown,
+		},
+		metrics: NopMetricsSink{},
+	}
+
+	for _, opt := range opts {
+		opt(h)
 	}
+
+	return h
 }
 
 // hashKey generates a hash value for the given key
-func (h *HashRing) hashKey(key string) uint32 {
-	return crc32.ChecksumIEEE([]byte(key))
+func (h *HashRing) hashKey(key string) uint64 {
+	return h.hashBytes([]byte(key))
+}
+
+// hashBytes generates a hash value for the given bytes
+func (h *HashRing) hashBytes(b []byte) uint64 {
+	return uint64(h.hasher.Hash(b))
 }
 
 // AddServer adds a server to the hash ring.
@@ -66,24 +153,63 @@ func (h *HashRing) hashKey(key string) uint32 {
 //		log.Printf("Failed to add server: %v", err)
 //	}
 func (h *HashRing) AddServer(server string) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	return h.writeMembership(func() error {
+		if err := h.addServer(ServerInfo{Name: server}); err != nil {
+			return err
+		}
 
-	if h.servers[server] {
+		h.recordChange(ChangeAdd, server)
+		return nil
+	})
+}
+
+// addServer places the server's virtual nodes on the ring. The caller must hold h.mu.
+func (h *HashRing) addServer(info ServerInfo) error {
+	server := info.Name
+	if h.hasServer(server) {
 		return fmt.Errorf("server %s already exists", server)
 	}
 
-	h.servers[server] = true
+	if len(info.Tokens) > 0 {
+		if err := h.validateTokens(info); err != nil {
+			return err
+		}
+	}
+
+	info, err := h.resolveWeight(info)
+	if err != nil {
+		return err
+	}
+
+	if info, err = h.resolveState(info, info.State); err != nil {
+		return err
+	}
 
-	// Add virtual nodes for this server
-	for i := 0; i < h.vnodes; i++ {
-		hash := h.hashKey(fmt.Sprintf("%s#%d", server, i))
-		h.ring[hash] = server
-		h.serverKeys = append(h.serverKeys, hash)
+	if err := h.validateServer(info); err != nil {
+		return err
+	}
+
+	h.servers[server] = info.clone()
+	h.normalized[h.normalize(server)] = server
+	h.ids[server] = int32(len(h.names))
+	h.names = append(h.names, server)
+	h.indexServers()
+
+	if h.deferring {
+		h.stale = true
+		return nil
+	}
+
+	if h.evenlySpaced() {
+		h.placeEvenly()
+		return nil
+	}
+
+	if err := h.placeServer(info); err != nil {
+		// back the server out, restoring the layout from before
+		return errors.Join(err, h.removeServer(server))
 	}
 
-	// Sort the keys
-	slices.Sort(h.serverKeys)
 	return nil
 }
 
@@ -93,7 +219,8 @@ func (h *HashRing) AddServer(server string) error {
 // mapped to this server will be redistributed to the remaining servers.
 // This operation is thread-safe.
 //
-// Returns an error if the server does not exist in the ring.
+// Returns an error if the server does not exist in the ring or a transition
+// hook vetoes its removal (see AddTransitionHook).
 //
 // Example:
 //
@@ -102,21 +229,95 @@ func (h *HashRing) AddServer(server string) error {
 //		log.Printf("Failed to remove server: %v", err)
 //	}
 func (h *HashRing) RemoveServer(server string) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	var from ServerState
+	if h.hooked() {
+		var err error
+		if from, err = h.stateFor(server); err != nil {
+			return err
+		}
+
+		if err := h.runHooks(server, from, StateRemoved); err != nil {
+			return err
+		}
+	}
+
+	return h.writeMembership(func() error {
+		if from != "" {
+			if err := h.checkUnchanged(server, from); err != nil {
+				return err
+			}
+		}
+
+		if err := h.removeServer(server); err != nil {
+			return err
+		}
 
-	if !h.servers[server] {
+		h.recordChange(ChangeRemove, server)
+		return nil
+	})
+}
+
+// removeServer deletes server's virtual nodes from the ring. The caller must hold h.mu.
+func (h *HashRing) removeServer(server string) error {
+	if !h.hasServer(server) {
 		return fmt.Errorf("server %s does not exist", server)
 	}
 
 	delete(h.servers, server)
+	delete(h.normalized, h.normalize(server))
+	h.indexServers()
+	h.unpinServer(server)
+	if h.canary.Server == server {
+		h.canary = Canary{}
+	}
+	delete(h.downFrom, server)
+	h.breakers.reset(server)
+
+	// settle places the remaining servers from scratch, so their virtual
+	// nodes don't need updating now
+	if h.deferring {
+		h.stale = true
+	}
 
-	for i := range h.vnodes {
-		hash := h.hashKey(fmt.Sprintf("%s#%d", server, i))
-		delete(h.ring, hash)
+	id := h.ids[server]
+	if !h.stale {
+		h.entries = slices.DeleteFunc(h.entries, func(v vnode) bool {
+			return v.server == id
+		})
+	}
+
+	// Move the last server in the table into the freed slot
+	last := int32(len(h.names) - 1)
+	if id != last {
+		moved := h.names[last]
+		h.names[id] = moved
+		h.ids[moved] = id
+		if !h.stale {
+			for i := range h.entries {
+				if h.entries[i].server == last {
+					h.entries[i].server = id
+				}
+			}
+		}
+	}
+
+	h.names = h.names[:last]
+	delete(h.ids, server)
+
+	if h.stale {
+		return nil
+	}
+
+	if h.evenlySpaced() {
+		h.placeEvenly()
+		return nil
+	}
 
-		idx := slices.Index(h.serverKeys, hash)
-		h.serverKeys = append(h.serverKeys[:idx], h.serverKeys[idx+1:]...)
+	// vnodes displaced by the server's may now be able to take their
+	// original positions. If one can't be placed, they all stay where they
+	// are, which still routes every key.
+	if h.collisions > 0 {
+		_ = h.rebuild()
 	}
 
 	return nil
@@ -142,23 +343,133 @@ func (h *HashRing) GetServer(key string) (string, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	if len(h.ring) == 0 {
+	return h.getServer(key, AccessRead)
+}
+
+// getServer finds the server responsible for key for the given access. The
+// caller must hold h.mu.
+func (h *HashRing) getServer(key string, access Access) (string, error) {
+	if len(h.entries) == 0 {
 		return "", errors.New("hash ring is empty")
 	}
 
-	hash := h.hashKey(key)
+	if h.profile.sampled(h.lookups.Add(1)) {
+		defer h.profile.finish(h.profile.start())
+	}
+
+	if h.observed() {
+		defer h.observeLookup(time.Now())
+	}
+
+	owner := h.resolve(key, access)
+	if owner == "" {
+		return "", errNoEligibleServer(key)
+	}
+
+	if h.allow(owner) {
+		return owner, nil
+	}
+
+	// The owner's breaker is open, so use the next eligible server that isn't
+	// tripped. If every server is tripped, fail open and use the owner.
+	hash := h.hashKey(h.routingKey(key))
+	accept := h.eligible(key, access)
+	server := owner
+	rejected := map[string]bool{owner: true}
+	h.walk(hash, func(candidate string) bool {
+		if rejected[candidate] {
+			return len(rejected) < len(h.servers)
+		}
+
+		if (accept == nil || accept(candidate)) && h.allow(candidate) {
+			server = candidate
+			return false
+		}
+
+		rejected[candidate] = true
+		return len(rejected) < len(h.servers)
+	})
+
+	return server, nil
+}
 
+// resolve returns the server key routes to for the given access, ignoring
+// circuit breakers (see locate), consulting the lookup cache for reads if
+// there is one. The caller must hold h.mu and ensure the ring isn't empty.
+func (h *HashRing) resolve(key string, access Access) string {
+	if h.cache == nil || access != AccessRead {
+		return h.locate(key, access)
+	}
+
+	if server, ok := h.cache.get(key, h.version); ok {
+		return server
+	}
+
+	server := h.locate(key, access)
+	h.cache.put(key, server, h.version)
+	return server
+}
+
+// locate returns the server key routes to for the given access, ignoring
+// circuit breakers: its pinned server or the canary, if any, or else the owner
+// of its position. Only servers eligible for the key are considered (see
+// ServerInfo.Tenants and SetState), and an empty string is returned if there
+// are none. The caller must hold h.mu and ensure the ring isn't empty.
+func (h *HashRing) locate(key string, access Access) string {
+	accept := h.eligible(key, access)
+	if server, ok := h.override(key); ok && (accept == nil || accept(server)) {
+		return server
+	}
+
+	hash := h.hashKey(h.routingKey(key))
+	if accept == nil {
+		return h.owner(h.search(hash))
+	}
+
+	if i := h.firstEligible(hash, accept); i >= 0 {
+		return h.owner(i)
+	}
+
+	return ""
+}
+
+// search returns the index in entries of the first virtual node clockwise
+// from hash. The caller must hold h.mu and ensure the ring isn't empty.
+func (h *HashRing) search(hash uint64) int {
 	// Binary search to find the first server clockwise from the key's hash
-	idx := sort.Search(len(h.serverKeys), func(i int) bool {
-		return h.serverKeys[i] >= hash
+	idx := sort.Search(len(h.entries), func(i int) bool {
+		return h.entries[i].hash >= hash
 	})
 
 	// Wrap around if we've gone past the end
-	if idx == len(h.serverKeys) {
+	if idx == len(h.entries) {
 		idx = 0
 	}
 
-	return h.ring[h.serverKeys[idx]], nil
+	return idx
+}
+
+// walk calls fn with the owner of each virtual node clockwise from hash,
+// visiting every virtual node at most once, until fn returns false. The caller
+// must hold h.mu.
+func (h *HashRing) walk(hash uint64, fn func(server string) bool) {
+	if len(h.entries) == 0 {
+		return
+	}
+
+	start := h.search(hash)
+	for i := range len(h.entries) {
+		idx := (start + i) % len(h.entries)
+		if !fn(h.owner(idx)) {
+			return
+		}
+	}
+}
+
+// owner returns the server owning the virtual node at index i of entries. The
+// caller must hold h.mu.
+func (h *HashRing) owner(i int) string {
+	return h.names[h.entries[i].server]
 }
 
 // GetServers returns a sorted list of all servers currently in the ring.
@@ -174,6 +485,17 @@ func (h *HashRing) GetServers() []string {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	return h.serverList()
+}
+
+// hasServer reports whether server is in the ring. The caller must hold h.mu.
+func (h *HashRing) hasServer(server string) bool {
+	_, ok := h.servers[server]
+	return ok
+}
+
+// serverList returns the sorted server names. The caller must hold h.mu.
+func (h *HashRing) serverList() []string {
 	servers := make([]string, 0, len(h.servers))
 	for server := range h.servers {
 		servers = append(servers, server)
@@ -191,7 +513,8 @@ func (h *HashRing) GetServers() []string {
 //   - Capacity planning
 //   - Debugging hot spots
 //
-// This operation is thread-safe but may be slow for large key sets.
+// This operation is thread-safe but may be slow for large key sets; use
+// WithProgress to follow along.
 //
 // Example:
 //
@@ -200,7 +523,21 @@ func (h *HashRing) GetServers() []string {
 //	for server, count := range dist {
 //		fmt.Printf("%s: %d keys\n", server, count)
 //	}
-func (h *HashRing) GetDistribution(keys []string) map[string]int {
+func (h *HashRing) GetDistribution(keys []string, opts ...AnalysisOption) map[string]int {
+	distribution, _ := h.GetDistributionContext(context.Background(), keys, opts...)
+	return distribution
+}
+
+// GetDistributionContext is like GetDistribution, but stops early once ctx is
+// done, returning nil and ctx's error, so analyses of huge key sets can be
+// bounded by a deadline.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+//	defer cancel()
+//	dist, err := ring.GetDistributionContext(ctx, keys)
+func (h *HashRing) GetDistributionContext(ctx context.Context, keys []string, opts ...AnalysisOption) (map[string]int, error) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
@@ -209,16 +546,76 @@ func (h *HashRing) GetDistribution(keys []string) map[string]int {
 		distribution[server] = 0
 	}
 
-	for _, key := range keys {
-		server, err := h.GetServer(key)
+	report := newAnalysisConfig(opts).reporter(len(keys))
+	for i, key := range keys {
+		// Checking every key would dominate the cost of small lookups
+		if i%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		server, err := h.getServer(key, AccessRead)
 		if err == nil {
 			distribution[server]++
 		}
+		report(i + 1)
+	}
+
+	return distribution, nil
+}
+
+// DistributionSeq is like GetDistribution, but reads keys from an iterator, so
+// huge key sets can be streamed from a file or database without holding them
+// all in memory.
+//
+// Unlike GetDistribution, the ring isn't locked while keys are read, since
+// the iterator may be slow; each key is looked up against the ring as it is
+// when the key arrives. Servers in the ring when streaming starts are always
+// in the result, with a zero count if no keys map to them.
+//
+// Example:
+//
+//	f, _ := os.Open("keys.txt")
+//	defer f.Close()
+//
+//	scanner := bufio.NewScanner(f)
+//	dist := ring.DistributionSeq(func(yield func(string) bool) {
+//		for scanner.Scan() && yield(scanner.Text()) {
+//		}
+//	})
+func (h *HashRing) DistributionSeq(keys iter.Seq[string]) map[string]int {
+	distribution := make(map[string]int)
+	for server := range h.Servers() {
+		distribution[server] = 0
+	}
+
+	for key := range keys {
+		if server, err := h.GetServer(key); err == nil {
+			distribution[server]++
+		}
 	}
 
 	return distribution
 }
 
+// DistributionChan is like DistributionSeq, but reads keys from a channel
+// until it's closed, e.g. from a producer goroutine paging through a
+// database.
+//
+// Example:
+//
+//	keys := make(chan string, 1024)
+//	go exportKeys(db, keys) // closes keys when done
+//	dist := ring.DistributionChan(keys)
+func (h *HashRing) DistributionChan(keys <-chan string) map[string]int {
+	return h.DistributionSeq(func(yield func(string) bool) {
+		for key := range keys {
+			if !yield(key) {
+				return
+			}
+		}
+	})
+}
+
 // Size returns the number of physical servers in the ring.
 //
 // This counts actual servers, not virtual nodes. For the total number of
@@ -242,25 +639,51 @@ func (h *HashRing) Size() int {
 //   - Key distribution across servers (uniformity)
 //   - Average lookup latency per key
 //   - Distribution quality using Coefficient of Variation (CV)
+//   - Skew, via the Gini coefficient and the most loaded server's share
 //
 // A lower CV percentage indicates better distribution:
 //   - CV < 5%: Excellent distribution
 //   - CV < 10%: Good distribution
 //   - CV > 10%: Consider adjusting virtual nodes
 //
-// This operation is thread-safe but may be slow for large key sets.
-// It's recommended to run this during testing or monitoring, not in hot paths.
+// This operation is thread-safe but may be slow for large key sets; use
+// WithProgress to follow along. It's recommended to run this during testing or
+// monitoring, not in hot paths. With no keys there's nothing to measure, and
+// the metrics are zero.
 //
 // Example:
 //
 //	testKeys := generateTestKeys(10000)
 //	metrics := ring.AnalyzePerformance(testKeys)
 //	metrics.Print() // Display formatted analysis
-func (h *HashRing) AnalyzePerformance(keys []string) PerformanceMetrics {
+func (h *HashRing) AnalyzePerformance(keys []string, opts ...AnalysisOption) PerformanceMetrics {
+	metrics, _ := h.AnalyzePerformanceContext(context.Background(), keys, opts...)
+	return metrics
+}
+
+// AnalyzePerformanceContext is like AnalyzePerformance, but stops early once
+// ctx is done, returning empty metrics and ctx's error.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+//	defer cancel()
+//	metrics, err := ring.AnalyzePerformanceContext(ctx, keys)
+//	if err != nil {
+//		return fmt.Errorf("analyzing ring: %w", err)
+//	}
+func (h *HashRing) AnalyzePerformanceContext(ctx context.Context, keys []string, opts ...AnalysisOption) (PerformanceMetrics, error) {
+	if len(keys) == 0 {
+		return PerformanceMetrics{}, nil
+	}
+
 	start := time.Now()
 
 	// Measure average latency
-	distribution := h.GetDistribution(keys)
+	distribution, err := h.GetDistributionContext(ctx, keys, opts...)
+	if err != nil {
+		return PerformanceMetrics{}, err
+	}
 	avgLatency := time.Since(start) / time.Duration(len(keys))
 
 	// Calculate distribution quality (Coefficient of Variation)
@@ -273,11 +696,7 @@ func (h *HashRing) AnalyzePerformance(keys []string) PerformanceMetrics {
 
 	stdDev := 0.0
 	if len(distribution) > 0 {
-		variance /= float64(len(distribution))
-		stdDev = variance
-		for range 10 { // Simple sqrt approximation
-			stdDev = (stdDev + variance/stdDev) / 2
-		}
+		stdDev = math.Sqrt(variance / float64(len(distribution)))
 	}
 
 	cv := 0.0
@@ -285,11 +704,19 @@ func (h *HashRing) AnalyzePerformance(keys []string) PerformanceMetrics {
 		cv = (stdDev / mean) * 100
 	}
 
+	gini, maxMin, maxShare := balance(distribution)
+
 	return PerformanceMetrics{
 		TotalKeys:      len(keys),
 		Servers:        len(distribution),
+		VirtualNodes:   h.vnodes,
 		AvgLatency:     avgLatency,
 		DistributionCV: cv,
+		Gini:           gini,
+		MaxMinRatio:    maxMin,
+		MaxShare:       maxShare,
 		Distribution:   distribution,
-	}
+		Expected:       h.OwnershipShare(),
+		Collisions:     h.Collisions(),
+	}, nil
 }
@@ -21,6 +21,23 @@ func BenchmarkGetServer(b *testing.B) {
 	}
 }
 
+func BenchmarkFrozenGetServer(b *testing.B) {
+	ring := New(150)
+	require.NoError(b, ring.AddServer("server1"))
+	require.NoError(b, ring.AddServer("server2"))
+	require.NoError(b, ring.AddServer("server3"))
+	require.NoError(b, ring.AddServer("server4"))
+	require.NoError(b, ring.AddServer("server5"))
+	frozen := ring.Freeze()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			key := fmt.Sprintf("key-%d", i%10000)
+			_, _ = frozen.GetServer(key)
+		}
+	})
+}
+
 func BenchmarkAddServer(b *testing.B) {
 	for b.Loop() {
 		b.StopTimer()
@@ -48,3 +65,14 @@ func BenchmarkDistribution(b *testing.B) {
 		ring.GetDistribution(keys)
 	}
 }
+
+func BenchmarkHashers(b *testing.B) {
+	key := []byte("user:12345:session")
+	for name, hasher := range hashers {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				hasher.Hash(key)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package hashring
 import (
 	"fmt"
 	"math"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -44,6 +45,17 @@ func TestRemoveServer(t *testing.T) {
 	// Removing non-existent server should fail
 	err = ring.RemoveServer("server2")
 	require.Error(t, err, "Expected error when removing non-existent server")
+
+	// The remaining servers route exactly as a ring built without server2
+	expected := New(150)
+	require.NoError(t, expected.AddServer("server1"))
+	require.NoError(t, expected.AddServer("server3"))
+	require.Equal(t, expected.layout(), ring.layout())
+
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.NoError(t, expected.RemoveServer("server1"))
+	require.Equal(t, expected.layout(), ring.layout())
+	require.Len(t, positions(ring), 150)
 }
 
 func TestGetServer(t *testing.T) {
@@ -134,6 +146,33 @@ func TestDistribution(t *testing.T) {
 	t.Logf("Distribution: %v", distribution)
 }
 
+func TestDistributionSeq(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("idle"))
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+	want := ring.GetDistribution(keys)
+
+	require.Equal(t, want, ring.DistributionSeq(slices.Values(keys)))
+
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for _, key := range keys {
+			ch <- key
+		}
+	}()
+	require.Equal(t, want, ring.DistributionChan(ch))
+
+	// Servers without keys are still reported
+	require.Equal(t, map[string]int{"server1": 0, "server2": 0, "idle": 0}, ring.DistributionSeq(slices.Values([]string(nil))))
+}
+
 func TestDistributionStandardDeviation(t *testing.T) {
 	ring := New(150)
 	require.NoError(t, ring.AddServer("server1"))
@@ -235,3 +274,22 @@ func TestConcurrency(t *testing.T) {
 		<-done
 	}
 }
+
+// positions returns the positions of the ring's virtual nodes, in order.
+func positions(h *HashRing) []uint64 {
+	keys := make([]uint64, len(h.entries))
+	for i, v := range h.entries {
+		keys[i] = v.hash
+	}
+
+	return keys
+}
+
+// ownerAt returns the server with a virtual node at pos, if any.
+func ownerAt(h *HashRing, pos uint64) string {
+	if i, ok := find(h.entries, pos); ok {
+		return h.owner(i)
+	}
+
+	return ""
+}
@@ -0,0 +1,239 @@
+package hashring
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// DefaultHistoryLimit is the number of topology changes a ring retains unless
+// configured otherwise with WithHistoryLimit.
+const DefaultHistoryLimit = 100
+
+// ChangeType describes the kind of topology change recorded in the history.
+type ChangeType string
+
+const (
+	// ChangeAdd records a server being added to the ring.
+	ChangeAdd ChangeType = "add"
+	// ChangeRemove records a server being removed from the ring.
+	ChangeRemove ChangeType = "remove"
+	// ChangeUpdate records a server's metadata being replaced.
+	ChangeUpdate ChangeType = "update"
+	// ChangeRollback records the ring being restored to an earlier version.
+	ChangeRollback ChangeType = "rollback"
+	// ChangeRebalance records virtual node counts being adjusted by Rebalance.
+	ChangeRebalance ChangeType = "rebalance"
+	// ChangeReseed records the ring's seed being changed by Reseed.
+	ChangeReseed ChangeType = "reseed"
+	// ChangeRename records a server being renamed by RenameServer.
+	ChangeRename ChangeType = "rename"
+	// ChangeReplace records a server being swapped for another by ReplaceServer.
+	ChangeReplace ChangeType = "replace"
+	// ChangeSync records membership being replaced by Apply or SetServers.
+	ChangeSync ChangeType = "sync"
+	// ChangeState records a server's lifecycle state being changed by SetState.
+	ChangeState ChangeType = "state"
+	// ChangePin records a key or prefix being pinned to a server by Pin, or
+	// unpinned from it by Unpin.
+	ChangePin ChangeType = "pin"
+	// ChangeCanary records a canary being set by SetCanary or cleared by
+	// ClearCanary.
+	ChangeCanary ChangeType = "canary"
+)
+
+// TopologyChange is a single entry in the ring's topology history.
+//
+// Each entry captures who made the change, when, what changed, and the full
+// membership that resulted from it, so the ring can be rolled back to any
+// version still in the history.
+type TopologyChange struct {
+	Version uint64       `json:"version"`          // ring version after the change
+	Time    time.Time    `json:"time"`             // when the change was applied
+	Actor   string       `json:"actor,omitempty"`  // who applied the change (see WithActor)
+	Type    ChangeType   `json:"type"`             // what kind of change this was
+	Server  string       `json:"server,omitempty"` // the server added or removed (empty for rollbacks and rebalances)
+	Target  uint64       `json:"target,omitempty"` // the version restored by a rollback
+	Servers []ServerInfo `json:"servers"`          // membership after the change, sorted by name
+}
+
+// WithHistoryLimit sets the maximum number of topology changes retained by the
+// ring. Older entries are discarded first. A limit of zero disables history,
+// which also disables Rollback.
+func WithHistoryLimit(limit int) Option {
+	return func(h *HashRing) {
+		h.historyLimit = max(limit, 0)
+	}
+}
+
+// WithActor sets the name recorded as the author of every topology change made
+// through this ring, such as a hostname, service account, or automation job.
+func WithActor(actor string) Option {
+	return func(h *HashRing) {
+		h.actor = actor
+	}
+}
+
+// History returns the recorded topology changes, oldest first.
+//
+// The history is bounded (see WithHistoryLimit), so only the most recent
+// changes are available. This operation is thread-safe and returns a copy.
+//
+// Example:
+//
+//	for _, change := range ring.History() {
+//		fmt.Printf("v%d %s %s %s by %s\n",
+//			change.Version, change.Time.Format(time.RFC3339), change.Type, change.Server, change.Actor)
+//	}
+func (h *HashRing) History() []TopologyChange {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.historyCopy()
+}
+
+// historyCopy returns a deep copy of the history. The caller must hold h.mu.
+func (h *HashRing) historyCopy() []TopologyChange {
+	history := make([]TopologyChange, len(h.history))
+	for i, change := range h.history {
+		change.Servers = cloneInfos(change.Servers)
+		history[i] = change
+	}
+
+	return history
+}
+
+// Rollback restores the ring membership recorded at the given version.
+//
+// Servers added since that version are removed and servers removed since then
+// are added back. The rollback itself is a topology change: it bumps the ring
+// version (versions never go backwards) and is recorded in the history.
+//
+// Returns an error if the version is no longer (or was never) in the history,
+// or its membership can't be restored, e.g. because it exceeds the ring's
+// limits (see WithMaxServers). The ring is unchanged on error.
+//
+// Example:
+//
+//	before := ring.Version()
+//	scaleUp(ring)
+//	if unhealthy() {
+//		if err := ring.Rollback(before); err != nil {
+//			log.Printf("rollback failed: %v", err)
+//		}
+//	}
+func (h *HashRing) Rollback(version uint64) error {
+	return h.write(func() error {
+		return h.rollback(version)
+	})
+}
+
+// rollback restores the membership recorded at version. The caller must hold
+// h.mu.
+func (h *HashRing) rollback(version uint64) error {
+	var target *TopologyChange
+	for i := range h.history {
+		if h.history[i].Version == version {
+			target = &h.history[i]
+			break
+		}
+	}
+
+	if target == nil {
+		return fmt.Errorf("version %d is not in the ring history", version)
+	}
+
+	if version == h.version {
+		return nil
+	}
+
+	// Copy the membership since recording the rollback may evict the target,
+	// and try it on a copy of the ring first so the ring is unchanged on error
+	servers := cloneInfos(target.Servers)
+	if err := h.clone().setMembership(cloneInfos(servers)); err != nil {
+		return err
+	}
+
+	if err := h.setMembership(servers); err != nil {
+		return err
+	}
+
+	h.recordChange(ChangeRollback, "")
+	h.history[len(h.history)-1].Target = version
+	return nil
+}
+
+// setMembership adds, removes, and updates servers so the ring's membership
+// matches infos, stopping at the first error. Servers whose tokens changed are
+// re-added. The caller must hold h.mu.
+func (h *HashRing) setMembership(infos []ServerInfo) error {
+	members := make(map[string]ServerInfo, len(infos))
+	for _, info := range infos {
+		members[info.Name] = info
+	}
+
+	for _, server := range h.serverList() {
+		info, ok := members[server]
+		if !ok || !slices.Equal(info.Tokens, h.servers[server].Tokens) {
+			if err := h.removeServer(server); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, server := range slices.Sorted(maps.Keys(members)) {
+		info := members[server]
+		if !h.hasServer(server) {
+			if err := h.addServer(info); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		info, err := h.resolveWeight(info)
+		if err != nil {
+			return err
+		}
+
+		if info, err = h.resolveState(info, info.State); err != nil {
+			return err
+		}
+
+		if err := h.checkVNodes(info, h.placedVNodes(h.servers[server])); err != nil {
+			return err
+		}
+
+		if err := h.updateServer(info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordChange bumps the ring version, emits metrics and planned moves for
+// the change, and appends it to the history. The caller must hold h.mu.
+func (h *HashRing) recordChange(typ ChangeType, server string) {
+	h.version++
+	h.observeChange(typ)
+	h.notifyMoves()
+
+	if h.historyLimit == 0 {
+		return
+	}
+
+	h.history = append(h.history, TopologyChange{
+		Version: h.version,
+		Time:    h.now(),
+		Actor:   h.actor,
+		Type:    typ,
+		Server:  server,
+		Servers: h.serverInfos(),
+	})
+
+	if over := len(h.history) - h.historyLimit; over > 0 {
+		h.history = append(h.history[:0], h.history[over:]...)
+	}
+}
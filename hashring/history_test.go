@@ -0,0 +1,122 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ring := New(150, WithActor("tester"))
+	ring.now = func() time.Time { return now }
+
+	require.Empty(t, ring.History())
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.RemoveServer("server1"))
+
+	history := ring.History()
+	require.Len(t, history, 3)
+
+	require.Equal(t, TopologyChange{
+		Version: 1,
+		Time:    now,
+		Actor:   "tester",
+		Type:    ChangeAdd,
+		Server:  "server1",
+		Servers: []ServerInfo{{Name: "server1"}},
+	}, history[0])
+
+	require.Equal(t, ChangeAdd, history[1].Type)
+	require.Equal(t, []ServerInfo{{Name: "server1"}, {Name: "server2"}}, history[1].Servers)
+
+	require.Equal(t, ChangeRemove, history[2].Type)
+	require.Equal(t, uint64(3), history[2].Version)
+	require.Equal(t, []ServerInfo{{Name: "server2"}}, history[2].Servers)
+
+	// Returned history is a copy
+	history[0].Servers[0].Name = "mutated"
+	require.Equal(t, "server1", ring.History()[0].Servers[0].Name)
+}
+
+func TestHistoryLimit(t *testing.T) {
+	ring := New(10, WithHistoryLimit(2))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	history := ring.History()
+	require.Len(t, history, 2)
+	require.Equal(t, uint64(2), history[0].Version)
+	require.Equal(t, uint64(3), history[1].Version)
+
+	disabled := New(10, WithHistoryLimit(0))
+	require.NoError(t, disabled.AddServer("server1"))
+	require.Empty(t, disabled.History())
+	require.Equal(t, uint64(1), disabled.Version())
+}
+
+func TestRollback(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	good := ring.Version()
+	checksum := ring.Checksum()
+	before, err := ring.GetServer("key1")
+	require.NoError(t, err)
+
+	// A bad scaling operation
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.NoError(t, ring.AddServer("server4"))
+	require.NoError(t, ring.AddServer("server5"))
+
+	require.NoError(t, ring.Rollback(good))
+	require.Equal(t, []string{"server1", "server2", "server3"}, ring.GetServers())
+	require.Equal(t, checksum, ring.Checksum())
+	require.Equal(t, uint64(7), ring.Version(), "Rollback should bump the version")
+
+	after, err := ring.GetServer("key1")
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	last := ring.History()[len(ring.History())-1]
+	require.Equal(t, ChangeRollback, last.Type)
+	require.Equal(t, good, last.Target)
+
+	// Rolling back to the current version is a no-op
+	require.NoError(t, ring.Rollback(ring.Version()))
+	require.Equal(t, uint64(7), ring.Version())
+
+	// Unknown versions fail
+	require.Error(t, ring.Rollback(100))
+}
+
+func TestRollbackLimits(t *testing.T) {
+	for _, opt := range []Option{WithMaxServers(2), WithMaxVNodes(25)} {
+		source := New(10)
+		for i := range 4 {
+			require.NoError(t, source.AddServer(fmt.Sprintf("server%d", i)))
+		}
+		full := source.Version()
+		for i := range 3 {
+			require.NoError(t, source.RemoveServer(fmt.Sprintf("server%d", i)))
+		}
+
+		// The restored ring has room for its one server, but not for the four
+		// in its history
+		ring, err := Restore(source.Snapshot(), opt)
+		require.NoError(t, err)
+		checksum, version := ring.Checksum(), ring.Version()
+
+		require.Error(t, ring.Rollback(full))
+		require.Equal(t, []string{"server3"}, ring.GetServers())
+		require.Equal(t, checksum, ring.Checksum())
+		require.Equal(t, version, ring.Version())
+	}
+}
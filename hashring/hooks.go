@@ -0,0 +1,115 @@
+package hashring
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// TransitionHook is called before a server moves from one state to another,
+// and vetoes the transition by returning an error. Removing a server is a
+// transition to StateRemoved.
+type TransitionHook func(server string, from, to ServerState) error
+
+// transitionHooks holds the hooks registered with AddTransitionHook. It has
+// its own lock so hooks can be registered while the ring is locked.
+type transitionHooks struct {
+	mu    sync.Mutex
+	next  int // id of the next hook
+	hooks []registeredHook
+}
+
+// registeredHook is a hook registered with AddTransitionHook.
+type registeredHook struct {
+	id   int
+	hook TransitionHook
+}
+
+// AddTransitionHook registers hook to run before every SetState and
+// RemoveServer, so operational invariants are enforced by the ring itself
+// rather than by every caller. It returns a function that removes the hook.
+//
+// Hooks run in the order they were registered, and the first error vetoes the
+// transition: the ring is left unchanged and the error is returned wrapped.
+// They run without the ring locked, so they may call back into it or wait on
+// slow checks without blocking lookups; if the server's state changes while
+// they run, the transition fails rather than act on a stale approval.
+//
+// Membership replaced wholesale by SetServers, Apply, or Restore follows a
+// decision made elsewhere and doesn't run hooks.
+//
+// Example:
+//
+//	// only remove servers whose data has been migrated off
+//	remove := ring.AddTransitionHook(func(server string, from, to hashring.ServerState) error {
+//		if to == hashring.StateRemoved && !migrator.Drained(server) {
+//			return fmt.Errorf("%s still holds data", server)
+//		}
+//		return nil
+//	})
+//	defer remove()
+func (h *HashRing) AddTransitionHook(hook TransitionHook) (remove func()) {
+	hooks := &h.hooks
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+
+	id := hooks.next
+	hooks.next++
+	hooks.hooks = append(hooks.hooks, registeredHook{id: id, hook: hook})
+
+	return func() {
+		hooks.mu.Lock()
+		defer hooks.mu.Unlock()
+
+		hooks.hooks = slices.DeleteFunc(hooks.hooks, func(r registeredHook) bool { return r.id == id })
+	}
+}
+
+// runHooks runs the transition hooks for server moving from one state to
+// another, returning the first veto. The caller must not hold h.mu.
+func (h *HashRing) runHooks(server string, from, to ServerState) error {
+	h.hooks.mu.Lock()
+	hooks := slices.Clone(h.hooks.hooks)
+	h.hooks.mu.Unlock()
+
+	for _, r := range hooks {
+		if err := r.hook(server, from, to); err != nil {
+			return fmt.Errorf("server %s: %s to %s vetoed: %w", server, from, to, err)
+		}
+	}
+
+	return nil
+}
+
+// hooked reports whether any transition hooks are registered.
+func (h *HashRing) hooked() bool {
+	h.hooks.mu.Lock()
+	defer h.hooks.mu.Unlock()
+
+	return len(h.hooks.hooks) > 0
+}
+
+// stateFor returns server's state, or an error if it isn't in the ring.
+func (h *HashRing) stateFor(server string) (ServerState, error) {
+	state, ok := h.State(server)
+	if !ok {
+		return "", fmt.Errorf("server %s does not exist", server)
+	}
+
+	return state, nil
+}
+
+// checkUnchanged returns an error if server's state is no longer from, after
+// hooks approved a transition from it. The caller must hold h.mu.
+func (h *HashRing) checkUnchanged(server string, from ServerState) error {
+	info, ok := h.servers[server]
+	if !ok {
+		return fmt.Errorf("server %s does not exist", server)
+	}
+
+	if state := stateOf(info); state != from {
+		return fmt.Errorf("server %s moved from %s to %s while transition hooks ran", server, from, state)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package hashring
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitionHooks(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	type transition struct {
+		server   string
+		from, to ServerState
+	}
+
+	var seen []transition
+	remove := ring.AddTransitionHook(func(server string, from, to ServerState) error {
+		seen = append(seen, transition{server, from, to})
+		return nil
+	})
+
+	// removal waits for the server to be drained
+	errNotDrained := errors.New("not drained")
+	drained := false
+	ring.AddTransitionHook(func(server string, from, to ServerState) error {
+		if to == StateRemoved && !drained {
+			return errNotDrained
+		}
+		return nil
+	})
+
+	version := ring.Version()
+	err := ring.RemoveServer("server1")
+	require.ErrorIs(t, err, errNotDrained)
+	require.ErrorContains(t, err, "server server1: active to removed vetoed")
+	require.Equal(t, version, ring.Version())
+	require.Equal(t, 2, ring.Size())
+
+	require.NoError(t, ring.SetState("server1", StateDraining))
+	drained = true
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.Equal(t, 1, ring.Size())
+
+	require.Equal(t, []transition{
+		{"server1", StateActive, StateRemoved},
+		{"server1", StateActive, StateDraining},
+		{"server1", StateDraining, StateRemoved},
+	}, seen)
+
+	// hooks don't run for transitions that aren't allowed anyway
+	require.Error(t, ring.SetState("server2", StateJoining))
+	require.Error(t, ring.RemoveServer("missing"))
+	require.Len(t, seen, 3)
+
+	remove()
+	require.NoError(t, ring.SetState("server2", StateDown))
+	require.Len(t, seen, 3)
+}
+
+func TestTransitionHookStaleApproval(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+
+	// the state changes while the hook runs, which it may do since the ring
+	// isn't locked
+	ring.AddTransitionHook(func(server string, from, to ServerState) error {
+		if to == StateRemoved {
+			return ring.SetServers([]ServerInfo{{Name: server, State: StateDown}})
+		}
+		return nil
+	})
+
+	require.ErrorContains(t, ring.RemoveServer("server1"), "moved from active to down while transition hooks ran")
+	require.Equal(t, 1, ring.Size())
+}
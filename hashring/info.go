@@ -0,0 +1,267 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ServerInfo describes a server in the ring.
+//
+// Only Name, Tokens, Weight, and VNodes affect placement: a server's virtual
+// nodes are placed at its explicit Tokens when set (see AddServerWithTokens),
+// and at positions derived from its Name otherwise. Weight scales the number of
+// virtual nodes (and so the share of keys) a server gets, and is derived from
+// Capacity when it isn't set (see WithWeightPolicy). VNodes, when set,
+// overrides the number of virtual nodes while Weight still sets the server's
+// target share, which is how Rebalance corrects unlucky placement. Zone and Tags are
+// metadata that can be used to select subsets of the ring (see View), e.g. to
+// route within a single availability zone or only to SSD-backed nodes.
+//
+// Tenants dedicates the server to the listed tenants, e.g. customers whose
+// data must be isolated for compliance: their keys only route to their
+// dedicated servers, and no other keys route there. Within each side, keys are
+// placed as usual by walking clockwise from the key's position to the first
+// eligible server, so other keys only move off the dedicated servers'
+// positions. This applies to every lookup, including pins, the canary, and
+// circuit breaker fallbacks, which are skipped when they'd cross the boundary;
+// lookups fail if a key has no eligible server. Tenants are key prefixes
+// unless the ring has a tenant extractor (see WithTenantExtractor).
+//
+// State is the server's lifecycle state, which decides whether it's routed
+// reads, writes, or both without moving its virtual nodes (see SetState). An
+// empty State is StateActive.
+type ServerInfo struct {
+	Name     string      `json:"name"`
+	Zone     string      `json:"zone,omitempty"`
+	Tags     []string    `json:"tags,omitempty"`
+	Tokens   []uint64    `json:"tokens,omitempty"`
+	Weight   float64     `json:"weight,omitempty"`  // relative to 1, the default
+	Capacity Capacity    `json:"capacity,omitzero"` // used to derive Weight when it isn't set
+	VNodes   int         `json:"vnodes,omitempty"`  // overrides the weighted vnode count (see Rebalance)
+	Tenants  []string    `json:"tenants,omitempty"` // tenants the server is dedicated to
+	State    ServerState `json:"state,omitempty"`   // lifecycle state, active if empty
+}
+
+// HasTag reports whether the server has the given tag.
+func (i ServerInfo) HasTag(tag string) bool {
+	return slices.Contains(i.Tags, tag)
+}
+
+// clone returns a copy of i that doesn't share its slices.
+func (i ServerInfo) clone() ServerInfo {
+	i.Tags = slices.Clone(i.Tags)
+	i.Tokens = slices.Clone(i.Tokens)
+	i.Tenants = slices.Clone(i.Tenants)
+	return i
+}
+
+// AddServerWithInfo adds a server to the hash ring along with its metadata.
+//
+// Placement is identical to AddServer(info.Name), or to AddServerWithTokens when
+// info.Tokens is set; the metadata is only used by features that inspect it,
+// such as View.
+//
+// Returns an error if the server already exists in the ring.
+//
+// Example:
+//
+//	err := ring.AddServerWithInfo(hashring.ServerInfo{
+//		Name: "cache-1",
+//		Zone: "us-east-1b",
+//		Tags: []string{"ssd"},
+//	})
+func (h *HashRing) AddServerWithInfo(info ServerInfo) error {
+	add := func() error {
+		if err := h.addServer(info); err != nil {
+			return err
+		}
+
+		h.recordChange(ChangeAdd, info.Name)
+		return nil
+	}
+
+	// tokens are checked against the placed virtual nodes, so they can't
+	// wait for a batch to be placed
+	if len(info.Tokens) > 0 {
+		return h.write(add)
+	}
+
+	return h.writeMembership(add)
+}
+
+// GetServerInfo returns the metadata for a server in the ring.
+//
+// Servers added with AddServer have an info containing only their name.
+//
+// Example:
+//
+//	if info, ok := ring.GetServerInfo("cache-1"); ok {
+//		fmt.Println(info.Zone)
+//	}
+func (h *HashRing) GetServerInfo(server string) (ServerInfo, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	info, ok := h.servers[server]
+	return info.clone(), ok
+}
+
+// SetServerInfo replaces the metadata of a server already in the ring.
+//
+// The server's placement only changes if its weight does, but views selecting
+// on metadata may route differently, so this always counts as a topology
+// change. A nil Tokens keeps the server's current tokens, and a zero Weight is
+// derived from Capacity as when adding a server. An empty State keeps the
+// server's current state.
+//
+// Returns an error if the server does not exist in the ring or info has
+// different tokens or a different state; changing tokens requires removing
+// and re-adding the server, and changing state requires SetState.
+//
+// Example:
+//
+//	info, _ := ring.GetServerInfo("cache-1")
+//	info.Tags = append(info.Tags, "draining")
+//	_ = ring.SetServerInfo(info)
+func (h *HashRing) SetServerInfo(info ServerInfo) error {
+	return h.write(func() error {
+		return h.setServerInfo(info)
+	})
+}
+
+// setServerInfo replaces a server's metadata. The caller must hold h.mu.
+func (h *HashRing) setServerInfo(info ServerInfo) error {
+	current, ok := h.servers[info.Name]
+	if !ok {
+		return fmt.Errorf("server %s does not exist", info.Name)
+	}
+
+	if info.Tokens == nil {
+		info.Tokens = current.Tokens
+	} else if !slices.Equal(info.Tokens, current.Tokens) {
+		return fmt.Errorf("server %s: tokens can't be changed in place", info.Name)
+	}
+
+	if info.State == "" {
+		info.State = stateOf(current)
+	} else if info.State != stateOf(current) {
+		return fmt.Errorf("server %s: state can only be changed with SetState", info.Name)
+	}
+
+	info, err := h.resolveWeight(info)
+	if err != nil {
+		return err
+	}
+
+	if info, err = h.resolveState(info, info.State); err != nil {
+		return err
+	}
+
+	if err := h.checkVNodes(info, h.placedVNodes(current)); err != nil {
+		return err
+	}
+
+	if err := h.updateServer(info); err != nil {
+		return err
+	}
+
+	h.recordChange(ChangeUpdate, info.Name)
+	return nil
+}
+
+// SetServers replaces the ring's membership with infos in a single change:
+// servers missing from infos are removed, new ones are added, and the rest are
+// updated as if by SetServerInfo, except that servers whose tokens changed are
+// re-added. It's recorded in the history as ChangeSync.
+//
+// This suits membership managed elsewhere, e.g. by service discovery or a
+// replicated set (see the membership package), where the ring only needs to
+// follow the latest view.
+//
+// Returns an error if infos has duplicate names or any server is invalid, in
+// which case the ring is unchanged.
+//
+// Example:
+//
+//	err := ring.SetServers([]hashring.ServerInfo{{Name: "cache-1"}, {Name: "cache-2"}})
+func (h *HashRing) SetServers(infos []ServerInfo) error {
+	return h.write(func() error {
+		seen := make(map[string]bool, len(infos))
+		for _, info := range infos {
+			if seen[info.Name] {
+				return fmt.Errorf("server %s is listed more than once", info.Name)
+			}
+			seen[info.Name] = true
+		}
+
+		// Try the change on a copy first so the ring is unchanged on error
+		if err := h.clone().setMembership(cloneInfos(infos)); err != nil {
+			return err
+		}
+
+		_ = h.setMembership(cloneInfos(infos))
+		h.recordChange(ChangeSync, "")
+		return nil
+	})
+}
+
+// updateServer replaces the info of a server in the ring, re-placing its
+// virtual nodes if its weight changed. Returns an error, leaving the server as
+// it was, if they can't be placed (see place). The caller must hold h.mu.
+func (h *HashRing) updateServer(info ServerInfo) error {
+	current := h.servers[info.Name]
+	h.servers[info.Name] = info.clone()
+	if err := h.replaceVNodes(info, current); err != nil {
+		h.servers[info.Name] = current
+		return errors.Join(err, h.rebuild())
+	}
+
+	h.indexServers()
+	if info.State != StateDown {
+		delete(h.downFrom, info.Name)
+	}
+
+	return nil
+}
+
+// replaceVNodes re-places a server's virtual nodes if info gives it a
+// different number than current. The caller must hold h.mu.
+func (h *HashRing) replaceVNodes(info, current ServerInfo) error {
+	if h.vnodesFor(info) == h.vnodesFor(current) {
+		return nil
+	}
+
+	if h.evenlySpaced() {
+		h.placeEvenly()
+		return nil
+	}
+
+	id := h.ids[info.Name]
+	h.entries = slices.DeleteFunc(h.entries, func(v vnode) bool {
+		return v.server == id
+	})
+
+	return h.placeServer(info)
+}
+
+// serverInfos returns the metadata of every server, sorted by name. The caller
+// must hold h.mu.
+func (h *HashRing) serverInfos() []ServerInfo {
+	infos := make([]ServerInfo, 0, len(h.servers))
+	for _, server := range h.serverList() {
+		infos = append(infos, h.servers[server].clone())
+	}
+
+	return infos
+}
+
+// cloneInfos deep copies infos.
+func cloneInfos(infos []ServerInfo) []ServerInfo {
+	cloned := make([]ServerInfo, len(infos))
+	for i, info := range infos {
+		cloned[i] = info.clone()
+	}
+
+	return cloned
+}
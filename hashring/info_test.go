@@ -0,0 +1,103 @@
+package hashring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerInfo(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server2", Zone: "us-east-1b", Tags: []string{"ssd"}}))
+	require.Error(t, ring.AddServerWithInfo(ServerInfo{Name: "server2"}), "Expected error when adding duplicate server")
+
+	info, ok := ring.GetServerInfo("server1")
+	require.True(t, ok)
+	require.Equal(t, ServerInfo{Name: "server1"}, info)
+
+	info, ok = ring.GetServerInfo("server2")
+	require.True(t, ok)
+	require.Equal(t, "us-east-1b", info.Zone)
+	require.True(t, info.HasTag("ssd"))
+	require.False(t, info.HasTag("hdd"))
+
+	// Returned info is a copy
+	info.Tags[0] = "mutated"
+	info, _ = ring.GetServerInfo("server2")
+	require.Equal(t, []string{"ssd"}, info.Tags)
+
+	_, ok = ring.GetServerInfo("server3")
+	require.False(t, ok)
+}
+
+func TestServerInfoPlacement(t *testing.T) {
+	plain := New(150)
+	require.NoError(t, plain.AddServer("server1"))
+	require.NoError(t, plain.AddServer("server2"))
+
+	withInfo := New(150)
+	require.NoError(t, withInfo.AddServerWithInfo(ServerInfo{Name: "server1", Zone: "a"}))
+	require.NoError(t, withInfo.AddServerWithInfo(ServerInfo{Name: "server2", Zone: "b"}))
+
+	for _, key := range []string{"key1", "key2", "key3", "user:42"} {
+		expected, err := plain.GetServer(key)
+		require.NoError(t, err)
+		actual, err := withInfo.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual, "Metadata should not affect placement")
+	}
+}
+
+func TestSetServerInfo(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server1", Zone: "a"}))
+
+	version, checksum := ring.Version(), ring.Checksum()
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server1", Zone: "b"}))
+	require.Greater(t, ring.Version(), version)
+	require.NotEqual(t, checksum, ring.Checksum())
+
+	info, _ := ring.GetServerInfo("server1")
+	require.Equal(t, "b", info.Zone)
+
+	require.Error(t, ring.SetServerInfo(ServerInfo{Name: "server2"}))
+
+	// Rollback restores metadata
+	require.NoError(t, ring.Rollback(version))
+	info, _ = ring.GetServerInfo("server1")
+	require.Equal(t, "a", info.Zone)
+}
+
+func TestSetServers(t *testing.T) {
+	ring := New(50, WithMaxServers(3))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServerWithTokens("server3", []uint64{100, 200}))
+
+	infos := []ServerInfo{
+		{Name: "server1", Zone: "a"},
+		{Name: "server3", Tokens: []uint64{300}},
+		{Name: "server4", Weight: 2},
+	}
+
+	version := ring.Version()
+	require.NoError(t, ring.SetServers(infos))
+	require.Equal(t, version+1, ring.Version())
+	require.Equal(t, ChangeSync, ring.History()[len(ring.History())-1].Type)
+
+	// same as building the ring from scratch
+	want := New(50)
+	for _, info := range infos {
+		require.NoError(t, want.AddServerWithInfo(info))
+	}
+	require.Equal(t, want.Checksum(), ring.Checksum())
+
+	// errors leave the ring unchanged
+	checksum := ring.Checksum()
+	require.Error(t, ring.SetServers([]ServerInfo{{Name: "server1"}, {Name: "server1"}}))
+	require.Error(t, ring.SetServers([]ServerInfo{{Name: "server1"}, {Name: ""}}))
+	require.Error(t, ring.SetServers([]ServerInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}))
+	require.Equal(t, checksum, ring.Checksum())
+	require.Equal(t, version+1, ring.Version())
+}
@@ -0,0 +1,140 @@
+package hashring
+
+import "iter"
+
+// Servers returns an iterator over the servers in the ring, in no particular
+// order. Use GetServers for a sorted slice.
+//
+// The ring is read-locked while iterating, so the loop body must not modify
+// it.
+//
+// Example:
+//
+//	for server := range ring.Servers() {
+//		fmt.Println(server)
+//	}
+func (h *HashRing) Servers() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+
+		for _, server := range h.names {
+			if !yield(server) {
+				return
+			}
+		}
+	}
+}
+
+// VNodes returns an iterator over the ring's virtual nodes, yielding each
+// node's position and owner in position order.
+//
+// The ring is read-locked while iterating, so the loop body must not modify
+// it.
+//
+// Example:
+//
+//	for pos, server := range ring.VNodes() {
+//		fmt.Printf("%10d %s\n", pos, server)
+//	}
+func (h *HashRing) VNodes() iter.Seq2[uint64, string] {
+	return func(yield func(uint64, string) bool) {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+
+		for i, v := range h.entries {
+			if !yield(v.hash, h.owner(i)) {
+				return
+			}
+		}
+	}
+}
+
+// OwnershipRanges returns an iterator over the ranges of positions owned by
+// each server, in position order. Adjacent ranges with the same owner are
+// merged, and together the ranges cover the whole hash space. An empty ring
+// yields nothing.
+//
+// The ring is read-locked while iterating, so the loop body must not modify
+// it.
+//
+// Example:
+//
+//	owned := make(map[string]uint64)
+//	for r, server := range ring.OwnershipRanges() {
+//		owned[server] += r.End - r.Start + 1
+//	}
+func (h *HashRing) OwnershipRanges() iter.Seq2[HashRange, string] {
+	return func(yield func(HashRange, string) bool) {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+
+		if len(h.entries) == 0 {
+			return
+		}
+
+		// Each virtual node owns the positions after the previous one, up to and
+		// including its own. Positions past the last node wrap to the first.
+		var current HashRange
+		owner := h.owner(0)
+		for i, v := range h.entries {
+			if server := h.owner(i); server != owner {
+				if !yield(current, owner) {
+					return
+				}
+
+				current.Start = current.End + 1
+				owner = server
+			}
+
+			current.End = v.hash
+		}
+
+		if current.End < maxHash && owner != h.owner(0) {
+			if !yield(current, owner) {
+				return
+			}
+
+			current = HashRange{Start: current.End + 1, End: current.End}
+			owner = h.owner(0)
+		}
+
+		current.End = maxHash
+		yield(current, owner)
+	}
+}
+
+// KeysForServer returns an iterator over the keys that server owns, taking
+// pins into account. Keys are resolved lazily as keys is consumed, which makes
+// it suitable for streaming large key sets through invalidation, warmup, or
+// migration jobs.
+//
+// Ownership ignores circuit breakers, so keys are attributed to their owner
+// even while it's tripped.
+//
+// Example:
+//
+//	for key := range ring.KeysForServer("cache-2", slices.Values(keys)) {
+//		warm(key)
+//	}
+func (h *HashRing) KeysForServer(server string, keys iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for key := range keys {
+			if h.ownerOf(key) == server && !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// ownerOf returns the owner of key, or an empty string if the ring is empty.
+func (h *HashRing) ownerOf(key string) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.entries) == 0 {
+		return ""
+	}
+
+	return h.resolve(key, AccessRead)
+}
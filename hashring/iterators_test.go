@@ -0,0 +1,95 @@
+package hashring
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServersIterator(t *testing.T) {
+	ring := New(10)
+	require.Empty(t, slices.Collect(ring.Servers()))
+
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server3"))
+	require.ElementsMatch(t, ring.GetServers(), slices.Collect(ring.Servers()))
+
+	for range ring.Servers() {
+		break
+	}
+}
+
+func TestVNodesIterator(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	var got []uint64
+	for pos, server := range ring.VNodes() {
+		require.Equal(t, ownerAt(ring, pos), server)
+		got = append(got, pos)
+	}
+
+	require.Equal(t, positions(ring), got)
+}
+
+func TestOwnershipRanges(t *testing.T) {
+	ring := New(10)
+	for range ring.OwnershipRanges() {
+		t.Fatal("empty ring shouldn't yield ranges")
+	}
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	l := ring.layout()
+	var (
+		next uint64
+		prev string
+	)
+
+	for r, server := range ring.OwnershipRanges() {
+		require.Equal(t, next, r.Start, "ranges should be contiguous")
+		require.LessOrEqual(t, r.Start, r.End)
+		require.NotEqual(t, prev, server, "adjacent ranges should be merged")
+		require.Equal(t, server, l.owner(r.Start))
+		require.Equal(t, server, l.owner(r.End))
+
+		next, prev = r.End+1, server
+	}
+
+	require.Equal(t, uint64(maxHash)+1, next, "ranges should cover the hash space")
+
+	// A single server owns everything
+	ring = New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	for r, server := range ring.OwnershipRanges() {
+		require.Equal(t, HashRange{Start: 0, End: maxHash}, r)
+		require.Equal(t, "server1", server)
+	}
+}
+
+func TestKeysForServer(t *testing.T) {
+	ring := New(50)
+	keys := slices.Values([]string{"key1", "key2", "key3", "key4", "key5", "key6"})
+	require.Empty(t, slices.Collect(ring.KeysForServer("server1", keys)))
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.Pin("key1", "server2"))
+
+	var all []string
+	for _, server := range ring.GetServers() {
+		for key := range ring.KeysForServer(server, keys) {
+			requireServer(t, ring, key, server)
+			all = append(all, key)
+		}
+	}
+
+	require.ElementsMatch(t, slices.Collect(keys), all)
+	require.Contains(t, slices.Collect(ring.KeysForServer("server2", keys)), "key1")
+	require.Empty(t, slices.Collect(ring.KeysForServer("unknown", keys)))
+}
@@ -0,0 +1,219 @@
+package hashring
+
+import (
+	"cmp"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// ketamaHashesPerServer is how many MD5 digests libketama computes for each
+// server when weights are equal. Each digest gives four points.
+const ketamaHashesPerServer = 40
+
+// KetamaRing reproduces the continuum libketama builds, which memcached
+// clients such as libmemcached, spymemcached, and pylibmc offer as their
+// ketama-compatible distribution, so a service can share a memcached pool
+// with them and agree on which server holds each key.
+//
+// Servers are hashed by name, which must be the address exactly as the other
+// clients are given it, e.g. "10.0.0.1:11211". Only the Name and Weight of
+// each server are used, and servers without a weight weigh 1.
+//
+// Like libketama, each lookup goes to the first point at or after the key's
+// hash, wrapping around. KetamaRing doesn't track availability; GetReplicas
+// lists the servers a key falls through to when its owner is unavailable.
+type KetamaRing struct {
+	points  []ketamaPoint // sorted by hash, without duplicates
+	servers []string      // sorted server names
+}
+
+// ketamaPoint is a position on a KetamaRing.
+type ketamaPoint struct {
+	hash   uint32
+	server string
+}
+
+// NewKetamaRing builds the continuum libketama would for servers.
+//
+// Each server gets 40 MD5 digests of "NAME-K", for K counting from 0, scaled
+// by its share of the total weight, and each digest gives four points. The
+// scaling uses single precision, as libketama does, so a server's point count
+// matches libketama's even where rounding makes it one digest short. When two
+// points collide, KetamaRing keeps the first server's.
+//
+// Returns an error if no servers are given, or a server has no name or is
+// given twice.
+//
+// Example:
+//
+//	ring, err := hashring.NewKetamaRing([]hashring.ServerInfo{
+//		{Name: "10.0.0.1:11211"},
+//		{Name: "10.0.0.2:11211", Weight: 2},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	server, err := ring.GetServer("user:42")
+func NewKetamaRing(servers []ServerInfo) (*KetamaRing, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("no servers given")
+	}
+
+	weights := make([]float64, len(servers))
+	var total float64
+	seen := make(map[string]bool, len(servers))
+	for i, server := range servers {
+		if server.Name == "" {
+			return nil, errors.New("server name cannot be empty")
+		}
+
+		if seen[server.Name] {
+			return nil, fmt.Errorf("server %s given twice", server.Name)
+		}
+		seen[server.Name] = true
+
+		weights[i] = server.Weight
+		if weights[i] <= 0 {
+			weights[i] = 1
+		}
+		total += weights[i]
+	}
+
+	r := &KetamaRing{}
+	n := float64(float32(len(servers)))
+	for i, server := range servers {
+		// floorf(pct * 40.0 * (float)numservers), with pct a float, as
+		// libketama computes it
+		pct := float32(weights[i]) / float32(total)
+		digests := int(math.Floor(float64(float32(float64(pct) * ketamaHashesPerServer * n))))
+
+		for k := range digests {
+			digest := md5.Sum([]byte(server.Name + "-" + strconv.Itoa(k)))
+			for h := range 4 {
+				hash := binary.LittleEndian.Uint32(digest[h*4:])
+				r.points = append(r.points, ketamaPoint{hash: hash, server: server.Name})
+			}
+		}
+
+		r.servers = append(r.servers, server.Name)
+	}
+
+	slices.SortStableFunc(r.points, func(a, b ketamaPoint) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+	r.points = slices.CompactFunc(r.points, func(a, b ketamaPoint) bool {
+		return a.hash == b.hash
+	})
+	slices.Sort(r.servers)
+
+	return r, nil
+}
+
+// KetamaHash returns a key's position on a KetamaRing: the first four bytes of
+// its MD5 digest, read little-endian.
+func KetamaHash(key string) uint32 {
+	digest := md5.Sum([]byte(key))
+	return binary.LittleEndian.Uint32(digest[:])
+}
+
+// KetamaBuilder returns a RouterBuilder that builds KetamaRings, to compare
+// ketama clients' routing with a ring's using VerifyConsistency.
+//
+// Example:
+//
+//	report, err := hashring.VerifyConsistency(servers, keys, hashring.KetamaBuilder(), hashring.RingBuilder(150))
+func KetamaBuilder() RouterBuilder {
+	return func(servers []ServerInfo) (Router, error) {
+		return NewKetamaRing(servers)
+	}
+}
+
+// GetServer returns the server ketama clients send key to.
+func (r *KetamaRing) GetServer(key string) (string, error) {
+	return r.GetServerForHash(KetamaHash(key)), nil
+}
+
+// GetServerForHash returns the server owning the first point at or after hash,
+// wrapping around to the first point.
+func (r *KetamaRing) GetServerForHash(hash uint32) string {
+	return r.points[r.search(hash)].server
+}
+
+// GetReplicas returns up to n distinct servers for key, in the order met
+// walking clockwise from its position: its owner, then the servers its keys
+// fall through to when the servers before them are unavailable.
+func (r *KetamaRing) GetReplicas(key string, n int) []string {
+	n = min(n, len(r.servers))
+	if n <= 0 {
+		return nil
+	}
+
+	replicas := make([]string, 0, n)
+	for server := range r.Successors(key) {
+		replicas = append(replicas, server)
+		if len(replicas) == n {
+			break
+		}
+	}
+
+	return replicas
+}
+
+// Successors returns an iterator over the distinct servers met walking
+// clockwise from key's position, as GetReplicas orders them. The walk stops
+// as soon as the loop does, so finding the first available server is cheap.
+//
+// Example:
+//
+//	for server := range ring.Successors(key) {
+//		if alive(server) {
+//			return server
+//		}
+//	}
+func (r *KetamaRing) Successors(key string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		start := r.search(KetamaHash(key))
+		var seen []string
+		for i := range r.points {
+			server := r.points[(start+i)%len(r.points)].server
+			if slices.Contains(seen, server) {
+				continue
+			}
+
+			if !yield(server) {
+				return
+			}
+
+			seen = append(seen, server)
+			if len(seen) == len(r.servers) {
+				return
+			}
+		}
+	}
+}
+
+// search returns the index of the first point at or after hash, wrapping
+// around to the first point.
+func (r *KetamaRing) search(hash uint32) int {
+	i, _ := slices.BinarySearchFunc(r.points, hash, func(p ketamaPoint, hash uint32) int {
+		return cmp.Compare(p.hash, hash)
+	})
+
+	return i % len(r.points)
+}
+
+// GetServers returns the sorted names of the ring's servers.
+func (r *KetamaRing) GetServers() []string {
+	return slices.Clone(r.servers)
+}
+
+// Size returns the number of points on the ring.
+func (r *KetamaRing) Size() int {
+	return len(r.points)
+}
@@ -0,0 +1,145 @@
+package hashring
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ Router = (*KetamaRing)(nil)
+
+func TestKetamaHash(t *testing.T) {
+	// md5("") is d41d8cd98f00b204e9800998ecf8427e
+	require.Equal(t, uint32(0xd98c1dd4), KetamaHash(""))
+	require.Equal(t, uint32(0xdb18bdac), KetamaHash("foo"))
+}
+
+func TestNewKetamaRing(t *testing.T) {
+	// The servers file shipped with libketama
+	ring, err := NewKetamaRing([]ServerInfo{
+		{Name: "10.0.1.1:11211", Weight: 600},
+		{Name: "10.0.1.2:11211", Weight: 300},
+		{Name: "10.0.1.3:11211", Weight: 200},
+		{Name: "10.0.1.4:11211", Weight: 350},
+		{Name: "10.0.1.5:11211", Weight: 1000},
+		{Name: "10.0.1.6:11211", Weight: 800},
+		{Name: "10.0.1.7:11211", Weight: 950},
+		{Name: "10.0.1.8:11211", Weight: 100},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1264, ring.Size())
+
+	counts := make(map[string]int)
+	for _, p := range ring.points {
+		counts[p.server]++
+	}
+	require.Equal(t, map[string]int{
+		"10.0.1.1:11211": 176,
+		"10.0.1.2:11211": 88,
+		"10.0.1.3:11211": 56,
+		"10.0.1.4:11211": 104,
+		"10.0.1.5:11211": 296,
+		"10.0.1.6:11211": 236,
+		"10.0.1.7:11211": 280,
+		"10.0.1.8:11211": 28,
+	}, counts)
+
+	require.Equal(t, uint32(762113), ring.points[0].hash)
+	require.Equal(t, uint32(4293620028), ring.points[len(ring.points)-1].hash)
+
+	tests := map[string]string{
+		"":             "10.0.1.4:11211",
+		"foo":          "10.0.1.7:11211",
+		"bar":          "10.0.1.6:11211",
+		"baz":          "10.0.1.2:11211",
+		"user:42":      "10.0.1.7:11211",
+		"session:1234": "10.0.1.2:11211",
+		"key:0":        "10.0.1.1:11211",
+	}
+	for key, want := range tests {
+		server, err := ring.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, want, server, key)
+	}
+
+	// Past the last point, lookups wrap around
+	require.Equal(t, "10.0.1.5:11211", ring.GetServerForHash(4293620029))
+
+	_, err = NewKetamaRing([]ServerInfo{{Name: "a"}, {Name: "a"}})
+	require.ErrorContains(t, err, "given twice")
+
+	_, err = NewKetamaRing([]ServerInfo{{}})
+	require.Error(t, err)
+
+	_, err = NewKetamaRing(nil)
+	require.Error(t, err)
+}
+
+func TestKetamaRingUnweighted(t *testing.T) {
+	ring, err := NewKetamaRing([]ServerInfo{
+		{Name: "127.0.0.1:11211"}, {Name: "127.0.0.1:11212"}, {Name: "127.0.0.1:11213"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3*160, ring.Size())
+	require.Equal(t, []string{"127.0.0.1:11211", "127.0.0.1:11212", "127.0.0.1:11213"}, ring.GetServers())
+
+	tests := map[string]string{
+		"foo":     "127.0.0.1:11213",
+		"bar":     "127.0.0.1:11212",
+		"baz":     "127.0.0.1:11211",
+		"user:42": "127.0.0.1:11212",
+	}
+	for key, want := range tests {
+		server, err := ring.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, want, server, key)
+	}
+}
+
+func TestKetamaRingGetReplicas(t *testing.T) {
+	ring, err := NewKetamaRing([]ServerInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+	require.NoError(t, err)
+
+	require.Nil(t, ring.GetReplicas("key", 0))
+
+	for i := range 100 {
+		key := fmt.Sprintf("key:%d", i)
+		owner, err := ring.GetServer(key)
+		require.NoError(t, err)
+
+		replicas := ring.GetReplicas(key, 5)
+		require.Len(t, replicas, 3)
+		require.ElementsMatch(t, []string{"a", "b", "c"}, replicas)
+		require.Equal(t, owner, replicas[0])
+
+		require.Equal(t, replicas, slices.Collect(ring.Successors(key)))
+
+		// The second replica owns the key once the first is gone
+		var rest []ServerInfo
+		for _, server := range replicas[1:] {
+			rest = append(rest, ServerInfo{Name: server})
+		}
+		without, err := NewKetamaRing(rest)
+		require.NoError(t, err)
+		next, err := without.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, replicas[1], next)
+	}
+}
+
+func TestKetamaRingSuccessorsStopEarly(t *testing.T) {
+	ring, err := NewKetamaRing([]ServerInfo{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+	require.NoError(t, err)
+
+	owner, err := ring.GetServer("key")
+	require.NoError(t, err)
+
+	var seen []string
+	for server := range ring.Successors("key") {
+		seen = append(seen, server)
+		break
+	}
+	require.Equal(t, []string{owner}, seen)
+}
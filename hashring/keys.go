@@ -0,0 +1,64 @@
+package hashring
+
+import (
+	"strings"
+)
+
+// WithHashTags enables Redis-style hash tags using the given delimiters.
+//
+// When a key contains a non-empty substring enclosed by open and close, only
+// that substring is hashed. This lets related keys co-locate on the same server
+// for multi-key operations:
+//
+//	ring := hashring.New(150, hashring.WithHashTags("{", "}"))
+//	// "order:{user42}:1" and "cart:{user42}" both hash "user42"
+//
+// As with Redis, only the first occurrence of open is considered, and keys
+// without a tag (or with an empty one, like "{}") are hashed in full. Pins
+// always match against the full key.
+func WithHashTags(open, close string) Option {
+	return func(h *HashRing) {
+		h.tagOpen, h.tagClose = open, close
+	}
+}
+
+// WithKeyExtractor sets a function that derives the routing key from a full
+// key, such as stripping a prefix, lowercasing, or taking a single field. This
+// keeps routing rules inside the ring instead of repeating them at every call
+// site.
+//
+//	ring := hashring.New(150, hashring.WithKeyExtractor(strings.ToLower))
+//	// "User:42" and "user:42" route to the same server
+//
+// The extractor runs before hash tags are applied (see WithHashTags). Pins
+// always match against the full key. Since functions can't be compared, the
+// extractor isn't reflected in Checksum or Snapshot; rings that must agree on
+// routing need to be configured with equivalent extractors.
+func WithKeyExtractor(extract func(string) string) Option {
+	return func(h *HashRing) {
+		h.extractor = extract
+	}
+}
+
+// routingKey derives the part of key that determines its placement.
+func (h *HashRing) routingKey(key string) string {
+	if h.extractor != nil {
+		key = h.extractor(key)
+	}
+
+	if h.tagOpen == "" || h.tagClose == "" {
+		return key
+	}
+
+	_, after, found := strings.Cut(key, h.tagOpen)
+	if !found {
+		return key
+	}
+
+	tag, _, found := strings.Cut(after, h.tagClose)
+	if !found || tag == "" {
+		return key
+	}
+
+	return tag
+}
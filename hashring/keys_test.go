@@ -0,0 +1,98 @@
+package hashring
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingKeyHashTags(t *testing.T) {
+	ring := New(150, WithHashTags("{", "}"))
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"order:{user42}:1", "user42"},
+		{"cart:{user42}", "user42"},
+		{"{user42}", "user42"},
+		{"no-tag", "no-tag"},
+		{"empty:{}:tag", "empty:{}:tag"},
+		{"unclosed:{user42", "unclosed:{user42"},
+		{"first:{a}:{b}", "a"},
+		{"close-first:}{a}", "a"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, ring.routingKey(tt.key), "key %s", tt.key)
+	}
+
+	// Disabled by default
+	require.Equal(t, "cart:{user42}", New(150).routingKey("cart:{user42}"))
+}
+
+func TestHashTagsColocation(t *testing.T) {
+	ring := New(150, WithHashTags("{", "}"))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	for _, user := range []string{"user1", "user42", "user1337"} {
+		expected, err := ring.GetServer(user)
+		require.NoError(t, err)
+
+		for _, key := range []string{"order:{" + user + "}:1", "cart:{" + user + "}", "{" + user + "}:profile"} {
+			server, err := ring.GetServer(key)
+			require.NoError(t, err)
+			require.Equal(t, expected, server, "%s should co-locate with %s", key, user)
+		}
+	}
+}
+
+func TestHashTagsCustomDelimiters(t *testing.T) {
+	ring := New(150, WithHashTags("<<", ">>"))
+	require.Equal(t, "user42", ring.routingKey("order:<<user42>>:1"))
+	require.Equal(t, "order:{user42}:1", ring.routingKey("order:{user42}:1"))
+}
+
+func TestHashTagsSnapshot(t *testing.T) {
+	ring := New(150, WithHashTags("{", "}"))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NotEqual(t, New(150).Checksum(), New(150, WithHashTags("{", "}")).Checksum())
+
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.Equal(t, "user42", restored.routingKey("cart:{user42}"))
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+}
+
+func TestKeyExtractor(t *testing.T) {
+	stripTenant := func(key string) string {
+		_, rest, _ := strings.Cut(key, "/")
+		return rest
+	}
+
+	ring := New(150, WithKeyExtractor(stripTenant))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	for i := range 100 {
+		key := fmt.Sprintf("user-%d", i)
+		expected, err := ring.GetServer("/" + key)
+		require.NoError(t, err)
+
+		for _, tenant := range []string{"acme", "globex"} {
+			server, err := ring.GetServer(tenant + "/" + key)
+			require.NoError(t, err)
+			require.Equal(t, expected, server)
+		}
+	}
+}
+
+func TestKeyExtractorWithHashTags(t *testing.T) {
+	ring := New(150, WithKeyExtractor(strings.ToLower), WithHashTags("{", "}"))
+	require.Equal(t, "user42", ring.routingKey("Order:{USER42}:1"))
+}
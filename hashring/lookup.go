@@ -0,0 +1,74 @@
+package hashring
+
+import "errors"
+
+// Result describes how a key was routed, as returned by Lookup.
+type Result struct {
+	Server string     // server that owns the key
+	Info   ServerInfo // the owner's metadata
+	Hash   uint64     // the key's position on the ring
+
+	// VNode is the position of the virtual node that matched the key, the
+	// first eligible one clockwise from Hash, and Index is its index among the ring's
+	// virtual nodes in position order (see VNodes). For pinned and canaried
+	// keys, VNode is zero and Index is -1.
+	VNode  uint64
+	Index  int
+	Pinned bool   // the key matched a pin (see Pin)
+	Canary bool   // the key was routed to the canary (see SetCanary)
+	Tenant string // the key's tenant, if it has dedicated servers (see ServerInfo.Tenants)
+}
+
+// Lookup returns the server that owns key along with its metadata and the
+// virtual node that matched, for debugging tools and callers that need more
+// than the server's name.
+//
+// Like Claim, Lookup ignores circuit breakers and reports the key's owner,
+// which GetServer only returns while its breaker is closed. Unlike Claim, the
+// owner is the server the key is read from (see SetState).
+//
+// Returns an error if the hash ring is empty or no server is eligible for the
+// key (see ServerInfo.Tenants).
+//
+// Example:
+//
+//	res, err := ring.Lookup("user:42")
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Printf("%s (zone %s) via vnode %d at %#x\n", res.Server, res.Info.Zone, res.Index, res.VNode)
+func (h *HashRing) Lookup(key string) (Result, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.entries) == 0 {
+		return Result{}, errors.New("hash ring is empty")
+	}
+
+	h.lookups.Add(1)
+	res := Result{Hash: h.hashKey(h.routingKey(key)), Index: -1, Tenant: h.tenant(key)}
+	accept := h.eligible(key, AccessRead)
+	eligible := func(server string) bool {
+		return accept == nil || accept(server)
+	}
+
+	if server, ok := h.pinned(key); ok && eligible(server) {
+		res.Server, res.Pinned = server, true
+	} else if h.canaried(res.Hash) && eligible(h.canary.Server) {
+		res.Server, res.Canary = h.canary.Server, true
+	} else {
+		if res.Index = h.search(res.Hash); accept != nil {
+			res.Index = h.firstEligible(res.Hash, accept)
+		}
+
+		if res.Index < 0 {
+			return Result{}, errNoEligibleServer(key)
+		}
+
+		res.VNode = h.entries[res.Index].hash
+		res.Server = h.owner(res.Index)
+	}
+
+	res.Info = h.servers[res.Server].clone()
+	return res, nil
+}
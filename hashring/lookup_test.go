@@ -0,0 +1,65 @@
+package hashring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	ring := New(50, WithCircuitBreaker(1, time.Hour))
+	_, err := ring.Lookup("key")
+	require.Error(t, err)
+
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server1", Zone: "us-east-1a"}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server2", Zone: "us-east-1b"}))
+
+	res, err := ring.Lookup("key")
+	require.NoError(t, err)
+	require.False(t, res.Pinned)
+	require.Equal(t, ring.Hash("key"), res.Hash)
+
+	server, err := ring.GetServer("key")
+	require.NoError(t, err)
+	require.Equal(t, server, res.Server)
+
+	info, ok := ring.GetServerInfo(server)
+	require.True(t, ok)
+	require.Equal(t, info, res.Info)
+
+	// the matched vnode is the first clockwise from the key and owned by the server
+	i := 0
+	for pos, owner := range ring.VNodes() {
+		if i == res.Index {
+			require.Equal(t, res.VNode, pos)
+			require.Equal(t, res.Server, owner)
+		}
+		if i == res.Index-1 {
+			require.Less(t, pos, res.Hash)
+		}
+		i++
+	}
+	if res.VNode < res.Hash {
+		require.Zero(t, res.Index, "Only wrapping around can match a lower position")
+	}
+
+	// open breakers don't change the owner
+	ring.ReportFailure(res.Server)
+	tripped, err := ring.Lookup("key")
+	require.NoError(t, err)
+	require.Equal(t, res, tripped)
+
+	other := "server1"
+	if res.Server == other {
+		other = "server2"
+	}
+	require.NoError(t, ring.Pin("key", other))
+	res, err = ring.Lookup("key")
+	require.NoError(t, err)
+	require.True(t, res.Pinned)
+	require.Equal(t, other, res.Server)
+	require.Equal(t, other, res.Info.Name)
+	require.Equal(t, -1, res.Index)
+	require.Zero(t, res.VNode)
+}
@@ -1,7 +1,15 @@
 package hashring
 
 import (
+	"cmp"
+	"encoding/csv"
 	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
 	"time"
 )
 
@@ -27,20 +35,43 @@ import (
 type PerformanceMetrics struct {
 	TotalKeys      int
 	Servers        int
-	VirtualNodes   int
+	VirtualNodes   int // virtual nodes per server (see New)
 	AvgLatency     time.Duration
 	DistributionCV float64 // Coefficient of Variation
+	Gini           float64 // Gini coefficient of the distribution, 0 (even) to 1 (one server has every key)
+	MaxMinRatio    float64 // keys on the most loaded server / keys on the least loaded (+Inf if it has none)
+	MaxShare       float64 // percentage of keys on the most loaded server
 	Distribution   map[string]int
+	Expected       map[string]float64 // each server's ownership share (see OwnershipShare)
+	Collisions     int                // virtual nodes moved due to hash collisions
+}
+
+// PrintOption configures the report written by Print and Fprint.
+type PrintOption func(*printConfig)
+
+type printConfig struct {
+	byCount bool
+}
+
+// SortByCount lists servers in the key distribution from most to fewest keys,
+// rather than by name, so hot spots come first. Ties are listed by name.
+func SortByCount() PrintOption {
+	return func(c *printConfig) {
+		c.byCount = true
+	}
 }
 
 // Print displays a formatted performance analysis report to stdout.
 //
 // The report includes:
 //   - Total number of keys analyzed
-//   - Number of servers in the ring
+//   - Number of servers in the ring and virtual nodes per server
 //   - Average latency per key lookup
 //   - Distribution quality (Coefficient of Variation)
-//   - Per-server key distribution with percentages
+//   - Skew: Gini coefficient, max/min load ratio, and the most loaded server's share
+//   - Virtual node collisions, if any
+//   - Per-server key distribution with percentages, sorted by server name
+//     (or by key count with SortByCount), so reports can be diffed
 //
 // The distribution quality is evaluated as:
 //   - CV < 5%: Excellent distribution (✅)
@@ -52,32 +83,152 @@ type PerformanceMetrics struct {
 //	=== Performance Analysis ===
 //	Total Keys: 10000
 //	Servers: 3
+//	Virtual Nodes: 150 per server
 //	Avg Latency: 125ns per key
 //	Distribution CV: 3.45%
 //	✅ Excellent distribution!
+//	Gini: 0.004
+//	Max/Min Load: 1.01x
+//	Max Share: 33.4%
 //
 //	Key Distribution:
 //	  server-1: 3342 keys (33.4%)
 //	  server-2: 3321 keys (33.2%)
 //	  server-3: 3337 keys (33.4%)
-func (metrics PerformanceMetrics) Print() {
-	fmt.Println("\n=== Performance Analysis ===")
-	fmt.Printf("Total Keys: %d\n", metrics.TotalKeys)
-	fmt.Printf("Servers: %d\n", metrics.Servers)
-	fmt.Printf("Avg Latency: %v per key\n", metrics.AvgLatency)
-	fmt.Printf("Distribution CV: %.2f%%\n", metrics.DistributionCV)
+func (metrics PerformanceMetrics) Print(opts ...PrintOption) {
+	metrics.Fprint(os.Stdout, opts...)
+}
+
+// Fprint writes the report printed by Print to w.
+func (metrics PerformanceMetrics) Fprint(w io.Writer, opts ...PrintOption) {
+	var cfg printConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	fmt.Fprintln(w, "\n=== Performance Analysis ===")
+	fmt.Fprintf(w, "Total Keys: %d\n", metrics.TotalKeys)
+	fmt.Fprintf(w, "Servers: %d\n", metrics.Servers)
+	if metrics.VirtualNodes > 0 {
+		fmt.Fprintf(w, "Virtual Nodes: %d per server\n", metrics.VirtualNodes)
+	}
+	fmt.Fprintf(w, "Avg Latency: %v per key\n", metrics.AvgLatency)
+	fmt.Fprintf(w, "Distribution CV: %.2f%%\n", metrics.DistributionCV)
 
 	if metrics.DistributionCV < 5 {
-		fmt.Println("✅ Excellent distribution!")
+		fmt.Fprintln(w, "✅ Excellent distribution!")
 	} else if metrics.DistributionCV < 10 {
-		fmt.Println("✅ Good distribution")
+		fmt.Fprintln(w, "✅ Good distribution")
 	} else {
-		fmt.Println("⚠️  Poor distribution - consider more virtual nodes")
+		fmt.Fprintln(w, "⚠️  Poor distribution - consider more virtual nodes")
 	}
 
-	fmt.Println("\nKey Distribution:")
-	for server, count := range metrics.Distribution {
+	fmt.Fprintf(w, "Gini: %.3f\n", metrics.Gini)
+	fmt.Fprintf(w, "Max/Min Load: %.2fx\n", metrics.MaxMinRatio)
+	fmt.Fprintf(w, "Max Share: %.1f%%\n", metrics.MaxShare)
+
+	if metrics.Collisions > 0 {
+		fmt.Fprintf(w, "Virtual Node Collisions: %d\n", metrics.Collisions)
+	}
+
+	servers := slices.Sorted(maps.Keys(metrics.Distribution))
+	if cfg.byCount {
+		slices.SortStableFunc(servers, func(a, b string) int {
+			return cmp.Compare(metrics.Distribution[b], metrics.Distribution[a])
+		})
+	}
+
+	fmt.Fprintln(w, "\nKey Distribution:")
+	for _, server := range servers {
+		count := metrics.Distribution[server]
 		percentage := float64(count) * 100 / float64(metrics.TotalKeys)
-		fmt.Printf("  %s: %d keys (%.1f%%)\n", server, count, percentage)
+		fmt.Fprintf(w, "  %s: %d keys (%.1f%%)\n", server, count, percentage)
+	}
+}
+
+// WriteCSV writes the per-server distribution to w as CSV, sorted by server,
+// for importing into spreadsheets and notebooks. Each row has the server, its
+// key count, its percentage of keys, the percentage it's expected to have
+// based on its ownership share, and the difference between the two in
+// percentage points.
+//
+// Example output:
+//
+//	server,keys,percent,expected_percent,delta
+//	server-1,3342,33.42,33.10,0.32
+//	server-2,3321,33.21,33.52,-0.31
+//	server-3,3337,33.37,33.38,-0.01
+func (metrics PerformanceMetrics) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"server", "keys", "percent", "expected_percent", "delta"}); err != nil {
+		return err
 	}
+
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+	for _, server := range slices.Sorted(maps.Keys(metrics.Distribution)) {
+		count := metrics.Distribution[server]
+
+		var percent float64
+		if metrics.TotalKeys > 0 {
+			percent = float64(count) * 100 / float64(metrics.TotalKeys)
+		}
+
+		expected := metrics.Expected[server] * 100
+		row := []string{server, strconv.Itoa(count), format(percent), format(expected), format(percent - expected)}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Lookups returns the number of keys the ring has resolved to servers, via
+// GetServer, GetReplicas, and the methods built on them. Lookups against an
+// empty ring aren't counted.
+//
+// The count only increases, so rates can be derived by sampling it
+// periodically.
+func (h *HashRing) Lookups() uint64 {
+	return h.lookups.Load()
+}
+
+// balance returns the Gini coefficient, max/min ratio, and max share (in
+// percent) of distribution. CV alone under-describes skew: a single overloaded
+// server barely moves it when there are many servers.
+func balance(distribution map[string]int) (gini, maxMin, maxShare float64) {
+	if len(distribution) == 0 {
+		return 0, 0, 0
+	}
+
+	counts := make([]int, 0, len(distribution))
+	total := 0
+	for _, count := range distribution {
+		counts = append(counts, count)
+		total += count
+	}
+
+	if total == 0 {
+		return 0, 0, 0
+	}
+
+	slices.Sort(counts)
+	lo, hi := counts[0], counts[len(counts)-1]
+
+	// G = 2*sum(i*x_i) / (n*sum(x)) - (n+1)/n, with x sorted ascending and i
+	// starting at 1.
+	n := float64(len(counts))
+	var weighted float64
+	for i, count := range counts {
+		weighted += float64(i+1) * float64(count)
+	}
+
+	gini = 2*weighted/(n*float64(total)) - (n+1)/n
+	maxMin = math.Inf(1)
+	if lo > 0 {
+		maxMin = float64(hi) / float64(lo)
+	}
+
+	return gini, maxMin, float64(hi) * 100 / float64(total)
 }
@@ -0,0 +1,159 @@
+package hashring
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalance(t *testing.T) {
+	tests := []struct {
+		name         string
+		distribution map[string]int
+		gini         float64
+		maxMin       float64
+		maxShare     float64
+	}{
+		{"empty", nil, 0, 0, 0},
+		{"no keys", map[string]int{"a": 0, "b": 0}, 0, 0, 0},
+		{"even", map[string]int{"a": 10, "b": 10, "c": 10, "d": 10}, 0, 1, 25},
+		{"skewed", map[string]int{"a": 10, "b": 30}, 0.25, 3, 75},
+		{"one server has every key", map[string]int{"a": 0, "b": 0, "c": 0, "d": 40}, 0.75, math.Inf(1), 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gini, maxMin, maxShare := balance(test.distribution)
+			require.InDelta(t, test.gini, gini, 1e-9)
+			require.Equal(t, test.maxMin, maxMin)
+			require.InDelta(t, test.maxShare, maxShare, 1e-9)
+		})
+	}
+}
+
+func TestAnalyzePerformanceNoKeys(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	require.Zero(t, ring.AnalyzePerformance(nil))
+	require.Zero(t, New(10).AnalyzePerformance([]string{}))
+
+	metrics, err := ring.AnalyzePerformanceContext(context.Background(), nil)
+	require.NoError(t, err)
+	require.Zero(t, metrics)
+}
+
+func TestAnalyzePerformanceBalance(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	keys := make([]string, 3000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	metrics := ring.AnalyzePerformance(keys)
+	gini, maxMin, maxShare := balance(metrics.Distribution)
+	require.Equal(t, gini, metrics.Gini)
+	require.Equal(t, maxMin, metrics.MaxMinRatio)
+	require.Equal(t, maxShare, metrics.MaxShare)
+	require.Less(t, metrics.Gini, 0.2)
+	require.GreaterOrEqual(t, metrics.MaxMinRatio, 1.0)
+	require.Greater(t, metrics.MaxShare, 100.0/3)
+
+	// the CV is exact however uneven the distribution is
+	var sum, squares float64
+	for _, count := range metrics.Distribution {
+		sum += float64(count)
+		squares += float64(count) * float64(count)
+	}
+	mean := sum / 3
+	require.InDelta(t, math.Sqrt(squares/3-mean*mean)/mean*100, metrics.DistributionCV, 1e-6)
+
+	keys = make([]string, 100_000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	require.Less(t, ring.AnalyzePerformance(keys).DistributionCV, 100.0)
+}
+
+func TestWriteCSV(t *testing.T) {
+	metrics := PerformanceMetrics{
+		TotalKeys:    200,
+		Distribution: map[string]int{"server2": 120, "server1": 80},
+		Expected:     map[string]float64{"server1": 0.45, "server2": 0.55},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, metrics.WriteCSV(&buf))
+	require.Equal(t, `server,keys,percent,expected_percent,delta
+server1,80,40.00,45.00,-5.00
+server2,120,60.00,55.00,5.00
+`, buf.String())
+
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	metrics = ring.AnalyzePerformance([]string{"key1", "key2"})
+	require.Equal(t, ring.OwnershipShare(), metrics.Expected)
+}
+
+func TestFprintOrder(t *testing.T) {
+	metrics := PerformanceMetrics{
+		TotalKeys:    400,
+		Servers:      4,
+		VirtualNodes: 50,
+		Distribution: map[string]int{"c": 120, "a": 80, "d": 80, "b": 120},
+	}
+
+	distribution := func(opts ...PrintOption) string {
+		var buf bytes.Buffer
+		metrics.Fprint(&buf, opts...)
+		require.Contains(t, buf.String(), "Virtual Nodes: 50 per server\n")
+
+		_, dist, ok := strings.Cut(buf.String(), "Key Distribution:\n")
+		require.True(t, ok)
+		return dist
+	}
+
+	require.Equal(t, `  a: 80 keys (20.0%)
+  b: 120 keys (30.0%)
+  c: 120 keys (30.0%)
+  d: 80 keys (20.0%)
+`, distribution())
+
+	require.Equal(t, `  b: 120 keys (30.0%)
+  c: 120 keys (30.0%)
+  a: 80 keys (20.0%)
+  d: 80 keys (20.0%)
+`, distribution(SortByCount()))
+
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.Equal(t, 50, ring.AnalyzePerformance([]string{"key1"}).VirtualNodes)
+}
+
+func TestLookups(t *testing.T) {
+	ring := New(50)
+	_, err := ring.GetServer("key")
+	require.Error(t, err)
+	require.Zero(t, ring.Lookups(), "lookups against an empty ring shouldn't count")
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	_, err = ring.GetServer("key")
+	require.NoError(t, err)
+	_, err = ring.GetReplicas("key", 2)
+	require.NoError(t, err)
+	ring.GetDistribution([]string{"key1", "key2", "key3"})
+	require.Equal(t, uint64(5), ring.Lookups())
+}
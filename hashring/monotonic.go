@@ -0,0 +1,98 @@
+package hashring
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// VerifyMonotonic checks that change, applied to a copy of ring, only moves
+// keys to or from the servers it changes, never between two servers it
+// leaves alone. That's the property that makes consistent hashing cheap to
+// scale: adding a server only takes keys from others, and removing one only
+// hands its keys out.
+//
+// The changed servers are those added, removed, or updated (including their
+// state and metadata), those whose pins were added or removed, and the old and
+// new canary. Each of keys that moved between two unchanged servers is
+// returned as a violation; none means the change is monotonic for keys.
+//
+// ring itself is never modified. Returns an error if change does.
+//
+// Example:
+//
+//	violations, err := hashring.VerifyMonotonic(ring, keys, func(r *hashring.HashRing) error {
+//		return r.AddServer("server-4")
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	for _, v := range violations {
+//		log.Printf("%s moved from %s to %s", v.Key, v.From, v.To)
+//	}
+func VerifyMonotonic(ring *HashRing, keys []string, change func(*HashRing) error) ([]KeyMove, error) {
+	ring.mu.RLock()
+	before, after := ring.clone(), ring.clone()
+	ring.mu.RUnlock()
+
+	if err := change(after); err != nil {
+		return nil, err
+	}
+
+	changed := changedServers(before, after)
+	var violations []KeyMove
+	for _, move := range MovedKeys(before, after, keys).Moves {
+		if !changed[move.From] && !changed[move.To] {
+			violations = append(violations, move)
+		}
+	}
+
+	return violations, nil
+}
+
+// changedServers returns the servers that differ between before and after:
+// in membership, info, pins, or canary.
+func changedServers(before, after *HashRing) map[string]bool {
+	before.mu.RLock()
+	defer before.mu.RUnlock()
+	after.mu.RLock()
+	defer after.mu.RUnlock()
+
+	changed := make(map[string]bool)
+	for server, info := range before.servers {
+		if other, ok := after.servers[server]; !ok || !reflect.DeepEqual(info, other) {
+			changed[server] = true
+		}
+	}
+
+	for server := range after.servers {
+		if !before.hasServer(server) {
+			changed[server] = true
+		}
+	}
+
+	for _, keyOrPrefix := range unionKeys(before.pins, after.pins) {
+		if before.pins[keyOrPrefix] != after.pins[keyOrPrefix] {
+			changed[before.pins[keyOrPrefix]] = true
+			changed[after.pins[keyOrPrefix]] = true
+		}
+	}
+
+	if before.canary != after.canary {
+		changed[before.canary.Server] = true
+		changed[after.canary.Server] = true
+	}
+
+	delete(changed, "")
+	return changed
+}
+
+// unionKeys returns the keys in either a or b.
+func unionKeys(a, b map[string]string) []string {
+	union := maps.Clone(a)
+	if union == nil {
+		union = make(map[string]string)
+	}
+	maps.Copy(union, b)
+	return slices.Collect(maps.Keys(union))
+}
@@ -0,0 +1,65 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyMonotonic(t *testing.T) {
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	ring := New(50)
+	for _, server := range []string{"server1", "server2", "server3", "server4"} {
+		require.NoError(t, ring.AddServer(server))
+	}
+	version := ring.Version()
+
+	for name, change := range map[string]func(*HashRing) error{
+		"add":    func(r *HashRing) error { return r.AddServer("server5") },
+		"remove": func(r *HashRing) error { return r.RemoveServer("server2") },
+		"weight": func(r *HashRing) error { return r.SetServerInfo(ServerInfo{Name: "server3", Weight: 2}) },
+		"pin":    func(r *HashRing) error { return r.Pin("key1", "server1") },
+		"canary": func(r *HashRing) error { return r.SetCanary("server4", 10) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			violations, err := VerifyMonotonic(ring, keys, change)
+			require.NoError(t, err)
+			require.Empty(t, violations)
+		})
+	}
+
+	// The ring itself is left alone
+	require.Equal(t, version, ring.Version())
+	require.Equal(t, 4, ring.Size())
+
+	_, err := VerifyMonotonic(ring, keys, func(r *HashRing) error { return errors.New("boom") })
+	require.EqualError(t, err, "boom")
+}
+
+func TestVerifyMonotonicViolations(t *testing.T) {
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	// Evenly spaced placement repositions every virtual node when membership
+	// changes, so keys shuffle between servers that weren't touched
+	ring := New(50, WithPlacement(PlacementEvenlySpaced))
+	for _, server := range []string{"server1", "server2", "server3", "server4"} {
+		require.NoError(t, ring.AddServer(server))
+	}
+
+	violations, err := VerifyMonotonic(ring, keys, func(r *HashRing) error { return r.AddServer("server5") })
+	require.NoError(t, err)
+	require.NotEmpty(t, violations)
+	for _, v := range violations {
+		require.NotEqual(t, "server5", v.To)
+		require.NotEqual(t, v.From, v.To)
+	}
+}
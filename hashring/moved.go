@@ -0,0 +1,75 @@
+package hashring
+
+// KeyMove describes a key whose owner changed between two rings. From is empty
+// when the key had no owner (the ring was empty), and To is empty when it no
+// longer has one.
+type KeyMove struct {
+	Key  string `json:"key"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MovedReport summarizes which of a set of keys changed owner between two
+// rings.
+type MovedReport struct {
+	Total int       `json:"total"` // number of keys checked
+	Moves []KeyMove `json:"moves"` // keys that moved, in the order given
+}
+
+// Moved returns the number of keys that changed owner.
+func (r MovedReport) Moved() int {
+	return len(r.Moves)
+}
+
+// Fraction returns the fraction of keys that changed owner, between 0 and 1.
+func (r MovedReport) Fraction() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+
+	return float64(len(r.Moves)) / float64(r.Total)
+}
+
+// MovedFrom returns the number of moved keys each server lost.
+func (r MovedReport) MovedFrom() map[string]int {
+	counts := make(map[string]int)
+	for _, move := range r.Moves {
+		counts[move.From]++
+	}
+
+	return counts
+}
+
+// MovedTo returns the number of moved keys each server gained.
+func (r MovedReport) MovedTo() map[string]int {
+	counts := make(map[string]int)
+	for _, move := range r.Moves {
+		counts[move.To]++
+	}
+
+	return counts
+}
+
+// MovedKeys reports which of keys are owned by a different server in after
+// than in before. Pins are taken into account, but circuit breakers aren't.
+//
+// This is the per-key counterpart to DiffRanges, for when the affected keys are
+// known up front.
+//
+// Example:
+//
+//	before, _ := hashring.Restore(ring.Snapshot())
+//	ring.AddServer("server-4")
+//	report := hashring.MovedKeys(before, ring, keys)
+//	fmt.Printf("%d keys moved (%.1f%%)\n", report.Moved(), report.Fraction()*100)
+func MovedKeys(before, after *HashRing, keys []string) MovedReport {
+	report := MovedReport{Total: len(keys)}
+	for _, key := range keys {
+		from, to := before.ownerOf(key), after.ownerOf(key)
+		if from != to {
+			report.Moves = append(report.Moves, KeyMove{Key: key, From: from, To: to})
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,62 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMovedKeys(t *testing.T) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+
+	report := MovedKeys(before, ring, keys)
+	require.Equal(t, 1000, report.Total)
+	require.Zero(t, report.Moved())
+	require.Zero(t, report.Fraction())
+
+	// Adding a server only moves keys to it
+	require.NoError(t, ring.AddServer("server4"))
+	report = MovedKeys(before, ring, keys)
+	require.Positive(t, report.Moved())
+	require.InDelta(t, 0.25, report.Fraction(), 0.1)
+	require.Equal(t, map[string]int{"server4": report.Moved()}, report.MovedTo())
+
+	from := report.MovedFrom()
+	require.NotContains(t, from, "server4")
+	for _, move := range report.Moves {
+		requireServer(t, before, move.Key, move.From)
+		requireServer(t, ring, move.Key, move.To)
+	}
+
+	// Pins are taken into account
+	before, err = Restore(ring.Snapshot())
+	require.NoError(t, err)
+
+	owner, err := ring.GetServer("key999")
+	require.NoError(t, err)
+	target := "server1"
+	if owner == target {
+		target = "server2"
+	}
+
+	require.NoError(t, ring.Pin("key999", target))
+	report = MovedKeys(before, ring, keys)
+	require.Equal(t, []KeyMove{{Key: "key999", From: owner, To: target}}, report.Moves)
+
+	// Keys have no owner in an empty ring
+	report = MovedKeys(New(50), ring, []string{"key999"})
+	require.Equal(t, []KeyMove{{Key: "key999", To: target}}, report.Moves)
+	require.Zero(t, MovedKeys(New(50), New(50), nil).Fraction())
+}
@@ -0,0 +1,141 @@
+package hashring
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Adjacency is a virtual node's neighbors: the owners of the nearest virtual
+// nodes of other servers on either side of it. Both are empty when the ring
+// has a single server.
+type Adjacency struct {
+	VNode       uint64 `json:"vnode"`       // the virtual node's position
+	Predecessor string `json:"predecessor"` // the neighbor counter-clockwise, whose position starts its range
+	Successor   string `json:"successor"`   // the neighbor clockwise, which it takes keys from when it joins and hands them back to if it leaves
+}
+
+// Neighbors returns the neighbors of each of server's virtual nodes, in
+// position order. Consecutive virtual nodes of the same server are skipped
+// over, so neighbors are always other servers.
+//
+// Adjacency is a property of the ring's layout: server states, circuit
+// breakers, pins, and the canary don't affect it.
+//
+// Returns an error if the server doesn't exist.
+//
+// Example:
+//
+//	adjacency, err := ring.Neighbors("cache-2")
+//	if err != nil {
+//		return err
+//	}
+//	for _, a := range adjacency {
+//		fmt.Printf("%#08x: %s <- cache-2 -> %s\n", a.VNode, a.Predecessor, a.Successor)
+//	}
+func (h *HashRing) Neighbors(server string) ([]Adjacency, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.neighbors(server)
+}
+
+// neighbors returns the neighbors of server's virtual nodes. The caller must
+// hold h.mu.
+func (h *HashRing) neighbors(server string) ([]Adjacency, error) {
+	id, ok := h.ids[server]
+	if !ok {
+		return nil, fmt.Errorf("server %s does not exist", server)
+	}
+
+	total := len(h.entries)
+	other := func(i, step int) string {
+		for n, j := 1, i; n < total; n++ {
+			j = (j + step + total) % total
+			if h.entries[j].server != id {
+				return h.owner(j)
+			}
+		}
+		return ""
+	}
+
+	var adjacency []Adjacency
+	for i, v := range h.entries {
+		if v.server == id {
+			adjacency = append(adjacency, Adjacency{
+				VNode:       v.hash,
+				Predecessor: other(i, -1),
+				Successor:   other(i, 1),
+			})
+		}
+	}
+
+	return adjacency, nil
+}
+
+// Successor returns the servers that directly follow server's virtual nodes
+// clockwise. A virtual node owns the keys after its predecessor's position up
+// to its own, so these are the servers it takes keys from when it joins, the
+// natural sources to repair it from, and the ones that take its keys back if
+// it leaves. They're ordered by how many of server's virtual nodes they
+// follow, most first, then by name. Without virtual nodes, or with evenly
+// spaced placement, there's usually just one.
+//
+// Returns an error if the server doesn't exist. A ring with no other servers
+// has no successors.
+//
+// Example:
+//
+//	successors, err := ring.Successor("cache-2")
+//	if err != nil {
+//		return err
+//	}
+//	for _, s := range successors {
+//		repairFrom(s)
+//	}
+func (h *HashRing) Successor(server string) ([]string, error) {
+	return h.adjacent(server, func(a Adjacency) string { return a.Successor })
+}
+
+// Predecessor returns the servers that directly precede server's virtual
+// nodes counter-clockwise: those whose positions start the ranges it owns.
+// Their keys stay put when server joins or leaves; only a predecessor's own
+// move shifts where server's ranges begin. They're ordered like Successor's.
+//
+// Returns an error if the server doesn't exist. A ring with no other servers
+// has no predecessors.
+//
+// Example:
+//
+//	predecessors, err := ring.Predecessor("cache-2")
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println("cache-2's ranges start after", predecessors)
+func (h *HashRing) Predecessor(server string) ([]string, error) {
+	return h.adjacent(server, func(a Adjacency) string { return a.Predecessor })
+}
+
+// adjacent aggregates the neighbors picked from each of server's virtual
+// nodes, most frequent first.
+func (h *HashRing) adjacent(server string, pick func(Adjacency) string) ([]string, error) {
+	adjacency, err := h.Neighbors(server)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, a := range adjacency {
+		if neighbor := pick(a); neighbor != "" {
+			counts[neighbor]++
+		}
+	}
+
+	servers := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(servers, func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+
+	return servers, nil
+}
@@ -0,0 +1,108 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNeighbors(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServerWithTokens("a", []uint64{10, 11, 40}))
+	require.NoError(t, ring.AddServerWithTokens("b", []uint64{20}))
+	require.NoError(t, ring.AddServerWithTokens("c", []uint64{30, 50}))
+
+	// Ring order: 10a 11a 20b 30c 40a 50c
+	adjacency, err := ring.Neighbors("a")
+	require.NoError(t, err)
+	require.Equal(t, []Adjacency{
+		{VNode: 10, Predecessor: "c", Successor: "b"},
+		{VNode: 11, Predecessor: "c", Successor: "b"},
+		{VNode: 40, Predecessor: "c", Successor: "c"},
+	}, adjacency)
+
+	successors, err := ring.Successor("a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, successors)
+
+	predecessors, err := ring.Predecessor("a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, predecessors)
+
+	successors, err = ring.Successor("c")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, successors)
+
+	predecessors, err = ring.Predecessor("b")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, predecessors)
+
+	_, err = ring.Successor("d")
+	require.ErrorContains(t, err, "does not exist")
+
+	_, err = ring.Neighbors("d")
+	require.Error(t, err)
+
+	// A lone server has no neighbors
+	lone := New(10)
+	require.NoError(t, lone.AddServer("a"))
+	adjacency, err = lone.Neighbors("a")
+	require.NoError(t, err)
+	require.Len(t, adjacency, 10)
+	require.Empty(t, adjacency[0].Successor)
+
+	successors, err = lone.Successor("a")
+	require.NoError(t, err)
+	require.Empty(t, successors)
+}
+
+func TestSuccessorTakesOverKeys(t *testing.T) {
+	ring := New(50)
+	for i := range 5 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	successors, err := ring.Successor("server2")
+	require.NoError(t, err)
+
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	before := ring.Freeze()
+	require.NoError(t, ring.RemoveServer("server2"))
+
+	// Every key server2 held moves to one of its successors
+	for _, move := range MovedKeys(before.Thaw(), ring, keys).Moves {
+		require.Equal(t, "server2", move.From)
+		require.Contains(t, successors, move.To)
+	}
+}
+
+func TestJoinTakesKeysFromSuccessor(t *testing.T) {
+	ring := New(50)
+	for i := range 4 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	keys := make([]string, 2000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	before := ring.Freeze()
+	require.NoError(t, ring.AddServer("server4"))
+
+	successors, err := ring.Successor("server4")
+	require.NoError(t, err)
+
+	// Every key server4 takes comes from one of its successors
+	moves := MovedKeys(before.Thaw(), ring, keys).Moves
+	require.NotEmpty(t, moves)
+	for _, move := range moves {
+		require.Equal(t, "server4", move.To)
+		require.Contains(t, successors, move.From)
+	}
+}
@@ -0,0 +1,159 @@
+package hashring
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"slices"
+	"strings"
+)
+
+// nginxPointsPerWeight is how many points nginx places per unit of a server's
+// weight, as Cache::Memcached::Fast's ketama does.
+const nginxPointsPerWeight = 160
+
+// NginxRing reproduces the ring nginx builds for an upstream using
+// `hash $key consistent` (ngx_http_upstream_hash_module's ketama mode), so
+// services behind nginx can predict which server it sends a key to.
+//
+// Servers are hashed by name, which must be the address exactly as written in
+// the upstream block, e.g. "10.0.0.1:8080", "backend.internal", or
+// "unix:/tmp/backend.sock". Only the Name and Weight of each server are used;
+// weights must be whole numbers, and servers without one weigh 1. Backup and
+// down servers aren't part of nginx's ring, so they shouldn't be given.
+//
+// Like nginx, each lookup goes to the first point at or after the key's CRC-32,
+// wrapping around. nginx moves on to the next point when a server is
+// unavailable; NginxRing doesn't track availability.
+type NginxRing struct {
+	points []nginxPoint // sorted by hash, without duplicates
+}
+
+// nginxPoint is a position on an NginxRing.
+type nginxPoint struct {
+	hash   uint32
+	server string
+}
+
+// NewNginxRing builds the ring nginx would for servers.
+//
+// Each server gets 160 points per unit of weight, hashed with CRC-32 from its
+// host, its port (if any), and the previous point's hash. When two points
+// collide, nginx keeps one arbitrarily and NginxRing keeps the first server's.
+//
+// Returns an error if no servers are given, a server has no name or is given
+// twice, or a weight isn't a whole number.
+//
+// Example:
+//
+//	ring, err := hashring.NewNginxRing([]hashring.ServerInfo{
+//		{Name: "10.0.0.1:8080"},
+//		{Name: "10.0.0.2:8080", Weight: 2},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	backend, err := ring.GetServer(r.URL.Path) // hash $uri consistent
+func NewNginxRing(servers []ServerInfo) (*NginxRing, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("no servers given")
+	}
+
+	r := &NginxRing{}
+	seen := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		if server.Name == "" {
+			return nil, errors.New("server name cannot be empty")
+		}
+
+		if seen[server.Name] {
+			return nil, fmt.Errorf("server %s given twice", server.Name)
+		}
+		seen[server.Name] = true
+
+		weight := server.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		if weight != math.Trunc(weight) {
+			return nil, fmt.Errorf("server %s: nginx weights are whole numbers, got %g", server.Name, weight)
+		}
+
+		// crc32(HOST \0 PORT PREV_HASH), as nginx computes it
+		host, port := nginxHostPort(server.Name)
+		base := host + "\x00" + port
+		var prev [4]byte
+		for range int(weight) * nginxPointsPerWeight {
+			hash := crc32.ChecksumIEEE(append([]byte(base), prev[:]...))
+			r.points = append(r.points, nginxPoint{hash: hash, server: server.Name})
+			binary.LittleEndian.PutUint32(prev[:], hash)
+		}
+	}
+
+	slices.SortStableFunc(r.points, func(a, b nginxPoint) int {
+		return cmp.Compare(a.hash, b.hash)
+	})
+	r.points = slices.CompactFunc(r.points, func(a, b nginxPoint) bool {
+		return a.hash == b.hash
+	})
+
+	return r, nil
+}
+
+// nginxHostPort splits a server address into host and port the way nginx
+// does when hashing it: the port is the digits after the last colon, if
+// any, and unix sockets have none.
+func nginxHostPort(server string) (host, port string) {
+	if len(server) >= 5 && strings.EqualFold(server[:5], "unix:") {
+		return server[5:], ""
+	}
+
+	for i := len(server) - 1; i >= 0; i-- {
+		c := server[i]
+		if c == ':' {
+			return server[:i], server[i+1:]
+		}
+
+		if c < '0' || c > '9' {
+			break
+		}
+	}
+
+	return server, ""
+}
+
+// NginxBuilder returns a RouterBuilder that builds NginxRings, to compare
+// nginx's routing with a ring's using VerifyConsistency.
+//
+// Example:
+//
+//	report, err := hashring.VerifyConsistency(servers, keys, hashring.NginxBuilder(), hashring.RingBuilder(150))
+func NginxBuilder() RouterBuilder {
+	return func(servers []ServerInfo) (Router, error) {
+		return NewNginxRing(servers)
+	}
+}
+
+// GetServer returns the server nginx routes key to, where key is the value of
+// the upstream's hash expression.
+func (r *NginxRing) GetServer(key string) (string, error) {
+	return r.GetServerForHash(crc32.ChecksumIEEE([]byte(key))), nil
+}
+
+// GetServerForHash returns the server owning the first point at or after hash,
+// wrapping around to the first point.
+func (r *NginxRing) GetServerForHash(hash uint32) string {
+	i, _ := slices.BinarySearchFunc(r.points, hash, func(p nginxPoint, hash uint32) int {
+		return cmp.Compare(p.hash, hash)
+	})
+
+	return r.points[i%len(r.points)].server
+}
+
+// Size returns the number of points on the ring.
+func (r *NginxRing) Size() int {
+	return len(r.points)
+}
@@ -0,0 +1,77 @@
+package hashring
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ Router = (*NginxRing)(nil)
+
+func TestNginxHostPort(t *testing.T) {
+	tests := []struct {
+		server, host, port string
+	}{
+		{"10.0.0.1:8080", "10.0.0.1", "8080"},
+		{"backend.internal", "backend.internal", ""},
+		{"unix:/tmp/backend.sock", "/tmp/backend.sock", ""},
+		{"[::1]:80", "[::1]", "80"},
+		{"host:", "host", ""},
+	}
+
+	for _, test := range tests {
+		host, port := nginxHostPort(test.server)
+		require.Equal(t, test.host, host, test.server)
+		require.Equal(t, test.port, port, test.server)
+	}
+}
+
+func TestNewNginxRing(t *testing.T) {
+	ring, err := NewNginxRing([]ServerInfo{{Name: "10.0.0.1:8080"}, {Name: "10.0.0.2:8080", Weight: 2}})
+	require.NoError(t, err)
+	require.Equal(t, 3*160, ring.Size())
+	require.True(t, sort.SliceIsSorted(ring.points, func(i, j int) bool { return ring.points[i].hash < ring.points[j].hash }))
+
+	// The first point hashes the host, a NUL, the port, and a zero previous
+	// hash; the next chains on from it
+	first := crc32.ChecksumIEEE([]byte("10.0.0.1\x008080\x00\x00\x00\x00"))
+	second := crc32.ChecksumIEEE(append([]byte("10.0.0.1\x008080"), byte(first), byte(first>>8), byte(first>>16), byte(first>>24)))
+	require.Equal(t, "10.0.0.1:8080", ring.GetServerForHash(first))
+	require.Equal(t, "10.0.0.1:8080", ring.GetServerForHash(second))
+
+	_, err = NewNginxRing([]ServerInfo{{Name: "a", Weight: 1.5}})
+	require.ErrorContains(t, err, "whole numbers")
+
+	_, err = NewNginxRing([]ServerInfo{{Name: "a"}, {Name: "a"}})
+	require.ErrorContains(t, err, "given twice")
+
+	_, err = NewNginxRing(nil)
+	require.Error(t, err)
+}
+
+func TestNginxRingGetServer(t *testing.T) {
+	ring, err := NewNginxRing([]ServerInfo{{Name: "a:80"}, {Name: "b:80"}, {Name: "c:80"}})
+	require.NoError(t, err)
+
+	successor := func(hash uint32) string {
+		i := sort.Search(len(ring.points), func(i int) bool { return ring.points[i].hash >= hash })
+		return ring.points[i%len(ring.points)].server
+	}
+
+	for i := range 1000 {
+		key := fmt.Sprintf("/users/%d", i)
+		server, err := ring.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, successor(crc32.ChecksumIEEE([]byte(key))), server, key)
+	}
+
+	last := ring.points[len(ring.points)-1]
+	require.Equal(t, ring.points[0].server, ring.GetServerForHash(last.hash+1))
+
+	report, err := VerifyConsistency([]ServerInfo{{Name: "a:80"}, {Name: "b:80"}}, []string{"x", "y", "z"}, NginxBuilder(), NginxBuilder())
+	require.NoError(t, err)
+	require.Empty(t, report.Divergent)
+}
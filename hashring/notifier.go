@@ -0,0 +1,86 @@
+package hashring
+
+import (
+	"slices"
+	"sync"
+)
+
+// notifier delivers events to subscribers in order on a separate goroutine,
+// so publishers holding the ring's lock never wait on subscribers. It backs
+// OnMove and OnStateChange.
+type notifier[T any] struct {
+	mu       sync.Mutex
+	next     int // id of the next subscription
+	subs     []notifierSub[T]
+	queue    []T  // events waiting to be delivered, oldest first
+	draining bool // whether a goroutine is delivering the queue
+}
+
+// notifierSub is a callback registered with a notifier.
+type notifierSub[T any] struct {
+	id int
+	fn func(T)
+}
+
+// subscribe registers fn, returning a function that cancels it.
+func (n *notifier[T]) subscribe(fn func(T)) (cancel func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	id := n.next
+	n.next++
+	n.subs = append(n.subs, notifierSub[T]{id: id, fn: fn})
+
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		n.subs = slices.DeleteFunc(n.subs, func(s notifierSub[T]) bool { return s.id == id })
+	}
+}
+
+// publish queues event for delivery to the current subscribers.
+func (n *notifier[T]) publish(event T) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(n.subs) == 0 {
+		return
+	}
+
+	n.queue = append(n.queue, event)
+	if !n.draining {
+		n.draining = true
+		go n.drain()
+	}
+}
+
+// subscribed reports whether anyone is subscribed.
+func (n *notifier[T]) subscribed() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return len(n.subs) > 0
+}
+
+// drain delivers queued events until the queue is empty.
+func (n *notifier[T]) drain() {
+	for {
+		n.mu.Lock()
+		if len(n.queue) == 0 {
+			n.draining = false
+			n.mu.Unlock()
+			return
+		}
+
+		event := n.queue[0]
+		n.queue = n.queue[1:]
+
+		subs := slices.Clone(n.subs)
+		n.mu.Unlock()
+
+		for _, sub := range subs {
+			sub.fn(event)
+		}
+	}
+}
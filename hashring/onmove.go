@@ -0,0 +1,86 @@
+package hashring
+
+import "sync"
+
+// moveHub delivers planned moves to OnMove subscribers, diffing each layout
+// against the one before it. It has its own lock so topology changes, which
+// hold h.mu, can queue moves without waiting for subscribers.
+type moveHub struct {
+	mu   sync.Mutex            // guards last; taken before subs' lock
+	last layout                // layout after the last change, while anyone is subscribed
+	subs notifier[[]RangeMove] // delivers the moves
+}
+
+// OnMove registers fn to be called with the ranges whose owner changed after
+// every topology change that moves any (adding, removing, or updating servers,
+// setting or clearing a canary, and rollbacks). It returns a function that
+// cancels the subscription.
+//
+// This lets data layers start migrating ranges as soon as ownership changes,
+// rather than discovering misses lazily. Ranges routed to the canary count as
+// its own. Pins aren't considered since they apply to keys rather than
+// positions.
+//
+// Callbacks run on a separate goroutine, one change at a time and in the order
+// the changes were made, so they may safely call back into the ring. A slow
+// callback delays later notifications but never blocks the ring. With write
+// batching, consecutive membership changes are reported together (see
+// WithWriteBatching).
+//
+// Example:
+//
+//	cancel := ring.OnMove(func(moves []hashring.RangeMove) {
+//		for _, move := range moves {
+//			migrator.Schedule(move.Range, move.From, move.To)
+//		}
+//	})
+//	defer cancel()
+func (h *HashRing) OnMove(fn func(moves []RangeMove)) (cancel func()) {
+	// Lock the ring first so no change slips in between copying the layout
+	// and registering the subscriber.
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	hub := &h.moves
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if !hub.subs.subscribed() {
+		hub.last = h.copyLayout()
+	}
+
+	unsubscribe := hub.subs.subscribe(fn)
+	return func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+
+		unsubscribe()
+		if !hub.subs.subscribed() {
+			hub.last = layout{}
+		}
+	}
+}
+
+// notifyMoves queues the moves caused by a topology change for delivery to
+// subscribers. The caller must hold h.mu for writing.
+func (h *HashRing) notifyMoves() {
+	if h.stale {
+		// settle notifies once the batch's servers are placed
+		return
+	}
+
+	hub := &h.moves
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if !hub.subs.subscribed() {
+		return
+	}
+
+	current := h.copyLayout()
+	moves := diffLayouts(hub.last, current)
+	hub.last = current
+	if len(moves) > 0 {
+		hub.subs.publish(moves)
+	}
+}
@@ -0,0 +1,83 @@
+package hashring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// receive returns the next moves delivered to ch.
+func receive(t *testing.T, ch <-chan []RangeMove) []RangeMove {
+	t.Helper()
+
+	select {
+	case moves := <-ch:
+		return moves
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for moves")
+		return nil
+	}
+}
+
+func TestOnMove(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	ch := make(chan []RangeMove, 10)
+	cancel := ring.OnMove(func(moves []RangeMove) {
+		// Callbacks can use the ring
+		_, _ = ring.GetServer("key")
+		ch <- moves
+	})
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.AddServer("server3"))
+	added, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.RemoveServer("server1"))
+
+	// Moves are delivered in order
+	require.Equal(t, DiffRanges(before, added), receive(t, ch))
+	require.Equal(t, DiffRanges(added, ring), receive(t, ch))
+
+	// Changes that don't move ranges aren't delivered
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server2", Zone: "us-east-1a"}))
+	require.NoError(t, ring.Pin("key", "server2"))
+
+	// Rollbacks are
+	require.NoError(t, ring.Rollback(added.Version()))
+	moves := receive(t, ch)
+	require.NotEmpty(t, moves)
+	for _, move := range moves {
+		require.Equal(t, "server1", move.To)
+	}
+
+	cancel()
+	require.NoError(t, ring.AddServer("server4"))
+	time.Sleep(10 * time.Millisecond)
+	require.Empty(t, ch)
+}
+
+func TestOnMoveMultipleSubscribers(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+
+	first, second := make(chan []RangeMove, 1), make(chan []RangeMove, 1)
+	cancel := ring.OnMove(func(moves []RangeMove) { first <- moves })
+	defer ring.OnMove(func(moves []RangeMove) { second <- moves })()
+
+	require.NoError(t, ring.AddServer("server2"))
+	require.Equal(t, receive(t, first), receive(t, second))
+
+	// Cancelling one subscription leaves the other
+	cancel()
+	require.NoError(t, ring.RemoveServer("server2"))
+	moves := receive(t, second)
+	require.NotEmpty(t, moves)
+	for _, move := range moves {
+		require.Equal(t, RangeMove{Range: move.Range, From: "server2", To: "server1"}, move)
+	}
+}
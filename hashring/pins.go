@@ -0,0 +1,108 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// Pin routes a key, or every key starting with a prefix, to a specific server,
+// overriding hash-based placement.
+//
+// Pins are matched against the full key by prefix, so pinning "tenant42:"
+// routes "tenant42:orders" and "tenant42:users" to the same server, while
+// pinning an exact key only affects keys that start with it. When several pins
+// match, the longest one wins. This makes it possible to isolate a
+// pathological tenant on a dedicated node without changing its key format.
+//
+// Pinning changes routing, so it's recorded in the history as ChangePin and
+// bumps the ring version. Pins to a server are dropped when that server is
+// removed from the ring.
+//
+// Returns an error if keyOrPrefix is empty or the server isn't in the ring.
+//
+// Example:
+//
+//	if err := ring.Pin("tenant42:", "dedicated-1"); err != nil {
+//		log.Printf("Failed to pin tenant: %v", err)
+//	}
+func (h *HashRing) Pin(keyOrPrefix, server string) error {
+	if keyOrPrefix == "" {
+		return errors.New("pin key or prefix must not be empty")
+	}
+
+	return h.write(func() error {
+		if !h.hasServer(server) {
+			return fmt.Errorf("server %s does not exist", server)
+		}
+
+		h.pins[keyOrPrefix] = server
+		h.recordChange(ChangePin, server)
+		return nil
+	})
+}
+
+// Unpin removes a pin previously created with Pin, returning the key or prefix
+// to hash-based placement.
+//
+// Like pinning, it's recorded in the history as ChangePin.
+//
+// Returns an error if keyOrPrefix isn't pinned.
+//
+// Example:
+//
+//	_ = ring.Unpin("tenant42:")
+func (h *HashRing) Unpin(keyOrPrefix string) error {
+	return h.write(func() error {
+		server, ok := h.pins[keyOrPrefix]
+		if !ok {
+			return fmt.Errorf("%s is not pinned", keyOrPrefix)
+		}
+
+		delete(h.pins, keyOrPrefix)
+		h.recordChange(ChangePin, server)
+		return nil
+	})
+}
+
+// Pins returns all active pins as a map of key or prefix to server.
+//
+// This operation is thread-safe and returns a copy.
+//
+// Example:
+//
+//	for prefix, server := range ring.Pins() {
+//		fmt.Printf("%s* → %s\n", prefix, server)
+//	}
+func (h *HashRing) Pins() map[string]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return maps.Clone(h.pins)
+}
+
+// pinned returns the server pinned by the longest pin matching key, if any.
+// The caller must hold h.mu.
+func (h *HashRing) pinned(key string) (string, bool) {
+	match, server := h.matchPin(key)
+	return server, match != ""
+}
+
+// matchPin returns the longest pin matching key and its server, or empty
+// strings if none does. The caller must hold h.mu.
+func (h *HashRing) matchPin(key string) (match, server string) {
+	for prefix, pinned := range h.pins {
+		if len(prefix) > len(match) && strings.HasPrefix(key, prefix) {
+			match, server = prefix, pinned
+		}
+	}
+
+	return match, server
+}
+
+// unpinServer drops every pin that targets server. The caller must hold h.mu.
+func (h *HashRing) unpinServer(server string) {
+	maps.DeleteFunc(h.pins, func(_, pinned string) bool {
+		return pinned == server
+	})
+}
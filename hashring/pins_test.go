@@ -0,0 +1,114 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPin(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("dedicated"))
+
+	require.NoError(t, ring.Pin("tenant42:", "dedicated"))
+	for i := range 100 {
+		server, err := ring.GetServer(fmt.Sprintf("tenant42:key-%d", i))
+		require.NoError(t, err)
+		require.Equal(t, "dedicated", server)
+	}
+
+	// Longest matching pin wins
+	require.NoError(t, ring.Pin("tenant42:hot", "server1"))
+	server, err := ring.GetServer("tenant42:hot-key")
+	require.NoError(t, err)
+	require.Equal(t, "server1", server)
+
+	require.Equal(t, map[string]string{
+		"tenant42:":    "dedicated",
+		"tenant42:hot": "server1",
+	}, ring.Pins())
+
+	// Unpinned keys follow hash placement
+	require.NoError(t, ring.Unpin("tenant42:hot"))
+	server, err = ring.GetServer("tenant42:hot-key")
+	require.NoError(t, err)
+	require.Equal(t, "dedicated", server)
+
+	require.Error(t, ring.Unpin("tenant42:hot"), "Expected error when unpinning a missing pin")
+}
+
+func TestPinErrors(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+
+	require.Error(t, ring.Pin("", "server1"), "Expected error for empty pin")
+	require.Error(t, ring.Pin("key", "server2"), "Expected error for unknown server")
+}
+
+func TestPinVersionAndChecksum(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	version, checksum := ring.Version(), ring.Checksum()
+	require.NoError(t, ring.Pin("key", "server2"))
+	require.Greater(t, ring.Version(), version)
+	require.NotEqual(t, checksum, ring.Checksum())
+
+	require.NoError(t, ring.Unpin("key"))
+	require.Equal(t, checksum, ring.Checksum())
+}
+
+func TestPinRollback(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	// Pins are recorded in the history, so their versions can be rolled
+	// back to
+	before := ring.Version()
+	require.NoError(t, ring.Pin("tenant:", "server2"))
+	pinned := ring.Version()
+	require.NoError(t, ring.AddServer("server3"))
+
+	history := ring.History()
+	require.Equal(t, ChangePin, history[len(history)-2].Type)
+	require.Equal(t, "server2", history[len(history)-2].Server)
+
+	require.NoError(t, ring.Rollback(pinned))
+	require.Equal(t, []string{"server1", "server2"}, ring.GetServers())
+	require.Equal(t, map[string]string{"tenant:": "server2"}, ring.Pins())
+
+	require.NoError(t, ring.Rollback(before))
+	require.NoError(t, ring.Unpin("tenant:"))
+	require.Equal(t, ChangePin, ring.History()[len(ring.History())-1].Type)
+}
+
+func TestPinDroppedOnRemove(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.Pin("tenant:", "server2"))
+
+	require.NoError(t, ring.RemoveServer("server2"))
+	require.Empty(t, ring.Pins())
+
+	server, err := ring.GetServer("tenant:1")
+	require.NoError(t, err)
+	require.Equal(t, "server1", server)
+}
+
+func TestPinSnapshot(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.Pin("tenant:", "server2"))
+
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.Equal(t, ring.Pins(), restored.Pins())
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+}
@@ -0,0 +1,114 @@
+package hashring
+
+import (
+	"cmp"
+	"encoding/binary"
+	"math/bits"
+	"slices"
+)
+
+// Placement determines where a server's virtual nodes are placed on the ring.
+type Placement string
+
+const (
+	// PlacementHashed places each virtual node at the hash of the server name
+	// and vnode index. Adding or removing a server only moves the keys adjacent
+	// to its virtual nodes, but the share of the ring each server owns varies
+	// with how its hashes happen to fall.
+	PlacementHashed Placement = "hashed"
+
+	// PlacementEvenlySpaced divides the ring into equal segments, one per
+	// virtual node, and interleaves the servers (ordered by name) so that every
+	// server owns exactly the same share of the ring. There's no placement
+	// variance at all, but every server's positions depend on the number of
+	// servers, so adding or removing one moves roughly half of all keys rather
+	// than 1/n of them.
+	PlacementEvenlySpaced Placement = "evenly-spaced"
+
+	// PlacementIterated places a server's first virtual node at the hash of
+	// its name and vnode index, like PlacementHashed, and each subsequent one
+	// at the hash of the previous position. Only the first position depends
+	// on the name, so servers with similar names (server-1, server-2, ...)
+	// don't get correlated positions when the hash function mixes similar
+	// inputs poorly. Adding or removing a server moves keys as with
+	// PlacementHashed.
+	PlacementIterated Placement = "iterated"
+)
+
+// WithPlacement sets how virtual nodes are placed on the ring. The default is
+// PlacementHashed.
+//
+// Example:
+//
+//	ring := hashring.New(16, hashring.WithPlacement(hashring.PlacementEvenlySpaced))
+func WithPlacement(p Placement) Option {
+	return func(h *HashRing) {
+		h.placement = p
+	}
+}
+
+// evenlySpaced reports whether virtual nodes are placed at evenly spaced
+// positions rather than hashed ones.
+func (h *HashRing) evenlySpaced() bool {
+	return h.placement == PlacementEvenlySpaced
+}
+
+// positions returns where a server's virtual nodes are placed before any
+// collisions are resolved. The caller must hold h.mu.
+func (h *HashRing) positions(server string, vnodes int) []uint64 {
+	positions := make([]uint64, vnodes)
+	buf := make([]byte, 0, len(server)+2*maxIntLen)
+	for i := range vnodes {
+		if i > 0 && h.placement == PlacementIterated {
+			buf = binary.BigEndian.AppendUint64(buf[:0], positions[i-1])
+		} else {
+			buf = vnodeKey(buf, server, i, 0)
+		}
+
+		positions[i] = h.hashBytes(buf)
+	}
+
+	return positions
+}
+
+// placeEvenly rebuilds the ring with every server's virtual nodes at evenly
+// spaced positions. Servers (in name order, at slots 0 to n-1) are interleaved
+// by placing virtual node i of the server at slot s with c virtual nodes at
+// fraction (i + s/n) / c of the way around, then spacing all virtual nodes
+// evenly in that order. Each server owns exactly c/total of the ring, and with
+// equal weights virtual node i of slot s lands at (i*n + s) / total. The
+// caller must hold h.mu.
+func (h *HashRing) placeEvenly() {
+	servers := h.serverList()
+	n := uint64(len(servers))
+
+	type slot struct {
+		server int32
+		index  int32
+		key    uint64 // i*n + s
+		count  uint64 // the server's virtual nodes
+	}
+
+	var slots []slot
+	for s, server := range servers {
+		count := uint64(h.vnodesFor(h.servers[server]))
+		for i := range count {
+			slots = append(slots, slot{server: h.ids[server], index: int32(i), key: i*n + uint64(s), count: count})
+		}
+	}
+
+	// Order by (i*n + s) / (n*c), comparing the cross products exactly
+	slices.SortStableFunc(slots, func(a, b slot) int {
+		return cmp.Compare(a.key*b.count, b.key*a.count)
+	})
+
+	total := uint64(len(slots))
+	h.entries = h.entries[:0]
+	for k, slot := range slots {
+		// k * 2^32 / total, without overflowing
+		hi, lo := bits.Mul64(uint64(k), maxHash+1)
+		pos, _ := bits.Div64(hi, lo, total)
+
+		h.entries = append(h.entries, vnode{hash: pos, server: slot.server, index: slot.index})
+	}
+}
@@ -0,0 +1,116 @@
+package hashring
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvenlySpacedPlacement(t *testing.T) {
+	ring := New(4, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server1"))
+
+	// Servers interleave at equal intervals, in name order
+	step := uint64(1 << 29)
+	var expected []uint64
+	for i := range 8 {
+		expected = append(expected, uint64(i)*step)
+	}
+	require.Equal(t, expected, positions(ring))
+
+	for i, pos := range expected {
+		require.Equal(t, fmt.Sprintf("server%d", i%2+1), ownerAt(ring, pos))
+	}
+
+	// Placement doesn't depend on insertion order
+	other := New(4, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, other.AddServer("server1"))
+	require.NoError(t, other.AddServer("server2"))
+	require.Equal(t, ring.Checksum(), other.Checksum())
+	require.Equal(t, positions(ring), positions(other))
+}
+
+func TestEvenlySpacedOwnership(t *testing.T) {
+	ring := New(10, WithPlacement(PlacementEvenlySpaced))
+	for i := range 3 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	// Every server owns the same share of the ring (to within rounding)
+	owned := make(map[string]uint64)
+	keys := positions(ring)
+	for i, pos := range keys {
+		prev := keys[(i+len(keys)-1)%len(keys)]
+		owned[ownerAt(ring, pos)] += (pos + maxHash + 1 - prev) % (maxHash + 1)
+	}
+
+	share := uint64(maxHash+1) / 3
+	for server, size := range owned {
+		require.InDelta(t, share, size, 10, "%s owns %d", server, size)
+	}
+
+	// Removing a server rebalances the rest evenly
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.Len(t, positions(ring), 20)
+	for _, pos := range positions(ring) {
+		require.NotEqual(t, "server1", ownerAt(ring, pos))
+	}
+}
+
+func TestPlacementChecksumAndSnapshot(t *testing.T) {
+	hashed := New(10)
+	require.NoError(t, hashed.AddServer("server1"))
+
+	even := New(10, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, even.AddServer("server1"))
+	require.NotEqual(t, hashed.Checksum(), even.Checksum())
+
+	data, err := json.Marshal(even.Snapshot())
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"placement":"evenly-spaced"`)
+
+	var snap Snapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+
+	restored, err := Restore(snap)
+	require.NoError(t, err)
+	require.Equal(t, positions(even), positions(restored))
+}
+
+func TestIteratedPlacement(t *testing.T) {
+	ring := New(150, WithPlacement(PlacementIterated))
+	hashed := New(150)
+	for i := range 20 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server-%d", i)))
+		require.NoError(t, hashed.AddServer(fmt.Sprintf("server-%d", i)))
+	}
+
+	// The first virtual node is placed as with hashed placement, and each
+	// later one at the hash of the previous position
+	positions := ring.positions("server-0", 3)
+	require.Equal(t, hashed.positions("server-0", 1)[0], positions[0])
+	require.Equal(t, ring.hashBytes(binary.BigEndian.AppendUint64(nil, positions[0])), positions[1])
+	require.Equal(t, ring.hashBytes(binary.BigEndian.AppendUint64(nil, positions[1])), positions[2])
+
+	// Similarly named servers get more even shares than with hashed placement
+	require.Less(t, ring.OwnershipCV(), hashed.OwnershipCV())
+
+	// Adding a server only moves keys to it
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.AddServer("server-20"))
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	report := MovedKeys(before, ring, keys)
+	for _, move := range report.Moves {
+		require.Equal(t, "server-20", move.To)
+	}
+}
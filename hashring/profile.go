@@ -0,0 +1,159 @@
+package hashring
+
+import (
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WithLookupProfiling records a sample of every rate-th GetServer call
+// (including GetServerFor and the methods built on them), keeping the most
+// recent size samples for LookupProfile. This captures regressions in the
+// routing path from production, where attaching a profiler isn't practical.
+//
+// Allocation counts come from runtime/metrics, which counts allocations for
+// the whole process and in batches, so individual samples are approximate and
+// should be read in aggregate. Unsampled lookups pay only for a counter check.
+//
+// Example:
+//
+//	ring := hashring.New(150, hashring.WithLookupProfiling(1000, 512))
+func WithLookupProfiling(rate, size int) Option {
+	return func(h *HashRing) {
+		if rate > 0 && size > 0 {
+			h.profile = &lookupProfiler{rate: uint64(rate), samples: make([]LookupSample, 0, size)}
+		}
+	}
+}
+
+// LookupSample is a profiled lookup.
+type LookupSample struct {
+	Time     time.Time     `json:"time"`     // when the lookup started
+	Duration time.Duration `json:"duration"` // how long it took
+	Allocs   uint64        `json:"allocs"`   // heap objects allocated while it ran
+	Bytes    uint64        `json:"bytes"`    // heap bytes allocated while it ran
+}
+
+// LookupProfile is the samples recorded by WithLookupProfiling.
+type LookupProfile struct {
+	Rate    int            `json:"rate"`    // one in Rate lookups is sampled, 0 if profiling is off
+	Samples []LookupSample `json:"samples"` // oldest first
+}
+
+// Percentile returns the duration that fraction q (between 0 and 1) of the
+// samples took at most, e.g. 0.99 for the p99. Returns 0 if there are no
+// samples.
+func (p LookupProfile) Percentile(q float64) time.Duration {
+	if len(p.Samples) == 0 {
+		return 0
+	}
+
+	durations := make([]time.Duration, len(p.Samples))
+	for i, s := range p.Samples {
+		durations[i] = s.Duration
+	}
+	slices.Sort(durations)
+
+	i := int(q*float64(len(durations))+0.5) - 1
+	return durations[min(max(i, 0), len(durations)-1)]
+}
+
+// MeanAllocs returns the mean heap objects allocated per sampled lookup.
+func (p LookupProfile) MeanAllocs() float64 {
+	if len(p.Samples) == 0 {
+		return 0
+	}
+
+	var total uint64
+	for _, s := range p.Samples {
+		total += s.Allocs
+	}
+
+	return float64(total) / float64(len(p.Samples))
+}
+
+// LookupProfile returns the samples recorded so far, oldest first. The profile
+// has a zero Rate and no samples unless the ring was created with
+// WithLookupProfiling.
+//
+// Example:
+//
+//	profile := ring.LookupProfile()
+//	fmt.Printf("p99 %s, %.1f allocs/op\n", profile.Percentile(0.99), profile.MeanAllocs())
+func (h *HashRing) LookupProfile() LookupProfile {
+	if h.profile == nil {
+		return LookupProfile{}
+	}
+
+	return h.profile.snapshot()
+}
+
+// lookupProfiler is a bounded buffer of lookup samples. It has its own lock so
+// lookups holding h.mu for reading can record into it.
+type lookupProfiler struct {
+	rate uint64
+
+	mu      sync.Mutex
+	samples []LookupSample // a circular buffer once full
+	next    int            // where the next sample goes once full
+}
+
+// probe is a lookup being sampled.
+type probe struct {
+	start  time.Time
+	before [2]metrics.Sample
+}
+
+// allocMetrics are the runtime metrics a probe reads.
+var allocMetrics = [2]string{"/gc/heap/allocs:objects", "/gc/heap/allocs:bytes"}
+
+// sampled reports whether the n-th lookup should be profiled.
+func (p *lookupProfiler) sampled(n uint64) bool {
+	return p != nil && n%p.rate == 0
+}
+
+// start begins sampling a lookup.
+func (p *lookupProfiler) start() *probe {
+	pr := &probe{}
+	pr.before[0].Name, pr.before[1].Name = allocMetrics[0], allocMetrics[1]
+	metrics.Read(pr.before[:])
+	pr.start = time.Now()
+	return pr
+}
+
+// finish records the lookup sampled by pr.
+func (p *lookupProfiler) finish(pr *probe) {
+	elapsed := time.Since(pr.start)
+	after := [2]metrics.Sample{{Name: allocMetrics[0]}, {Name: allocMetrics[1]}}
+	metrics.Read(after[:])
+
+	sample := LookupSample{
+		Time:     pr.start,
+		Duration: elapsed,
+		Allocs:   after[0].Value.Uint64() - pr.before[0].Value.Uint64(),
+		Bytes:    after[1].Value.Uint64() - pr.before[1].Value.Uint64(),
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.samples) < cap(p.samples) {
+		p.samples = append(p.samples, sample)
+		return
+	}
+
+	p.samples[p.next] = sample
+	p.next = (p.next + 1) % len(p.samples)
+}
+
+// snapshot returns the profile, oldest sample first.
+func (p *lookupProfiler) snapshot() LookupProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	samples := make([]LookupSample, 0, len(p.samples))
+	samples = append(samples, p.samples[p.next:]...)
+	samples = append(samples, p.samples[:p.next]...)
+	return LookupProfile{Rate: int(p.rate), Samples: samples}
+}
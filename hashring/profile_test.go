@@ -0,0 +1,62 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupProfiling(t *testing.T) {
+	require.Equal(t, LookupProfile{}, New(50).LookupProfile())
+
+	ring := New(50, WithLookupProfiling(10, 4))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	start := time.Now()
+	for i := range 25 {
+		_, err := ring.GetServer(fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+	}
+
+	// Every 10th lookup is sampled
+	profile := ring.LookupProfile()
+	require.Equal(t, 10, profile.Rate)
+	require.Len(t, profile.Samples, 2)
+	for _, s := range profile.Samples {
+		require.False(t, s.Time.Before(start))
+		require.Positive(t, s.Duration)
+	}
+
+	// Only the most recent samples are kept, oldest first
+	for i := range 50 {
+		_, _ = ring.GetServer(fmt.Sprintf("key%d", i))
+	}
+
+	profile = ring.LookupProfile()
+	require.Len(t, profile.Samples, 4)
+	for i := 1; i < len(profile.Samples); i++ {
+		require.False(t, profile.Samples[i].Time.Before(profile.Samples[i-1].Time))
+	}
+
+	require.Positive(t, profile.Percentile(0.99))
+	require.GreaterOrEqual(t, profile.Percentile(0.99), profile.Percentile(0.5))
+}
+
+func TestLookupProfilePercentile(t *testing.T) {
+	var profile LookupProfile
+	require.Zero(t, profile.Percentile(0.5))
+	require.Zero(t, profile.MeanAllocs())
+
+	for i := 1; i <= 100; i++ {
+		profile.Samples = append(profile.Samples, LookupSample{Duration: time.Duration(i), Allocs: uint64(i % 2)})
+	}
+
+	require.Equal(t, time.Duration(50), profile.Percentile(0.5))
+	require.Equal(t, time.Duration(99), profile.Percentile(0.99))
+	require.Equal(t, time.Duration(100), profile.Percentile(1))
+	require.Equal(t, time.Duration(1), profile.Percentile(0))
+	require.InDelta(t, 0.5, profile.MeanAllocs(), 1e-9)
+}
@@ -0,0 +1,315 @@
+package hashring
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// maxHash is the largest hash position on the ring.
+const maxHash = math.MaxUint32
+
+// HashRange is an inclusive range of positions on the ring.
+//
+// Ranges never wrap: ownership that crosses the top of the hash space is
+// reported as two ranges, one ending at the largest position and one starting
+// at zero.
+type HashRange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+// Contains reports whether hash lies within the range.
+func (r HashRange) Contains(hash uint64) bool {
+	return hash >= r.Start && hash <= r.End
+}
+
+// RangeMove describes a range of positions whose owner changed between two
+// rings. From is empty when the range had no owner (the ring was empty), and To
+// is empty when it no longer has one.
+type RangeMove struct {
+	Range HashRange `json:"range"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+}
+
+// Hash returns the position of key on the ring, after applying the ring's key
+// extractor and hash tags. Pins aren't considered.
+//
+// Example:
+//
+//	hash := ring.Hash("user:12345")
+//	for _, move := range moves {
+//		if move.Range.Contains(hash) {
+//			// user:12345 is being migrated
+//		}
+//	}
+func (h *HashRing) Hash(key string) uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.hashKey(h.routingKey(key))
+}
+
+// DiffRanges returns the ranges of positions whose owner differs between
+// before and after, ordered by position. Adjacent ranges moving between the
+// same servers are merged.
+//
+// This is the data that has to be migrated when a ring changes from before to
+// after. Ranges routed to a canary (see SetCanary) are owned by it. Pins aren't
+// considered since they apply to keys rather than positions.
+//
+// Example:
+//
+//	before, _ := hashring.Restore(ring.Snapshot())
+//	ring.AddServer("db-shard-5")
+//	for _, move := range hashring.DiffRanges(before, ring) {
+//		log.Printf("copy %d-%d from %s to %s", move.Range.Start, move.Range.End, move.From, move.To)
+//	}
+func DiffRanges(before, after *HashRing) []RangeMove {
+	return diffLayouts(before.layout(), after.layout())
+}
+
+// diffLayouts returns the ranges whose owner differs between b and a.
+func diffLayouts(b, a layout) []RangeMove {
+	bounds := make([]uint64, 0, len(b.positions)+len(a.positions))
+	bounds = append(bounds, b.positions...)
+	bounds = append(bounds, a.positions...)
+	for _, l := range []layout{b, a} {
+		for _, r := range l.canaried {
+			bounds = append(bounds, r.End)
+			if r.Start > 0 {
+				bounds = append(bounds, r.Start-1)
+			}
+		}
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	var moves []RangeMove
+	add := func(start, end uint64, from, to string) {
+		if from == to {
+			return
+		}
+
+		if n := len(moves); n > 0 {
+			last := &moves[n-1]
+			if last.Range.End+1 == start && last.From == from && last.To == to {
+				last.Range.End = end
+				return
+			}
+		}
+
+		moves = append(moves, RangeMove{Range: HashRange{Start: start, End: end}, From: from, To: to})
+	}
+
+	if len(bounds) == 0 {
+		return nil
+	}
+
+	// Each segment ends at a boundary and is owned by the first virtual node at
+	// or after it, or the canary throughout. The segment past the last
+	// boundary wraps to the first virtual node.
+	var start uint64
+	for _, bound := range bounds {
+		add(start, bound, b.owner(bound), a.owner(bound))
+		start = bound + 1
+	}
+
+	if last := bounds[len(bounds)-1]; last < maxHash {
+		add(last+1, maxHash, b.owner(maxHash), a.owner(maxHash))
+	}
+
+	return moves
+}
+
+// layout is an immutable copy of the ring's virtual node positions and the
+// ranges routed to its canary.
+type layout struct {
+	positions []uint64 // sorted, unique
+	owners    []string // owner of each position
+
+	canary   string
+	canaried []HashRange // sorted by start
+}
+
+// layout copies the ring's virtual node positions.
+func (h *HashRing) layout() layout {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.copyLayout()
+}
+
+// copyLayout copies the ring's virtual node positions and canary ranges. The
+// caller must hold h.mu.
+func (h *HashRing) copyLayout() layout {
+	positions := make([]uint64, len(h.entries))
+	owners := make([]string, len(h.entries))
+	for i, v := range h.entries {
+		positions[i] = v.hash
+		owners[i] = h.names[v.server]
+	}
+
+	return layout{positions: positions, owners: owners, canary: h.canary.Server, canaried: h.canaryRanges()}
+}
+
+// owner returns the owner of hash, or an empty string if there are no virtual
+// nodes.
+func (l layout) owner(hash uint64) string {
+	if len(l.positions) == 0 {
+		return ""
+	}
+
+	if i, _ := slices.BinarySearchFunc(l.canaried, hash, func(r HashRange, hash uint64) int {
+		return cmp.Compare(r.End, hash)
+	}); i < len(l.canaried) && l.canaried[i].Contains(hash) {
+		return l.canary
+	}
+
+	idx, _ := slices.BinarySearch(l.positions, hash)
+	if idx == len(l.positions) {
+		idx = 0
+	}
+
+	return l.owners[idx]
+}
+
+// OwnershipShare returns the fraction of the hash space, between 0 and 1, that
+// each server owns. Since keys hash uniformly, this is the share of load each
+// server should expect, computed without sampling keys. Pins aren't
+// considered.
+//
+// Example:
+//
+//	for server, share := range ring.OwnershipShare() {
+//		fmt.Printf("%s: %.1f%%\n", server, share*100)
+//	}
+func (h *HashRing) OwnershipShare() map[string]float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.shares()
+}
+
+// shares returns the fraction of the hash space each server owns. The caller
+// must hold h.mu.
+func (h *HashRing) shares() map[string]float64 {
+	shares := make(map[string]float64, len(h.servers))
+	if len(h.entries) == 0 {
+		return shares
+	}
+
+	// Each virtual node owns the positions after the previous one, and the
+	// first also owns those past the last.
+	prev := h.entries[len(h.entries)-1].hash
+	for i, v := range h.entries {
+		shares[h.owner(i)] += float64((v.hash - prev) & maxHash)
+		prev = v.hash
+	}
+
+	if len(h.entries) == 1 {
+		// The only virtual node owns everything
+		shares[h.owner(0)] = float64(maxHash) + 1
+	}
+
+	for server := range shares {
+		shares[server] /= float64(maxHash) + 1
+	}
+
+	return shares
+}
+
+// OwnershipCV returns the coefficient of variation, in percent, of the servers'
+// ownership shares (see OwnershipShare). This is the distribution CV that
+// AnalyzePerformance converges to for large key sets, without sampling keys.
+func (h *HashRing) OwnershipCV() float64 {
+	shares := h.OwnershipShare()
+	if len(shares) == 0 {
+		return 0
+	}
+
+	mean := 1 / float64(len(shares))
+	var variance float64
+	for _, share := range shares {
+		diff := share - mean
+		variance += diff * diff
+	}
+
+	variance /= float64(len(shares))
+	return math.Sqrt(variance) / mean * 100
+}
+
+// OwnedRange is a range of positions and the server that owns it.
+type OwnedRange struct {
+	Range  HashRange `json:"range"`
+	Server string    `json:"server"`
+}
+
+// ServersInRange returns the servers owning any position from start to end,
+// inclusive, in the order their positions come up. See RangeOwners for the
+// sub-ranges each owns.
+//
+// Example:
+//
+//	// the servers a scan of the first quarter of the ring has to visit
+//	servers := ring.ServersInRange(0, 1<<30-1)
+func (h *HashRing) ServersInRange(start, end uint64) []string {
+	var servers []string
+	seen := make(map[string]bool)
+	for _, owned := range h.RangeOwners(start, end) {
+		if !seen[owned.Server] {
+			seen[owned.Server] = true
+			servers = append(servers, owned.Server)
+		}
+	}
+
+	return servers
+}
+
+// RangeOwners splits the positions from start to end, inclusive, into the
+// ranges owned by each server, in position order from start, so backup and
+// scan jobs covering part of the hash space know which server to read each
+// piece from. Adjacent ranges with the same owner are merged.
+//
+// When start is after end, the range wraps around the top of the hash space:
+// it covers start to the largest position, then zero to end, and is split
+// there, since ranges never wrap. Positions past the largest are treated as
+// the largest. Pins aren't considered, and an empty ring owns nothing.
+//
+// Example:
+//
+//	for _, owned := range ring.RangeOwners(start, end) {
+//		go scan(owned.Server, owned.Range)
+//	}
+func (h *HashRing) RangeOwners(start, end uint64) []OwnedRange {
+	start, end = min(start, maxHash), min(end, maxHash)
+
+	var ranges []OwnedRange
+	for r, server := range h.OwnershipRanges() {
+		ranges = append(ranges, OwnedRange{Range: r, Server: server})
+	}
+
+	if start > end {
+		return append(clipRanges(ranges, start, maxHash), clipRanges(ranges, 0, end)...)
+	}
+
+	return clipRanges(ranges, start, end)
+}
+
+// clipRanges returns the parts of ranges, which are in position order, that
+// lie between start and end, inclusive.
+func clipRanges(ranges []OwnedRange, start, end uint64) []OwnedRange {
+	var clipped []OwnedRange
+	for _, owned := range ranges {
+		if owned.Range.End < start || owned.Range.Start > end {
+			continue
+		}
+
+		owned.Range.Start = max(owned.Range.Start, start)
+		owned.Range.End = min(owned.Range.End, end)
+		clipped = append(clipped, owned)
+	}
+
+	return clipped
+}
@@ -0,0 +1,171 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashRangeContains(t *testing.T) {
+	r := HashRange{Start: 10, End: 20}
+	require.True(t, r.Contains(10))
+	require.True(t, r.Contains(20))
+	require.False(t, r.Contains(9))
+	require.False(t, r.Contains(21))
+}
+
+func TestDiffRanges(t *testing.T) {
+	before := New(50)
+	require.NoError(t, before.AddServer("server1"))
+	require.NoError(t, before.AddServer("server2"))
+	require.NoError(t, before.AddServer("server3"))
+
+	after, err := Restore(before.Snapshot())
+	require.NoError(t, err)
+	require.Empty(t, DiffRanges(before, after), "identical rings should have no moves")
+
+	require.NoError(t, after.AddServer("server4"))
+	moves := DiffRanges(before, after)
+	require.NotEmpty(t, moves)
+
+	for i, move := range moves {
+		require.Equal(t, "server4", move.To, "keys should only move to the new server")
+		require.NotEqual(t, "server4", move.From)
+		require.LessOrEqual(t, move.Range.Start, move.Range.End)
+
+		if i > 0 {
+			require.Greater(t, move.Range.Start, moves[i-1].Range.End, "moves should be ordered and disjoint")
+		}
+	}
+
+	// Every key that changed owner lies in exactly the move describing it
+	for i := range 5000 {
+		key := fmt.Sprintf("key:%d", i)
+		from, _ := before.GetServer(key)
+		to, _ := after.GetServer(key)
+
+		hash := after.Hash(key)
+		var found *RangeMove
+		for _, move := range moves {
+			if move.Range.Contains(hash) {
+				found = &move
+				break
+			}
+		}
+
+		if from == to {
+			require.Nil(t, found, "unmoved key %s shouldn't be in a moved range", key)
+			continue
+		}
+
+		require.NotNil(t, found, "moved key %s should be in a moved range", key)
+		require.Equal(t, from, found.From)
+		require.Equal(t, to, found.To)
+	}
+
+	// The reverse diff undoes the change
+	reverse := DiffRanges(after, before)
+	require.Len(t, reverse, len(moves))
+	for i := range moves {
+		require.Equal(t, moves[i].Range, reverse[i].Range)
+		require.Equal(t, moves[i].From, reverse[i].To)
+		require.Equal(t, moves[i].To, reverse[i].From)
+	}
+}
+
+func TestDiffRangesEmpty(t *testing.T) {
+	empty := New(10)
+	require.Empty(t, DiffRanges(empty, New(10)))
+
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+
+	moves := DiffRanges(empty, ring)
+	require.Equal(t, []RangeMove{{Range: HashRange{Start: 0, End: maxHash}, To: "server1"}}, moves)
+
+	moves = DiffRanges(ring, empty)
+	require.Equal(t, []RangeMove{{Range: HashRange{Start: 0, End: maxHash}, From: "server1"}}, moves)
+}
+
+func TestOwnershipShare(t *testing.T) {
+	ring := New(50)
+	require.Empty(t, ring.OwnershipShare())
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.Equal(t, map[string]float64{"server1": 1}, ring.OwnershipShare())
+
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	var total float64
+	shares := ring.OwnershipShare()
+	for _, server := range ring.GetServers() {
+		require.Positive(t, shares[server])
+		total += shares[server]
+	}
+	require.InDelta(t, 1, total, 1e-9)
+
+	// Shares track how keys are actually distributed
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	for server, count := range ring.GetDistribution(keys) {
+		require.InDelta(t, shares[server], float64(count)/float64(len(keys)), 0.03)
+	}
+
+	ring = New(10, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	shares = ring.OwnershipShare()
+	require.InDelta(t, 0.5, shares["server1"], 1e-9)
+	require.InDelta(t, 0.5, shares["server2"], 1e-9)
+}
+
+func TestOwnershipCV(t *testing.T) {
+	require.Zero(t, New(10).OwnershipCV())
+
+	ring := New(10, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.InDelta(t, 0, ring.OwnershipCV(), 0.001)
+}
+
+func TestRangeOwners(t *testing.T) {
+	ring := New(10)
+	require.Nil(t, ring.RangeOwners(0, maxHash))
+	require.Nil(t, ring.ServersInRange(0, maxHash))
+
+	require.NoError(t, ring.AddServerWithTokens("a", []uint64{100, 300}))
+	require.NoError(t, ring.AddServerWithTokens("b", []uint64{200}))
+
+	require.Equal(t, []OwnedRange{
+		{HashRange{50, 100}, "a"},
+		{HashRange{101, 200}, "b"},
+		{HashRange{201, 250}, "a"},
+	}, ring.RangeOwners(50, 250))
+	require.Equal(t, []string{"a", "b"}, ring.ServersInRange(50, 250))
+
+	require.Equal(t, []OwnedRange{{HashRange{150, 160}, "b"}}, ring.RangeOwners(150, 160))
+	require.Equal(t, []string{"b"}, ring.ServersInRange(150, 150))
+
+	// Wrapping ranges are split at the top of the hash space
+	require.Equal(t, []OwnedRange{
+		{HashRange{4_000_000_000, maxHash}, "a"},
+		{HashRange{0, 100}, "a"},
+		{HashRange{101, 150}, "b"},
+	}, ring.RangeOwners(4_000_000_000, 150))
+	require.Equal(t, []string{"a", "b"}, ring.ServersInRange(4_000_000_000, 150))
+
+	// Positions past the top are clamped
+	require.Equal(t, []OwnedRange{{HashRange{250, maxHash}, "a"}}, ring.RangeOwners(250, 1<<40))
+
+	// The whole hash space is covered exactly once
+	var covered uint64
+	for _, owned := range ring.RangeOwners(0, maxHash) {
+		covered += owned.Range.End - owned.Range.Start + 1
+	}
+	require.Equal(t, uint64(maxHash)+1, covered)
+}
@@ -0,0 +1,188 @@
+package hashring
+
+import (
+	"errors"
+	"math"
+)
+
+// maxRebalanceSteps bounds the number of adjustments Rebalance makes per
+// server.
+const maxRebalanceSteps = 50
+
+// Rebalance adjusts the number of virtual nodes of each server to bring its
+// ownership share (see OwnershipShare) within tolerance of its target, the
+// server's weight as a fraction of the total weight. Tolerance is relative to
+// the target, so 0.05 accepts shares within 5% of it.
+//
+// Hashed placement can leave a small ring noticeably skewed by unlucky hashes.
+// Rebalance corrects this by adding virtual nodes to servers that own too
+// little and removing them from servers that own too much, keeping each
+// server's count between half and double its weighted count. A server's
+// first virtual nodes stay put, so only the ranges around the virtual nodes
+// added or removed move. The counts are stored in each server's VNodes, so
+// they survive snapshots and can be rolled back.
+//
+// It returns the ranges that moved, which are empty if the ring was already
+// within tolerance. If the tolerance can't be reached, the closest layout
+// found is kept.
+//
+// Returns an error if tolerance isn't positive or the ring has servers with
+// explicit tokens, whose shares can't be adjusted. Rings with evenly spaced
+// placement are always balanced, so there's nothing to do.
+//
+// Example:
+//
+//	moves, err := ring.Rebalance(0.05)
+//	for _, move := range moves {
+//		log.Printf("%d-%d moved from %s to %s", move.Range.Start, move.Range.End, move.From, move.To)
+//	}
+func (h *HashRing) Rebalance(tolerance float64) ([]RangeMove, error) {
+	var moves []RangeMove
+	err := h.write(func() error {
+		var err error
+		moves, err = h.rebalance(tolerance)
+		return err
+	})
+
+	return moves, err
+}
+
+// rebalance adjusts virtual node counts until every share is within tolerance
+// of its target. The caller must hold h.mu.
+func (h *HashRing) rebalance(tolerance float64) ([]RangeMove, error) {
+	if tolerance <= 0 {
+		return nil, errors.New("rebalance tolerance must be positive")
+	}
+
+	for _, info := range h.servers {
+		if len(info.Tokens) > 0 {
+			return nil, errors.New("rings with explicit tokens can't be rebalanced")
+		}
+	}
+
+	if h.evenlySpaced() || len(h.servers) < 2 {
+		return nil, nil
+	}
+
+	before := h.copyLayout()
+	original := h.serverInfos()
+	targets := h.targets()
+
+	best, bestDeviation := original, math.Inf(1)
+	stuck := make(map[string]bool) // servers at their bounds
+	for range maxRebalanceSteps * len(h.servers) {
+		deviation, worst := h.deviation(targets, stuck)
+		if deviation < bestDeviation {
+			best, bestDeviation = h.serverInfos(), deviation
+		}
+
+		if deviation <= tolerance || worst == "" {
+			break
+		}
+
+		info := h.servers[worst]
+		next := h.adjustedVNodes(info, h.shares()[worst], targets[worst])
+		if h.maxVNodes > 0 {
+			next = min(next, h.maxVNodes-len(h.entries)+h.vnodesFor(info))
+		}
+
+		if next == h.vnodesFor(info) {
+			stuck[worst] = true
+			continue
+		}
+
+		info.VNodes = next
+		if err := h.updateServer(info); err != nil {
+			stuck[worst] = true
+			continue
+		}
+		clear(stuck)
+	}
+
+	for _, info := range best {
+		if err := h.updateServer(info); err != nil {
+			return nil, err
+		}
+	}
+
+	if infosEqual(original, best) {
+		return nil, nil
+	}
+
+	h.recordChange(ChangeRebalance, "")
+	return diffLayouts(before, h.copyLayout()), nil
+}
+
+// targets returns each server's target share: its weight as a fraction of the
+// total weight. The caller must hold h.mu.
+func (h *HashRing) targets() map[string]float64 {
+	var total float64
+	for _, info := range h.servers {
+		total += weightOf(info)
+	}
+
+	targets := make(map[string]float64, len(h.servers))
+	for server, info := range h.servers {
+		targets[server] = weightOf(info) / total
+	}
+
+	return targets
+}
+
+// adjustedVNodes returns the virtual node count that moves a server's share
+// towards target, kept between half and double its weighted count. The
+// caller must hold h.mu.
+func (h *HashRing) adjustedVNodes(info ServerInfo, share, target float64) int {
+	count := h.vnodesFor(info)
+	base := h.weightedVNodes(info)
+
+	// Move halfway to the count that would hit the target if share scaled
+	// linearly, but by at least one virtual node
+	scaled := float64(count) * target / max(share, 1e-9)
+	next := int(math.Round((float64(count) + scaled) / 2))
+	switch {
+	case next == count && share > target:
+		next--
+	case next == count:
+		next++
+	}
+
+	return min(max(next, max(1, base/2)), base*2)
+}
+
+// deviation returns the largest deviation of any server's share from its
+// target, relative to the target, and the server not in skip with the largest
+// deviation. The caller must hold h.mu.
+func (h *HashRing) deviation(targets map[string]float64, skip map[string]bool) (float64, string) {
+	var (
+		largest, worstDeviation float64
+		worst                   string
+	)
+
+	shares := h.shares()
+	for _, server := range h.serverList() {
+		deviation := math.Abs(shares[server]-targets[server]) / targets[server]
+		largest = max(largest, deviation)
+		if !skip[server] && deviation > worstDeviation {
+			worst, worstDeviation = server, deviation
+		}
+	}
+
+	return largest, worst
+}
+
+// infosEqual reports whether a and b, which must be sorted by name, describe
+// the same placement.
+func infosEqual(a, b []ServerInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].VNodes != b[i].VNodes {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,138 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireBalanced checks every server's share is within tolerance of its
+// weighted target.
+func requireBalanced(t *testing.T, ring *HashRing, tolerance float64) {
+	t.Helper()
+
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	deviation, _ := ring.deviation(ring.targets(), nil)
+	require.LessOrEqual(t, deviation, tolerance)
+}
+
+func TestRebalance(t *testing.T) {
+	ring := New(50)
+	for i := range 5 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server-%d", i)))
+	}
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+
+	moves, err := ring.Rebalance(0.05)
+	require.NoError(t, err)
+	require.NotEmpty(t, moves)
+	require.Equal(t, DiffRanges(before, ring), moves)
+	requireBalanced(t, ring, 0.05)
+
+	history := ring.History()
+	require.Equal(t, ChangeRebalance, history[len(history)-1].Type)
+
+	// Counts stay within bounds and survive snapshots
+	for _, info := range ring.Snapshot().Servers {
+		if info.VNodes != 0 {
+			require.GreaterOrEqual(t, info.VNodes, 25)
+			require.LessOrEqual(t, info.VNodes, 100)
+		}
+	}
+
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.Equal(t, positions(ring), positions(restored))
+
+	// Balanced rings are left alone
+	version := ring.Version()
+	moves, err = ring.Rebalance(0.05)
+	require.NoError(t, err)
+	require.Empty(t, moves)
+	require.Equal(t, version, ring.Version())
+
+	// ...and rebalances can be rolled back
+	require.NoError(t, ring.Rollback(before.Version()))
+	require.Equal(t, positions(before), positions(ring))
+}
+
+func TestRebalanceWeighted(t *testing.T) {
+	ring := New(40)
+	require.NoError(t, ring.AddServer("server-1"))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server-2", Weight: 2}))
+	require.NoError(t, ring.AddServer("server-3"))
+
+	_, err := ring.Rebalance(0.05)
+	require.NoError(t, err)
+	requireBalanced(t, ring, 0.05)
+
+	shares := ring.OwnershipShare()
+	require.InDelta(t, 0.5, shares["server-2"], 0.5*0.05)
+}
+
+func TestRebalanceUnreachable(t *testing.T) {
+	ring := New(4)
+	require.NoError(t, ring.AddServer("server-1"))
+	require.NoError(t, ring.AddServer("server-2"))
+	require.NoError(t, ring.AddServer("server-3"))
+
+	ring.mu.RLock()
+	initial, _ := ring.deviation(ring.targets(), nil)
+	ring.mu.RUnlock()
+
+	// The closest layout found is kept
+	_, err := ring.Rebalance(1e-9)
+	require.NoError(t, err)
+
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	final, _ := ring.deviation(ring.targets(), nil)
+	require.LessOrEqual(t, final, initial)
+	for _, info := range ring.servers {
+		require.LessOrEqual(t, ring.vnodesFor(info), 8)
+		require.GreaterOrEqual(t, ring.vnodesFor(info), 2)
+	}
+}
+
+func TestRebalanceNoop(t *testing.T) {
+	_, err := New(10).Rebalance(0)
+	require.ErrorContains(t, err, "must be positive")
+
+	moves, err := New(10).Rebalance(0.05)
+	require.NoError(t, err)
+	require.Empty(t, moves)
+
+	ring := New(10, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, ring.AddServer("server-1"))
+	require.NoError(t, ring.AddServer("server-2"))
+	moves, err = ring.Rebalance(0.05)
+	require.NoError(t, err)
+	require.Empty(t, moves)
+
+	ring = New(10)
+	require.NoError(t, ring.AddServer("server-1"))
+	require.NoError(t, ring.AddServerWithTokens("server-2", []uint64{1, 2}))
+	_, err = ring.Rebalance(0.05)
+	require.ErrorContains(t, err, "explicit tokens")
+}
+
+func TestVNodesOverride(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server-1", VNodes: 3}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server-2", Weight: 2, VNodes: 7}))
+	require.Equal(t, map[string]int{"server-1": 3, "server-2": 7}, vnodeCounts(ring))
+
+	err := ring.AddServerWithInfo(ServerInfo{Name: "server-3", VNodes: -1})
+	require.ErrorContains(t, err, "invalid virtual node count")
+
+	checksum := ring.Checksum()
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server-1", VNodes: 4}))
+	require.Equal(t, 4, vnodeCounts(ring)["server-1"])
+	require.NotEqual(t, checksum, ring.Checksum())
+}
@@ -0,0 +1,107 @@
+package hashring
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// RenameServer changes a server's name, e.g. after a host rename or IP
+// change, without moving any keys.
+//
+// The server keeps its metadata and the exact positions of its virtual nodes,
+// which become its explicit tokens (see AddServerWithTokens) since hashed
+// positions are derived from the name. As with any server with tokens, its
+// Weight and VNodes no longer apply. Pins targeting the server follow it.
+// OnMove subscribers see each of the server's ranges move to the new name.
+//
+// Returns an error if old doesn't exist, new is invalid or clashes with
+// another server, or the ring uses evenly spaced placement, where positions
+// depend on the order of server names.
+//
+// Example:
+//
+//	err := ring.RenameServer("10.0.0.12:6379", "10.0.1.40:6379")
+func (h *HashRing) RenameServer(old, new string) error {
+	return h.write(func() error {
+		info, ok := h.servers[old]
+		if !ok {
+			return fmt.Errorf("server %s does not exist", old)
+		}
+
+		info.Name = new
+		if err := h.handOver(old, info); err != nil {
+			return err
+		}
+
+		h.recordChange(ChangeRename, new)
+		return nil
+	})
+}
+
+// handOver replaces server old with the server described by info, which takes
+// over old's virtual nodes at their exact positions as its tokens. Pins to old
+// are moved to the new server. The caller must hold h.mu.
+func (h *HashRing) handOver(old string, info ServerInfo) error {
+	if !h.hasServer(old) {
+		return fmt.Errorf("server %s does not exist", old)
+	}
+
+	if h.evenlySpaced() {
+		return errors.New("servers can't take over another's virtual nodes with evenly spaced placement")
+	}
+
+	if err := h.validateName(info.Name, old); err != nil {
+		return err
+	}
+
+	id := h.ids[old]
+	info = info.clone()
+	var owned []vnode
+	for _, v := range h.entries {
+		if v.server == id {
+			owned = append(owned, v)
+		}
+	}
+
+	// Listed by index, so restoring the server keeps its virtual nodes' indices
+	slices.SortFunc(owned, func(a, b vnode) int { return cmp.Compare(a.index, b.index) })
+	info.Tokens = info.Tokens[:0]
+	for _, v := range owned {
+		info.Tokens = append(info.Tokens, v.hash)
+	}
+
+	// the tokens fix the server's virtual node count
+	info.Weight, info.VNodes = 0, 0
+
+	delete(h.servers, old)
+	delete(h.normalized, h.normalize(old))
+	delete(h.ids, old)
+	h.breakers.reset(old)
+
+	h.servers[info.Name] = info
+	h.normalized[h.normalize(info.Name)] = info.Name
+	h.ids[info.Name] = id
+	h.names[id] = info.Name
+	h.indexServers()
+
+	for keyOrPrefix, server := range h.pins {
+		if server == old {
+			h.pins[keyOrPrefix] = info.Name
+		}
+	}
+
+	if h.canary.Server == old {
+		h.canary.Server = info.Name
+	}
+
+	if prior, ok := h.downFrom[old]; ok {
+		delete(h.downFrom, old)
+		if info.State == StateDown {
+			h.downFrom[info.Name] = prior
+		}
+	}
+
+	return nil
+}
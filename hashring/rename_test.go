@@ -0,0 +1,72 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameServer(t *testing.T) {
+	ring := New(100)
+	for i := range 4 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server1", Zone: "us-east-1a", Weight: 2}))
+	require.NoError(t, ring.Pin("tenant:acme:", "server1"))
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.RenameServer("server1", "cache-1"))
+
+	require.Equal(t, []string{"cache-1", "server0", "server2", "server3"}, ring.GetServers())
+	require.Equal(t, ChangeRename, ring.History()[len(ring.History())-1].Type)
+
+	info, ok := ring.GetServerInfo("cache-1")
+	require.True(t, ok)
+	require.Equal(t, "us-east-1a", info.Zone)
+	require.Len(t, info.Tokens, 200)
+	require.Equal(t, map[string]string{"tenant:acme:": "cache-1"}, ring.Pins())
+
+	// No key changes position, only the name of its owner
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	report := MovedKeys(before, ring, keys)
+	for _, move := range report.Moves {
+		require.Equal(t, "server1", move.From)
+		require.Equal(t, "cache-1", move.To)
+	}
+
+	// The positions survive other servers being re-placed
+	require.NoError(t, ring.RemoveServer("server0"))
+	require.NoError(t, ring.AddServer("server0"))
+	report = MovedKeys(before, ring, keys)
+	for _, move := range report.Moves {
+		require.Equal(t, "cache-1", move.To)
+	}
+
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+}
+
+func TestRenameServerErrors(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("Cache-1"))
+	require.NoError(t, ring.AddServer("cache-2"))
+
+	require.Error(t, ring.RenameServer("cache-3", "cache-4"))
+	require.Error(t, ring.RenameServer("Cache-1", ""))
+	require.Error(t, ring.RenameServer("Cache-1", "CACHE-2"))
+
+	// Changing only the case of a name is allowed
+	require.NoError(t, ring.RenameServer("Cache-1", "cache-1"))
+	require.Equal(t, []string{"cache-1", "cache-2"}, ring.GetServers())
+
+	even := New(10, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, even.AddServer("server1"))
+	require.Error(t, even.RenameServer("server1", "server2"))
+}
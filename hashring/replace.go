@@ -0,0 +1,36 @@
+package hashring
+
+import "slices"
+
+// ReplaceServer swaps server old for a new server that inherits the exact
+// positions of old's virtual nodes, so no key moves to or from any other
+// server. This suits hardware swap-outs, where removing old and adding new
+// would shuffle about 2/n of the keyspace.
+//
+// Unlike RenameServer, the new server doesn't keep old's metadata: it's added
+// with only its name, old's positions as its tokens (see AddServerWithTokens),
+// and old's tenants, since keys would otherwise cross the tenant boundary. It
+// also keeps old's state, so replacing a draining, joining, or down server
+// doesn't start routing to the new one; a down server's replacement returns
+// to old's prior state on MarkUp. Use SetServerInfo to give it a zone or
+// tags. Pins targeting old are moved to the new server, and old's circuit
+// breaker is discarded.
+//
+// Returns an error if old doesn't exist, new is invalid or clashes with
+// another server, or the ring uses evenly spaced placement.
+//
+// Example:
+//
+//	err := ring.ReplaceServer("cache-3", "cache-3-replacement")
+func (h *HashRing) ReplaceServer(old, new string) error {
+	return h.write(func() error {
+		prior := h.servers[old]
+		info := ServerInfo{Name: new, Tenants: slices.Clone(prior.Tenants), State: prior.State}
+		if err := h.handOver(old, info); err != nil {
+			return err
+		}
+
+		h.recordChange(ChangeReplace, new)
+		return nil
+	})
+}
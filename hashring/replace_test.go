@@ -0,0 +1,142 @@
+package hashring
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceServer(t *testing.T) {
+	ring := New(100)
+	for i := range 4 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server2", Zone: "us-east-1a", Tags: []string{"ssd"}}))
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.ReplaceServer("server2", "server4"))
+	require.Equal(t, ChangeReplace, ring.History()[len(ring.History())-1].Type)
+
+	// The replacement starts with only its name and the old positions
+	info, ok := ring.GetServerInfo("server4")
+	require.True(t, ok)
+	require.Empty(t, info.Zone)
+	require.Empty(t, info.Tags)
+	require.Len(t, info.Tokens, 100)
+
+	_, ok = ring.GetServerInfo("server2")
+	require.False(t, ok)
+
+	// Only server2's keys move, and all of them go to server4
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	report := MovedKeys(before, ring, keys)
+	require.Equal(t, before.GetDistribution(keys)["server2"], report.Moved())
+	for _, move := range report.Moves {
+		require.Equal(t, "server2", move.From)
+		require.Equal(t, "server4", move.To)
+	}
+
+	// Compared with removing and adding, which moves keys between other servers
+	churned, err := Restore(before.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, churned.RemoveServer("server2"))
+	require.NoError(t, churned.AddServer("server4"))
+	require.Greater(t, MovedKeys(before, churned, keys).Moved(), report.Moved())
+}
+
+func TestReplaceDedicatedServer(t *testing.T) {
+	ring := New(50)
+	for i := range 3 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "acme-1", Tenants: []string{"acme:"}}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "acme-2", Tenants: []string{"acme:"}}))
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.ReplaceServer("acme-1", "acme-3"))
+
+	info, ok := ring.GetServerInfo("acme-3")
+	require.True(t, ok)
+	require.Equal(t, []string{"acme:"}, info.Tenants)
+
+	// Only acme-1's keys move, all to acme-3, and no other tenant's keys
+	// reach it
+	keys := make([]string, 0, 4000)
+	for i := range 2000 {
+		keys = append(keys, fmt.Sprintf("acme:user%d", i), fmt.Sprintf("user%d", i))
+	}
+
+	report := MovedKeys(before, ring, keys)
+	require.Equal(t, before.GetDistribution(keys)["acme-1"], report.Moved())
+	for _, move := range report.Moves {
+		require.Equal(t, "acme-1", move.From)
+		require.Equal(t, "acme-3", move.To)
+		require.True(t, strings.HasPrefix(move.Key, "acme:"), move.Key)
+	}
+}
+
+func TestReplaceServerKeepsState(t *testing.T) {
+	for _, state := range []ServerState{StateJoining, StateDraining, StateDown} {
+		t.Run(string(state), func(t *testing.T) {
+			ring := New(10)
+			require.NoError(t, ring.AddServer("server1"))
+			require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server2", State: state}))
+
+			before, err := Restore(ring.Snapshot())
+			require.NoError(t, err)
+			require.NoError(t, ring.ReplaceServer("server2", "server3"))
+
+			info, ok := ring.GetServerInfo("server3")
+			require.True(t, ok)
+			require.Equal(t, state, info.State)
+
+			// Reads and writes route as they did before, with server3 in
+			// server2's place
+			for i := range 200 {
+				key := fmt.Sprintf("key%d", i)
+				for _, access := range []Access{AccessRead, AccessWrite} {
+					want, err := before.GetServerFor(key, access)
+					require.NoError(t, err)
+					if want == "server2" {
+						want = "server3"
+					}
+
+					got, err := ring.GetServerFor(key, access)
+					require.NoError(t, err)
+					require.Equal(t, want, got)
+				}
+			}
+		})
+	}
+
+	// A down server's replacement comes back up in the state it went down from
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.SetState("server2", StateDraining))
+	require.NoError(t, ring.MarkDown("server2"))
+
+	require.NoError(t, ring.ReplaceServer("server2", "server3"))
+	require.NoError(t, ring.MarkUp("server3"))
+	info, _ := ring.GetServerInfo("server3")
+	require.Equal(t, StateDraining, info.State)
+}
+
+func TestReplaceServerErrors(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	require.Error(t, ring.ReplaceServer("server3", "server4"))
+	require.Error(t, ring.ReplaceServer("server1", "server2"))
+	require.Error(t, ring.ReplaceServer("server1", " "))
+	require.Equal(t, uint64(2), ring.Version())
+}
@@ -0,0 +1,92 @@
+package hashring
+
+import (
+	"errors"
+	"time"
+)
+
+// GetReplicas returns up to n distinct servers for the given key, in the order
+// they should be tried.
+//
+// The first server is the key's owner (the same server GetServer returns). The
+// rest are the next distinct servers found walking clockwise around the ring,
+// which makes them natural candidates for replicas and failover. Fewer than n
+// servers are returned when the ring doesn't have enough. Only servers
+// eligible for the key are returned (see ServerInfo.Tenants).
+//
+// Servers whose circuit breaker is open (see ReportFailure) are moved after the
+// others, so they're only returned when there aren't enough healthy servers.
+//
+// Returns an error if the hash ring is empty or no server is eligible for the
+// key.
+//
+// Example:
+//
+//	replicas, err := ring.GetReplicas("user:12345", 3)
+//	if err != nil {
+//		return err
+//	}
+//	for _, server := range replicas {
+//		if err := write(server); err == nil {
+//			break
+//		}
+//	}
+func (h *HashRing) GetReplicas(key string, n int) ([]string, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.getReplicas(key, n, AccessRead)
+}
+
+// getReplicas finds up to n distinct servers for key for the given access. The
+// caller must hold h.mu.
+func (h *HashRing) getReplicas(key string, n int, access Access) ([]string, error) {
+	if len(h.entries) == 0 {
+		return nil, errors.New("hash ring is empty")
+	}
+
+	h.lookups.Add(1)
+	if h.observed() {
+		defer h.observeLookup(time.Now())
+	}
+
+	n = min(n, len(h.servers))
+	accept := h.eligible(key, access)
+	replicas := make([]string, 0, n)
+	var tripped []string
+	seen := make(map[string]bool, n)
+
+	add := func(server string) {
+		seen[server] = true
+		switch {
+		case accept != nil && !accept(server):
+		case h.allow(server):
+			replicas = append(replicas, server)
+		default:
+			tripped = append(tripped, server)
+		}
+	}
+
+	if server, ok := h.override(key); ok && n > 0 {
+		add(server)
+	}
+
+	h.walk(h.hashKey(h.routingKey(key)), func(server string) bool {
+		if len(replicas) == n || len(seen) == len(h.servers) {
+			return false
+		}
+
+		if !seen[server] {
+			add(server)
+		}
+
+		return true
+	})
+
+	replicas = append(replicas, tripped...)
+	if n > 0 && len(replicas) == 0 {
+		return nil, errNoEligibleServer(key)
+	}
+
+	return replicas[:min(n, len(replicas))], nil
+}
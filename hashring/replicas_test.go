@@ -0,0 +1,72 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReplicas(t *testing.T) {
+	ring := New(150)
+
+	_, err := ring.GetReplicas("key1", 2)
+	require.Error(t, err, "Expected error for empty ring")
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+	require.NoError(t, ring.AddServer("server4"))
+
+	for i := range 100 {
+		key := fmt.Sprintf("key-%d", i)
+		owner, err := ring.GetServer(key)
+		require.NoError(t, err)
+
+		replicas, err := ring.GetReplicas(key, 3)
+		require.NoError(t, err)
+		require.Len(t, replicas, 3)
+		require.Equal(t, owner, replicas[0], "First replica should be the owner")
+		require.NotEqual(t, replicas[0], replicas[1])
+		require.NotEqual(t, replicas[1], replicas[2])
+		require.NotEqual(t, replicas[0], replicas[2])
+	}
+
+	// Asking for more replicas than servers returns every server
+	replicas, err := ring.GetReplicas("key1", 10)
+	require.NoError(t, err)
+	require.ElementsMatch(t, ring.GetServers(), replicas)
+
+	replicas, err = ring.GetReplicas("key1", 0)
+	require.NoError(t, err)
+	require.Empty(t, replicas)
+}
+
+func TestGetReplicasStable(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	replicas, err := ring.GetReplicas("key1", 2)
+	require.NoError(t, err)
+
+	// Removing the owner promotes the first successor
+	require.NoError(t, ring.RemoveServer(replicas[0]))
+	owner, err := ring.GetServer("key1")
+	require.NoError(t, err)
+	require.Equal(t, replicas[1], owner)
+}
+
+func TestGetReplicasPinned(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+	require.NoError(t, ring.Pin("key1", "server3"))
+
+	replicas, err := ring.GetReplicas("key1", 3)
+	require.NoError(t, err)
+	require.Equal(t, "server3", replicas[0])
+	require.ElementsMatch(t, []string{"server1", "server2", "server3"}, replicas)
+}
@@ -0,0 +1,208 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// RingSet manages independent hash rings by namespace.
+//
+// Different workloads often need their own ring, e.g. "cache", "sessions", and
+// "search", each with its own membership and virtual node count. A RingSet
+// gives them a single lookup API, lets one discovery feed drive all of them,
+// and aggregates their metrics.
+//
+// The set is thread-safe and supports concurrent operations.
+type RingSet struct {
+	mu    sync.RWMutex
+	rings map[string]*HashRing
+}
+
+// NewRingSet creates an empty ring set.
+//
+// Example:
+//
+//	set := hashring.NewRingSet()
+//	set.Add("cache", hashring.New(150))
+//	set.Add("sessions", hashring.New(100))
+//	set.AddServer("node-1") // joins every ring
+//	server, _ := set.GetServer("cache", "user:42")
+func NewRingSet() *RingSet {
+	return &RingSet{rings: make(map[string]*HashRing)}
+}
+
+// Add registers a ring under the given namespace.
+//
+// Returns an error if the namespace is already registered.
+func (s *RingSet) Add(namespace string, ring *HashRing) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rings[namespace]; ok {
+		return fmt.Errorf("namespace %s already exists", namespace)
+	}
+
+	s.rings[namespace] = ring
+	return nil
+}
+
+// Remove unregisters the ring for the given namespace.
+//
+// Returns an error if the namespace isn't registered.
+func (s *RingSet) Remove(namespace string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rings[namespace]; !ok {
+		return fmt.Errorf("namespace %s does not exist", namespace)
+	}
+
+	delete(s.rings, namespace)
+	return nil
+}
+
+// Ring returns the ring registered for the given namespace.
+func (s *RingSet) Ring(namespace string) (*HashRing, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ring, ok := s.rings[namespace]
+	return ring, ok
+}
+
+// Namespaces returns the sorted list of registered namespaces.
+func (s *RingSet) Namespaces() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	namespaces := make([]string, 0, len(s.rings))
+	for namespace := range s.rings {
+		namespaces = append(namespaces, namespace)
+	}
+
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// GetServer returns the server responsible for key in the given namespace.
+//
+// Returns an error if the namespace isn't registered or its ring is empty.
+//
+// Example:
+//
+//	server, err := set.GetServer("sessions", sessionID)
+func (s *RingSet) GetServer(namespace, key string) (string, error) {
+	ring, ok := s.Ring(namespace)
+	if !ok {
+		return "", fmt.Errorf("namespace %s does not exist", namespace)
+	}
+
+	return ring.GetServer(key)
+}
+
+// AddServer adds a server to the rings for the given namespaces, or to every
+// ring when no namespaces are given.
+//
+// This is the entry point for a shared discovery feed: rings that already
+// contain the server are left untouched, so the same event can be delivered
+// more than once. Returns an error if any namespace isn't registered, or if
+// the server can't be added to a ring (e.g. it's full); the other rings still
+// get the server.
+//
+// Example:
+//
+//	for event := range discovery.Events() {
+//		if event.Up {
+//			set.AddServer(event.Addr)
+//		} else {
+//			set.RemoveServer(event.Addr)
+//		}
+//	}
+func (s *RingSet) AddServer(server string, namespaces ...string) error {
+	return s.each(namespaces, func(ring *HashRing) error {
+		return ring.write(func() error {
+			if ring.hasServer(server) {
+				return nil
+			}
+
+			if err := ring.addServer(ServerInfo{Name: server}); err != nil {
+				return err
+			}
+
+			ring.recordChange(ChangeAdd, server)
+			return nil
+		})
+	})
+}
+
+// RemoveServer removes a server from the rings for the given namespaces, or
+// from every ring when no namespaces are given.
+//
+// Rings that don't contain the server are left untouched. Each ring's
+// transition hooks run as they do for HashRing.RemoveServer. Returns an error
+// if any namespace isn't registered, or if a hook vetoes the removal from a
+// ring; the other rings still lose the server.
+func (s *RingSet) RemoveServer(server string, namespaces ...string) error {
+	return s.each(namespaces, func(ring *HashRing) error {
+		if _, ok := ring.GetServerInfo(server); !ok {
+			return nil
+		}
+
+		return ring.RemoveServer(server)
+	})
+}
+
+// AnalyzePerformance runs AnalyzePerformance against every ring in the set
+// using the same keys, returning the metrics by namespace.
+//
+// Example:
+//
+//	for namespace, metrics := range set.AnalyzePerformance(keys) {
+//		fmt.Printf("%s: CV %.2f%%\n", namespace, metrics.DistributionCV)
+//	}
+func (s *RingSet) AnalyzePerformance(keys []string) map[string]PerformanceMetrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	metrics := make(map[string]PerformanceMetrics, len(s.rings))
+	for namespace, ring := range s.rings {
+		metrics[namespace] = ring.AnalyzePerformance(keys)
+	}
+
+	return metrics
+}
+
+// each calls fn for the rings in namespaces, or every ring if none are given,
+// returning the errors fn returned. All namespaces are validated before any
+// ring is touched.
+func (s *RingSet) each(namespaces []string, fn func(*HashRing) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(namespaces) == 0 {
+		namespaces = slices.Sorted(maps.Keys(s.rings))
+	}
+
+	var errs []error
+	for _, namespace := range namespaces {
+		if _, ok := s.rings[namespace]; !ok {
+			errs = append(errs, fmt.Errorf("namespace %s does not exist", namespace))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, namespace := range namespaces {
+		if err := fn(s.rings[namespace]); err != nil {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
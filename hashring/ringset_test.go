@@ -0,0 +1,116 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingSet(t *testing.T) {
+	set := NewRingSet()
+	cache := New(150)
+	sessions := New(100)
+
+	require.NoError(t, set.Add("cache", cache))
+	require.NoError(t, set.Add("sessions", sessions))
+	require.Error(t, set.Add("cache", New(10)), "Expected error for duplicate namespace")
+	require.Equal(t, []string{"cache", "sessions"}, set.Namespaces())
+
+	ring, ok := set.Ring("cache")
+	require.True(t, ok)
+	require.Same(t, cache, ring)
+
+	_, err := set.GetServer("cache", "key1")
+	require.Error(t, err, "Expected error for empty ring")
+
+	_, err = set.GetServer("search", "key1")
+	require.Error(t, err, "Expected error for unknown namespace")
+
+	require.NoError(t, set.Remove("sessions"))
+	require.Error(t, set.Remove("sessions"))
+	require.Equal(t, []string{"cache"}, set.Namespaces())
+}
+
+func TestRingSetSharedDiscovery(t *testing.T) {
+	set := NewRingSet()
+	cache, sessions, search := New(150), New(150), New(150)
+	require.NoError(t, set.Add("cache", cache))
+	require.NoError(t, set.Add("sessions", sessions))
+	require.NoError(t, set.Add("search", search))
+
+	// Broadcast to every ring
+	require.NoError(t, set.AddServer("node-1"))
+	require.NoError(t, set.AddServer("node-2"))
+	require.NoError(t, set.AddServer("node-2"), "Duplicate events should be ignored")
+
+	for _, ring := range []*HashRing{cache, sessions, search} {
+		require.Equal(t, []string{"node-1", "node-2"}, ring.GetServers())
+		require.Equal(t, uint64(2), ring.Version())
+	}
+
+	// Targeted namespaces
+	require.NoError(t, set.AddServer("search-1", "search"))
+	require.Equal(t, []string{"node-1", "node-2", "search-1"}, search.GetServers())
+	require.Equal(t, []string{"node-1", "node-2"}, cache.GetServers())
+
+	require.Error(t, set.AddServer("node-3", "cache", "unknown"))
+	require.Equal(t, []string{"node-1", "node-2"}, cache.GetServers(), "Nothing should change on error")
+
+	require.NoError(t, set.RemoveServer("node-1"))
+	require.NoError(t, set.RemoveServer("node-1"))
+	require.Equal(t, []string{"node-2"}, cache.GetServers())
+	require.Equal(t, []string{"node-2", "search-1"}, search.GetServers())
+
+	server, err := set.GetServer("cache", "key1")
+	require.NoError(t, err)
+	require.Equal(t, "node-2", server)
+}
+
+func TestRingSetHooksAndBatching(t *testing.T) {
+	set := NewRingSet()
+	cache, sessions := New(50), New(50, WithWriteBatching(time.Millisecond))
+	require.NoError(t, set.Add("cache", cache))
+	require.NoError(t, set.Add("sessions", sessions))
+
+	require.NoError(t, set.AddServer("node-1"))
+	require.NoError(t, set.AddServer("node-2"))
+	require.Equal(t, []string{"node-1", "node-2"}, sessions.GetServers())
+
+	// A hook on one ring vetoes the removal there, but not from the others
+	cache.AddTransitionHook(func(server string, from, to ServerState) error {
+		if to == StateRemoved {
+			return errors.New("still holds data")
+		}
+		return nil
+	})
+
+	err := set.RemoveServer("node-1")
+	require.ErrorContains(t, err, "namespace cache")
+	require.ErrorContains(t, err, "still holds data")
+	require.Equal(t, []string{"node-1", "node-2"}, cache.GetServers())
+	require.Equal(t, []string{"node-2"}, sessions.GetServers())
+	require.Equal(t, uint64(3), sessions.Version())
+}
+
+func TestRingSetAnalyzePerformance(t *testing.T) {
+	set := NewRingSet()
+	require.NoError(t, set.Add("cache", New(150)))
+	require.NoError(t, set.Add("sessions", New(150)))
+	require.NoError(t, set.AddServer("node-1"))
+	require.NoError(t, set.AddServer("node-2"))
+	require.NoError(t, set.AddServer("node-3", "cache"))
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	metrics := set.AnalyzePerformance(keys)
+	require.Len(t, metrics, 2)
+	require.Equal(t, 3, metrics["cache"].Servers)
+	require.Equal(t, 2, metrics["sessions"].Servers)
+	require.Equal(t, 1000, metrics["sessions"].TotalKeys)
+}
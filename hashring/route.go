@@ -0,0 +1,144 @@
+package hashring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxAttempts is the number of servers Route tries by default.
+const DefaultMaxAttempts = 3
+
+// RouteOption configures a call to Route.
+type RouteOption func(*routeConfig)
+
+type routeConfig struct {
+	attempts  int
+	backoff   func(attempt int) time.Duration
+	retryable func(error) bool
+}
+
+// WithMaxAttempts sets the maximum number of servers tried: the key's owner
+// and then its successors clockwise around the ring.
+func WithMaxAttempts(n int) RouteOption {
+	return func(c *routeConfig) {
+		c.attempts = max(n, 1)
+	}
+}
+
+// WithBackoff sets how long to wait before each retry. The function is called
+// with the number of the attempt that just failed, starting at 1.
+func WithBackoff(backoff func(attempt int) time.Duration) RouteOption {
+	return func(c *routeConfig) {
+		c.backoff = backoff
+	}
+}
+
+// WithRetryIf sets which errors are worth retrying on another server. Errors
+// it rejects (e.g. validation errors that every server would return) are
+// returned immediately. By default every error is retried.
+func WithRetryIf(retryable func(error) bool) RouteOption {
+	return func(c *routeConfig) {
+		c.retryable = retryable
+	}
+}
+
+// ExponentialBackoff returns a backoff that starts at base and doubles after
+// every attempt, up to limit.
+func ExponentialBackoff(base, limit time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for range attempt - 1 {
+			if d >= limit/2 {
+				return limit
+			}
+			d *= 2
+		}
+
+		return min(d, limit)
+	}
+}
+
+// Route calls fn with the server responsible for key, failing over to the
+// next servers clockwise around the ring when it returns a retryable error.
+//
+// Every attempt is reported to the server's circuit breaker (see
+// ReportFailure): retryable errors count as failures and a nil error as a
+// success. Errors that aren't retryable don't say anything about the server's
+// health, so they aren't reported.
+//
+// Returns nil once fn succeeds. Otherwise it returns the first non-retryable
+// error, or the errors from every attempt, joined.
+//
+// Example:
+//
+//	err := ring.Route("user:12345", func(server string) error {
+//		return client.Put(server, "user:12345", data)
+//	},
+//		hashring.WithMaxAttempts(2),
+//		hashring.WithBackoff(hashring.ExponentialBackoff(10*time.Millisecond, time.Second)),
+//		hashring.WithRetryIf(isTransient),
+//	)
+func (h *HashRing) Route(key string, fn func(server string) error, opts ...RouteOption) error {
+	return h.RouteContext(context.Background(), key, fn, opts...)
+}
+
+// RouteContext is like Route, but stops retrying once ctx is done. Context
+// errors returned by fn are never retried.
+func (h *HashRing) RouteContext(ctx context.Context, key string, fn func(server string) error, opts ...RouteOption) error {
+	cfg := routeConfig{
+		attempts:  DefaultMaxAttempts,
+		retryable: func(error) bool { return true },
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	servers, err := h.GetReplicas(key, cfg.attempts)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, server := range servers {
+		if i > 0 && cfg.backoff != nil {
+			if err := sleep(ctx, cfg.backoff(i)); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+		}
+
+		err := fn(server)
+		if err == nil {
+			h.ReportSuccess(server)
+			return nil
+		}
+
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || !cfg.retryable(err) {
+			return err
+		}
+
+		h.ReportFailure(server)
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
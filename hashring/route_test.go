@@ -0,0 +1,115 @@
+package hashring
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute(t *testing.T) {
+	ring := New(50, WithCircuitBreaker(1, time.Minute))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+	require.NoError(t, ring.AddServer("server4"))
+
+	replicas, err := ring.GetReplicas("key", 4)
+	require.NoError(t, err)
+
+	// Succeeds on the owner
+	var tried []string
+	require.NoError(t, ring.Route("key", func(server string) error {
+		tried = append(tried, server)
+		return nil
+	}))
+	require.Equal(t, replicas[:1], tried)
+
+	// Fails over to successors, reporting failures
+	errDown := errors.New("down")
+	tried = nil
+	require.NoError(t, ring.Route("key", func(server string) error {
+		tried = append(tried, server)
+		if len(tried) < 3 {
+			return errDown
+		}
+		return nil
+	}))
+	require.Equal(t, replicas[:3], tried)
+	require.True(t, ring.Tripped(replicas[0]))
+	require.True(t, ring.Tripped(replicas[1]))
+	require.False(t, ring.Tripped(replicas[2]))
+
+	ring.ReportSuccess(replicas[0])
+	ring.ReportSuccess(replicas[1])
+
+	// Gives up after the max attempts
+	tried = nil
+	err = ring.Route("key", func(server string) error {
+		tried = append(tried, server)
+		return errDown
+	}, WithMaxAttempts(2))
+	require.ErrorIs(t, err, errDown)
+	require.Equal(t, replicas[:2], tried)
+}
+
+func TestRouteRetryIf(t *testing.T) {
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	errInvalid := errors.New("invalid")
+
+	attempts := 0
+	err := ring.Route("key", func(string) error {
+		attempts++
+		return errInvalid
+	}, WithRetryIf(func(err error) bool { return !errors.Is(err, errInvalid) }))
+	require.ErrorIs(t, err, errInvalid)
+	require.Equal(t, 1, attempts, "non-retryable errors shouldn't fail over")
+
+	owner, err := ring.GetServer("key")
+	require.NoError(t, err)
+	require.False(t, ring.Tripped(owner))
+}
+
+func TestRouteBackoff(t *testing.T) {
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+
+	var waits []int
+	err := ring.Route("key", func(string) error {
+		return errors.New("down")
+	}, WithBackoff(func(attempt int) time.Duration {
+		waits = append(waits, attempt)
+		return time.Millisecond
+	}))
+	require.Error(t, err)
+	require.Equal(t, []int{1, 2}, waits)
+
+	// Cancelling the context stops retries
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err = ring.RouteContext(ctx, "key", func(string) error {
+		attempts++
+		cancel()
+		return errors.New("down")
+	}, WithBackoff(func(int) time.Duration { return time.Hour }))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 1, attempts)
+
+	require.Error(t, New(10).Route("key", func(string) error { return nil }))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	require.Equal(t, 10*time.Millisecond, backoff(1))
+	require.Equal(t, 20*time.Millisecond, backoff(2))
+	require.Equal(t, 40*time.Millisecond, backoff(3))
+	require.Equal(t, 50*time.Millisecond, backoff(4))
+	require.Equal(t, 50*time.Millisecond, backoff(100))
+}
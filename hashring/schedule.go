@@ -0,0 +1,255 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScheduledChange is a membership change queued to be applied later (see
+// ScheduleAdd and ScheduleRemove).
+type ScheduledChange struct {
+	ID     int        `json:"id"`
+	Type   ChangeType `json:"type"` // ChangeAdd or ChangeRemove
+	Server string     `json:"server"`
+	At     time.Time  `json:"at"`
+}
+
+// MaintenanceWindow is a recurring daily window in which changes may be
+// applied, e.g. 02:00 to 04:00 UTC.
+type MaintenanceWindow struct {
+	Start    time.Duration  // offset from midnight
+	Duration time.Duration  // length of the window
+	Location *time.Location // nil for UTC
+}
+
+// Next returns t if it falls within the window, or else the start of the next
+// window after t.
+//
+// Example:
+//
+//	window := hashring.MaintenanceWindow{Start: 2 * time.Hour, Duration: 2 * time.Hour}
+//	ring.ScheduleAdd("cache-4", window.Next(time.Now()))
+func (w MaintenanceWindow) Next(t time.Time) time.Time {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	local := t.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	for _, day := range []int{-1, 0, 1} {
+		start := midnight.AddDate(0, 0, day).Add(w.Start)
+		if !t.Before(start) && t.Before(start.Add(w.Duration)) {
+			return t
+		}
+
+		if start.After(t) {
+			return start
+		}
+	}
+
+	return midnight.AddDate(0, 0, 2).Add(w.Start)
+}
+
+// scheduler holds a ring's scheduled changes and their subscribers. It has its
+// own lock so changes can be applied, which takes h.mu, from its timers.
+type scheduler struct {
+	mu      sync.Mutex
+	next    int                // id of the next change or subscription
+	pending map[int]*scheduled // by change id
+	subs    []notice           // in the order they were registered
+}
+
+// scheduled is a pending change with its timers.
+type scheduled struct {
+	change  ScheduledChange
+	timer   *time.Timer         // applies the change
+	notices map[int]*time.Timer // by subscription id
+}
+
+// notice is a subscription registered with OnScheduledChange.
+type notice struct {
+	id   int
+	lead time.Duration
+	fn   func(ScheduledChange)
+}
+
+// ScheduleAdd queues server to be added to the ring at the given time, e.g.
+// the start of a maintenance window (see MaintenanceWindow). Times in the past
+// apply the change immediately. It returns the change's id, which can be
+// passed to CancelScheduled.
+//
+// The change is applied as if by AddServer. Since the ring may change in the
+// meantime, errors can only be detected then; they're reported to the
+// metrics sink as the scheduled_change_failures counter (see WithMetricsSink).
+//
+// Example:
+//
+//	id, err := ring.ScheduleAdd("cache-4", time.Now().Add(time.Hour))
+func (h *HashRing) ScheduleAdd(server string, at time.Time) (int, error) {
+	return h.schedule(ChangeAdd, server, at)
+}
+
+// ScheduleRemove queues server to be removed from the ring at the given time.
+// See ScheduleAdd.
+func (h *HashRing) ScheduleRemove(server string, at time.Time) (int, error) {
+	return h.schedule(ChangeRemove, server, at)
+}
+
+// CancelScheduled cancels a pending scheduled change, reporting whether it
+// was still pending.
+func (h *HashRing) CancelScheduled(id int) bool {
+	s := &h.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[id]
+	if !ok {
+		return false
+	}
+
+	p.stop()
+	delete(s.pending, id)
+	return true
+}
+
+// Scheduled returns the pending scheduled changes, soonest first.
+func (h *HashRing) Scheduled() []ScheduledChange {
+	s := &h.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := make([]ScheduledChange, 0, len(s.pending))
+	for _, p := range s.pending {
+		changes = append(changes, p.change)
+	}
+
+	slices.SortFunc(changes, func(a, b ScheduledChange) int {
+		if c := a.At.Compare(b.At); c != 0 {
+			return c
+		}
+
+		return a.ID - b.ID
+	})
+
+	return changes
+}
+
+// OnScheduledChange registers fn to be called lead before each scheduled
+// change is applied, so dependent systems can prepare, e.g. by warming a new
+// server's cache. Changes due sooner than lead are announced immediately. It
+// returns a function that cancels the subscription.
+//
+// Callbacks run on their own goroutines, so they may call back into the ring.
+//
+// Example:
+//
+//	cancel := ring.OnScheduledChange(10*time.Minute, func(change hashring.ScheduledChange) {
+//		log.Printf("%s of %s at %s", change.Type, change.Server, change.At)
+//	})
+//	defer cancel()
+func (h *HashRing) OnScheduledChange(lead time.Duration, fn func(ScheduledChange)) (cancel func()) {
+	s := &h.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := notice{id: s.next, lead: lead, fn: fn}
+	s.next++
+	s.subs = append(s.subs, n)
+
+	for _, p := range s.pending {
+		p.notify(n, h.now())
+	}
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.subs = slices.DeleteFunc(s.subs, func(sub notice) bool { return sub.id == n.id })
+		for _, p := range s.pending {
+			if t, ok := p.notices[n.id]; ok {
+				t.Stop()
+				delete(p.notices, n.id)
+			}
+		}
+	}
+}
+
+// schedule queues a change and starts its timers.
+func (h *HashRing) schedule(typ ChangeType, server string, at time.Time) (int, error) {
+	if strings.TrimSpace(server) == "" {
+		return 0, errors.New("server name must not be empty")
+	}
+
+	if at.IsZero() {
+		return 0, fmt.Errorf("server %s: no time given for scheduled %s", server, typ)
+	}
+
+	s := &h.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == nil {
+		s.pending = make(map[int]*scheduled)
+	}
+
+	p := &scheduled{
+		change:  ScheduledChange{ID: s.next, Type: typ, Server: server, At: at},
+		notices: make(map[int]*time.Timer),
+	}
+	s.next++
+	s.pending[p.change.ID] = p
+
+	now := h.now()
+	for _, n := range s.subs {
+		p.notify(n, now)
+	}
+
+	p.timer = time.AfterFunc(at.Sub(now), func() { h.applyScheduled(p.change.ID) })
+	return p.change.ID, nil
+}
+
+// applyScheduled applies the pending change with the given id, unless it was
+// cancelled.
+func (h *HashRing) applyScheduled(id int) {
+	s := &h.scheduler
+	s.mu.Lock()
+	p, ok := s.pending[id]
+	delete(s.pending, id)
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	var err error
+	switch p.change.Type {
+	case ChangeAdd:
+		err = h.AddServer(p.change.Server)
+	case ChangeRemove:
+		err = h.RemoveServer(p.change.Server)
+	}
+
+	if err != nil {
+		h.metrics.Counter("scheduled_change_failures", 1, Label{Name: "type", Value: string(p.change.Type)})
+	}
+}
+
+// notify starts a timer calling n's callback lead before the change. The
+// caller must hold the scheduler's lock.
+func (p *scheduled) notify(n notice, now time.Time) {
+	change := p.change
+	p.notices[n.id] = time.AfterFunc(change.At.Add(-n.lead).Sub(now), func() { n.fn(change) })
+}
+
+// stop stops the change's timers. The caller must hold the scheduler's lock.
+func (p *scheduled) stop() {
+	p.timer.Stop()
+	for _, t := range p.notices {
+		t.Stop()
+	}
+}
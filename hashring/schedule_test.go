@@ -0,0 +1,108 @@
+package hashring
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleAdd(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+
+	type notice struct {
+		change  ScheduledChange
+		servers []string
+	}
+
+	notices := make(chan notice, 1)
+	cancel := ring.OnScheduledChange(40*time.Millisecond, func(change ScheduledChange) {
+		notices <- notice{change: change, servers: ring.GetServers()}
+	})
+	defer cancel()
+
+	at := time.Now().Add(50 * time.Millisecond)
+	id, err := ring.ScheduleAdd("server2", at)
+	require.NoError(t, err)
+	require.Equal(t, []ScheduledChange{{ID: id, Type: ChangeAdd, Server: "server2", At: at}}, ring.Scheduled())
+
+	select {
+	case n := <-notices:
+		require.Equal(t, id, n.change.ID)
+		// announced before the change is applied
+		require.Equal(t, []string{"server1"}, n.servers)
+	case <-time.After(time.Second):
+		require.Fail(t, "Expected a notice before the change")
+	}
+
+	require.Eventually(t, func() bool {
+		return slices.Equal(ring.GetServers(), []string{"server1", "server2"})
+	}, time.Second, time.Millisecond)
+	require.Empty(t, ring.Scheduled())
+}
+
+func TestScheduleRemoveAndCancel(t *testing.T) {
+	sink := &recordingSink{}
+	ring := New(10, WithMetricsSink(sink))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	now := time.Now()
+	later, err := ring.ScheduleRemove("server1", now.Add(time.Hour))
+	require.NoError(t, err)
+	soon, err := ring.ScheduleRemove("server2", now.Add(10*time.Millisecond))
+	require.NoError(t, err)
+
+	// soonest first
+	scheduled := ring.Scheduled()
+	require.Len(t, scheduled, 2)
+	require.Equal(t, soon, scheduled[0].ID)
+	require.Equal(t, later, scheduled[1].ID)
+
+	require.True(t, ring.CancelScheduled(later))
+	require.False(t, ring.CancelScheduled(later))
+
+	require.Eventually(t, func() bool {
+		return slices.Equal(ring.GetServers(), []string{"server1"})
+	}, time.Second, time.Millisecond)
+
+	// Changes that can no longer be applied are reported to the sink
+	_, err = ring.ScheduleRemove("server3", time.Now())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		return slices.Contains(sink.metrics, "counter scheduled_change_failures[type=remove]=1")
+	}, time.Second, time.Millisecond)
+
+	_, err = ring.ScheduleAdd("", time.Now())
+	require.Error(t, err)
+	_, err = ring.ScheduleAdd("server3", time.Time{})
+	require.Error(t, err)
+}
+
+func TestMaintenanceWindow(t *testing.T) {
+	window := MaintenanceWindow{Start: 2 * time.Hour, Duration: 2 * time.Hour}
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	// before the window
+	require.Equal(t, day.Add(2*time.Hour), window.Next(day.Add(time.Hour)))
+	// within it
+	require.Equal(t, day.Add(3*time.Hour), window.Next(day.Add(3*time.Hour)))
+	// after it, so the next day's
+	require.Equal(t, day.Add(26*time.Hour), window.Next(day.Add(5*time.Hour)))
+
+	// windows spanning midnight
+	overnight := MaintenanceWindow{Start: 23 * time.Hour, Duration: 2 * time.Hour}
+	require.Equal(t, day.Add(30*time.Minute), overnight.Next(day.Add(30*time.Minute)))
+	require.Equal(t, day.Add(23*time.Hour), overnight.Next(day.Add(2*time.Hour)))
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err == nil {
+		local := MaintenanceWindow{Start: 2 * time.Hour, Duration: time.Hour, Location: tokyo}
+		next := local.Next(day)
+		require.Equal(t, 2, next.In(tokyo).Hour())
+	}
+}
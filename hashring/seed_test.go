@@ -0,0 +1,97 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeededHasher(t *testing.T) {
+	// XXH64 reference values with seed 42
+	require.Equal(t, uint64(0xc3629e6318d53932), xxhash64([]byte("hello"), 42))
+	require.Equal(t, uint64(0x4959b9e1984a00b9), xxhash64([]byte("0123456789abcdef0123456789abcdef0123456789abc"), 42))
+	require.Equal(t, uint32(3686246225), SeededHasher(42).Hash([]byte("hello")))
+	require.Equal(t, "xxhash64-seed-42", SeededHasher(42).Name())
+}
+
+func TestHasherByName(t *testing.T) {
+	for _, hasher := range []Hasher{CRC32, CRC32C, FNV1a, XXHash64, Murmur3, SeededHasher(7)} {
+		got, ok := HasherByName(hasher.Name())
+		require.True(t, ok, hasher.Name())
+		require.Equal(t, hasher.Hash([]byte("key")), got.Hash([]byte("key")))
+	}
+
+	_, ok := HasherByName("md5")
+	require.False(t, ok)
+}
+
+func TestWithDeterministicSeed(t *testing.T) {
+	ring := New(100, WithDeterministicSeed(42))
+	for i := range 3 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	seed, ok := ring.Seed()
+	require.True(t, ok)
+	require.Equal(t, uint64(42), seed)
+
+	_, ok = New(100).Seed()
+	require.False(t, ok)
+
+	// These mappings must never change
+	want := map[string]string{
+		"user:1": "server0",
+		"user:2": "server0",
+		"user:3": "server2",
+		"user:4": "server0",
+		"user:5": "server0",
+	}
+	for key, server := range want {
+		got, err := ring.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, server, got, "key %s", key)
+	}
+
+	// Snapshots of seeded rings restore without options
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+
+	other := New(100, WithDeterministicSeed(43))
+	for i := range 3 {
+		require.NoError(t, other.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	require.NotEqual(t, ring.Checksum(), other.Checksum())
+}
+
+func TestReseed(t *testing.T) {
+	ring := New(100, WithDeterministicSeed(42))
+	for i := range 3 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	checksum := ring.Checksum()
+
+	next := ring.Reseed(43)
+	require.Equal(t, checksum, ring.Checksum(), "Expected the original ring to be unchanged")
+
+	seed, _ := next.Seed()
+	require.Equal(t, uint64(43), seed)
+	require.Equal(t, ring.Version()+1, next.Version())
+	require.Equal(t, ChangeReseed, next.History()[len(next.History())-1].Type)
+
+	// The reseeded ring matches one built with the new seed
+	fresh := New(100, WithDeterministicSeed(43))
+	for i := range 3 {
+		require.NoError(t, fresh.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	require.Equal(t, fresh.Checksum(), next.Checksum())
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+
+	report := MovedKeys(ring, next, keys)
+	require.Greater(t, report.Fraction(), 0.3)
+}
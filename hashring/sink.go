@@ -0,0 +1,86 @@
+package hashring
+
+import "time"
+
+// Label is a name/value pair attached to a metric, such as the server a
+// breaker tripped for.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// MetricsSink receives metrics emitted by a ring, so any monitoring backend can
+// be attached without the ring depending on its client library.
+//
+// The ring emits:
+//
+//   - lookups (counter): a key resolved to a server
+//   - lookup_duration_seconds (histogram): how long the lookup took
+//   - topology_changes (counter, labeled by type): a server added, removed,
+//     or updated, or a rollback
+//   - servers (gauge): servers in the ring, after each topology change
+//   - collisions (gauge): virtual nodes displaced by hash collisions, after
+//     each topology change
+//   - breaker_trips (counter, labeled by server): a server's circuit breaker
+//     opened
+//   - scheduled_change_failures (counter, labeled by type): a scheduled change
+//     couldn't be applied
+//   - write_batch_size (histogram): changes applied together (see
+//     WithWriteBatching)
+//
+// Methods are called synchronously, sometimes while the ring is locked, so
+// they must be fast and must not call back into the ring.
+type MetricsSink interface {
+	Counter(name string, delta int64, labels ...Label)
+	Gauge(name string, value float64, labels ...Label)
+	Histogram(name string, value float64, labels ...Label)
+}
+
+// NopMetricsSink is a MetricsSink that discards everything. It's the default,
+// and rings using it skip timing lookups entirely.
+type NopMetricsSink struct{}
+
+// Counter implements MetricsSink.
+func (NopMetricsSink) Counter(string, int64, ...Label) {}
+
+// Gauge implements MetricsSink.
+func (NopMetricsSink) Gauge(string, float64, ...Label) {}
+
+// Histogram implements MetricsSink.
+func (NopMetricsSink) Histogram(string, float64, ...Label) {}
+
+// WithMetricsSink sets the sink the ring emits metrics into. A nil sink
+// disables metrics.
+//
+// Example:
+//
+//	ring := hashring.New(150, hashring.WithMetricsSink(mySink))
+func WithMetricsSink(sink MetricsSink) Option {
+	return func(h *HashRing) {
+		if sink == nil {
+			sink = NopMetricsSink{}
+		}
+
+		h.metrics = sink
+	}
+}
+
+// observed reports whether metrics are being collected, so lookups can skip
+// reading the clock when they aren't.
+func (h *HashRing) observed() bool {
+	_, nop := h.metrics.(NopMetricsSink)
+	return !nop
+}
+
+// observeLookup emits metrics for a lookup that started at start.
+func (h *HashRing) observeLookup(start time.Time) {
+	h.metrics.Counter("lookups", 1)
+	h.metrics.Histogram("lookup_duration_seconds", time.Since(start).Seconds())
+}
+
+// observeChange emits metrics for a topology change. The caller must hold h.mu.
+func (h *HashRing) observeChange(typ ChangeType) {
+	h.metrics.Counter("topology_changes", 1, Label{Name: "type", Value: string(typ)})
+	h.metrics.Gauge("servers", float64(len(h.servers)))
+	h.metrics.Gauge("collisions", float64(h.collisions))
+}
@@ -0,0 +1,92 @@
+package hashring
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink records emitted metrics as "kind name[labels]=value".
+type recordingSink struct {
+	mu      sync.Mutex
+	metrics []string
+}
+
+func (s *recordingSink) record(kind, name string, value any, labels []Label) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ls []string
+	for _, l := range labels {
+		ls = append(ls, l.Name+"="+l.Value)
+	}
+
+	s.metrics = append(s.metrics, fmt.Sprintf("%s %s[%s]=%v", kind, name, strings.Join(ls, ","), value))
+}
+
+func (s *recordingSink) Counter(name string, delta int64, labels ...Label) {
+	s.record("counter", name, delta, labels)
+}
+
+func (s *recordingSink) Gauge(name string, value float64, labels ...Label) {
+	s.record("gauge", name, value, labels)
+}
+
+func (s *recordingSink) Histogram(name string, _ float64, labels ...Label) {
+	s.record("histogram", name, "?", labels)
+}
+
+func (s *recordingSink) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics := s.metrics
+	s.metrics = nil
+	return metrics
+}
+
+func TestMetricsSink(t *testing.T) {
+	sink := new(recordingSink)
+	ring := New(10, WithMetricsSink(sink), WithCircuitBreaker(1, time.Minute))
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.Equal(t, []string{
+		"counter topology_changes[type=add]=1",
+		"gauge servers[]=1",
+		"gauge collisions[]=0",
+		"counter topology_changes[type=add]=1",
+		"gauge servers[]=2",
+		"gauge collisions[]=0",
+	}, sink.take())
+
+	_, err := ring.GetServer("key")
+	require.NoError(t, err)
+	_, err = ring.GetReplicas("key", 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"counter lookups[]=1",
+		"histogram lookup_duration_seconds[]=?",
+		"counter lookups[]=1",
+		"histogram lookup_duration_seconds[]=?",
+	}, sink.take())
+
+	// Only the failure that opens the breaker counts as a trip
+	ring.ReportFailure("server1")
+	ring.ReportFailure("server1")
+	require.Equal(t, []string{"counter breaker_trips[server=server1]=1"}, sink.take())
+
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.True(t, slices.Contains(sink.take(), "counter topology_changes[type=remove]=1"))
+}
+
+func TestNopMetricsSink(t *testing.T) {
+	require.False(t, New(10).observed())
+	require.False(t, New(10, WithMetricsSink(nil)).observed())
+	require.True(t, New(10, WithMetricsSink(new(recordingSink))).observed())
+}
@@ -0,0 +1,189 @@
+package hashring
+
+import (
+	"fmt"
+	"maps"
+)
+
+// Snapshot is a point-in-time capture of a ring's full state.
+//
+// Snapshots are plain values, so they can be stored on disk, embedded in test
+// fixtures, or sent to another process (e.g. as JSON) and turned back into an
+// identical ring with Restore.
+type Snapshot struct {
+	Version      uint64            `json:"version"`
+	VirtualNodes int               `json:"virtual_nodes"`
+	Placement    Placement         `json:"placement,omitempty"`
+	Hasher       string            `json:"hasher,omitempty"`
+	Servers      []ServerInfo      `json:"servers"`
+	Pins         map[string]string `json:"pins,omitempty"`
+	Canary       Canary            `json:"canary,omitzero"`
+	HashTags     [2]string         `json:"hash_tags,omitzero"`
+	History      []TopologyChange  `json:"history,omitempty"`
+	Checksum     uint64            `json:"checksum"`
+}
+
+// Snapshot captures the ring's current state.
+//
+// The snapshot includes the membership (with server metadata), virtual node
+// count and placement, pins, the canary, hash tag delimiters, version, and
+// topology history, along with the ring's checksum so that Restore can verify
+// the snapshot wasn't altered in transit. This operation is thread-safe.
+//
+// Example:
+//
+//	data, _ := json.Marshal(ring.Snapshot())
+//	os.WriteFile("ring.json", data, 0o600)
+func (h *HashRing) Snapshot() Snapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return Snapshot{
+		Version:      h.version,
+		VirtualNodes: h.vnodes,
+		Placement:    h.placement,
+		Hasher:       h.hasher.Name(),
+		Servers:      h.serverInfos(),
+		Pins:         maps.Clone(h.pins),
+		Canary:       h.canary,
+		HashTags:     [2]string{h.tagOpen, h.tagClose},
+		History:      h.historyCopy(),
+		Checksum:     h.checksum(),
+	}
+}
+
+// Restore creates a new ring from a snapshot.
+//
+// The restored ring has the same membership, version, and history as the ring
+// the snapshot was taken from, so it routes every key identically. Options
+// (which aren't part of a snapshot) can be supplied just like with New.
+//
+// Returns an error if the snapshot contains duplicate servers or its checksum
+// doesn't match the restored ring.
+//
+// Example:
+//
+//	var snap hashring.Snapshot
+//	if err := json.Unmarshal(data, &snap); err != nil {
+//		return err
+//	}
+//	ring, err := hashring.Restore(snap)
+func Restore(s Snapshot, opts ...Option) (*HashRing, error) {
+	if s.VirtualNodes <= 0 {
+		return nil, fmt.Errorf("invalid snapshot: virtual node count must be positive, got %d", s.VirtualNodes)
+	}
+
+	base := []Option{WithHashTags(s.HashTags[0], s.HashTags[1])}
+	if s.Placement != "" {
+		base = append(base, WithPlacement(s.Placement))
+	}
+
+	if hasher, ok := HasherByName(s.Hasher); ok {
+		base = append(base, WithHasher(hasher))
+	}
+
+	h := New(s.VirtualNodes, append(base, opts...)...)
+	if s.Hasher != "" && s.Hasher != h.hasher.Name() {
+		return nil, fmt.Errorf("invalid snapshot: hasher %s isn't built in, so it must be given with WithHasher", s.Hasher)
+	}
+
+	for _, info := range s.Servers {
+		if err := h.addServer(info); err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+	}
+
+	for keyOrPrefix, server := range s.Pins {
+		if !h.hasServer(server) {
+			return nil, fmt.Errorf("invalid snapshot: %s is pinned to unknown server %s", keyOrPrefix, server)
+		}
+		h.pins[keyOrPrefix] = server
+	}
+
+	if s.Canary.Server != "" && !h.hasServer(s.Canary.Server) {
+		return nil, fmt.Errorf("invalid snapshot: canary %s is an unknown server", s.Canary.Server)
+	}
+	h.canary = s.Canary
+
+	h.version = s.Version
+	h.history = append(h.history, s.History...)
+	if over := len(h.history) - h.historyLimit; over > 0 {
+		h.history = h.history[over:]
+	}
+
+	if s.Checksum != 0 && s.Checksum != h.checksum() {
+		return nil, fmt.Errorf("invalid snapshot: checksum mismatch (expected %d, got %d)", s.Checksum, h.checksum())
+	}
+
+	return h, nil
+}
+
+// Apply replaces the ring's membership, pins, and canary with the snapshot's,
+// e.g. to adopt the topology of a peer that changed first (see the gossip
+// package). Options and history are kept. The ring's version becomes the
+// snapshot's, or one more than its own if that's greater, so it always
+// changes; the change is recorded in the history as ChangeSync.
+//
+// Returns an error if the snapshot is invalid (see Restore), was taken from a
+// ring that places servers differently (with another virtual node count,
+// placement, hasher, or hash tags), or exceeds the ring's limits (see
+// WithMaxServers and WithMaxVNodes). The ring is unchanged on error.
+//
+// Example:
+//
+//	if peer.Version > ring.Version() {
+//		err = ring.Apply(peer)
+//	}
+func (h *HashRing) Apply(s Snapshot) error {
+	return h.write(func() error {
+		return h.apply(s)
+	})
+}
+
+// apply adopts the snapshot's membership, pins, and canary. The caller must
+// hold h.mu.
+func (h *HashRing) apply(s Snapshot) error {
+	placement := s.Placement
+	if placement == "" {
+		placement = PlacementHashed
+	}
+
+	switch {
+	case s.VirtualNodes != h.vnodes:
+		return fmt.Errorf("snapshot has %d virtual nodes per server, ring has %d", s.VirtualNodes, h.vnodes)
+	case placement != h.placement:
+		return fmt.Errorf("snapshot uses %s placement, ring uses %s", placement, h.placement)
+	case s.Hasher != "" && s.Hasher != h.hasher.Name():
+		return fmt.Errorf("snapshot uses hasher %s, ring uses %s", s.Hasher, h.hasher.Name())
+	case s.HashTags != [2]string{h.tagOpen, h.tagClose}:
+		return fmt.Errorf("snapshot uses hash tags %q, ring uses %q", s.HashTags, [2]string{h.tagOpen, h.tagClose})
+	}
+
+	// Validate the snapshot before touching the ring
+	if _, err := Restore(s, WithHasher(h.hasher)); err != nil {
+		return err
+	}
+
+	// Try the membership on a copy first, since the ring's own limits (see
+	// WithMaxServers) may reject it, so the ring is unchanged on error
+	if err := h.clone().setMembership(cloneInfos(s.Servers)); err != nil {
+		return err
+	}
+
+	if err := h.setMembership(cloneInfos(s.Servers)); err != nil {
+		return err
+	}
+
+	h.pins = maps.Clone(s.Pins)
+	if h.pins == nil {
+		h.pins = make(map[string]string)
+	}
+	h.canary = s.Canary
+
+	if s.Version > h.version {
+		h.version = s.Version - 1
+	}
+
+	h.recordChange(ChangeSync, "")
+	return nil
+}
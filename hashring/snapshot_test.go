@@ -0,0 +1,153 @@
+package hashring
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.NoError(t, ring.AddServer("server3"))
+	require.NoError(t, ring.RemoveServer("server2"))
+
+	snap := ring.Snapshot()
+	require.Equal(t, uint64(4), snap.Version)
+	require.Equal(t, 150, snap.VirtualNodes)
+	require.Equal(t, []ServerInfo{{Name: "server1"}, {Name: "server3"}}, snap.Servers)
+	require.Len(t, snap.History, 4)
+	require.Equal(t, ring.Checksum(), snap.Checksum)
+
+	// Round trip through JSON to simulate a cross-process transfer
+	data, err := json.Marshal(snap)
+	require.NoError(t, err)
+
+	var decoded Snapshot
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	restored, err := Restore(decoded)
+	require.NoError(t, err)
+	require.Equal(t, ring.Version(), restored.Version())
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+	require.Equal(t, ring.GetServers(), restored.GetServers())
+	require.Len(t, restored.History(), 4)
+
+	for i := range 1000 {
+		key := fmt.Sprintf("key-%d", i)
+		expected, err := ring.GetServer(key)
+		require.NoError(t, err)
+		actual, err := restored.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual, "Restored ring routed %s differently", key)
+	}
+
+	// The restored history supports rollback
+	require.NoError(t, restored.Rollback(3))
+	require.Equal(t, []string{"server1", "server2", "server3"}, restored.GetServers())
+}
+
+func TestRestoreErrors(t *testing.T) {
+	_, err := Restore(Snapshot{VirtualNodes: 10, Servers: []ServerInfo{{Name: "server1"}, {Name: "server1"}}})
+	require.Error(t, err, "Expected error for duplicate servers")
+
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	snap := ring.Snapshot()
+	snap.Servers = append(snap.Servers, ServerInfo{Name: "server2"})
+
+	_, err = Restore(snap)
+	require.Error(t, err, "Expected error for checksum mismatch")
+}
+
+func TestRestoreWithOptions(t *testing.T) {
+	ring := New(10)
+	for i := range 5 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	restored, err := Restore(ring.Snapshot(), WithHistoryLimit(2))
+	require.NoError(t, err)
+	require.Len(t, restored.History(), 2)
+	require.Equal(t, uint64(5), restored.History()[1].Version)
+}
+
+func TestApply(t *testing.T) {
+	source := New(50)
+	require.NoError(t, source.AddServer("server1"))
+	require.NoError(t, source.AddServerWithInfo(ServerInfo{Name: "server2", Weight: 2}))
+	require.NoError(t, source.AddServer("server3"))
+	require.NoError(t, source.Pin("key", "server3"))
+
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server4"))
+
+	// the snapshot's version is newer
+	require.NoError(t, ring.Apply(source.Snapshot()))
+	require.Equal(t, source.Checksum(), ring.Checksum())
+	require.Equal(t, source.Version(), ring.Version())
+	server, err := ring.GetServer("key")
+	require.NoError(t, err)
+	require.Equal(t, "server3", server)
+
+	history := ring.History()
+	require.Equal(t, ChangeSync, history[len(history)-1].Type)
+
+	// the ring's version is newer, so it's bumped instead
+	require.NoError(t, ring.AddServer("server5"))
+	require.NoError(t, ring.AddServer("server6"))
+	version := ring.Version()
+	require.NoError(t, ring.Apply(source.Snapshot()))
+	require.Equal(t, version+1, ring.Version())
+	require.Equal(t, []string{"server1", "server2", "server3"}, ring.GetServers())
+}
+
+func TestApplyLimits(t *testing.T) {
+	source := New(10)
+	for i := range 4 {
+		require.NoError(t, source.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	for _, opt := range []Option{WithMaxServers(2), WithMaxVNodes(25)} {
+		ring := New(10, opt)
+		require.NoError(t, ring.AddServer("a"))
+		require.NoError(t, ring.AddServer("b"))
+		checksum, version := ring.Checksum(), ring.Version()
+
+		// The snapshot is valid, but too big for this ring
+		require.Error(t, ring.Apply(source.Snapshot()))
+		require.Equal(t, []string{"a", "b"}, ring.GetServers())
+		require.Equal(t, checksum, ring.Checksum())
+		require.Equal(t, version, ring.Version())
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	ring := New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	checksum, version := ring.Checksum(), ring.Version()
+
+	other := New(50, WithHasher(FNV1a))
+	require.NoError(t, other.AddServer("server2"))
+	require.ErrorContains(t, ring.Apply(other.Snapshot()), "hasher")
+
+	other = New(100)
+	require.NoError(t, other.AddServer("server2"))
+	require.ErrorContains(t, ring.Apply(other.Snapshot()), "virtual nodes")
+
+	other = New(50, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, other.AddServer("server2"))
+	require.ErrorContains(t, ring.Apply(other.Snapshot()), "placement")
+
+	// corrupted snapshots are rejected
+	snap := New(50).Snapshot()
+	snap.Servers = append(snap.Servers, ServerInfo{Name: "server2"})
+	require.Error(t, ring.Apply(snap))
+
+	require.Equal(t, checksum, ring.Checksum())
+	require.Equal(t, version, ring.Version())
+}
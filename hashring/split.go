@@ -0,0 +1,78 @@
+package hashring
+
+import "math/bits"
+
+// SplitKeyspace divides the hash space into n contiguous ranges of about
+// equal size, in position order, for jobs that work through every key in
+// parallel, such as repair, compaction, or verification.
+//
+// Where possible, ranges end at virtual node positions, so no server's range
+// of positions is split between two of them and each worker reads every key
+// it covers from the same servers as the ring does. A boundary is moved to
+// the nearest virtual node within a quarter of a range's ideal size, which
+// keeps every range within 50% of it; boundaries with no virtual node that
+// close, and all of them on an empty ring, stay where they'd evenly divide
+// the hash space.
+//
+// Returns nil if n isn't positive. n is capped at the number of positions in
+// the hash space.
+//
+// Example:
+//
+//	var wg sync.WaitGroup
+//	for _, r := range ring.SplitKeyspace(runtime.NumCPU()) {
+//		wg.Add(1)
+//		go func() {
+//			defer wg.Done()
+//			verify(r)
+//		}()
+//	}
+//	wg.Wait()
+func (h *HashRing) SplitKeyspace(n int) []HashRange {
+	if n <= 0 {
+		return nil
+	}
+
+	total := uint64(min(uint64(n), maxHash+1))
+	tolerance := (maxHash + 1) / total / 4
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	ranges := make([]HashRange, 0, total)
+	var start uint64
+	for k := uint64(1); k < total; k++ {
+		// k * 2^32 / total - 1, without overflowing
+		hi, lo := bits.Mul64(k, maxHash+1)
+		ideal, _ := bits.Div64(hi, lo, total)
+		ideal--
+
+		end := ideal
+		if pos, ok := h.nearestVNode(ideal, tolerance); ok && pos >= start {
+			end = pos
+		}
+
+		ranges = append(ranges, HashRange{Start: start, End: end})
+		start = end + 1
+	}
+
+	return append(ranges, HashRange{Start: start, End: maxHash})
+}
+
+// nearestVNode returns the virtual node position closest to hash, if one is
+// within tolerance of it. The caller must hold h.mu.
+func (h *HashRing) nearestVNode(hash, tolerance uint64) (uint64, bool) {
+	dist := func(pos uint64) uint64 { return max(pos, hash) - min(pos, hash) }
+
+	i, _ := find(h.entries, hash)
+	if i > 0 && (i == len(h.entries) || dist(h.entries[i-1].hash) < dist(h.entries[i].hash)) {
+		// The virtual node before hash is closer
+		i--
+	}
+
+	if i == len(h.entries) || dist(h.entries[i].hash) > tolerance {
+		return 0, false
+	}
+
+	return h.entries[i].hash, true
+}
@@ -0,0 +1,69 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireCovers checks that ranges cover the whole hash space in order.
+func requireCovers(t *testing.T, ranges []HashRange) {
+	t.Helper()
+
+	var next uint64
+	for _, r := range ranges {
+		require.Equal(t, next, r.Start)
+		require.GreaterOrEqual(t, r.End, r.Start)
+		next = r.End + 1
+	}
+	require.Equal(t, uint64(maxHash)+1, next)
+}
+
+func TestSplitKeyspace(t *testing.T) {
+	require.Nil(t, New(10).SplitKeyspace(0))
+	require.Equal(t, []HashRange{{0, maxHash}}, New(10).SplitKeyspace(1))
+
+	// An empty ring is divided evenly
+	require.Equal(t, []HashRange{
+		{0, 1<<30 - 1}, {1 << 30, 2<<30 - 1}, {2 << 30, 3<<30 - 1}, {3 << 30, maxHash},
+	}, New(10).SplitKeyspace(4))
+	requireCovers(t, New(10).SplitKeyspace(7))
+
+	// Boundaries move to nearby virtual nodes, but not distant ones
+	ring := New(10)
+	require.NoError(t, ring.AddServerWithTokens("a", []uint64{1<<30 + 1000, 3 << 29}))
+	require.Equal(t, []HashRange{
+		{0, 1<<30 + 1000}, {1<<30 + 1001, 2<<30 - 1}, {2 << 30, 3<<30 - 1}, {3 << 30, maxHash},
+	}, ring.SplitKeyspace(4))
+
+	ring = New(100)
+	for i := range 5 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	positions := make(map[uint64]bool)
+	for pos := range ring.VNodes() {
+		positions[pos] = true
+	}
+
+	for _, n := range []int{2, 3, 8, 16, 64} {
+		ranges := ring.SplitKeyspace(n)
+		require.Len(t, ranges, n)
+		requireCovers(t, ranges)
+
+		ideal := float64(maxHash+1) / float64(n)
+		aligned := 0
+		for k, r := range ranges[:n-1] {
+			// Ranges end at a virtual node, or exactly where they'd evenly
+			// divide the hash space if none is close
+			even := uint64(k+1)*(maxHash+1)/uint64(n) - 1
+			require.True(t, positions[r.End] || r.End == even, "n=%d: range %v isn't aligned", n, r)
+			if positions[r.End] {
+				aligned++
+			}
+			require.InDelta(t, ideal, float64(r.End-r.Start+1), ideal/2)
+		}
+		require.Greater(t, aligned, (n-1)/2, "n=%d", n)
+	}
+}
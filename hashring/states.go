@@ -0,0 +1,220 @@
+package hashring
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ServerState is a server's lifecycle state, which decides whether it's routed
+// reads, writes, or both (see Access).
+type ServerState string
+
+const (
+	// StateJoining is a server being brought in: it receives writes, so it
+	// fills with new data, but not reads, which go to the next server
+	// clockwise that still has the data.
+	StateJoining ServerState = "joining"
+	// StateActive is a server in normal service. Servers are active unless
+	// given another state.
+	StateActive ServerState = "active"
+	// StateDraining is a server being taken out: it still serves reads for the
+	// data it holds, but writes go to the next server clockwise.
+	StateDraining ServerState = "draining"
+	// StateDown is a server that receives no traffic but keeps its place on the
+	// ring, so its keys return to it when it comes back.
+	StateDown ServerState = "down"
+	// StateRemoved isn't a state servers are in, but the one transition hooks
+	// see a server move to when it's removed (see AddTransitionHook).
+	StateRemoved ServerState = "removed"
+)
+
+// transitions lists the states each state can move to.
+var transitions = map[ServerState][]ServerState{
+	StateJoining:  {StateActive, StateDown},
+	StateActive:   {StateDraining, StateDown},
+	StateDraining: {StateActive, StateDown},
+	StateDown:     {StateJoining, StateActive, StateDraining},
+}
+
+// CanTransition reports whether a server can move from state s to state to.
+func (s ServerState) CanTransition(to ServerState) bool {
+	return slices.Contains(transitions[s], to)
+}
+
+// serves reports whether servers in state s are routed the given access.
+func (s ServerState) serves(access Access) bool {
+	switch s {
+	case StateJoining:
+		return access == AccessWrite
+	case StateDraining:
+		return access == AccessRead
+	case StateDown:
+		return false
+	default:
+		return true
+	}
+}
+
+// Access is the kind of operation a lookup is for.
+type Access int
+
+const (
+	// AccessRead looks up the server to read a key from. It's what GetServer
+	// and GetReplicas use.
+	AccessRead Access = iota
+	// AccessWrite looks up the server to write a key to.
+	AccessWrite
+)
+
+// StateChange describes a server moving between states, as delivered to
+// OnStateChange subscribers.
+type StateChange struct {
+	Server  string      `json:"server"`
+	From    ServerState `json:"from"`
+	To      ServerState `json:"to"`
+	Version uint64      `json:"version"` // ring version after the change
+}
+
+// State returns a server's lifecycle state.
+//
+// Returns false if the server isn't in the ring.
+func (h *HashRing) State(server string) (ServerState, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	info, ok := h.servers[server]
+	if !ok {
+		return "", false
+	}
+
+	return stateOf(info), true
+}
+
+// SetState moves a server to a new lifecycle state, changing which lookups
+// route to it without moving its virtual nodes. It's recorded in the history
+// as ChangeState, and OnStateChange subscribers are notified.
+//
+// Servers are added active unless their info says otherwise, e.g. a new
+// cache node can join in StateJoining, warm up on writes, and be made active
+// once full. Allowed transitions are:
+//
+//	joining  -> active, down
+//	active   -> draining, down
+//	draining -> active, down
+//	down     -> joining, active, draining
+//
+// Returns an error if the server doesn't exist, the transition isn't allowed,
+// or a transition hook vetoes it (see AddTransitionHook).
+//
+// Example:
+//
+//	ring.AddServerWithInfo(hashring.ServerInfo{Name: "cache-4", State: hashring.StateJoining})
+//	// ... once it's warm
+//	err := ring.SetState("cache-4", hashring.StateActive)
+func (h *HashRing) SetState(server string, state ServerState) error {
+	from, err := h.stateFor(server)
+	if err != nil {
+		return err
+	}
+
+	if !from.CanTransition(state) {
+		return fmt.Errorf("server %s: can't move from %s to %s", server, from, state)
+	}
+
+	if err := h.runHooks(server, from, state); err != nil {
+		return err
+	}
+
+	return h.write(func() error {
+		if err := h.checkUnchanged(server, from); err != nil {
+			return err
+		}
+
+		info, err := h.resolveState(h.servers[server], state)
+		if err != nil {
+			return err
+		}
+
+		if err := h.updateServer(info); err != nil {
+			return err
+		}
+
+		if state == StateDown {
+			h.downFrom[server] = from
+		}
+
+		h.recordChange(ChangeState, server)
+		h.states.publish(StateChange{Server: server, From: from, To: state, Version: h.version})
+		return nil
+	})
+}
+
+// GetServerFor returns the server responsible for key for the given access,
+// as GetServer does for reads: joining servers are only returned for writes
+// and draining servers only for reads.
+//
+// Returns an error if the hash ring is empty or no server is eligible for the
+// key.
+//
+// Example:
+//
+//	server, err := ring.GetServerFor("user:12345", hashring.AccessWrite)
+func (h *HashRing) GetServerFor(key string, access Access) (string, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.getServer(key, access)
+}
+
+// GetReplicasFor returns up to n distinct servers for key for the given
+// access, as GetReplicas does for reads.
+//
+// Returns an error if the hash ring is empty or no server is eligible for the
+// key.
+func (h *HashRing) GetReplicasFor(key string, n int, access Access) ([]string, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.getReplicas(key, n, access)
+}
+
+// OnStateChange registers fn to be called after every SetState. It returns a
+// function that cancels the subscription.
+//
+// As with OnMove, callbacks run on a separate goroutine, one change at a time
+// and in the order the changes were made, so they may call back into the
+// ring.
+//
+// Example:
+//
+//	cancel := ring.OnStateChange(func(c hashring.StateChange) {
+//		log.Printf("%s: %s -> %s", c.Server, c.From, c.To)
+//	})
+//	defer cancel()
+func (h *HashRing) OnStateChange(fn func(StateChange)) (cancel func()) {
+	return h.states.subscribe(fn)
+}
+
+// resolveState sets info's state to state, validating it. Active servers are
+// stored without a state, so they match servers from before states existed.
+func (h *HashRing) resolveState(info ServerInfo, state ServerState) (ServerInfo, error) {
+	switch state {
+	case StateActive:
+		state = ""
+	case "", StateJoining, StateDraining, StateDown:
+	default:
+		return info, fmt.Errorf("server %s: invalid state %q", info.Name, state)
+	}
+
+	info.State = state
+	return info, nil
+}
+
+// stateOf returns the state of the server described by info.
+func stateOf(info ServerInfo) ServerState {
+	if info.State == "" {
+		return StateActive
+	}
+
+	return info.State
+}
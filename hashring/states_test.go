@@ -0,0 +1,195 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerStates(t *testing.T) {
+	ring := New(50, WithLookupCache(100))
+	for i := range 3 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	checksum := ring.Checksum()
+
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "new", State: StateJoining}))
+	state, ok := ring.State("new")
+	require.True(t, ok)
+	require.Equal(t, StateJoining, state)
+
+	// a joining server takes writes but reads stay where the data is
+	writes := 0
+	for i := range 1000 {
+		key := fmt.Sprintf("key%d", i)
+		read, err := ring.GetServer(key)
+		require.NoError(t, err)
+		want, err := before.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, want, read, key)
+
+		write, err := ring.GetServerFor(key, AccessWrite)
+		require.NoError(t, err)
+		if write == "new" {
+			writes++
+		}
+	}
+	require.Positive(t, writes)
+
+	// once active it serves both
+	require.NoError(t, ring.SetState("new", StateActive))
+	state, _ = ring.State("new")
+	require.Equal(t, StateActive, state)
+	info, _ := ring.GetServerInfo("new")
+	require.Empty(t, info.State)
+
+	owned := 0
+	for i := range 1000 {
+		key := fmt.Sprintf("key%d", i)
+		read, err := ring.GetServer(key)
+		require.NoError(t, err)
+		write, err := ring.GetServerFor(key, AccessWrite)
+		require.NoError(t, err)
+		require.Equal(t, read, write)
+		if read == "new" {
+			owned++
+		}
+	}
+	require.Equal(t, writes, owned)
+
+	// a draining server keeps serving reads but takes no writes
+	require.NoError(t, ring.SetState("new", StateDraining))
+	for i := range 1000 {
+		key := fmt.Sprintf("key%d", i)
+		write, err := ring.GetServerFor(key, AccessWrite)
+		require.NoError(t, err)
+		require.NotEqual(t, "new", write)
+		want, err := before.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, want, write, key)
+	}
+	replicas, err := ring.GetReplicasFor("key1", 4, AccessWrite)
+	require.NoError(t, err)
+	require.Len(t, replicas, 3)
+	require.NotContains(t, replicas, "new")
+	replicas, err = ring.GetReplicas("key1", 4)
+	require.NoError(t, err)
+	require.Contains(t, replicas, "new")
+
+	// a down server gets nothing, but keeps its place
+	require.NoError(t, ring.SetState("new", StateDown))
+	for i := range 100 {
+		key := fmt.Sprintf("key%d", i)
+		read, err := ring.GetServer(key)
+		require.NoError(t, err)
+		want, err := before.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, want, read, key)
+	}
+	require.Equal(t, 4, ring.Size())
+
+	// removing it leaves the ring as it was
+	require.NoError(t, ring.RemoveServer("new"))
+	require.Equal(t, checksum, ring.Checksum())
+}
+
+func TestSetStateTransitions(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+
+	require.ErrorContains(t, ring.SetState("server1", StateJoining), "can't move from active to joining")
+	require.ErrorContains(t, ring.SetState("server1", StateActive), "can't move from active to active")
+	require.ErrorContains(t, ring.SetState("server1", "paused"), "can't move from active to paused")
+	require.ErrorContains(t, ring.SetState("missing", StateDown), "does not exist")
+
+	version := ring.Version()
+	require.NoError(t, ring.SetState("server1", StateDraining))
+	require.Equal(t, version+1, ring.Version())
+
+	history := ring.History()
+	require.Equal(t, ChangeState, history[len(history)-1].Type)
+	require.Equal(t, "server1", history[len(history)-1].Server)
+
+	require.NoError(t, ring.SetState("server1", StateDown))
+	require.NoError(t, ring.SetState("server1", StateJoining))
+	require.NoError(t, ring.SetState("server1", StateActive))
+
+	require.True(t, StateDown.CanTransition(StateActive))
+	require.False(t, StateJoining.CanTransition(StateDraining))
+}
+
+func TestServerStateValidation(t *testing.T) {
+	ring := New(10)
+	require.ErrorContains(t, ring.AddServerWithInfo(ServerInfo{Name: "server1", State: "paused"}), `invalid state "paused"`)
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server1", State: StateActive}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server2", State: StateDraining}))
+
+	// SetServerInfo keeps the state unless it's changed, which needs SetState
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server2", Zone: "a"}))
+	state, _ := ring.State("server2")
+	require.Equal(t, StateDraining, state)
+	require.ErrorContains(t, ring.SetServerInfo(ServerInfo{Name: "server2", State: StateActive}), "only be changed with SetState")
+
+	// states are part of the topology
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+	state, _ = restored.State("server2")
+	require.Equal(t, StateDraining, state)
+
+	checksum := ring.Checksum()
+	require.NoError(t, ring.SetState("server2", StateActive))
+	require.NotEqual(t, checksum, ring.Checksum())
+}
+
+func TestNoServerForAccess(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server1", State: StateJoining}))
+
+	_, err := ring.GetServer("key")
+	require.ErrorContains(t, err, "no server is eligible")
+	_, err = ring.GetReplicas("key", 2)
+	require.ErrorContains(t, err, "no server is eligible")
+
+	server, err := ring.GetServerFor("key", AccessWrite)
+	require.NoError(t, err)
+	require.Equal(t, "server1", server)
+}
+
+func TestOnStateChange(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+
+	changes := make(chan StateChange, 10)
+	cancel := ring.OnStateChange(func(c StateChange) {
+		changes <- c
+	})
+
+	require.NoError(t, ring.SetState("server1", StateDraining))
+	require.NoError(t, ring.SetState("server1", StateDown))
+
+	for _, want := range []StateChange{
+		{Server: "server1", From: StateActive, To: StateDraining, Version: 2},
+		{Server: "server1", From: StateDraining, To: StateDown, Version: 3},
+	} {
+		select {
+		case got := <-changes:
+			require.Equal(t, want, got)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for state change")
+		}
+	}
+
+	cancel()
+	require.NoError(t, ring.SetState("server1", StateJoining))
+	select {
+	case c := <-changes:
+		t.Fatalf("unexpected change after cancel: %+v", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,145 @@
+package hashring
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithTenantExtractor sets a function that returns the tenant a key belongs
+// to, for routing tenants to their dedicated servers (see ServerInfo.Tenants).
+// An empty result means the key belongs to no tenant.
+//
+// Without an extractor, tenants are key prefixes: a key belongs to the longest
+// tenant it starts with, so dedicating a server to "tenant42:" isolates
+// "tenant42:orders" and "tenant42:users".
+//
+// Like WithKeyExtractor, the extractor isn't reflected in Checksum or
+// Snapshot.
+//
+// Example:
+//
+//	// keys look like "<tenant>/<kind>/<id>"
+//	ring := hashring.New(150, hashring.WithTenantExtractor(func(key string) string {
+//		tenant, _, _ := strings.Cut(key, "/")
+//		return tenant
+//	}))
+//	ring.AddServerWithInfo(hashring.ServerInfo{Name: "acme-1", Tenants: []string{"acme"}})
+func WithTenantExtractor(extract func(key string) string) Option {
+	return func(h *HashRing) {
+		h.tenantOf = extract
+	}
+}
+
+// errNoEligibleServer returns the error for a lookup of a key that no server
+// is eligible for.
+func errNoEligibleServer(key string) error {
+	return fmt.Errorf("no server is eligible for key %s", key)
+}
+
+// indexServers rebuilds the indexes of dedicated servers by tenant and of
+// servers that aren't active after a membership change. The caller must hold
+// h.mu.
+func (h *HashRing) indexServers() {
+	clear(h.dedicated)
+	clear(h.restricted)
+	for name, info := range h.servers {
+		if info.State != "" {
+			h.restricted[name] = info.State
+		}
+
+		for _, tenant := range info.Tenants {
+			if tenant == "" {
+				continue
+			}
+
+			if h.dedicated[tenant] == nil {
+				h.dedicated[tenant] = make(map[string]bool)
+			}
+			h.dedicated[tenant][name] = true
+		}
+	}
+}
+
+// eligible returns a filter accepting the servers key may route to for the
+// given access, or nil if every server is eligible because none is dedicated
+// or inactive. The caller must hold h.mu.
+func (h *HashRing) eligible(key string, access Access) func(server string) bool {
+	tenants := h.tenantFilter(key)
+	if len(h.restricted) == 0 {
+		return tenants
+	}
+
+	return func(server string) bool {
+		if state, ok := h.restricted[server]; ok && !state.serves(access) {
+			return false
+		}
+
+		return tenants == nil || tenants(server)
+	}
+}
+
+// tenantFilter returns a filter accepting the servers key's tenant may route
+// to, or nil if no server is dedicated. The caller must hold h.mu.
+func (h *HashRing) tenantFilter(key string) func(server string) bool {
+	if len(h.dedicated) == 0 {
+		return nil
+	}
+
+	if tenant := h.tenant(key); tenant != "" {
+		servers := h.dedicated[tenant]
+		return func(server string) bool {
+			return servers[server]
+		}
+	}
+
+	return func(server string) bool {
+		return len(h.servers[server].Tenants) == 0
+	}
+}
+
+// tenant returns the tenant key belongs to if it has dedicated servers, or an
+// empty string. The caller must hold h.mu.
+func (h *HashRing) tenant(key string) string {
+	if h.tenantOf != nil {
+		if tenant := h.tenantOf(key); h.dedicated[tenant] != nil {
+			return tenant
+		}
+
+		return ""
+	}
+
+	var match string
+	for tenant := range h.dedicated {
+		if len(tenant) > len(match) && strings.HasPrefix(key, tenant) {
+			match = tenant
+		}
+	}
+
+	return match
+}
+
+// firstEligible returns the index in entries of the first virtual node
+// clockwise from hash whose server accept accepts, or -1 if there's none. The
+// caller must hold h.mu.
+func (h *HashRing) firstEligible(hash uint64, accept func(string) bool) int {
+	if len(h.entries) == 0 {
+		return -1
+	}
+
+	start := h.search(hash)
+	rejected := make(map[string]bool)
+	for i := range len(h.entries) {
+		idx := (start + i) % len(h.entries)
+		server := h.owner(idx)
+		if accept(server) {
+			return idx
+		}
+
+		// stop early once every server has been rejected
+		if rejected[server] = true; len(rejected) == len(h.servers) {
+			break
+		}
+	}
+
+	return -1
+}
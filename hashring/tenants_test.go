@@ -0,0 +1,133 @@
+package hashring
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedicatedTenants(t *testing.T) {
+	ring := New(50, WithCircuitBreaker(1, time.Hour))
+	for i := range 4 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	checksum := ring.Checksum()
+
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "acme-1", Tenants: []string{"acme:"}}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "acme-2", Tenants: []string{"acme:"}}))
+
+	acme := make(map[string]int)
+	for i := range 1000 {
+		// the tenant's keys only go to its servers
+		key := fmt.Sprintf("acme:user%d", i)
+		server, err := ring.GetServer(key)
+		require.NoError(t, err)
+		acme[server]++
+
+		// and other keys never do, nor move between the other servers
+		key = fmt.Sprintf("user%d", i)
+		server, err = ring.GetServer(key)
+		require.NoError(t, err)
+		want, err := before.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, want, server, key)
+	}
+	require.Len(t, acme, 2)
+	require.Contains(t, acme, "acme-1")
+	require.Contains(t, acme, "acme-2")
+
+	// replicas stay on the right side too
+	replicas, err := ring.GetReplicas("acme:user1", 3)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"acme-1", "acme-2"}, replicas)
+	replicas, err = ring.GetReplicas("user1", 10)
+	require.NoError(t, err)
+	require.Len(t, replicas, 4)
+	require.NotContains(t, replicas, "acme-1")
+
+	// as do breaker fallbacks and pins
+	owner, err := ring.GetServer("acme:user1")
+	require.NoError(t, err)
+	ring.ReportFailure(owner)
+	fallback, err := ring.GetServer("acme:user1")
+	require.NoError(t, err)
+	require.NotEqual(t, owner, fallback)
+	require.True(t, strings.HasPrefix(fallback, "acme-"))
+
+	require.NoError(t, ring.Pin("acme:user2", "server0"))
+	server, err := ring.GetServer("acme:user2")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(server, "acme-"))
+
+	res, err := ring.Lookup("acme:user2")
+	require.NoError(t, err)
+	require.Equal(t, "acme:", res.Tenant)
+	require.False(t, res.Pinned)
+	exp, err := ring.Explain("acme:user2")
+	require.NoError(t, err)
+	require.Equal(t, "acme:", exp.Tenant)
+	require.Equal(t, res.Server, exp.Server)
+
+	frozen := ring.Freeze()
+	for i := range 100 {
+		key := fmt.Sprintf("acme:user%d", i)
+		res, err := ring.Lookup(key)
+		require.NoError(t, err)
+		got, err := frozen.GetServer(key)
+		require.NoError(t, err)
+		require.Equal(t, res.Server, got, key)
+	}
+
+	// removing the dedication returns the servers to general use
+	require.NoError(t, ring.RemoveServer("acme-1"))
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "acme-2"}))
+	res, err = ring.Lookup("acme:user1")
+	require.NoError(t, err)
+	require.Empty(t, res.Tenant)
+	require.NoError(t, ring.RemoveServer("acme-2"))
+	require.NoError(t, ring.Unpin("acme:user2"))
+	require.Equal(t, checksum, ring.Checksum())
+}
+
+func TestTenantExtractor(t *testing.T) {
+	ring := New(50, WithTenantExtractor(func(key string) string {
+		tenant, _, _ := strings.Cut(key, "/")
+		return tenant
+	}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "acme-1", Tenants: []string{"acme"}}))
+
+	server, err := ring.GetServer("acme/orders/1")
+	require.NoError(t, err)
+	require.Equal(t, "acme-1", server)
+
+	// keys of other tenants have nowhere to go
+	_, err = ring.GetServer("globex/orders/1")
+	require.ErrorContains(t, err, "no server is eligible")
+	_, _, err = ring.Claim("globex/orders/1")
+	require.Error(t, err)
+	_, err = ring.GetReplicas("globex/orders/1", 2)
+	require.Error(t, err)
+	_, err = ring.Lookup("globex/orders/1")
+	require.Error(t, err)
+	_, err = ring.Freeze().GetServer("globex/orders/1")
+	require.Error(t, err)
+	exp, err := ring.Explain("globex/orders/1")
+	require.NoError(t, err)
+	require.Empty(t, exp.Server)
+
+	require.NoError(t, ring.AddServer("server1"))
+	server, err = ring.GetServer("globex/orders/1")
+	require.NoError(t, err)
+	require.Equal(t, "server1", server)
+
+	view := ring.View(func(ServerInfo) bool { return true })
+	server, err = view.GetServer("acme/orders/1")
+	require.NoError(t, err)
+	require.Equal(t, "acme-1", server)
+}
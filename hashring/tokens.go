@@ -0,0 +1,88 @@
+package hashring
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AddServerWithTokens adds a server whose virtual nodes are placed at explicit
+// positions on the ring, rather than at hashed ones.
+//
+// This reproduces the layout of token-based systems like Cassandra, where
+// operators assign each node its tokens, and lets operators migrating from them
+// keep every key on the same node. The server owns the keys hashing to each of
+// its tokens and the range counter-clockwise up to the previous token. The
+// ring's virtual node count doesn't apply to servers with tokens.
+//
+// Returns an error if the server already exists, no tokens are given, a token
+// is outside the hash space, or a token is already taken by another virtual
+// node (including duplicates in tokens).
+//
+// Example:
+//
+//	ring.AddServerWithTokens("cassandra-1", []uint64{0, 1 << 30, 2 << 30, 3 << 30})
+func (h *HashRing) AddServerWithTokens(server string, tokens []uint64) error {
+	if len(tokens) == 0 {
+		return fmt.Errorf("server %s: no tokens given", server)
+	}
+
+	return h.AddServerWithInfo(ServerInfo{Name: server, Tokens: tokens})
+}
+
+// validateTokens checks that info's tokens can be placed on the ring. The
+// caller must hold h.mu.
+func (h *HashRing) validateTokens(info ServerInfo) error {
+	if h.evenlySpaced() {
+		return errors.New("explicit tokens can't be used with evenly spaced placement")
+	}
+
+	seen := make(map[uint64]bool, len(info.Tokens))
+	for _, token := range info.Tokens {
+		if token > maxHash {
+			return fmt.Errorf("server %s: token %d is outside the hash space (max %d)", info.Name, token, uint64(maxHash))
+		}
+
+		if seen[token] {
+			return fmt.Errorf("server %s: duplicate token %d", info.Name, token)
+		}
+		seen[token] = true
+
+		if i, ok := find(h.entries, token); ok {
+			return fmt.Errorf("server %s: token %d overlaps with server %s", info.Name, token, h.owner(i))
+		}
+	}
+
+	return nil
+}
+
+// Token is a virtual node on the ring.
+type Token struct {
+	Hash   uint64 `json:"hash"`   // the virtual node's position
+	Server string `json:"server"` // its owner
+	VNode  int    `json:"vnode"`  // its index among the owner's virtual nodes
+}
+
+// Tokens returns every virtual node on the ring in position order, for
+// exporting or auditing the layout, or drawing it with external tools.
+//
+// A virtual node's index is the one its position is derived from: the i in
+// the key hashed for the server's ith virtual node, its slot with evenly
+// spaced placement, or its index in ServerInfo.Tokens. A virtual node moved
+// to resolve a collision keeps its index. This operation is thread-safe.
+//
+// Example:
+//
+//	for _, t := range ring.Tokens() {
+//		fmt.Printf("%#08x %s#%d\n", t.Hash, t.Server, t.VNode)
+//	}
+func (h *HashRing) Tokens() []Token {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	tokens := make([]Token, len(h.entries))
+	for i, v := range h.entries {
+		tokens[i] = Token{Hash: v.hash, Server: h.owner(i), VNode: int(v.index)}
+	}
+
+	return tokens
+}
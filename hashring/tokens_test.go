@@ -0,0 +1,150 @@
+package hashring
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddServerWithTokens(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServerWithTokens("server1", []uint64{100, 300}))
+	require.NoError(t, ring.AddServerWithTokens("server2", []uint64{200, 400}))
+
+	// Each token owns the range counter-clockwise up to the previous token
+	owners := map[uint64]string{
+		0:       "server1",
+		100:     "server1",
+		101:     "server2",
+		200:     "server2",
+		250:     "server1",
+		400:     "server2",
+		401:     "server1", // wraps around to the first token
+		maxHash: "server1",
+	}
+
+	for hash, expected := range owners {
+		require.Equal(t, expected, ring.owner(ring.search(hash)), "hash %d", hash)
+	}
+
+	info, ok := ring.GetServerInfo("server1")
+	require.True(t, ok)
+	require.Equal(t, []uint64{100, 300}, info.Tokens)
+
+	// Removing the server removes exactly its tokens
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.Equal(t, []uint64{200, 400}, positions(ring))
+}
+
+func TestAddServerWithTokensValidation(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServerWithTokens("server1", []uint64{100, 300}))
+
+	require.Error(t, ring.AddServerWithTokens("server2", nil))
+	require.ErrorContains(t, ring.AddServerWithTokens("server2", []uint64{200, 300}), "overlaps with server server1")
+	require.ErrorContains(t, ring.AddServerWithTokens("server2", []uint64{200, 200}), "duplicate token")
+	require.ErrorContains(t, ring.AddServerWithTokens("server2", []uint64{maxHash + 1}), "outside the hash space")
+	require.Error(t, ring.AddServerWithTokens("server1", []uint64{500}))
+	require.Equal(t, []string{"server1"}, ring.GetServers(), "failed adds shouldn't change the ring")
+
+	even := New(10, WithPlacement(PlacementEvenlySpaced))
+	require.Error(t, even.AddServerWithTokens("server1", []uint64{100}))
+}
+
+func TestServerTokensMetadata(t *testing.T) {
+	ring := New(150)
+	require.NoError(t, ring.AddServerWithTokens("server1", []uint64{100, 300}))
+
+	// Metadata updates keep the tokens but can't change them
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server1", Zone: "us-east-1a"}))
+	info, _ := ring.GetServerInfo("server1")
+	require.Equal(t, []uint64{100, 300}, info.Tokens)
+	require.Error(t, ring.SetServerInfo(ServerInfo{Name: "server1", Tokens: []uint64{500}}))
+
+	// Tokens are part of the checksum and survive snapshots
+	other := New(150)
+	require.NoError(t, other.AddServerWithInfo(ServerInfo{Name: "server1", Zone: "us-east-1a", Tokens: []uint64{100, 301}}))
+	require.NotEqual(t, ring.Checksum(), other.Checksum())
+
+	data, err := json.Marshal(ring.Snapshot())
+	require.NoError(t, err)
+
+	var snap Snapshot
+	require.NoError(t, json.Unmarshal(data, &snap))
+
+	restored, err := Restore(snap)
+	require.NoError(t, err)
+	require.Equal(t, positions(ring), positions(restored))
+	require.Equal(t, ring.Checksum(), restored.Checksum())
+}
+
+func TestTokens(t *testing.T) {
+	ring := New(20)
+	require.NoError(t, ring.AddServer("a"))
+	require.NoError(t, ring.AddServer("b"))
+	require.NoError(t, ring.AddServerWithTokens("c", []uint64{300, 100, 200}))
+
+	tokens := ring.Tokens()
+	require.Len(t, tokens, 43)
+	require.True(t, slices.IsSortedFunc(tokens, func(a, b Token) int { return cmp.Compare(a.Hash, b.Hash) }))
+
+	indices := make(map[string][]int)
+	for _, tok := range tokens {
+		indices[tok.Server] = append(indices[tok.Server], tok.VNode)
+	}
+	each := make([]int, 20)
+	for i := range each {
+		each[i] = i
+	}
+	for _, server := range []string{"a", "b"} {
+		slices.Sort(indices[server])
+		require.Equal(t, each, indices[server])
+	}
+
+	// Hashed virtual nodes sit where their index puts them
+	positions := ring.positions("a", 20)
+	for _, tok := range tokens {
+		if tok.Server == "a" {
+			require.Equal(t, positions[tok.VNode], tok.Hash)
+		}
+	}
+
+	// Explicit tokens are indexed in the order given
+	require.Equal(t, []Token{{100, "c", 1}, {200, "c", 2}, {300, "c", 0}}, tokens[:3])
+
+	// Renaming keeps indices, even once the ring is restored
+	require.NoError(t, ring.RenameServer("a", "d"))
+	renamed := func(r *HashRing) map[uint64]int {
+		indices := make(map[uint64]int)
+		for _, tok := range r.Tokens() {
+			if tok.Server == "d" {
+				indices[tok.Hash] = tok.VNode
+			}
+		}
+		return indices
+	}
+
+	want := make(map[uint64]int)
+	for i, pos := range positions {
+		want[pos] = i
+	}
+	require.Equal(t, want, renamed(ring))
+
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.Equal(t, want, renamed(restored))
+
+	// Evenly spaced virtual nodes are indexed by slot
+	even := New(4, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, even.AddServer("a"))
+	require.NoError(t, even.AddServer("b"))
+	require.Equal(t, []Token{
+		{0, "a", 0}, {1 << 29, "b", 0}, {2 << 29, "a", 1}, {3 << 29, "b", 1},
+		{4 << 29, "a", 2}, {5 << 29, "b", 2}, {6 << 29, "a", 3}, {7 << 29, "b", 3},
+	}, even.Tokens())
+
+	require.Empty(t, New(10).Tokens())
+}
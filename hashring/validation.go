@@ -0,0 +1,112 @@
+package hashring
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeName is the default name normalizer. It trims surrounding
+// whitespace and lower-cases the name, since host names are case-insensitive.
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// WithNameNormalizer sets the function used to detect server names that refer
+// to the same server, e.g. "Cache-1" and "cache-1 ". A server whose normalized
+// name matches an existing server's is rejected. Names are stored as given;
+// only the comparison is normalized. The default is NormalizeName.
+//
+// Example:
+//
+//	// treat names as case-sensitive
+//	ring := hashring.New(150, hashring.WithNameNormalizer(strings.TrimSpace))
+func WithNameNormalizer(normalize func(string) string) Option {
+	return func(h *HashRing) {
+		h.normalize = normalize
+	}
+}
+
+// WithMaxServers limits the number of servers in the ring. Adding a server to
+// a full ring fails. Zero (the default) means no limit.
+func WithMaxServers(n int) Option {
+	return func(h *HashRing) {
+		h.maxServers = max(n, 0)
+	}
+}
+
+// WithMaxVNodes limits the total number of virtual nodes in the ring, which
+// bounds its memory use. Adding a server or raising its weight past the limit
+// fails. Zero (the default) means no limit.
+func WithMaxVNodes(n int) Option {
+	return func(h *HashRing) {
+		h.maxVNodes = max(n, 0)
+	}
+}
+
+// validateServer checks that info can be added to the ring: its name isn't
+// empty and doesn't clash with an existing server once normalized, and the
+// ring's limits aren't exceeded. The caller must hold h.mu.
+func (h *HashRing) validateServer(info ServerInfo) error {
+	if err := h.validateName(info.Name, ""); err != nil {
+		return err
+	}
+
+	if h.maxServers > 0 && len(h.servers) >= h.maxServers {
+		return fmt.Errorf("server %s: ring is full (max %d servers)", info.Name, h.maxServers)
+	}
+
+	return h.checkVNodes(info, 0)
+}
+
+// validateName checks that name isn't empty and doesn't clash with an existing
+// server other than replacing once normalized. The caller must hold h.mu.
+func (h *HashRing) validateName(name, replacing string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("server name must not be empty")
+	}
+
+	if existing, ok := h.normalized[h.normalize(name)]; ok && existing != replacing {
+		return fmt.Errorf("server %s conflicts with existing server %s", name, existing)
+	}
+
+	return nil
+}
+
+// checkVNodes checks that replacing released virtual nodes with info's
+// doesn't exceed the ring's virtual node limit. The caller must hold h.mu.
+func (h *HashRing) checkVNodes(info ServerInfo, released int) error {
+	if h.maxVNodes == 0 {
+		return nil
+	}
+
+	if total := h.vnodeCount() - released + h.placedVNodes(info); total > h.maxVNodes {
+		return fmt.Errorf("server %s: %d virtual nodes would exceed the ring's limit of %d", info.Name, total, h.maxVNodes)
+	}
+
+	return nil
+}
+
+// vnodeCount returns the number of virtual nodes on the ring, including those
+// of servers a write batch hasn't placed yet. The caller must hold h.mu.
+func (h *HashRing) vnodeCount() int {
+	if !h.stale {
+		return len(h.entries)
+	}
+
+	var n int
+	for _, info := range h.servers {
+		n += h.placedVNodes(info)
+	}
+
+	return n
+}
+
+// placedVNodes returns the number of virtual nodes info occupies on the ring:
+// one per token when it has explicit tokens, vnodesFor otherwise.
+func (h *HashRing) placedVNodes(info ServerInfo) int {
+	if len(info.Tokens) > 0 {
+		return len(info.Tokens)
+	}
+
+	return h.vnodesFor(info)
+}
@@ -0,0 +1,78 @@
+package hashring
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPanicsOnInvalidVNodes(t *testing.T) {
+	require.Panics(t, func() { New(0) })
+	require.Panics(t, func() { New(-1) })
+
+	_, err := Restore(Snapshot{})
+	require.Error(t, err)
+}
+
+func TestServerNameValidation(t *testing.T) {
+	ring := New(10)
+	require.Error(t, ring.AddServer(""))
+	require.Error(t, ring.AddServer("  "))
+
+	require.NoError(t, ring.AddServer("Cache-1"))
+	require.Error(t, ring.AddServer("cache-1"), "Expected error for name differing only by case")
+	require.Error(t, ring.AddServer(" cache-1 "), "Expected error for name differing only by whitespace")
+	require.Equal(t, []string{"Cache-1"}, ring.GetServers())
+
+	// The normalized name is released on removal
+	require.NoError(t, ring.RemoveServer("Cache-1"))
+	require.NoError(t, ring.AddServer("cache-1"))
+}
+
+func TestWithNameNormalizer(t *testing.T) {
+	ring := New(10, WithNameNormalizer(strings.TrimSpace))
+	require.NoError(t, ring.AddServer("Cache-1"))
+	require.NoError(t, ring.AddServer("cache-1"))
+	require.Error(t, ring.AddServer("cache-1 "))
+}
+
+func TestWithMaxServers(t *testing.T) {
+	ring := New(10, WithMaxServers(2))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.Error(t, ring.AddServer("server3"))
+	require.Equal(t, uint64(2), ring.Version())
+
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.NoError(t, ring.AddServer("server3"))
+}
+
+func TestWithMaxVNodes(t *testing.T) {
+	ring := New(10, WithMaxVNodes(25))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	require.Error(t, ring.AddServer("server3"))
+	require.NoError(t, ring.AddServerWithTokens("server3", []uint64{1, 2, 3}))
+
+	// Raising a weight can't push the ring past the limit either
+	require.Error(t, ring.SetServerInfo(ServerInfo{Name: "server1", Weight: 2}))
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server1", Weight: 1.2}))
+	require.Len(t, ring.entries, 25)
+
+	// Existing tokens are released when their server is updated
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server3", Zone: "us-east-1a"}))
+}
+
+func TestRingSetAddServerErrors(t *testing.T) {
+	set := NewRingSet()
+	small, large := New(10, WithMaxServers(1)), New(10)
+	require.NoError(t, set.Add("small", small))
+	require.NoError(t, set.Add("large", large))
+	require.NoError(t, set.AddServer("server1"))
+
+	err := set.AddServer("server2")
+	require.ErrorContains(t, err, "namespace small")
+	require.Equal(t, []string{"server1"}, small.GetServers())
+	require.Equal(t, []string{"server1", "server2"}, large.GetServers())
+}
@@ -0,0 +1,51 @@
+package hashring
+
+// Version returns the ring's current topology version.
+//
+// The version (or epoch) starts at zero for an empty ring and increases by one
+// on every topology change, such as adding or removing a server. It never
+// decreases, so callers can compare versions to tell whether a routing
+// decision was made against an older ring.
+//
+// This operation is thread-safe.
+//
+// Example:
+//
+//	v := ring.Version()
+//	// ... later
+//	if ring.Version() != v {
+//		log.Println("topology changed, re-resolving")
+//	}
+func (h *HashRing) Version() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.version
+}
+
+// GetServerVersioned returns the server responsible for the given key along
+// with the ring version the decision was made against.
+//
+// The lookup and the version are read atomically, so the returned version is
+// guaranteed to describe the ring that produced the server. Callers can store
+// the version alongside the routing decision and later compare it with
+// Version() to detect decisions made before a rebalance.
+//
+// Returns an error if the hash ring is empty.
+//
+// Example:
+//
+//	server, version, err := ring.GetServerVersioned("user:12345")
+//	if err != nil {
+//		return err
+//	}
+//	// ... later
+//	if version != ring.Version() {
+//		// the mapping may be stale
+//	}
+func (h *HashRing) GetServerVersioned(key string) (string, uint64, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	server, err := h.getServer(key, AccessRead)
+	return server, h.version, err
+}
@@ -0,0 +1,47 @@
+package hashring
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion(t *testing.T) {
+	ring := New(150)
+	require.Equal(t, uint64(0), ring.Version(), "Empty ring should start at version 0")
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.Equal(t, uint64(1), ring.Version())
+
+	require.NoError(t, ring.AddServer("server2"))
+	require.Equal(t, uint64(2), ring.Version())
+
+	// Failed operations don't bump the version
+	require.Error(t, ring.AddServer("server2"))
+	require.Error(t, ring.RemoveServer("server3"))
+	require.Equal(t, uint64(2), ring.Version())
+
+	require.NoError(t, ring.RemoveServer("server1"))
+	require.Equal(t, uint64(3), ring.Version())
+}
+
+func TestGetServerVersioned(t *testing.T) {
+	ring := New(150)
+
+	_, _, err := ring.GetServerVersioned("key1")
+	require.Error(t, err, "Expected error for empty ring")
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	server, version, err := ring.GetServerVersioned("key1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), version)
+
+	expected, err := ring.GetServer("key1")
+	require.NoError(t, err)
+	require.Equal(t, expected, server)
+
+	require.NoError(t, ring.AddServer("server3"))
+	require.NotEqual(t, version, ring.Version(), "Stale version should be detectable")
+}
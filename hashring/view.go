@@ -0,0 +1,104 @@
+package hashring
+
+import (
+	"errors"
+	"sort"
+)
+
+// View is a read-only, filtered view of a HashRing.
+//
+// A view shares the underlying ring, so it always reflects the ring's current
+// membership and costs nothing to create. Lookups walk the ring clockwise from
+// the key's position and return the first server accepted by the filter. Keys
+// owned by an accepted server route exactly as they do in the full ring, while
+// keys owned by rejected servers fall through to the next accepted server.
+type View struct {
+	ring   *HashRing
+	filter func(ServerInfo) bool
+}
+
+// View returns a read-only view of the ring that only routes to servers
+// accepted by filter.
+//
+// The filter is evaluated during lookups, so it must be fast and safe to call
+// concurrently. It must not call back into the ring.
+//
+// Example:
+//
+//	east := ring.View(func(info hashring.ServerInfo) bool {
+//		return info.Zone == "us-east-1b"
+//	})
+//	ssd := ring.View(func(info hashring.ServerInfo) bool {
+//		return info.HasTag("ssd")
+//	})
+//	server, err := east.GetServer("user:12345")
+func (h *HashRing) View(filter func(ServerInfo) bool) *View {
+	return &View{ring: h, filter: filter}
+}
+
+// GetServer returns the server responsible for the given key within the view.
+//
+// Pins and the canary are honoured when their server is part of the view.
+// Servers that aren't eligible for the key (see ServerInfo.Tenants) or don't
+// serve reads (see SetState) are skipped as if the view rejected them.
+//
+// Returns an error if no server in the ring is accepted by the view.
+func (v *View) GetServer(key string) (string, error) {
+	h := v.ring
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	eligible := h.eligible(key, AccessRead)
+	accept := func(server string) bool {
+		return v.filter(h.servers[server]) && (eligible == nil || eligible(server))
+	}
+
+	if server, ok := h.override(key); ok && accept(server) {
+		return server, nil
+	}
+
+	accepted := make(map[string]bool)
+	var owner string
+	h.walk(h.hashKey(h.routingKey(key)), func(server string) bool {
+		ok, seen := accepted[server]
+		if !seen {
+			ok = accept(server)
+			accepted[server] = ok
+		}
+
+		if ok {
+			owner = server
+		}
+
+		// stop early once every server has been rejected
+		return !ok && len(accepted) < len(h.servers)
+	})
+
+	if owner == "" {
+		return "", errors.New("no servers in view")
+	}
+
+	return owner, nil
+}
+
+// GetServers returns a sorted list of the servers in the view.
+func (v *View) GetServers() []string {
+	h := v.ring
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	servers := make([]string, 0, len(h.servers))
+	for server, info := range h.servers {
+		if v.filter(info) {
+			servers = append(servers, server)
+		}
+	}
+
+	sort.Strings(servers)
+	return servers
+}
+
+// Size returns the number of servers in the view.
+func (v *View) Size() int {
+	return len(v.GetServers())
+}
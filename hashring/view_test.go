@@ -0,0 +1,79 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newZonedRing(t *testing.T) *HashRing {
+	t.Helper()
+
+	ring := New(150)
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "east-1", Zone: "east", Tags: []string{"ssd"}}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "east-2", Zone: "east"}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "west-1", Zone: "west", Tags: []string{"ssd"}}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "west-2", Zone: "west"}))
+	return ring
+}
+
+func inZone(zone string) func(ServerInfo) bool {
+	return func(info ServerInfo) bool { return info.Zone == zone }
+}
+
+func TestView(t *testing.T) {
+	ring := newZonedRing(t)
+	east := ring.View(inZone("east"))
+
+	require.Equal(t, []string{"east-1", "east-2"}, east.GetServers())
+	require.Equal(t, 2, east.Size())
+
+	for i := range 1000 {
+		key := fmt.Sprintf("key-%d", i)
+		server, err := east.GetServer(key)
+		require.NoError(t, err)
+		require.Contains(t, []string{"east-1", "east-2"}, server)
+
+		// Keys owned by an accepted server route as they do in the full ring
+		owner, err := ring.GetServer(key)
+		require.NoError(t, err)
+		if owner == "east-1" || owner == "east-2" {
+			require.Equal(t, owner, server)
+		}
+	}
+
+	ssd := ring.View(func(info ServerInfo) bool { return info.HasTag("ssd") })
+	require.Equal(t, []string{"east-1", "west-1"}, ssd.GetServers())
+}
+
+func TestViewTracksRing(t *testing.T) {
+	ring := newZonedRing(t)
+	east := ring.View(inZone("east"))
+
+	require.NoError(t, ring.RemoveServer("east-2"))
+	require.Equal(t, []string{"east-1"}, east.GetServers())
+
+	server, err := east.GetServer("key1")
+	require.NoError(t, err)
+	require.Equal(t, "east-1", server)
+
+	require.NoError(t, ring.RemoveServer("east-1"))
+	_, err = east.GetServer("key1")
+	require.Error(t, err, "Expected error when no servers match")
+}
+
+func TestViewPins(t *testing.T) {
+	ring := newZonedRing(t)
+	require.NoError(t, ring.Pin("tenant:", "west-1"))
+
+	west := ring.View(inZone("west"))
+	server, err := west.GetServer("tenant:1")
+	require.NoError(t, err)
+	require.Equal(t, "west-1", server)
+
+	east := ring.View(inZone("east"))
+	server, err = east.GetServer("tenant:1")
+	require.NoError(t, err)
+	require.Contains(t, []string{"east-1", "east-2"}, server, "Pins outside the view should be ignored")
+}
@@ -0,0 +1,115 @@
+package hashring
+
+import (
+	"cmp"
+	"slices"
+)
+
+// WarmupPlan describes what a server will own once it takes traffic, as
+// returned by HashRing.WarmupPlan.
+type WarmupPlan struct {
+	Server string `json:"server"`
+
+	// Ranges are the ranges of positions the server will own, ordered by
+	// position, with From set to the server that owns them without it.
+	Ranges []RangeMove `json:"ranges"`
+
+	// Keys are the sampled keys the server will own, most accessed first.
+	Keys []WarmupKey `json:"keys"`
+}
+
+// WarmupKey is a key a server will own, as reported by WarmupPlan.
+type WarmupKey struct {
+	Key      string `json:"key"`
+	From     string `json:"from"`     // the server currently serving the key's reads
+	Accesses int    `json:"accesses"` // the number of times the key appears in the sample
+}
+
+// WarmupPlan returns the ranges and sampled keys server will own once it takes
+// traffic, along with the servers currently serving them, so its cache can be
+// pre-populated before it does.
+//
+// The server may be one about to be added with its name alone, or one already
+// in the ring that isn't active yet, e.g. joining (see SetState). The canary
+// is taken into account throughout. Pins and tenant isolation are taken into
+// account for keys, but not for ranges, which cover positions rather than
+// keys.
+//
+// The ring doesn't track how often keys are accessed, so keySample should be a
+// sample of recent accesses, e.g. from a request log, repeats included: keys
+// are ordered by how often they appear in it, so the hottest are warmed first.
+//
+// Returns an error if server isn't in the ring and can't be added to it.
+//
+// Example:
+//
+//	plan, err := ring.WarmupPlan("cache-4", recentKeys)
+//	if err != nil {
+//		return err
+//	}
+//	for _, k := range plan.Keys {
+//		copyKey(k.Key, k.From, plan.Server)
+//	}
+//	_ = ring.AddServer("cache-4")
+func (h *HashRing) WarmupPlan(server string, keySample []string) (WarmupPlan, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	// Plan against a copy of the ring with the server active, comparing it to
+	// the current layout without the server
+	after := h.clone()
+	before := h.copyLayout()
+	if info, ok := h.servers[server]; ok {
+		info.State = ""
+		after.servers[server] = info
+		after.indexServers()
+
+		id := h.ids[server]
+		i := 0
+		for _, v := range h.entries {
+			if v.server != id {
+				before.positions[i] = v.hash
+				before.owners[i] = h.names[v.server]
+				i++
+			}
+		}
+		before.positions, before.owners = before.positions[:i], before.owners[:i]
+	} else if err := after.addServer(ServerInfo{Name: server}); err != nil {
+		return WarmupPlan{}, err
+	}
+
+	plan := WarmupPlan{Server: server}
+	for _, move := range diffLayouts(before, after.copyLayout()) {
+		if move.To == server {
+			plan.Ranges = append(plan.Ranges, move)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, key := range keySample {
+		if counts[key]++; counts[key] > 1 {
+			continue
+		}
+
+		if after.locate(key, AccessRead) != server {
+			continue
+		}
+
+		var from string
+		if len(h.entries) > 0 {
+			from = h.locate(key, AccessRead)
+		}
+		plan.Keys = append(plan.Keys, WarmupKey{Key: key, From: from})
+	}
+
+	for i := range plan.Keys {
+		plan.Keys[i].Accesses = counts[plan.Keys[i].Key]
+	}
+
+	// Keys seen equally often stay in the order they were first seen
+	slices.SortStableFunc(plan.Keys, func(a, b WarmupKey) int {
+		return cmp.Compare(b.Accesses, a.Accesses)
+	})
+
+	return plan, nil
+}
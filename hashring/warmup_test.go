@@ -0,0 +1,83 @@
+package hashring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmupPlan(t *testing.T) {
+	ring := New(50)
+	for i := range 3 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+
+	var sample []string
+	for i := range 500 {
+		sample = append(sample, fmt.Sprintf("key%d", i))
+	}
+	// key7 and key3 are hot, whoever owns them
+	sample = append(sample, "key7", "key7", "key3")
+
+	plan, err := ring.WarmupPlan("new", sample)
+	require.NoError(t, err)
+	require.Equal(t, "new", plan.Server)
+	require.NotEmpty(t, plan.Ranges)
+	require.NotEmpty(t, plan.Keys)
+	require.Equal(t, 3, ring.Size())
+
+	// the plan matches what the server owns once added
+	before, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.NoError(t, ring.AddServer("new"))
+	require.Equal(t, DiffRanges(before, ring), plan.Ranges)
+
+	var owned []string
+	for i := range 500 {
+		key := fmt.Sprintf("key%d", i)
+		if server, _ := ring.GetServer(key); server == "new" {
+			owned = append(owned, key)
+		}
+	}
+	require.Len(t, plan.Keys, len(owned))
+
+	for i, k := range plan.Keys {
+		require.Contains(t, owned, k.Key)
+		from, err := before.GetServer(k.Key)
+		require.NoError(t, err)
+		require.Equal(t, from, k.From)
+		if i > 0 {
+			require.LessOrEqual(t, k.Accesses, plan.Keys[i-1].Accesses)
+		}
+	}
+}
+
+func TestWarmupPlanJoining(t *testing.T) {
+	ring := New(50)
+	for i := range 3 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("server%d", i)))
+	}
+	want, err := ring.WarmupPlan("new", []string{"a", "b", "c", "d", "e", "f", "b"})
+	require.NoError(t, err)
+
+	// planning for a joining server gives the same plan as before it joined
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "new", State: StateJoining}))
+	plan, err := ring.WarmupPlan("new", []string{"a", "b", "c", "d", "e", "f", "b"})
+	require.NoError(t, err)
+	require.Equal(t, want, plan)
+
+	state, _ := ring.State("new")
+	require.Equal(t, StateJoining, state)
+}
+
+func TestWarmupPlanInvalid(t *testing.T) {
+	ring := New(10)
+	_, err := ring.WarmupPlan("", nil)
+	require.Error(t, err)
+
+	// an empty ring hands everything to the new server
+	plan, err := ring.WarmupPlan("server1", []string{"a"})
+	require.NoError(t, err)
+	require.Equal(t, []WarmupKey{{Key: "a", Accesses: 1}}, plan.Keys)
+}
@@ -0,0 +1,247 @@
+package hashring
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Capacity describes the resources of a server, used to derive its weight
+// when one isn't given explicitly (see WithWeightPolicy). Units are up to the
+// caller, but must be consistent across servers, e.g. cores for CPU and GiB
+// for Memory and Disk.
+type Capacity struct {
+	CPU    float64 `json:"cpu,omitempty"`
+	Memory float64 `json:"memory,omitempty"`
+	Disk   float64 `json:"disk,omitempty"`
+	Score  float64 `json:"score,omitempty"` // custom score, e.g. from a benchmark
+}
+
+// IsZero reports whether no capacity is set.
+func (c Capacity) IsZero() bool {
+	return c == Capacity{}
+}
+
+// WeightPolicy converts a server's capacity into its weight, relative to a
+// server of weight 1.
+type WeightPolicy func(Capacity) float64
+
+// ScoreWeight is the default weight policy. It uses the capacity's Score as
+// the weight, or 1 if there's no score.
+func ScoreWeight(c Capacity) float64 {
+	if c.Score > 0 {
+		return c.Score
+	}
+
+	return 1
+}
+
+// BottleneckWeight returns a policy that weighs servers against a baseline
+// server of weight 1. A server's weight is the smallest ratio of its capacity
+// to the baseline's across the resources set in both, since the scarcest
+// resource bounds how much load it can take. Servers sharing no resources with
+// the baseline get weight 1.
+//
+// Example:
+//
+//	// an m5.2xlarge (8 cores, 32GiB) gets twice the load of the baseline
+//	policy := hashring.BottleneckWeight(hashring.Capacity{CPU: 4, Memory: 16})
+func BottleneckWeight(baseline Capacity) WeightPolicy {
+	return func(c Capacity) float64 {
+		weight := math.Inf(1)
+		for _, pair := range [][2]float64{
+			{c.CPU, baseline.CPU},
+			{c.Memory, baseline.Memory},
+			{c.Disk, baseline.Disk},
+			{c.Score, baseline.Score},
+		} {
+			if pair[0] > 0 && pair[1] > 0 {
+				weight = min(weight, pair[0]/pair[1])
+			}
+		}
+
+		if math.IsInf(weight, 1) {
+			return 1
+		}
+
+		return weight
+	}
+}
+
+// WithWeightPolicy sets how servers added with a Capacity but no Weight are
+// weighted. The default is ScoreWeight.
+//
+// The derived weight is stored in the server's info, so snapshots restore the
+// same layout regardless of the restoring ring's policy.
+//
+// Example:
+//
+//	ring := hashring.New(100, hashring.WithWeightPolicy(hashring.BottleneckWeight(baseline)))
+//	ring.AddServerWithInfo(hashring.ServerInfo{
+//		Name:     "cache-large-1",
+//		Capacity: hashring.Capacity{CPU: 16, Memory: 64},
+//	})
+func WithWeightPolicy(policy WeightPolicy) Option {
+	return func(h *HashRing) {
+		h.weightPolicy = policy
+	}
+}
+
+// SetWeight changes a server's weight, adding or removing virtual nodes to
+// match. Virtual nodes are removed from the end of the server's sequence and
+// added back in the same order, so lowering a weight only moves the keys of
+// the virtual nodes removed, and raising it again restores the same layout. A
+// count adjusted by Rebalance is scaled along with the weight.
+//
+// Returns an error if the server doesn't exist or has explicit tokens, the
+// weight isn't positive, or the ring's virtual node limit would be exceeded.
+// To take a server out entirely, remove it.
+//
+// Example:
+//
+//	err := ring.SetWeight("cache-1", 0.5)
+func (h *HashRing) SetWeight(server string, weight float64) error {
+	return h.write(func() error {
+		return h.setWeight(server, weight)
+	})
+}
+
+// StepWeight moves a server's weight to target in steps of at most step,
+// waiting interval between them, so capacity can be bled off a server before
+// it's decommissioned (or ramped onto a new one) without moving all of its keys
+// at once. Each step is a separate topology change, so OnMove subscribers can
+// migrate data as it goes.
+//
+// It blocks until target is reached, returning nil, or ctx is done, returning
+// ctx's error and leaving the weight at the last step taken. Returns an error
+// if step isn't positive or a step can't be applied (see SetWeight).
+//
+// Example:
+//
+//	// bleed off 10% of the server's share every minute
+//	err := ring.StepWeight(ctx, "cache-1", 0.1, 0.1, time.Minute)
+//	if err == nil {
+//		err = ring.RemoveServer("cache-1")
+//	}
+func (h *HashRing) StepWeight(ctx context.Context, server string, target, step float64, interval time.Duration) error {
+	if !(step > 0) {
+		return fmt.Errorf("invalid weight step %v", step)
+	}
+
+	h.mu.RLock()
+	info, ok := h.servers[server]
+	h.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("server %s does not exist", server)
+	}
+
+	// Spread the change evenly so the last step lands exactly on target
+	start := weightOf(info)
+	steps := int(math.Ceil(math.Abs(target-start) / step))
+
+	for i := 1; i <= steps; i++ {
+		weight := start + (target-start)*float64(i)/float64(steps)
+		if err := h.SetWeight(server, weight); err != nil {
+			return err
+		}
+
+		if i < steps {
+			if err := sleep(ctx, interval); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// setWeight changes a server's weight. The caller must hold h.mu.
+func (h *HashRing) setWeight(server string, weight float64) error {
+	current, ok := h.servers[server]
+	if !ok {
+		return fmt.Errorf("server %s does not exist", server)
+	}
+
+	if len(current.Tokens) > 0 {
+		return fmt.Errorf("server %s: weights can't be used with explicit tokens", server)
+	}
+
+	if !(weight > 0) || math.IsInf(weight, 0) {
+		return fmt.Errorf("server %s: invalid weight %v", server, weight)
+	}
+
+	info := current.clone()
+	info.Weight = weight
+	if current.VNodes > 0 {
+		info.VNodes = max(1, int(math.Round(float64(current.VNodes)*weight/weightOf(current))))
+	}
+
+	if err := h.checkVNodes(info, h.placedVNodes(current)); err != nil {
+		return err
+	}
+
+	if err := h.updateServer(info); err != nil {
+		return err
+	}
+
+	h.recordChange(ChangeUpdate, server)
+	return nil
+}
+
+// resolveWeight fills in info's weight from its capacity if it doesn't have
+// one, and validates it. Servers with tokens aren't weighted. The caller must
+// hold h.mu.
+func (h *HashRing) resolveWeight(info ServerInfo) (ServerInfo, error) {
+	if len(info.Tokens) > 0 {
+		if info.Weight != 0 || info.VNodes != 0 {
+			return info, fmt.Errorf("server %s: weights can't be used with explicit tokens", info.Name)
+		}
+
+		return info, nil
+	}
+
+	if info.Weight == 0 && !info.Capacity.IsZero() {
+		info.Weight = h.weightPolicy(info.Capacity)
+	}
+
+	if info.Weight < 0 || math.IsNaN(info.Weight) || math.IsInf(info.Weight, 0) {
+		return info, fmt.Errorf("server %s: invalid weight %v", info.Name, info.Weight)
+	}
+
+	if info.VNodes < 0 {
+		return info, fmt.Errorf("server %s: invalid virtual node count %d", info.Name, info.VNodes)
+	}
+
+	return info, nil
+}
+
+// vnodesFor returns the number of hashed virtual nodes for a server: its
+// VNodes if set, or else the ring's virtual node count scaled by the server's
+// weight, and at least one.
+func (h *HashRing) vnodesFor(info ServerInfo) int {
+	if info.VNodes > 0 {
+		return info.VNodes
+	}
+
+	return h.weightedVNodes(info)
+}
+
+// weightedVNodes returns the ring's virtual node count scaled by the server's
+// weight, ignoring any VNodes override.
+func (h *HashRing) weightedVNodes(info ServerInfo) int {
+	if info.Weight == 0 {
+		return h.vnodes
+	}
+
+	return max(1, int(math.Round(float64(h.vnodes)*info.Weight)))
+}
+
+// weightOf returns the server's effective weight.
+func weightOf(info ServerInfo) float64 {
+	if info.Weight == 0 {
+		return 1
+	}
+
+	return info.Weight
+}
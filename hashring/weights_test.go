@@ -0,0 +1,182 @@
+package hashring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// vnodeCounts returns the number of virtual nodes each server has.
+func vnodeCounts(h *HashRing) map[string]int {
+	counts := make(map[string]int)
+	for i := range h.entries {
+		counts[h.owner(i)]++
+	}
+
+	return counts
+}
+
+func TestWeights(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server2", Weight: 2.5}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server3", Weight: 0.01}))
+	require.Equal(t, map[string]int{"server1": 10, "server2": 25, "server3": 1}, vnodeCounts(ring))
+
+	// Weighted servers keep the same positions for their first vnodes, so
+	// changing a weight only moves the ranges of the vnodes added or removed
+	plain := New(10)
+	require.NoError(t, plain.AddServer("server2"))
+	weighted := New(10)
+	require.NoError(t, weighted.AddServerWithInfo(ServerInfo{Name: "server2", Weight: 2.5}))
+	require.Subset(t, positions(weighted), positions(plain))
+
+	err := ring.AddServerWithInfo(ServerInfo{Name: "server4", Weight: -1})
+	require.ErrorContains(t, err, "invalid weight")
+	require.False(t, ring.hasServer("server4"))
+
+	err = ring.AddServerWithInfo(ServerInfo{Name: "server4", Weight: 2, Tokens: []uint64{1, 2}})
+	require.ErrorContains(t, err, "can't be used with explicit tokens")
+}
+
+func TestWeightsEvenlySpaced(t *testing.T) {
+	ring := New(8, WithPlacement(PlacementEvenlySpaced))
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server2", Weight: 2}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server3", Weight: 0.5}))
+
+	shares := ring.OwnershipShare()
+	require.InDelta(t, 8.0/28, shares["server1"], 1e-9)
+	require.InDelta(t, 16.0/28, shares["server2"], 1e-9)
+	require.InDelta(t, 4.0/28, shares["server3"], 1e-9)
+
+	// Servers are interleaved rather than clustered
+	run, longest := 1, 1
+	for i := 1; i < len(ring.entries); i++ {
+		if ring.entries[i].server == ring.entries[i-1].server {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 1
+		}
+	}
+	require.LessOrEqual(t, longest, 2)
+}
+
+func TestWeightPolicy(t *testing.T) {
+	// By default the capacity score is the weight
+	ring := New(10)
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server1", Capacity: Capacity{Score: 3}}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "server2", Capacity: Capacity{CPU: 8}}))
+	require.Equal(t, map[string]int{"server1": 30, "server2": 10}, vnodeCounts(ring))
+
+	baseline := Capacity{CPU: 4, Memory: 16}
+	ring = New(10, WithWeightPolicy(BottleneckWeight(baseline)))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "large", Capacity: Capacity{CPU: 16, Memory: 32}}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "small", Capacity: Capacity{CPU: 2, Memory: 16}}))
+	require.NoError(t, ring.AddServerWithInfo(ServerInfo{Name: "explicit", Weight: 3, Capacity: Capacity{CPU: 4}}))
+	require.Equal(t, map[string]int{"large": 20, "small": 5, "explicit": 30}, vnodeCounts(ring))
+
+	// The derived weight is stored, so restoring doesn't depend on the policy
+	info, _ := ring.GetServerInfo("large")
+	require.Equal(t, 2.0, info.Weight)
+
+	restored, err := Restore(ring.Snapshot())
+	require.NoError(t, err)
+	require.Equal(t, positions(ring), positions(restored))
+}
+
+func TestBottleneckWeight(t *testing.T) {
+	policy := BottleneckWeight(Capacity{CPU: 4, Memory: 16, Disk: 100})
+	require.Equal(t, 2.0, policy(Capacity{CPU: 8, Memory: 64, Disk: 200}))
+	require.Equal(t, 0.5, policy(Capacity{CPU: 8, Memory: 8}))
+	require.Equal(t, 1.0, policy(Capacity{Score: 10}), "no shared resources")
+}
+
+func TestSetServerInfoWeight(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	before := positions(ring)
+	checksum := ring.Checksum()
+
+	// Changing metadata doesn't move anything
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server2", Zone: "us-east-1a"}))
+	require.Equal(t, before, positions(ring))
+
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server2", Weight: 2}))
+	require.Equal(t, map[string]int{"server1": 10, "server2": 20}, vnodeCounts(ring))
+	require.Subset(t, positions(ring), before)
+	require.NotEqual(t, checksum, ring.Checksum())
+
+	// Rollbacks restore the weight
+	require.NoError(t, ring.Rollback(ring.Version()-2))
+	require.Equal(t, before, positions(ring))
+	require.Equal(t, checksum, ring.Checksum())
+
+	require.ErrorContains(t, ring.SetServerInfo(ServerInfo{Name: "server2", Weight: -2}), "invalid weight")
+}
+
+func TestSetWeight(t *testing.T) {
+	ring := New(10)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	before := positions(ring)
+
+	// Lowering the weight only removes the server's virtual nodes
+	require.NoError(t, ring.SetWeight("server2", 0.5))
+	require.Equal(t, map[string]int{"server1": 10, "server2": 5}, vnodeCounts(ring))
+	require.Subset(t, before, positions(ring))
+
+	// and raising it again restores the same ones
+	require.NoError(t, ring.SetWeight("server2", 1))
+	require.Equal(t, before, positions(ring))
+
+	// Counts adjusted by Rebalance are scaled with the weight
+	require.NoError(t, ring.SetServerInfo(ServerInfo{Name: "server1", VNodes: 12}))
+	require.NoError(t, ring.SetWeight("server1", 0.5))
+	info, _ := ring.GetServerInfo("server1")
+	require.Equal(t, 6, info.VNodes)
+
+	require.Error(t, ring.SetWeight("server3", 1))
+	require.Error(t, ring.SetWeight("server1", 0))
+	require.Error(t, ring.SetWeight("server1", -1))
+	require.NoError(t, ring.AddServerWithTokens("server3", []uint64{1, 2, 3}))
+	require.Error(t, ring.SetWeight("server3", 2))
+}
+
+func TestStepWeight(t *testing.T) {
+	ring := New(100)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+	version := ring.Version()
+
+	require.NoError(t, ring.StepWeight(context.Background(), "server2", 0.25, 0.3, time.Millisecond))
+	require.Equal(t, version+3, ring.Version())
+	require.Equal(t, map[string]int{"server1": 100, "server2": 25}, vnodeCounts(ring))
+
+	// Each step is a separate change
+	require.Len(t, ring.History(), 5)
+	for i, want := range []float64{0.75, 0.5, 0.25} {
+		info := ring.History()[2+i].Servers[1]
+		require.InDelta(t, want, info.Weight, 1e-9)
+	}
+
+	require.Error(t, ring.StepWeight(context.Background(), "server2", 1, 0, time.Millisecond))
+	require.Error(t, ring.StepWeight(context.Background(), "server3", 1, 0.1, time.Millisecond))
+}
+
+func TestStepWeightCancel(t *testing.T) {
+	ring := New(100)
+	require.NoError(t, ring.AddServer("server1"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The first step is taken before waiting
+	require.ErrorIs(t, ring.StepWeight(ctx, "server1", 0.5, 0.1, time.Hour), context.Canceled)
+	info, _ := ring.GetServerInfo("server1")
+	require.InDelta(t, 0.9, info.Weight, 1e-9)
+}
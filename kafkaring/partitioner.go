@@ -0,0 +1,128 @@
+// Package kafkaring provides a franz-go partitioner that maps record keys to
+// partitions using a consistent hash ring.
+//
+// Each partition is a server on the ring, so keys hash the same way they do
+// everywhere else hashlab is used, and growing a topic from n to n+1
+// partitions only remaps about 1/(n+1) of the keys, where the default
+// murmur2-modulo partitioner remaps nearly all of them.
+package kafkaring
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// DefaultVirtualNodes is the number of virtual nodes placed for each partition.
+const DefaultVirtualNodes = 150
+
+// Option configures a Partitioner.
+type Option func(*Partitioner)
+
+// WithVirtualNodes sets the number of virtual nodes placed for each partition.
+func WithVirtualNodes(n int) Option {
+	return func(p *Partitioner) {
+		p.vnodes = n
+	}
+}
+
+// Partitioner is a kgo.Partitioner that places record keys on a hash ring of
+// partitions.
+//
+// Records without a key aren't hashed; they're spread round-robin across
+// partitions and may be moved to another partition if theirs is unavailable.
+//
+// It is safe for concurrent use.
+type Partitioner struct {
+	vnodes int
+
+	mu    sync.Mutex
+	rings map[int]*hashring.HashRing // partition count -> ring
+}
+
+var _ kgo.Partitioner = (*Partitioner)(nil)
+
+// NewPartitioner creates a ring-backed partitioner.
+//
+// Example:
+//
+//	client, err := kgo.NewClient(
+//		kgo.SeedBrokers("localhost:9092"),
+//		kgo.RecordPartitioner(kafkaring.NewPartitioner()),
+//	)
+func NewPartitioner(opts ...Option) *Partitioner {
+	p := &Partitioner{
+		vnodes: DefaultVirtualNodes,
+		rings:  make(map[int]*hashring.HashRing),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// ForTopic returns the partitioner for a topic.
+func (p *Partitioner) ForTopic(string) kgo.TopicPartitioner {
+	return &topicPartitioner{p: p}
+}
+
+// Partition returns the partition, among n, that key maps to. It's the mapping
+// used for keyed records and can be used by consumers or other services to
+// locate a key's partition without producing.
+func (p *Partitioner) Partition(key []byte, n int) int {
+	if n <= 1 {
+		return 0
+	}
+
+	server, err := p.ring(n).GetServer(string(key))
+	if err != nil {
+		return 0
+	}
+
+	partition, _ := strconv.Atoi(server)
+	return partition
+}
+
+// ring returns the (cached) ring containing partitions 0 through n-1.
+func (p *Partitioner) ring(n int) *hashring.HashRing {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ring, ok := p.rings[n]; ok {
+		return ring
+	}
+
+	ring := hashring.New(p.vnodes)
+	for i := range n {
+		_ = ring.AddServer(strconv.Itoa(i))
+	}
+
+	p.rings[n] = ring
+	return ring
+}
+
+type topicPartitioner struct {
+	p    *Partitioner
+	next int
+}
+
+// RequiresConsistency reports whether r must always go to the same partition,
+// which is the case for keyed records.
+func (t *topicPartitioner) RequiresConsistency(r *kgo.Record) bool {
+	return r.Key != nil
+}
+
+// Partition returns the partition for r among n partitions. kgo guarantees a
+// topic partitioner isn't used concurrently.
+func (t *topicPartitioner) Partition(r *kgo.Record, n int) int {
+	if r.Key != nil {
+		return t.p.Partition(r.Key, n)
+	}
+
+	t.next = (t.next + 1) % n
+	return t.next
+}
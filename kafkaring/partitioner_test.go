@@ -0,0 +1,64 @@
+package kafkaring
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestPartition(t *testing.T) {
+	p := NewPartitioner()
+	tp := p.ForTopic("events")
+
+	seen := make(map[int]bool)
+	for i := range 1000 {
+		r := &kgo.Record{Key: fmt.Appendf(nil, "user:%d", i)}
+		require.True(t, tp.RequiresConsistency(r))
+
+		partition := tp.Partition(r, 8)
+		require.GreaterOrEqual(t, partition, 0)
+		require.Less(t, partition, 8)
+		require.Equal(t, partition, tp.Partition(r, 8), "keyed records should be consistent")
+		require.Equal(t, partition, p.Partition(r.Key, 8))
+
+		seen[partition] = true
+	}
+
+	require.Len(t, seen, 8, "keys should be spread across every partition")
+	require.Zero(t, p.Partition([]byte("key"), 1))
+}
+
+func TestPartitionUnkeyed(t *testing.T) {
+	tp := NewPartitioner().ForTopic("events")
+
+	r := &kgo.Record{Value: []byte("value")}
+	require.False(t, tp.RequiresConsistency(r))
+
+	seen := make(map[int]bool)
+	for range 4 {
+		seen[tp.Partition(r, 4)] = true
+	}
+	require.Len(t, seen, 4, "unkeyed records should be spread round-robin")
+}
+
+func TestPartitionCountChange(t *testing.T) {
+	p := NewPartitioner()
+
+	const keys = 10000
+
+	moved := 0
+	for i := range keys {
+		key := fmt.Appendf(nil, "user:%d", i)
+
+		before, after := p.Partition(key, 8), p.Partition(key, 9)
+		if before != after {
+			require.Equal(t, 8, after, "keys should only move to the new partition")
+			moved++
+		}
+	}
+
+	// Roughly 1/9 of the keys should move
+	require.InDelta(t, keys/9, moved, keys*0.05)
+}
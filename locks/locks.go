@@ -0,0 +1,198 @@
+// Package locks shards coordination responsibilities, such as cron jobs or
+// singleton workers, across a fleet by mapping lock names to owner servers
+// through a consistent hash ring.
+//
+// Every node holds the same ring and asks a Sharder which server owns each
+// lock; only the owner runs the work. Because owners come from the ring,
+// adding or removing a server only moves the locks adjacent to it, and nodes
+// are told which registered locks changed hands so they can start or stop
+// work (see Sharder.OnRebalance).
+//
+// Ownership follows the ring, not a lock service, so two nodes with different
+// views of the ring may briefly both believe they own a lock. Pass the fencing
+// token from Sharder.Claim to any storage the work writes to if that matters
+// (see hashring.FenceGuard).
+package locks
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// Handoff records a registered lock moving between servers. From is empty for
+// locks registered while the ring was empty, and To is empty when the ring
+// becomes empty.
+type Handoff struct {
+	Lock  string
+	From  string
+	To    string
+	Fence uint64 // the ring version the new owner claimed the lock at
+}
+
+// Sharder maps lock names to the servers that own them.
+//
+// It is safe for concurrent use.
+type Sharder struct {
+	ring   *hashring.HashRing
+	cancel func()
+
+	mu     sync.Mutex
+	owners map[string]string // owner of each registered lock
+	next   int               // id of the next subscription
+	subs   map[int]func([]Handoff)
+}
+
+// New creates a sharder over ring. Call Close to stop following the ring's
+// changes.
+//
+// Example:
+//
+//	sharder := locks.New(ring)
+//	defer sharder.Close()
+//	sharder.Register("nightly-report", "expire-sessions")
+//	if owner, _ := sharder.LockOwner("nightly-report"); owner == self {
+//		runNightlyReport()
+//	}
+func New(ring *hashring.HashRing) *Sharder {
+	s := &Sharder{
+		ring:   ring,
+		owners: make(map[string]string),
+		subs:   make(map[int]func([]Handoff)),
+	}
+
+	s.cancel = ring.OnMove(func([]hashring.RangeMove) { s.rebalance() })
+	return s
+}
+
+// Close stops following the ring's changes. OnRebalance subscribers aren't
+// notified after it returns.
+func (s *Sharder) Close() {
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.subs)
+}
+
+// LockOwner returns the server that owns the named lock. Circuit breakers are
+// ignored, so a lock never has a stand-in owner (see hashring.HashRing.Claim).
+//
+// Returns an error if the ring is empty.
+func (s *Sharder) LockOwner(name string) (string, error) {
+	owner, _, err := s.ring.Claim(name)
+	return owner, err
+}
+
+// Claim returns the server that owns the named lock along with a fencing
+// token (see hashring.HashRing.Claim).
+func (s *Sharder) Claim(name string) (string, uint64, error) {
+	return s.ring.Claim(name)
+}
+
+// Register adds locks whose handoffs are reported to OnRebalance subscribers.
+// Registering a lock again has no effect.
+func (s *Sharder) Register(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		if _, ok := s.owners[name]; !ok {
+			s.owners[name], _ = s.LockOwner(name)
+		}
+	}
+}
+
+// Unregister removes locks registered with Register.
+func (s *Sharder) Unregister(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		delete(s.owners, name)
+	}
+}
+
+// Locks returns the registered locks server owns, sorted.
+func (s *Sharder) Locks(server string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var locks []string
+	for _, name := range slices.Sorted(maps.Keys(s.owners)) {
+		if s.owners[name] == server {
+			locks = append(locks, name)
+		}
+	}
+
+	return locks
+}
+
+// OnRebalance registers fn to be called with the registered locks that changed
+// owner after a topology change, sorted by lock name. It returns a function
+// that cancels the subscription.
+//
+// Callbacks run one change at a time and in order, on the ring's OnMove
+// goroutine (see hashring.HashRing.OnMove), so they may call back into the
+// sharder and the ring. Pins don't move ranges, so locks pinned elsewhere are
+// only noticed at the next change that does.
+//
+// Example:
+//
+//	cancel := sharder.OnRebalance(func(handoffs []locks.Handoff) {
+//		for _, h := range handoffs {
+//			switch self {
+//			case h.From:
+//				workers.Stop(h.Lock)
+//			case h.To:
+//				workers.Start(h.Lock)
+//			}
+//		}
+//	})
+//	defer cancel()
+func (s *Sharder) OnRebalance(fn func([]Handoff)) (cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.next
+	s.next++
+	s.subs[id] = fn
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.subs, id)
+	}
+}
+
+// rebalance recomputes the owners of the registered locks and notifies
+// subscribers of those that moved.
+func (s *Sharder) rebalance() {
+	s.mu.Lock()
+
+	var handoffs []Handoff
+	for _, name := range slices.Sorted(maps.Keys(s.owners)) {
+		owner, fence, _ := s.ring.Claim(name)
+		if owner != s.owners[name] {
+			handoffs = append(handoffs, Handoff{Lock: name, From: s.owners[name], To: owner, Fence: fence})
+			s.owners[name] = owner
+		}
+	}
+
+	subs := make([]func([]Handoff), 0, len(s.subs))
+	for _, id := range slices.Sorted(maps.Keys(s.subs)) {
+		subs = append(subs, s.subs[id])
+	}
+
+	s.mu.Unlock()
+
+	if len(handoffs) == 0 {
+		return
+	}
+
+	for _, fn := range subs {
+		fn(handoffs)
+	}
+}
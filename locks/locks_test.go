@@ -0,0 +1,80 @@
+package locks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockOwner(t *testing.T) {
+	ring := hashring.New(50)
+	sharder := New(ring)
+	defer sharder.Close()
+
+	_, err := sharder.LockOwner("cron")
+	require.Error(t, err)
+
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	owner, err := sharder.LockOwner("cron")
+	require.NoError(t, err)
+	expected, err := ring.GetServer("cron")
+	require.NoError(t, err)
+	require.Equal(t, expected, owner)
+
+	_, fence, err := sharder.Claim("cron")
+	require.NoError(t, err)
+	require.Equal(t, ring.Version(), fence)
+}
+
+func TestOnRebalance(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("server1"))
+	require.NoError(t, ring.AddServer("server2"))
+
+	sharder := New(ring)
+	defer sharder.Close()
+
+	var names []string
+	for i := range 100 {
+		names = append(names, fmt.Sprintf("lock%d", i))
+	}
+	sharder.Register(names...)
+	require.Len(t, append(sharder.Locks("server1"), sharder.Locks("server2")...), 100)
+
+	handoffs := make(chan []Handoff, 10)
+	cancel := sharder.OnRebalance(func(h []Handoff) { handoffs <- h })
+	defer cancel()
+
+	require.NoError(t, ring.AddServer("server3"))
+
+	var moved []Handoff
+	select {
+	case moved = <-handoffs:
+	case <-time.After(time.Second):
+		require.Fail(t, "Expected handoffs after adding a server")
+	}
+
+	require.NotEmpty(t, moved)
+	for _, h := range moved {
+		require.Equal(t, "server3", h.To, "Only locks moving to the new server should be handed off")
+		require.NotEqual(t, "server3", h.From)
+		require.Equal(t, ring.Version(), h.Fence)
+	}
+	require.Len(t, sharder.Locks("server3"), len(moved))
+
+	// unregistered locks aren't reported
+	sharder.Unregister(names...)
+	require.NoError(t, ring.RemoveServer("server3"))
+	require.NoError(t, ring.AddServer("server4"))
+
+	select {
+	case h := <-handoffs:
+		require.Fail(t, "Unexpected handoffs", "%v", h)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,220 @@
+// Package membership provides a replicated set of ring servers that many
+// administrators or agents can change concurrently.
+//
+// Set is an observed-remove set (OR-Set), a CRDT: every replica applies
+// changes locally and exchanges its State with the others in any order, as
+// often as it likes, and all replicas that have seen the same changes agree on
+// the same members. When an add and a remove of the same server race, the add
+// wins, since the remove only covers the adds its replica had seen. When two
+// replicas add or update the same server concurrently, the change with the
+// higher Tag wins everywhere.
+//
+// Rings follow the set with Set.Rebuild, which replaces their membership with
+// the converged one.
+package membership
+
+import (
+	"cmp"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// Tag uniquely identifies an add made by a replica.
+type Tag struct {
+	Replica string `json:"replica"`
+	Seq     uint64 `json:"seq"`
+}
+
+// Compare orders tags by sequence number, then replica.
+func (t Tag) Compare(other Tag) int {
+	if c := cmp.Compare(t.Seq, other.Seq); c != 0 {
+		return c
+	}
+
+	return strings.Compare(t.Replica, other.Replica)
+}
+
+// Add is a server added to the set under a tag.
+type Add struct {
+	Tag  Tag                 `json:"tag"`
+	Info hashring.ServerInfo `json:"info"`
+}
+
+// State is the replicated state of a Set, which replicas exchange to merge
+// each other's changes.
+type State struct {
+	Adds    []Add `json:"adds"`    // live adds, sorted by tag
+	Removed []Tag `json:"removed"` // removed adds, sorted
+}
+
+// Set is a replicated set of servers. See the package docs.
+type Set struct {
+	mu      sync.Mutex
+	replica string
+	seq     uint64                      // highest sequence number seen
+	adds    map[Tag]hashring.ServerInfo // live adds
+	removed map[Tag]bool                // tombstones of removed adds
+}
+
+// New creates an empty set for the given replica, whose id must be unique
+// among the replicas.
+func New(replica string) *Set {
+	return &Set{
+		replica: replica,
+		adds:    make(map[Tag]hashring.ServerInfo),
+		removed: make(map[Tag]bool),
+	}
+}
+
+// Add adds a server to the set, or updates its info if it's already a member.
+func (s *Set) Add(info hashring.ServerInfo) error {
+	if strings.TrimSpace(info.Name) == "" {
+		return errors.New("server name must not be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// replace the adds seen so far, so the set holds one per server until
+	// replicas race
+	s.remove(info.Name)
+
+	s.seq++
+	s.adds[Tag{Replica: s.replica, Seq: s.seq}] = cloneInfo(info)
+	return nil
+}
+
+// Remove removes a server from the set, reporting whether it was a member.
+// Adds of the server this replica hasn't seen yet survive.
+func (s *Set) Remove(server string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.remove(server)
+}
+
+// Members returns the servers in the set, sorted by name. A server added
+// concurrently by several replicas has the info of the add with the highest
+// tag.
+func (s *Set) Members() []hashring.ServerInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	winners := make(map[string]Tag)
+	for tag, info := range s.adds {
+		if best, ok := winners[info.Name]; !ok || tag.Compare(best) > 0 {
+			winners[info.Name] = tag
+		}
+	}
+
+	members := make([]hashring.ServerInfo, 0, len(winners))
+	for _, server := range slices.Sorted(maps.Keys(winners)) {
+		members = append(members, cloneInfo(s.adds[winners[server]]))
+	}
+
+	return members
+}
+
+// State returns the set's replicated state, to be sent to other replicas.
+func (s *Set) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := State{
+		Adds:    make([]Add, 0, len(s.adds)),
+		Removed: slices.SortedFunc(maps.Keys(s.removed), Tag.Compare),
+	}
+
+	for _, tag := range slices.SortedFunc(maps.Keys(s.adds), Tag.Compare) {
+		state.Adds = append(state.Adds, Add{Tag: tag, Info: cloneInfo(s.adds[tag])})
+	}
+
+	return state
+}
+
+// Merge merges another replica's state into the set. Merging is commutative,
+// associative, and idempotent, so states can be exchanged in any order and
+// more than once.
+func (s *Set) Merge(other State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, tag := range other.Removed {
+		s.removed[tag] = true
+		delete(s.adds, tag)
+		s.seq = max(s.seq, tag.Seq)
+	}
+
+	for _, add := range other.Adds {
+		if !s.removed[add.Tag] {
+			s.adds[add.Tag] = cloneInfo(add.Info)
+		}
+		s.seq = max(s.seq, add.Tag.Seq)
+	}
+}
+
+// Rebuild replaces the ring's membership with the set's members (see
+// hashring.HashRing.SetServers), leaving it unchanged if they already match.
+//
+// Example:
+//
+//	set.Merge(peerState)
+//	if err := set.Rebuild(ring); err != nil {
+//		log.Printf("membership: %v", err)
+//	}
+func (s *Set) Rebuild(ring *hashring.HashRing) error {
+	members := s.Members()
+
+	current := make([]hashring.ServerInfo, 0, ring.Size())
+	for _, server := range ring.GetServers() {
+		info, _ := ring.GetServerInfo(server)
+		current = append(current, info)
+	}
+
+	if slices.EqualFunc(members, current, sameInfo) {
+		return nil
+	}
+
+	return ring.SetServers(members)
+}
+
+// remove tombstones the live adds of server, reporting whether there were
+// any. The caller must hold s.mu.
+func (s *Set) remove(server string) bool {
+	removed := false
+	for tag, info := range s.adds {
+		if info.Name == server {
+			s.removed[tag] = true
+			delete(s.adds, tag)
+			removed = true
+		}
+	}
+
+	return removed
+}
+
+// cloneInfo returns a copy of info that doesn't share its slices.
+func cloneInfo(info hashring.ServerInfo) hashring.ServerInfo {
+	info.Tags = slices.Clone(info.Tags)
+	info.Tokens = slices.Clone(info.Tokens)
+	info.Tenants = slices.Clone(info.Tenants)
+	return info
+}
+
+// sameInfo reports whether a and b describe the same server.
+func sameInfo(a, b hashring.ServerInfo) bool {
+	return a.Name == b.Name &&
+		a.Zone == b.Zone &&
+		slices.Equal(a.Tags, b.Tags) &&
+		slices.Equal(a.Tokens, b.Tokens) &&
+		a.Weight == b.Weight &&
+		a.Capacity == b.Capacity &&
+		a.VNodes == b.VNodes &&
+		slices.Equal(a.Tenants, b.Tenants) &&
+		a.State == b.State
+}
@@ -0,0 +1,118 @@
+package membership
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// names returns the names of the set's members.
+func names(s *Set) []string {
+	var names []string
+	for _, info := range s.Members() {
+		names = append(names, info.Name)
+	}
+
+	return names
+}
+
+// mergeAll merges the states of every pair of sets.
+func mergeAll(sets ...*Set) {
+	for _, a := range sets {
+		for _, b := range sets {
+			b.Merge(a.State())
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	a := New("a")
+	require.NoError(t, a.Add(hashring.ServerInfo{Name: "server1"}))
+	require.NoError(t, a.Add(hashring.ServerInfo{Name: "server2"}))
+	require.Equal(t, []string{"server1", "server2"}, names(a))
+
+	require.True(t, a.Remove("server1"))
+	require.False(t, a.Remove("server1"))
+	require.Equal(t, []string{"server2"}, names(a))
+
+	require.Error(t, a.Add(hashring.ServerInfo{Name: " "}))
+}
+
+func TestSetConcurrentChanges(t *testing.T) {
+	a, b, c := New("a"), New("b"), New("c")
+	require.NoError(t, a.Add(hashring.ServerInfo{Name: "server1"}))
+	require.NoError(t, a.Add(hashring.ServerInfo{Name: "server2"}))
+	mergeAll(a, b, c)
+
+	// b removes server1 while c re-adds it: the add wins
+	require.True(t, b.Remove("server1"))
+	require.NoError(t, c.Add(hashring.ServerInfo{Name: "server1", Zone: "c"}))
+
+	// a and b update server2 at the same time: the higher tag wins
+	require.NoError(t, a.Add(hashring.ServerInfo{Name: "server2", Zone: "a"}))
+	require.NoError(t, b.Add(hashring.ServerInfo{Name: "server2", Zone: "b"}))
+
+	// and a removes server2 without having seen b's update, which survives
+	require.True(t, a.Remove("server2"))
+
+	// merge in different orders
+	c.Merge(b.State())
+	c.Merge(a.State())
+	a.Merge(c.State())
+	b.Merge(a.State())
+
+	for _, s := range []*Set{a, b, c} {
+		require.Equal(t, []hashring.ServerInfo{
+			{Name: "server1", Zone: "c"},
+			{Name: "server2", Zone: "b"},
+		}, s.Members())
+	}
+
+	// merging again changes nothing
+	state := a.State()
+	a.Merge(b.State())
+	a.Merge(state)
+	require.Equal(t, state, a.State())
+}
+
+func TestStateJSON(t *testing.T) {
+	a := New("a")
+	require.NoError(t, a.Add(hashring.ServerInfo{Name: "server1", Tags: []string{"ssd"}}))
+	require.NoError(t, a.Add(hashring.ServerInfo{Name: "server2"}))
+	a.Remove("server2")
+
+	data, err := json.Marshal(a.State())
+	require.NoError(t, err)
+
+	var state State
+	require.NoError(t, json.Unmarshal(data, &state))
+
+	b := New("b")
+	b.Merge(state)
+	require.Equal(t, a.Members(), b.Members())
+	require.Equal(t, a.State(), b.State())
+}
+
+func TestRebuild(t *testing.T) {
+	a, b := New("a"), New("b")
+	require.NoError(t, a.Add(hashring.ServerInfo{Name: "server1"}))
+	require.NoError(t, b.Add(hashring.ServerInfo{Name: "server2", Weight: 2}))
+	require.NoError(t, b.Add(hashring.ServerInfo{Name: "server3"}))
+	b.Remove("server3")
+	mergeAll(a, b)
+
+	ringA, ringB := hashring.New(50), hashring.New(50)
+	require.NoError(t, ringA.AddServer("server4"))
+	require.NoError(t, a.Rebuild(ringA))
+	require.NoError(t, b.Rebuild(ringB))
+
+	require.Equal(t, []string{"server1", "server2"}, ringA.GetServers())
+	require.Equal(t, ringA.Checksum(), ringB.Checksum())
+
+	// no change when the ring already matches
+	version := ringA.Version()
+	require.NoError(t, a.Rebuild(ringA))
+	require.Equal(t, version, ringA.Version())
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// KeyFunc derives the routing key from a request.
+type KeyFunc func(*http.Request) string
+
+// Header routes by the value of the named request header.
+func Header(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Cookie routes by the value of the named cookie. Requests without the cookie
+// use an empty key.
+func Cookie(name string) KeyFunc {
+	return func(r *http.Request) string {
+		c, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+
+		return c.Value
+	}
+}
+
+// ClientIP routes by the IP address of the client, as seen by this server.
+//
+// It deliberately ignores X-Forwarded-For, which clients can forge. Wrap the
+// proxy in trusted middleware that rewrites RemoteAddr if it runs behind
+// another proxy.
+func ClientIP() KeyFunc {
+	return func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+
+		return host
+	}
+}
+
+// PathSegment routes by the zero-based segment of the URL path, e.g.
+// PathSegment(1) routes "/tenants/acme/orders" by "acme". Requests with fewer
+// segments use an empty key.
+func PathSegment(i int) KeyFunc {
+	return func(r *http.Request) string {
+		segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if i < 0 || i >= len(segments) {
+			return ""
+		}
+
+		return segments[i]
+	}
+}
@@ -0,0 +1,29 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyFuncs(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/tenants/acme/orders", nil)
+	r.RemoteAddr = "10.1.2.3:54321"
+	r.Header.Set("X-User-ID", "user-42")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc123"})
+
+	require.Equal(t, "user-42", Header("X-User-ID")(r))
+	require.Empty(t, Header("X-Missing")(r))
+
+	require.Equal(t, "abc123", Cookie("session")(r))
+	require.Empty(t, Cookie("missing")(r))
+
+	require.Equal(t, "10.1.2.3", ClientIP()(r))
+
+	require.Equal(t, "tenants", PathSegment(0)(r))
+	require.Equal(t, "acme", PathSegment(1)(r))
+	require.Empty(t, PathSegment(5)(r))
+	require.Empty(t, PathSegment(-1)(r))
+}
@@ -0,0 +1,356 @@
+// Package proxy provides net/http integrations that route requests to backends
+// chosen from a consistent hash ring.
+//
+// Proxy is a reverse proxy that routes inbound requests by a configurable key
+// (a header, cookie, client IP, or path segment), so requests sharing a key
+// consistently reach the same backend. Backends that fail are temporarily
+// excluded and the request is retried on the next backend clockwise around the
+// ring. Sticky is middleware that resolves a session's backend without
+// proxying, and Transport shards outbound client requests the same way.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+const (
+	// DefaultRetries is the number of additional backends tried after the
+	// primary one fails.
+	DefaultRetries = 2
+
+	// DefaultCooldown is how long a failed backend is excluded from routing.
+	DefaultCooldown = 10 * time.Second
+)
+
+// Option configures a Proxy.
+type Option func(*Proxy)
+
+// WithKeyFunc sets how the routing key is derived from a request. The default
+// is ClientIP().
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(p *Proxy) {
+		p.key = fn
+	}
+}
+
+// WithTarget sets how a server name from the ring is turned into a backend URL.
+// The default treats server names as host:port pairs reachable over plain HTTP.
+func WithTarget(fn func(server string) (*url.URL, error)) Option {
+	return func(p *Proxy) {
+		p.target = fn
+	}
+}
+
+// WithRetries sets how many additional backends are tried when the primary
+// backend fails. Requests with a body are never retried since the body can't be
+// replayed.
+func WithRetries(n int) Option {
+	return func(p *Proxy) {
+		p.retries = max(n, 0)
+	}
+}
+
+// WithCooldown sets how long a backend that failed a request is excluded from
+// routing before it's tried again.
+func WithCooldown(d time.Duration) Option {
+	return func(p *Proxy) {
+		p.cooldown = d
+	}
+}
+
+// WithTransport sets the transport used to reach backends. The default is
+// http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(p *Proxy) {
+		p.transport = rt
+	}
+}
+
+// WithErrorLog sets the logger used for backend errors. By default errors are
+// logged with the standard logger.
+func WithErrorLog(l *log.Logger) Option {
+	return func(p *Proxy) {
+		p.errorLog = l
+	}
+}
+
+// Proxy is an http.Handler that forwards requests to ring-selected backends.
+//
+// It is safe for concurrent use.
+type Proxy struct {
+	ring      *hashring.HashRing
+	key       KeyFunc
+	target    func(string) (*url.URL, error)
+	retries   int
+	cooldown  time.Duration
+	transport http.RoundTripper
+	errorLog  *log.Logger
+	now       func() time.Time
+
+	mu        sync.Mutex
+	unhealthy map[string]time.Time // server -> excluded until
+	proxies   map[string]*httputil.ReverseProxy
+}
+
+// New creates a proxy that routes requests using ring.
+//
+// Example:
+//
+//	ring := hashring.New(150)
+//	ring.AddServer("10.0.0.1:8080")
+//	ring.AddServer("10.0.0.2:8080")
+//
+//	p := proxy.New(ring, proxy.WithKeyFunc(proxy.Header("X-User-ID")))
+//	log.Fatal(http.ListenAndServe(":8000", p))
+func New(ring *hashring.HashRing, opts ...Option) *Proxy {
+	p := &Proxy{
+		ring:      ring,
+		key:       ClientIP(),
+		target:    httpTarget,
+		retries:   DefaultRetries,
+		cooldown:  DefaultCooldown,
+		transport: http.DefaultTransport,
+		now:       time.Now,
+		unhealthy: make(map[string]time.Time),
+		proxies:   make(map[string]*httputil.ReverseProxy),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// ServeHTTP forwards the request to the backend responsible for its key,
+// falling back to the next backends on the ring when it fails.
+//
+// Responds with 503 Service Unavailable when no healthy backends exist, and
+// with 502 Bad Gateway when every attempted backend fails.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	candidates, err := p.candidates(p.key(r))
+	if err != nil || len(candidates) == 0 {
+		http.Error(w, "no healthy backends", http.StatusServiceUnavailable)
+		return
+	}
+
+	attempts := p.retries + 1
+	if !replayable(r) {
+		attempts = 1
+	}
+
+	for _, server := range candidates[:min(attempts, len(candidates))] {
+		rp, err := p.proxyFor(server)
+		if err != nil {
+			p.logf("proxy: invalid target for %s: %v", server, err)
+			p.MarkUnhealthy(server)
+			continue
+		}
+
+		state := &attempt{}
+		rp.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), attemptKey{}, state)))
+		if state.err == nil {
+			return
+		}
+
+		p.logf("proxy: backend %s failed: %v", server, state.err)
+		p.MarkUnhealthy(server)
+
+		if errors.Is(state.err, context.Canceled) {
+			return
+		}
+	}
+
+	http.Error(w, "all backends failed", http.StatusBadGateway)
+}
+
+// MarkUnhealthy excludes a backend from routing for the configured cooldown.
+//
+// The proxy calls this itself when a request to the backend fails. It can also
+// be called by external health checks.
+func (p *Proxy) MarkUnhealthy(server string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unhealthy[server] = p.now().Add(p.cooldown)
+}
+
+// MarkHealthy makes a backend eligible for routing again immediately.
+func (p *Proxy) MarkHealthy(server string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.unhealthy, server)
+}
+
+// Healthy reports whether a backend is currently eligible for routing.
+func (p *Proxy) Healthy(server string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.healthy(server)
+}
+
+// CheckHealth sends a GET request for path to every backend in the ring and
+// updates its health based on whether it responds with a 2xx status.
+//
+// It performs a single round of checks; schedule it (e.g. with a time.Ticker)
+// for continuous active health checking.
+//
+// Example:
+//
+//	go func() {
+//		for range time.Tick(5 * time.Second) {
+//			p.CheckHealth(ctx, "/healthz")
+//		}
+//	}()
+func (p *Proxy) CheckHealth(ctx context.Context, path string) {
+	client := &http.Client{Transport: p.transport}
+
+	var wg sync.WaitGroup
+	for _, server := range p.ring.GetServers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if p.probe(ctx, client, server, path) {
+				p.MarkHealthy(server)
+			} else {
+				p.MarkUnhealthy(server)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// probe reports whether server responds to path with a 2xx status.
+func (p *Proxy) probe(ctx context.Context, client *http.Client, server, path string) bool {
+	target, err := p.target(server)
+	if err != nil {
+		return false
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.JoinPath(path).String(), nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// candidates returns the healthy backends for key in the order they should be
+// tried.
+func (p *Proxy) candidates(key string) ([]string, error) {
+	replicas, err := p.ring.GetReplicas(key, p.ring.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	healthy := replicas[:0]
+	for _, server := range replicas {
+		if p.healthy(server) {
+			healthy = append(healthy, server)
+		}
+	}
+
+	return healthy, nil
+}
+
+// healthy reports whether server is eligible for routing. The caller must hold
+// p.mu.
+func (p *Proxy) healthy(server string) bool {
+	until, ok := p.unhealthy[server]
+	if !ok {
+		return true
+	}
+
+	if p.now().Before(until) {
+		return false
+	}
+
+	delete(p.unhealthy, server)
+	return true
+}
+
+// proxyFor returns the (cached) reverse proxy for server.
+func (p *Proxy) proxyFor(server string) (*httputil.ReverseProxy, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if rp, ok := p.proxies[server]; ok {
+		return rp, nil
+	}
+
+	target, err := p.target(server)
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+		},
+		Transport: p.transport,
+		ErrorLog:  p.errorLog,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if state, ok := r.Context().Value(attemptKey{}).(*attempt); ok {
+				state.err = err
+				return
+			}
+
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	p.proxies[server] = rp
+	return rp, nil
+}
+
+func (p *Proxy) logf(format string, args ...any) {
+	if p.errorLog != nil {
+		p.errorLog.Printf(format, args...)
+		return
+	}
+
+	log.Printf(format, args...)
+}
+
+// attempt carries the outcome of forwarding a request to a single backend.
+type attempt struct {
+	err error
+}
+
+type attemptKey struct{}
+
+// httpTarget treats server as a host:port reachable over plain HTTP.
+func httpTarget(server string) (*url.URL, error) {
+	u, err := url.Parse("http://" + server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend %q: %w", server, err)
+	}
+
+	return u, nil
+}
+
+// replayable reports whether r can safely be sent to more than one backend.
+func replayable(r *http.Request) bool {
+	return r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0
+}
@@ -0,0 +1,180 @@
+package proxy
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// newBackends starts n backends that respond with their own address.
+func newBackends(t *testing.T, n int) (*hashring.HashRing, map[string]*httptest.Server) {
+	t.Helper()
+
+	ring := hashring.New(150)
+	backends := make(map[string]*httptest.Server, n)
+	for range n {
+		var addr string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/healthz" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			_, _ = io.WriteString(w, addr)
+		}))
+		t.Cleanup(srv.Close)
+
+		addr = strings.TrimPrefix(srv.URL, "http://")
+		backends[addr] = srv
+		require.NoError(t, ring.AddServer(addr))
+	}
+
+	return ring, backends
+}
+
+func get(t *testing.T, h http.Handler, userID string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User-ID", userID)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func quietLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func TestProxyRoutesByKey(t *testing.T) {
+	ring, _ := newBackends(t, 3)
+	p := New(ring, WithKeyFunc(Header("X-User-ID")), WithErrorLog(quietLogger()))
+
+	for _, user := range []string{"user-1", "user-2", "user-3", "user-42"} {
+		expected, err := ring.GetServer(user)
+		require.NoError(t, err)
+
+		for range 3 {
+			w := get(t, p, user)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, expected, w.Body.String(), "Requests for %s should be sticky", user)
+		}
+	}
+}
+
+func TestProxyRetriesOnNextNode(t *testing.T) {
+	ring, backends := newBackends(t, 3)
+	p := New(ring, WithKeyFunc(Header("X-User-ID")), WithErrorLog(quietLogger()))
+
+	replicas, err := ring.GetReplicas("user-42", 2)
+	require.NoError(t, err)
+
+	// Take the primary down
+	backends[replicas[0]].Close()
+
+	w := get(t, p, "user-42")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, replicas[1], w.Body.String(), "Request should fail over to the next node")
+	require.False(t, p.Healthy(replicas[0]), "Failed backend should be excluded")
+}
+
+func TestProxyCooldown(t *testing.T) {
+	ring, _ := newBackends(t, 2)
+	p := New(ring, WithKeyFunc(Header("X-User-ID")), WithCooldown(time.Minute))
+
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	owner, err := ring.GetServer("user-42")
+	require.NoError(t, err)
+
+	p.MarkUnhealthy(owner)
+	require.NotEqual(t, owner, get(t, p, "user-42").Body.String())
+
+	now = now.Add(2 * time.Minute)
+	require.True(t, p.Healthy(owner))
+	require.Equal(t, owner, get(t, p, "user-42").Body.String())
+}
+
+func TestProxyNoHealthyBackends(t *testing.T) {
+	ring, backends := newBackends(t, 2)
+	p := New(ring, WithKeyFunc(Header("X-User-ID")), WithErrorLog(quietLogger()))
+
+	for server := range backends {
+		p.MarkUnhealthy(server)
+	}
+
+	require.Equal(t, http.StatusServiceUnavailable, get(t, p, "user-42").Code)
+	require.Equal(t, http.StatusServiceUnavailable, get(t, New(hashring.New(10)), "user-42").Code)
+}
+
+func TestProxyAllBackendsFail(t *testing.T) {
+	ring, backends := newBackends(t, 2)
+	for _, srv := range backends {
+		srv.Close()
+	}
+
+	p := New(ring, WithKeyFunc(Header("X-User-ID")), WithErrorLog(quietLogger()))
+	require.Equal(t, http.StatusBadGateway, get(t, p, "user-42").Code)
+}
+
+func TestProxyDoesNotRetryBodies(t *testing.T) {
+	ring, backends := newBackends(t, 2)
+	p := New(ring, WithKeyFunc(Header("X-User-ID")), WithErrorLog(quietLogger()))
+
+	owner, err := ring.GetServer("user-42")
+	require.NoError(t, err)
+	backends[owner].Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload"))
+	r.Header.Set("X-User-ID", "user-42")
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestProxyCheckHealth(t *testing.T) {
+	ring, backends := newBackends(t, 2)
+	p := New(ring)
+
+	var down string
+	for server := range backends {
+		down = server
+		break
+	}
+	backends[down].Close()
+
+	p.CheckHealth(t.Context(), "/healthz")
+	for server := range backends {
+		require.Equal(t, server != down, p.Healthy(server))
+	}
+}
+
+func TestProxyWithTarget(t *testing.T) {
+	_, backends := newBackends(t, 1)
+	var addr string
+	for server := range backends {
+		addr = server
+	}
+
+	named := hashring.New(10)
+	require.NoError(t, named.AddServer("backend-a"))
+
+	p := New(named, WithTarget(func(server string) (*url.URL, error) {
+		require.Equal(t, "backend-a", server)
+		return url.Parse("http://" + addr)
+	}))
+
+	w := get(t, p, "user-1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, addr, w.Body.String())
+}
@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultSessionCookie is the cookie Sticky reads session identifiers from
+// unless configured otherwise.
+const DefaultSessionCookie = "hashlab_session"
+
+// StickyOption configures the Sticky middleware.
+type StickyOption func(*sticky)
+
+// WithSessionCookie sets the cookie the session identifier is read from.
+func WithSessionCookie(name string) StickyOption {
+	return func(s *sticky) {
+		s.cookie = name
+	}
+}
+
+// WithSessionHeader sets a header the session identifier is read from when the
+// request doesn't carry the session cookie.
+func WithSessionHeader(name string) StickyOption {
+	return func(s *sticky) {
+		s.header = name
+	}
+}
+
+// WithIssuedCookie enables issuing a session cookie to first-time clients.
+//
+// The template controls the cookie's attributes (Path, MaxAge, Secure, etc.);
+// its Name and Value are set by the middleware. Issuing a cookie makes the
+// stickiness key stable for clients that don't have a session yet.
+func WithIssuedCookie(template http.Cookie) StickyOption {
+	return func(s *sticky) {
+		s.issue = &template
+	}
+}
+
+type sticky struct {
+	ring   *hashring.HashRing
+	cookie string
+	header string
+	issue  *http.Cookie
+}
+
+type contextKey int
+
+const (
+	backendKey contextKey = iota
+	sessionKey
+)
+
+// Sticky returns middleware that resolves the backend for each request's
+// session and stores it in the request context.
+//
+// The session identifier is read from a cookie (DefaultSessionCookie unless
+// overridden) or, failing that, an optional header. Requests without a session
+// are passed through unannotated unless WithIssuedCookie is used, in which case
+// a new session is created and its cookie set on the response.
+//
+// Downstream handlers read the result with BackendFromContext and
+// SessionFromContext.
+//
+// Example:
+//
+//	mw := proxy.Sticky(ring, proxy.WithIssuedCookie(http.Cookie{Path: "/", HttpOnly: true}))
+//	http.Handle("/", mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		backend, _ := proxy.BackendFromContext(r.Context())
+//		fmt.Fprintf(w, "routed to %s", backend)
+//	})))
+func Sticky(ring *hashring.HashRing, opts ...StickyOption) func(http.Handler) http.Handler {
+	s := &sticky{ring: ring, cookie: DefaultSessionCookie}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := s.session(r)
+			if session == "" && s.issue != nil {
+				session = newSessionID()
+				c := *s.issue
+				c.Name, c.Value = s.cookie, session
+				http.SetCookie(w, &c)
+			}
+
+			if session == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), sessionKey, session)
+			if backend, err := s.ring.GetServer(session); err == nil {
+				ctx = context.WithValue(ctx, backendKey, backend)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// BackendFromContext returns the backend resolved by Sticky for the request.
+func BackendFromContext(ctx context.Context) (string, bool) {
+	backend, ok := ctx.Value(backendKey).(string)
+	return backend, ok
+}
+
+// SessionFromContext returns the session identifier Sticky routed the request
+// by, including sessions it issued.
+func SessionFromContext(ctx context.Context) (string, bool) {
+	session, ok := ctx.Value(sessionKey).(string)
+	return session, ok
+}
+
+// session extracts the session identifier from r.
+func (s *sticky) session(r *http.Request) string {
+	if c, err := r.Cookie(s.cookie); err == nil && c.Value != "" {
+		return c.Value
+	}
+
+	if s.header != "" {
+		return r.Header.Get(s.header)
+	}
+
+	return ""
+}
+
+// newSessionID returns a random 128-bit session identifier.
+func newSessionID() string {
+	var buf [16]byte
+	_, _ = rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
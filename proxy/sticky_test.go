@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+func newStickyRing(t *testing.T) *hashring.HashRing {
+	t.Helper()
+
+	ring := hashring.New(150)
+	require.NoError(t, ring.AddServer("backend-1"))
+	require.NoError(t, ring.AddServer("backend-2"))
+	require.NoError(t, ring.AddServer("backend-3"))
+	return ring
+}
+
+// capture records the context values Sticky sets.
+type capture struct {
+	backend, session string
+	hasBackend       bool
+}
+
+func (c *capture) ServeHTTP(_ http.ResponseWriter, r *http.Request) {
+	c.backend, c.hasBackend = BackendFromContext(r.Context())
+	c.session, _ = SessionFromContext(r.Context())
+}
+
+func TestStickyCookie(t *testing.T) {
+	ring := newStickyRing(t)
+	next := &capture{}
+	h := Sticky(ring)(next)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: DefaultSessionCookie, Value: "session-abc123"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	expected, err := ring.GetServer("session-abc123")
+	require.NoError(t, err)
+	require.True(t, next.hasBackend)
+	require.Equal(t, expected, next.backend)
+	require.Equal(t, "session-abc123", next.session)
+}
+
+func TestStickyHeader(t *testing.T) {
+	ring := newStickyRing(t)
+	next := &capture{}
+	h := Sticky(ring, WithSessionCookie("sid"), WithSessionHeader("X-Session-ID"))(next)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Session-ID", "session-def456")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	expected, err := ring.GetServer("session-def456")
+	require.NoError(t, err)
+	require.Equal(t, expected, next.backend)
+
+	// Cookie takes precedence over the header
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "session-abc123"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	require.Equal(t, "session-abc123", next.session)
+}
+
+func TestStickyWithoutSession(t *testing.T) {
+	next := &capture{}
+	h := Sticky(newStickyRing(t))(next)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.False(t, next.hasBackend)
+	require.Empty(t, w.Result().Cookies())
+}
+
+func TestStickyIssuesCookie(t *testing.T) {
+	ring := newStickyRing(t)
+	next := &capture{}
+	h := Sticky(ring, WithIssuedCookie(http.Cookie{Path: "/", HttpOnly: true}))(next)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, DefaultSessionCookie, cookies[0].Name)
+	require.True(t, cookies[0].HttpOnly)
+	require.Equal(t, cookies[0].Value, next.session)
+	require.True(t, next.hasBackend)
+	first := next.backend
+
+	// The issued cookie keeps the client on the same backend
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, first, next.backend)
+	require.Empty(t, w.Result().Cookies(), "Existing sessions shouldn't be reissued")
+}
+
+func TestStickyEmptyRing(t *testing.T) {
+	next := &capture{}
+	h := Sticky(hashring.New(10))(next)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: DefaultSessionCookie, Value: "session-abc123"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	require.False(t, next.hasBackend)
+	require.Equal(t, "session-abc123", next.session)
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+type routingKey struct{}
+
+// WithRoutingKey returns a copy of ctx carrying the key Transport routes by.
+func WithRoutingKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, routingKey{}, key)
+}
+
+// RoutingKey routes by the key stored in the request context with
+// WithRoutingKey. It's the default KeyFunc for Transport.
+func RoutingKey() KeyFunc {
+	return func(r *http.Request) string {
+		key, _ := r.Context().Value(routingKey{}).(string)
+		return key
+	}
+}
+
+// Transport is an http.RoundTripper that dispatches outbound requests to the
+// backend selected from the ring for each request's routing key.
+//
+// The request's scheme and host are replaced with those of the selected backend
+// while the path, query, headers, and body are left untouched. This lets an
+// ordinary http.Client shard calls across a service fleet transparently.
+//
+// Example:
+//
+//	client := &http.Client{Transport: &proxy.Transport{Ring: ring}}
+//
+//	ctx := proxy.WithRoutingKey(ctx, "user-42")
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://profiles/users/42", nil)
+//	resp, err := client.Do(req) // sent to the backend owning "user-42"
+type Transport struct {
+	// Ring selects the backend for each request.
+	Ring *hashring.HashRing
+
+	// Key derives the routing key from the request. Defaults to RoutingKey().
+	Key KeyFunc
+
+	// Target turns a server name into the backend URL whose scheme and host
+	// are used. Defaults to plain HTTP to the server name as host:port.
+	Target func(server string) (*url.URL, error)
+
+	// Base performs the actual request. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+//
+// Returns an error without sending the request if it has no routing key or no
+// backend can be selected.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	keyFn := t.Key
+	if keyFn == nil {
+		keyFn = RoutingKey()
+	}
+
+	key := keyFn(req)
+	if key == "" {
+		closeBody(req)
+		return nil, errors.New("proxy: request has no routing key")
+	}
+
+	server, err := t.Ring.GetServer(key)
+	if err != nil {
+		closeBody(req)
+		return nil, fmt.Errorf("proxy: selecting backend: %w", err)
+	}
+
+	targetFn := t.Target
+	if targetFn == nil {
+		targetFn = httpTarget
+	}
+
+	target, err := targetFn(server)
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.URL.Scheme = target.Scheme
+	out.URL.Host = target.Host
+	out.Host = target.Host
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return base.RoundTrip(out)
+}
+
+// closeBody closes the request body, as RoundTrippers must even on error.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	ring, _ := newBackends(t, 3)
+	client := &http.Client{Transport: &Transport{Ring: ring}}
+
+	for _, user := range []string{"user-1", "user-2", "user-3", "user-42"} {
+		expected, err := ring.GetServer(user)
+		require.NoError(t, err)
+
+		req, err := http.NewRequestWithContext(WithRoutingKey(t.Context(), user), http.MethodGet, "http://profiles/users", nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, expected, string(body))
+		require.Equal(t, "profiles", req.URL.Host, "The original request shouldn't be modified")
+	}
+}
+
+func TestTransportKeyFunc(t *testing.T) {
+	ring, _ := newBackends(t, 3)
+	client := &http.Client{Transport: &Transport{Ring: ring, Key: Header("X-User-ID")}}
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://profiles/users", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-User-ID", "user-42")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	expected, err := ring.GetServer("user-42")
+	require.NoError(t, err)
+	require.Equal(t, expected, string(body))
+}
+
+func TestTransportErrors(t *testing.T) {
+	ring, _ := newBackends(t, 1)
+	client := &http.Client{Transport: &Transport{Ring: ring}}
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://profiles/users", nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req) //nolint:bodyclose // no response on error
+	require.Error(t, err, "Expected error without a routing key")
+}
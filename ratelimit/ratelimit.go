@@ -0,0 +1,250 @@
+// Package ratelimit rate limits very large keyspaces across several limiter
+// nodes by routing each key's token bucket to a node with a consistent hash
+// ring.
+//
+// Every client routes a key to the same node, so its bucket lives in exactly
+// one place and limits hold no matter which client a request arrives at.
+// Adding or removing a node only moves the buckets adjacent to it; moved
+// buckets start full on their new node, so keys may briefly get up to one
+// extra burst.
+//
+// Nodes are reached through the Shard interface. Buckets is the in-process
+// implementation, which a node serves (e.g. over RPC) and clients wrap in a
+// Shard of their own; for sharding within a single process, use LocalShards.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// Shard holds the token buckets of the keys routed to a node.
+type Shard interface {
+	// AllowN reports whether n tokens could be taken from key's bucket,
+	// taking them if so.
+	AllowN(ctx context.Context, key string, n int) (bool, error)
+}
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithFailOpen makes Allow allow requests when their shard fails or the ring
+// is empty, rather than returning the error, so an unavailable limiter node
+// doesn't take down traffic.
+func WithFailOpen() Option {
+	return func(l *Limiter) {
+		l.failOpen = true
+	}
+}
+
+// Limiter routes rate limit checks to the shards of a ring.
+//
+// It is safe for concurrent use.
+type Limiter struct {
+	ring     *hashring.HashRing
+	shard    func(node string) Shard
+	failOpen bool
+
+	mu      sync.Mutex
+	version uint64
+	shards  map[string]Shard
+}
+
+// New creates a limiter over the nodes in ring, reaching them through the
+// shards returned by shard, which is called once per node.
+//
+// Example:
+//
+//	ring := hashring.New(150)
+//	ring.AddServer("limiter-1:9000")
+//	ring.AddServer("limiter-2:9000")
+//
+//	limiter := ratelimit.New(ring, func(node string) ratelimit.Shard {
+//		return newLimiterClient(node)
+//	}, ratelimit.WithFailOpen())
+//
+//	if ok, _ := limiter.Allow(ctx, "api-key:"+apiKey); !ok {
+//		http.Error(w, "slow down", http.StatusTooManyRequests)
+//	}
+func New(ring *hashring.HashRing, shard func(node string) Shard, opts ...Option) *Limiter {
+	l := &Limiter{
+		ring:   ring,
+		shard:  shard,
+		shards: make(map[string]Shard),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// LocalShards returns a shard function for New that gives each node its own
+// in-process Buckets, for sharding bucket locks within a single process.
+func LocalShards(rate float64, burst int) func(node string) Shard {
+	return func(string) Shard {
+		return NewBuckets(rate, burst)
+	}
+}
+
+// Allow reports whether a request for key may proceed, taking a token from
+// its bucket if so.
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN reports whether n tokens could be taken from key's bucket, taking
+// them if so.
+//
+// Returns an error if the ring is empty or the shard fails, unless the
+// limiter fails open (see WithFailOpen).
+func (l *Limiter) AllowN(ctx context.Context, key string, n int) (bool, error) {
+	node, version, err := l.ring.GetServerVersioned(key)
+	if err == nil {
+		var ok bool
+		if ok, err = l.shardFor(node, version).AllowN(ctx, key, n); err == nil {
+			return ok, nil
+		}
+
+		err = fmt.Errorf("ratelimit: node %s: %w", node, err)
+	}
+
+	if l.failOpen {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// shardFor returns the (cached) shard for node. When the ring has changed
+// since the last call, shards for nodes that left the ring are dropped.
+func (l *Limiter) shardFor(node string, version uint64) Shard {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if version != l.version {
+		l.version = version
+		for n := range l.shards {
+			if _, ok := l.ring.GetServerInfo(n); !ok {
+				delete(l.shards, n)
+			}
+		}
+	}
+
+	s, ok := l.shards[node]
+	if !ok {
+		s = l.shard(node)
+		l.shards[node] = s
+	}
+
+	return s
+}
+
+// BucketsOption configures Buckets.
+type BucketsOption func(*Buckets)
+
+// WithClock sets the function Buckets reads the time from.
+func WithClock(now func() time.Time) BucketsOption {
+	return func(b *Buckets) {
+		b.now = now
+	}
+}
+
+// Buckets is a set of token buckets, one per key, that each refill at the
+// same rate up to the same burst. It implements Shard.
+//
+// It is safe for concurrent use.
+type Buckets struct {
+	rate  float64 // tokens per second
+	burst int
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is a key's token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time // when tokens was last updated
+}
+
+// NewBuckets creates token buckets that refill at rate tokens per second and
+// hold at most burst tokens. Buckets start full.
+func NewBuckets(rate float64, burst int, opts ...BucketsOption) *Buckets {
+	b := &Buckets{
+		rate:    rate,
+		burst:   burst,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// AllowN reports whether n tokens could be taken from key's bucket, taking
+// them if so. It never returns an error.
+func (b *Buckets) AllowN(_ context.Context, key string, n int) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	bkt, ok := b.buckets[key]
+	if !ok {
+		bkt = &bucket{tokens: float64(b.burst), last: now}
+		b.buckets[key] = bkt
+	}
+
+	bkt.refill(now, b.rate, b.burst)
+	if bkt.tokens < float64(n) {
+		return false, nil
+	}
+
+	bkt.tokens -= float64(n)
+	return true, nil
+}
+
+// Len returns the number of buckets held.
+func (b *Buckets) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.buckets)
+}
+
+// Prune drops buckets that have refilled completely, which behave the same as
+// missing ones, and returns how many were dropped. Call it periodically to
+// bound memory with large keyspaces.
+func (b *Buckets) Prune() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	pruned := 0
+	for key, bkt := range b.buckets {
+		bkt.refill(now, b.rate, b.burst)
+		if bkt.tokens >= float64(b.burst) {
+			delete(b.buckets, key)
+			pruned++
+		}
+	}
+
+	return pruned
+}
+
+// refill adds the tokens earned since the bucket was last updated.
+func (bkt *bucket) refill(now time.Time, rate float64, burst int) {
+	if elapsed := now.Sub(bkt.last).Seconds(); elapsed > 0 {
+		bkt.tokens = min(float64(burst), bkt.tokens+elapsed*rate)
+	}
+	bkt.last = now
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// failingShard is a Shard whose node is unreachable.
+type failingShard struct{}
+
+func (failingShard) AllowN(context.Context, string, int) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	buckets := NewBuckets(2, 3, WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	// the burst is available up front
+	for range 3 {
+		ok, err := buckets.AllowN(ctx, "key", 1)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	ok, _ := buckets.AllowN(ctx, "key", 1)
+	require.False(t, ok)
+
+	// other keys have their own bucket
+	ok, _ = buckets.AllowN(ctx, "other", 3)
+	require.True(t, ok)
+
+	// refills at the rate, up to the burst
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = buckets.AllowN(ctx, "key", 1)
+	require.True(t, ok)
+	ok, _ = buckets.AllowN(ctx, "key", 1)
+	require.False(t, ok)
+
+	now = now.Add(time.Hour)
+	ok, _ = buckets.AllowN(ctx, "key", 4)
+	require.False(t, ok, "Requests over the burst are never allowed")
+
+	// full buckets are pruned
+	ok, _ = buckets.AllowN(ctx, "key", 1)
+	require.True(t, ok)
+	require.Equal(t, 2, buckets.Len())
+	require.Equal(t, 1, buckets.Prune())
+	require.Equal(t, 1, buckets.Len())
+}
+
+func TestLimiter(t *testing.T) {
+	ring := hashring.New(50)
+	for i := range 4 {
+		require.NoError(t, ring.AddServer(fmt.Sprintf("limiter%d", i)))
+	}
+
+	shards := make(map[string]*Buckets)
+	limiter := New(ring, func(node string) Shard {
+		shards[node] = NewBuckets(0, 5)
+		return shards[node]
+	})
+	ctx := context.Background()
+
+	// each key is limited on its own node
+	for i := range 100 {
+		key := fmt.Sprintf("key%d", i)
+		for range 5 {
+			ok, err := limiter.Allow(ctx, key)
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+
+		ok, err := limiter.Allow(ctx, key)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		node, err := ring.GetServer(key)
+		require.NoError(t, err)
+		require.Positive(t, shards[node].Len())
+	}
+	require.Len(t, shards, 4)
+
+	// shards for nodes that left are dropped
+	require.NoError(t, ring.RemoveServer("limiter0"))
+	_, err := limiter.Allow(ctx, "key0")
+	require.NoError(t, err)
+	require.Len(t, limiter.shards, 3)
+}
+
+func TestLimiterErrors(t *testing.T) {
+	ring := hashring.New(50)
+	ctx := context.Background()
+	shard := func(string) Shard { return failingShard{} }
+
+	_, err := New(ring, shard).Allow(ctx, "key")
+	require.Error(t, err)
+
+	require.NoError(t, ring.AddServer("limiter1"))
+	ok, err := New(ring, shard).Allow(ctx, "key")
+	require.ErrorContains(t, err, "limiter1")
+	require.False(t, ok)
+
+	ok, err = New(ring, shard, WithFailOpen()).Allow(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = New(hashring.New(50), shard, WithFailOpen()).Allow(ctx, "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestLocalShards(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("shard1"))
+	require.NoError(t, ring.AddServer("shard2"))
+
+	limiter := New(ring, LocalShards(1, 1))
+	ok, err := limiter.Allow(context.Background(), "key")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = limiter.Allow(context.Background(), "key")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
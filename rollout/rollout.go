@@ -0,0 +1,151 @@
+// Package rollout gradually enables feature flags for a percentage of users,
+// built on the consistent bucketing in package bucketing.
+//
+// Rollouts are sticky and monotonic: a user's flag only changes when the
+// percentage does, and raising the percentage only enables more users, so
+// everyone enabled at 10% stays enabled at 20%. Lowering it disables users in
+// the reverse order. Each flag buckets users independently, so the same users
+// aren't always the first to get every feature.
+package rollout
+
+import (
+	"sync"
+
+	"github.com/pseudomuto/hashlab/bucketing"
+)
+
+// Bucket names used for each flag's bucketer.
+const (
+	on  = "on"
+	off = "off"
+)
+
+// Default is the Rollout used by Enabled.
+var Default = New()
+
+// Enabled reports whether flag is enabled for userID at the given percentage
+// (0 to 100), using the Default rollout.
+//
+// Example:
+//
+//	if rollout.Enabled("new-checkout", user.ID, 20) {
+//		renderNewCheckout(w)
+//	}
+func Enabled(flag, userID string, percent float64) bool {
+	return Default.Enabled(flag, userID, percent)
+}
+
+// Option configures a Rollout.
+type Option func(*Rollout)
+
+// WithResolution sets the number of virtual nodes each flag places per
+// percentage point (see bucketing.WithResolution). Lower resolutions use less
+// memory per flag at the cost of enabled shares tracking percentages less
+// closely.
+func WithResolution(n int) Option {
+	return func(r *Rollout) {
+		r.resolution = n
+	}
+}
+
+// Rollout tracks the rollout of a set of flags.
+//
+// It is safe for concurrent use.
+type Rollout struct {
+	resolution int
+
+	mu    sync.Mutex
+	flags map[string]*flag
+}
+
+// flag is the bucketing of users for one flag at its current percentage.
+type flag struct {
+	name       string
+	resolution int
+
+	mu       sync.RWMutex
+	percent  float64
+	bucketer *bucketing.Bucketer // nil until first used
+}
+
+// New creates a rollout with no flags. Flags are set up the first time
+// they're checked.
+func New(opts ...Option) *Rollout {
+	r := &Rollout{
+		resolution: bucketing.DefaultResolution,
+		flags:      make(map[string]*flag),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Enabled reports whether flag is enabled for userID at the given percentage,
+// which is clamped to between 0 and 100. Percentages are usually constant
+// between deployments or config reloads; when one changes, the flag is
+// rebucketed once.
+func (r *Rollout) Enabled(flag, userID string, percent float64) bool {
+	switch {
+	case !(percent > 0): // also catches NaN
+		return false
+	case percent >= 100:
+		return true
+	}
+
+	return r.flag(flag).bucket(userID, percent) == on
+}
+
+// Forget drops a flag's state, e.g. once it's fully rolled out.
+func (r *Rollout) Forget(flag string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.flags, flag)
+}
+
+// flag returns the state for name, creating it if needed.
+func (r *Rollout) flag(name string) *flag {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.flags[name]
+	if !ok {
+		f = &flag{name: name, resolution: r.resolution}
+		r.flags[name] = f
+	}
+
+	return f
+}
+
+// bucket returns userID's bucket at percent, which must be between 0 and 100
+// exclusive, rebucketing the flag first if the percentage changed.
+func (f *flag) bucket(userID string, percent float64) string {
+	f.mu.RLock()
+	if f.bucketer != nil && f.percent == percent {
+		defer f.mu.RUnlock()
+		return f.bucketer.Bucket(userID)
+	}
+	f.mu.RUnlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Both weights are positive, so neither call can fail. Raising the "on"
+	// weight only adds its virtual nodes and lowering "off" only removes its
+	// own, so no enabled user is disabled.
+	if f.bucketer == nil {
+		f.bucketer, _ = bucketing.New([]bucketing.Bucket{
+			{Name: on, Weight: percent},
+			{Name: off, Weight: 100 - percent},
+		}, bucketing.WithSalt(f.name+":"), bucketing.WithResolution(f.resolution))
+	} else if f.percent != percent {
+		_ = f.bucketer.SetWeight(on, percent)
+		_ = f.bucketer.SetWeight(off, 100-percent)
+	}
+
+	f.percent = percent
+	return f.bucketer.Bucket(userID)
+}
@@ -0,0 +1,72 @@
+package rollout
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// enabled returns which of n users have flag enabled at percent.
+func enabled(r *Rollout, flag string, n int, percent float64) []bool {
+	users := make([]bool, n)
+	for i := range users {
+		users[i] = r.Enabled(flag, fmt.Sprintf("user%d", i), percent)
+	}
+
+	return users
+}
+
+func count(users []bool) int {
+	n := 0
+	for _, on := range users {
+		if on {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestEnabled(t *testing.T) {
+	r := New()
+
+	prev := enabled(r, "new-checkout", 10000, 0)
+	require.Zero(t, count(prev))
+
+	for _, percent := range []float64{1, 10, 20, 50, 99, 100} {
+		users := enabled(r, "new-checkout", 10000, percent)
+		require.InDelta(t, percent*100, count(users), 150, "at %v%%", percent)
+
+		// users enabled at a lower percentage stay enabled
+		for i, on := range prev {
+			if on {
+				require.True(t, users[i], "user%d disabled at %v%%", i, percent)
+			}
+		}
+		prev = users
+	}
+
+	// lowering the percentage restores the earlier rollout
+	at20 := enabled(r, "new-checkout", 10000, 20)
+	require.Equal(t, at20, enabled(New(), "new-checkout", 10000, 20))
+
+	require.False(t, r.Enabled("new-checkout", "user1", -5))
+	require.False(t, r.Enabled("new-checkout", "user1", math.NaN()))
+	require.True(t, r.Enabled("new-checkout", "user1", 150))
+}
+
+func TestEnabledFlagsIndependent(t *testing.T) {
+	r := New(WithResolution(10))
+	a := enabled(r, "flag-a", 1000, 50)
+	b := enabled(r, "flag-b", 1000, 50)
+	require.NotEqual(t, a, b)
+
+	// state is kept per flag, and forgetting it doesn't change the rollout
+	r.Forget("flag-a")
+	require.Equal(t, a, enabled(r, "flag-a", 1000, 50))
+	require.Equal(t, a, enabled(New(WithResolution(10)), "flag-a", 1000, 50))
+
+	require.Equal(t, Default.Enabled("flag-a", "user1", 30), Enabled("flag-a", "user1", 30))
+}
@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultParallelism is the number of handoffs a Rebalancer runs at once.
+const DefaultParallelism = 8
+
+// Handoff moves a job between workers. From is empty when the job had no
+// worker, e.g. because the ring was empty, and To is empty when it no longer
+// has one.
+type Handoff struct {
+	Job  string
+	From string
+	To   string
+}
+
+// Assigner maps jobs to workers. Both hashring.HashRing and
+// hashring.FrozenRing are assigners.
+type Assigner interface {
+	GetServer(key string) (string, error)
+}
+
+// Plan returns the handoffs needed to move jobs from their workers in before
+// to their workers in after, sorted by job. Only jobs whose worker changed are
+// included, so this is the minimal set of handoffs.
+func Plan(jobs []string, before, after Assigner) []Handoff {
+	var handoffs []Handoff
+	for _, job := range slices.Sorted(slices.Values(jobs)) {
+		from, _ := before.GetServer(job)
+		to, _ := after.GetServer(job)
+		if from != to {
+			handoffs = append(handoffs, Handoff{Job: job, From: from, To: to})
+		}
+	}
+
+	return slices.CompactFunc(handoffs, func(a, b Handoff) bool { return a.Job == b.Job })
+}
+
+// Workers is how a Rebalancer reaches the workers, typically by calling the
+// Drain and Acquire methods of their schedulers over RPC.
+type Workers interface {
+	// Drain stops job on worker, returning once it has stopped.
+	Drain(ctx context.Context, worker, job string) error
+	// Acquire starts job on worker.
+	Acquire(ctx context.Context, worker, job string) error
+}
+
+// RebalanceOption configures a Rebalancer.
+type RebalanceOption func(*Rebalancer)
+
+// WithParallelism sets the number of handoffs a Rebalancer runs at once.
+func WithParallelism(n int) RebalanceOption {
+	return func(r *Rebalancer) {
+		r.parallelism = max(n, 1)
+	}
+}
+
+// Rebalancer moves jobs between workers when the ring changes, making sure a
+// job has stopped on its old worker before it starts on the new one, so it's
+// never run twice. It's meant to run on a single coordinator, with the workers'
+// schedulers created WithCoordinatedHandoffs.
+type Rebalancer struct {
+	ring        *hashring.HashRing
+	workers     Workers
+	parallelism int
+}
+
+// NewRebalancer creates a rebalancer that changes ring and moves jobs between
+// workers.
+//
+// Example:
+//
+//	rebalancer := scheduler.NewRebalancer(ring, rpcWorkers{})
+//	_, err := rebalancer.Apply(ctx, jobs, func(ring *hashring.HashRing) error {
+//		return ring.AddServer("worker-4")
+//	})
+func NewRebalancer(ring *hashring.HashRing, workers Workers, opts ...RebalanceOption) *Rebalancer {
+	r := &Rebalancer{
+		ring:        ring,
+		workers:     workers,
+		parallelism: DefaultParallelism,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Apply makes a topology change with change, then hands off the jobs whose
+// worker it changed (see Plan and Execute). It returns the handoffs, including
+// any that failed.
+//
+// Changes made to the ring concurrently, other than through Apply, may be
+// attributed to this one.
+func (r *Rebalancer) Apply(ctx context.Context, jobs []string, change func(*hashring.HashRing) error) ([]Handoff, error) {
+	before := r.ring.Freeze()
+	if err := change(r.ring); err != nil {
+		return nil, err
+	}
+
+	handoffs := Plan(jobs, before, r.ring)
+	return handoffs, r.Execute(ctx, handoffs)
+}
+
+// Execute runs handoffs, up to the configured parallelism at once. Each job is
+// drained from its old worker and, once that's acknowledged, acquired by its
+// new one. If draining fails the job isn't acquired, since it may still be
+// running; retrying the handoff is safe since draining a stopped job and
+// acquiring a running one have no effect.
+//
+// Returns the errors of the failed handoffs, joined.
+func (r *Rebalancer) Execute(ctx context.Context, handoffs []Handoff) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	sem := make(chan struct{}, r.parallelism)
+	for _, h := range handoffs {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := r.handoff(ctx, h); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// handoff drains h.Job from its old worker and then acquires it on the new one.
+func (r *Rebalancer) handoff(ctx context.Context, h Handoff) error {
+	if h.From != "" {
+		if err := r.workers.Drain(ctx, h.From, h.Job); err != nil {
+			return fmt.Errorf("job %s: draining from %s: %w", h.Job, h.From, err)
+		}
+	}
+
+	if h.To != "" {
+		if err := r.workers.Acquire(ctx, h.To, h.Job); err != nil {
+			return fmt.Errorf("job %s: acquiring on %s: %w", h.Job, h.To, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// localWorkers reaches schedulers in the same process, tracking how many
+// copies of each job run at once.
+type localWorkers struct {
+	mu       sync.Mutex
+	scheds   map[string]*Scheduler
+	copies   map[string]int // running copies by job
+	overlaps int
+	failing  string // worker whose drains fail
+}
+
+func newLocalWorkers(ring *hashring.HashRing, workers ...string) *localWorkers {
+	w := &localWorkers{
+		scheds: make(map[string]*Scheduler),
+		copies: make(map[string]int),
+	}
+
+	for _, worker := range workers {
+		w.scheds[worker] = New(ring, worker,
+			WithCoordinatedHandoffs(),
+			WithOnStart(func(job string) {
+				w.mu.Lock()
+				defer w.mu.Unlock()
+				w.copies[job]++
+				if w.copies[job] > 1 {
+					w.overlaps++
+				}
+			}),
+			WithOnStop(func(job string) {
+				w.mu.Lock()
+				defer w.mu.Unlock()
+				w.copies[job]--
+			}),
+		)
+	}
+
+	return w
+}
+
+func (w *localWorkers) Drain(_ context.Context, worker, job string) error {
+	if worker == w.failing {
+		return errors.New("unreachable")
+	}
+
+	w.scheds[worker].Drain(job)
+	return nil
+}
+
+func (w *localWorkers) Acquire(_ context.Context, worker, job string) error {
+	w.scheds[worker].Acquire(job)
+	return nil
+}
+
+func TestPlan(t *testing.T) {
+	before := hashring.New(50)
+	require.NoError(t, before.AddServer("worker1"))
+	require.NoError(t, before.AddServer("worker2"))
+
+	after := before.Freeze().Thaw()
+	require.NoError(t, after.AddServer("worker3"))
+
+	var jobs []string
+	for i := range 100 {
+		jobs = append(jobs, fmt.Sprintf("job%d", i))
+	}
+
+	handoffs := Plan(append(jobs, "job0"), before, after)
+	require.NotEmpty(t, handoffs)
+	require.Less(t, len(handoffs), 60, "Only jobs moving to the new worker should be handed off")
+	for _, h := range handoffs {
+		require.Equal(t, "worker3", h.To)
+	}
+
+	// jobs without a worker yet
+	handoffs = Plan([]string{"job1"}, hashring.New(50), after)
+	require.Len(t, handoffs, 1)
+	require.Empty(t, handoffs[0].From)
+}
+
+func TestRebalancer(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("worker1"))
+	require.NoError(t, ring.AddServer("worker2"))
+
+	workers := newLocalWorkers(ring, "worker1", "worker2", "worker3")
+	var jobs []string
+	for i := range 100 {
+		jobs = append(jobs, fmt.Sprintf("job%d", i))
+	}
+	for _, sched := range workers.scheds {
+		sched.AddJob(jobs...)
+	}
+
+	// every job runs once, on its assigned worker
+	assigned := func() {
+		t.Helper()
+		for _, job := range jobs {
+			worker, err := ring.GetServer(job)
+			require.NoError(t, err)
+			require.Contains(t, workers.scheds[worker].Running(), job)
+			require.Equal(t, 1, workers.copies[job], job)
+		}
+	}
+	assigned()
+
+	rebalancer := NewRebalancer(ring, workers, WithParallelism(4))
+	handoffs, err := rebalancer.Apply(context.Background(), jobs, func(ring *hashring.HashRing) error {
+		return ring.AddServer("worker3")
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, handoffs)
+	assigned()
+
+	_, err = rebalancer.Apply(context.Background(), jobs, func(ring *hashring.HashRing) error {
+		return ring.RemoveServer("worker1")
+	})
+	require.NoError(t, err)
+	assigned()
+	require.Empty(t, workers.scheds["worker1"].Running())
+	require.Zero(t, workers.overlaps)
+
+	// jobs that can't be drained aren't started elsewhere
+	workers.failing = "worker2"
+	handoffs, err = rebalancer.Apply(context.Background(), jobs, func(ring *hashring.HashRing) error {
+		return ring.AddServer("worker1")
+	})
+	require.ErrorContains(t, err, "draining from worker2")
+	require.Zero(t, workers.overlaps)
+
+	for _, h := range handoffs {
+		if h.From == "worker2" {
+			require.NotContains(t, workers.scheds["worker1"].Running(), h.Job)
+		}
+	}
+
+	// failed changes don't hand anything off
+	_, err = rebalancer.Apply(context.Background(), jobs, func(ring *hashring.HashRing) error {
+		return ring.AddServer("worker1")
+	})
+	require.Error(t, err)
+}
+
+func TestCoordinatedDuplicateAddJob(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("worker1"))
+
+	workers := newLocalWorkers(ring, "worker1", "worker2")
+	jobs := []string{"a", "b", "c", "d", "e", "f"}
+	for _, sched := range workers.scheds {
+		sched.AddJob(jobs...)
+	}
+	require.Equal(t, jobs, workers.scheds["worker1"].Running())
+
+	// Without a Rebalancer, a topology change starts nothing, and adding a
+	// job that's already scheduled doesn't either
+	require.NoError(t, ring.AddServer("worker2"))
+	workers.scheds["worker2"].AddJob("a")
+	workers.scheds["worker1"].AddJob("a")
+	require.Empty(t, workers.scheds["worker2"].Running())
+	require.Equal(t, jobs, workers.scheds["worker1"].Running())
+	require.Zero(t, workers.overlaps)
+}
@@ -0,0 +1,270 @@
+// Package scheduler distributes jobs across a fleet of workers with a
+// consistent hash ring.
+//
+// Each worker is a server in the ring and every node holds the same ring and
+// job list. A Scheduler runs on each node and tells it, through the start and
+// stop callbacks, which jobs it should be running: those whose id the ring
+// assigns to the node. When workers join or leave, only the jobs adjacent to
+// them move, and each node stops the jobs it lost before starting the ones it
+// gained.
+//
+// By default assignments follow the ring rather than a coordinator, so a job
+// may briefly run on two nodes while they see different rings, or while one
+// is still stopping it. Jobs that mustn't overlap can either fence their
+// writes (see hashring.HashRing.Claim), or leave moving them to a Rebalancer
+// by creating the schedulers WithCoordinatedHandoffs.
+package scheduler
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithOnStart sets the function called when the node gains a job.
+func WithOnStart(fn func(job string)) Option {
+	return func(s *Scheduler) {
+		s.onStart = fn
+	}
+}
+
+// WithOnStop sets the function called when the node loses a job, or the job
+// is removed.
+func WithOnStop(fn func(job string)) Option {
+	return func(s *Scheduler) {
+		s.onStop = fn
+	}
+}
+
+// WithCoordinatedHandoffs stops the scheduler from following the ring's
+// changes. Jobs that move between workers are instead stopped and started by a
+// Rebalancer calling Drain and Acquire, so the new worker only starts a job
+// once the old one has stopped it. Jobs added to the schedule still start on
+// their assigned worker.
+func WithCoordinatedHandoffs() Option {
+	return func(s *Scheduler) {
+		s.coordinated = true
+	}
+}
+
+// Scheduler assigns jobs to workers and runs the node's share of them.
+//
+// It is safe for concurrent use, but the start and stop callbacks must not add
+// or remove jobs or close the scheduler.
+type Scheduler struct {
+	ring    *hashring.HashRing
+	self    string
+	onStart func(job string)
+	onStop  func(job string)
+	cancel  func()
+
+	coordinated bool // jobs move via Drain and Acquire
+
+	// reconcile serializes reconciling so callbacks run one at a time, in
+	// order. It's taken before mu.
+	reconcile sync.Mutex
+
+	mu      sync.Mutex
+	jobs    map[string]bool
+	running map[string]bool // jobs this node has started
+	closed  bool
+}
+
+// New creates a scheduler for the worker named self, which should be a server
+// in ring once the node is ready to take jobs. It follows the ring's changes
+// until Close is called.
+//
+// Example:
+//
+//	sched := scheduler.New(ring, "worker-3",
+//		scheduler.WithOnStart(func(job string) { runner.Start(job) }),
+//		scheduler.WithOnStop(func(job string) { runner.Stop(job) }),
+//	)
+//	defer sched.Close()
+//	sched.AddJob("reindex-orders", "expire-carts")
+func New(ring *hashring.HashRing, self string, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		ring:    ring,
+		self:    self,
+		onStart: func(string) {},
+		onStop:  func(string) {},
+		jobs:    make(map[string]bool),
+		running: make(map[string]bool),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.cancel = func() {}
+	if !s.coordinated {
+		s.cancel = ring.OnMove(func([]hashring.RangeMove) { s.sync(nil) })
+	}
+
+	return s
+}
+
+// AddJob adds jobs to the schedule, starting those assigned to this node.
+// Adding a job again has no effect.
+func (s *Scheduler) AddJob(ids ...string) {
+	s.mu.Lock()
+	var added []string
+	for _, id := range ids {
+		if !s.jobs[id] {
+			s.jobs[id] = true
+			added = append(added, id)
+		}
+	}
+	s.mu.Unlock()
+
+	if s.coordinated {
+		// Jobs already in the schedule are moved only by the Rebalancer, and
+		// a nil slice would reconcile all of them
+		if len(added) > 0 {
+			s.sync(added)
+		}
+		return
+	}
+
+	s.sync(nil)
+}
+
+// RemoveJob removes jobs from the schedule, stopping those this node runs.
+func (s *Scheduler) RemoveJob(ids ...string) {
+	s.mu.Lock()
+	for _, id := range ids {
+		delete(s.jobs, id)
+	}
+	s.mu.Unlock()
+
+	s.sync([]string{})
+}
+
+// Drain stops a job if this node runs it. With coordinated handoffs, a
+// Rebalancer calls it, e.g. through an RPC handler, on the job's old worker;
+// returning acknowledges that the job has stopped.
+func (s *Scheduler) Drain(job string) {
+	s.reconcile.Lock()
+	defer s.reconcile.Unlock()
+
+	s.mu.Lock()
+	running := s.running[job]
+	delete(s.running, job)
+	s.mu.Unlock()
+
+	if running {
+		s.onStop(job)
+	}
+}
+
+// Acquire starts a job on this node if it's in the schedule and not already
+// running. With coordinated handoffs, a Rebalancer calls it on the job's new
+// worker once the old one has drained it.
+func (s *Scheduler) Acquire(job string) {
+	s.reconcile.Lock()
+	defer s.reconcile.Unlock()
+
+	s.mu.Lock()
+	start := s.jobs[job] && !s.running[job] && !s.closed
+	if start {
+		s.running[job] = true
+	}
+	s.mu.Unlock()
+
+	if start {
+		s.onStart(job)
+	}
+}
+
+// Assignment returns the worker a job is assigned to. The job needn't be in
+// the schedule.
+//
+// Returns an error if the ring is empty.
+func (s *Scheduler) Assignment(id string) (string, error) {
+	worker, _, err := s.ring.Claim(id)
+	return worker, err
+}
+
+// Running returns the jobs this node is running, sorted.
+func (s *Scheduler) Running() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Sorted(maps.Keys(s.running))
+}
+
+// Close stops following the ring and stops every job this node runs, e.g.
+// before it shuts down. It should be removed from the ring too, so the jobs
+// are started elsewhere.
+func (s *Scheduler) Close() {
+	s.cancel()
+
+	s.reconcile.Lock()
+	defer s.reconcile.Unlock()
+
+	s.mu.Lock()
+	s.closed = true
+	stop := slices.Sorted(maps.Keys(s.running))
+	clear(s.running)
+	s.mu.Unlock()
+
+	for _, job := range stop {
+		s.onStop(job)
+	}
+}
+
+// sync starts the given jobs that are assigned to this node but not running,
+// stops the ones it runs that are no longer assigned to it, and stops jobs
+// that were removed from the schedule. Stops come first. A nil jobs checks
+// every job in the schedule.
+func (s *Scheduler) sync(jobs []string) {
+	s.reconcile.Lock()
+	defer s.reconcile.Unlock()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+
+	if jobs == nil {
+		jobs = slices.Sorted(maps.Keys(s.jobs))
+	}
+	slices.Sort(jobs)
+
+	var start, stop []string
+	for _, job := range jobs {
+		worker, _, err := s.ring.Claim(job)
+		mine := err == nil && worker == s.self
+		switch {
+		case mine && !s.running[job]:
+			start = append(start, job)
+			s.running[job] = true
+		case !mine && s.running[job]:
+			stop = append(stop, job)
+			delete(s.running, job)
+		}
+	}
+
+	for _, job := range slices.Sorted(maps.Keys(s.running)) {
+		if !s.jobs[job] {
+			stop = append(stop, job)
+			delete(s.running, job)
+		}
+	}
+	s.mu.Unlock()
+
+	slices.Sort(stop)
+	for _, job := range stop {
+		s.onStop(job)
+	}
+
+	for _, job := range start {
+		s.onStart(job)
+	}
+}
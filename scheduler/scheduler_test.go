@@ -0,0 +1,136 @@
+package scheduler
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// fleet tracks which worker runs each job.
+type fleet struct {
+	mu      sync.Mutex
+	running map[string]map[string]bool // jobs by worker
+	events  []string
+}
+
+func (f *fleet) scheduler(ring *hashring.HashRing, worker string) *Scheduler {
+	f.mu.Lock()
+	f.running[worker] = make(map[string]bool)
+	f.mu.Unlock()
+
+	return New(ring, worker,
+		WithOnStart(func(job string) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.running[worker][job] = true
+			f.events = append(f.events, "start "+job)
+		}),
+		WithOnStop(func(job string) {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			delete(f.running[worker], job)
+			f.events = append(f.events, "stop "+job)
+		}),
+	)
+}
+
+// assignments returns the workers running each job.
+func (f *fleet) assignments() map[string][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	jobs := make(map[string][]string)
+	for _, worker := range slices.Sorted(maps.Keys(f.running)) {
+		for job := range f.running[worker] {
+			jobs[job] = append(jobs[job], worker)
+		}
+	}
+
+	return jobs
+}
+
+// settled reports whether every job runs on exactly its assigned worker.
+func (f *fleet) settled(ring *hashring.HashRing, jobs []string) bool {
+	assignments := f.assignments()
+	if len(assignments) != len(jobs) {
+		return false
+	}
+
+	for _, job := range jobs {
+		worker, err := ring.GetServer(job)
+		if err != nil || !slices.Equal(assignments[job], []string{worker}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestScheduler(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("worker1"))
+	require.NoError(t, ring.AddServer("worker2"))
+
+	f := &fleet{running: make(map[string]map[string]bool)}
+	var scheds []*Scheduler
+	for i := 1; i <= 3; i++ {
+		sched := f.scheduler(ring, fmt.Sprintf("worker%d", i))
+		defer sched.Close()
+		scheds = append(scheds, sched)
+	}
+
+	var jobs []string
+	for i := range 50 {
+		jobs = append(jobs, fmt.Sprintf("job%d", i))
+	}
+
+	for _, sched := range scheds {
+		sched.AddJob(jobs...)
+	}
+	require.True(t, f.settled(ring, jobs))
+	require.Empty(t, scheds[2].Running(), "worker3 isn't in the ring yet")
+
+	// a worker joins and takes some jobs
+	require.NoError(t, ring.AddServer("worker3"))
+	require.Eventually(t, func() bool { return f.settled(ring, jobs) }, time.Second, time.Millisecond)
+	require.NotEmpty(t, scheds[2].Running())
+
+	// and leaves again
+	require.NoError(t, ring.RemoveServer("worker1"))
+	require.Eventually(t, func() bool { return f.settled(ring, jobs) }, time.Second, time.Millisecond)
+	require.Empty(t, scheds[0].Running())
+
+	// removed jobs are stopped
+	for _, sched := range scheds {
+		sched.RemoveJob(jobs[10:]...)
+	}
+	require.True(t, f.settled(ring, jobs[:10]))
+
+	worker, err := scheds[0].Assignment("job0")
+	require.NoError(t, err)
+	require.Contains(t, f.assignments()["job0"], worker)
+}
+
+func TestSchedulerClose(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("worker1"))
+
+	f := &fleet{running: make(map[string]map[string]bool)}
+	sched := f.scheduler(ring, "worker1")
+	sched.AddJob("job2", "job1")
+	require.Equal(t, []string{"job1", "job2"}, sched.Running())
+
+	sched.Close()
+	require.Empty(t, sched.Running())
+	require.Equal(t, []string{"start job1", "start job2", "stop job1", "stop job2"}, f.events)
+
+	// closed schedulers ignore changes
+	sched.AddJob("job3")
+	require.Empty(t, sched.Running())
+}
@@ -0,0 +1,241 @@
+// Package sessionstore stores web sessions across a set of nodes using a
+// consistent hash ring, replicating each session so it survives the loss of a
+// node.
+//
+// Each session is written to its owner and the next distinct nodes clockwise
+// around the ring (see hashring.HashRing.GetReplicas), two in all by default.
+// Reads go to the owner first and fall back to the replicas when it fails or
+// doesn't have the session, e.g. because it just joined the ring; the next Set
+// of the session writes it to the new owner.
+//
+// The store is transport agnostic: nodes are reached through the Node
+// interface, so any backend, such as Redis or memcached instances, can be
+// plugged in. Node failures are reported to the ring's circuit breakers (see
+// hashring.WithCircuitBreaker), so failing nodes are tried last.
+//
+// A node misses the writes and deletes made while it's down or tripped, so it
+// could serve stale sessions once it recovers. Nodes should rejoin empty, as
+// session caches usually do after a restart.
+package sessionstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+const (
+	// DefaultReplicas is the number of nodes each session is written to.
+	DefaultReplicas = 2
+
+	// DefaultTimeout bounds each node operation.
+	DefaultTimeout = 100 * time.Millisecond
+)
+
+// ErrNotFound is returned by Get when a session doesn't exist. Node
+// implementations must return it (or wrap it) for missing sessions.
+var ErrNotFound = errors.New("sessionstore: session not found")
+
+// Node is a client for a single session node.
+type Node interface {
+	Get(ctx context.Context, id string) ([]byte, error)
+	Set(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithReplicas sets the number of nodes each session is written to, including
+// its owner.
+func WithReplicas(n int) Option {
+	return func(s *Store) {
+		s.replicas = max(n, 1)
+	}
+}
+
+// WithTimeout sets the timeout for each node operation. Zero disables the
+// timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(s *Store) {
+		s.timeout = d
+	}
+}
+
+// Store routes sessions to the nodes in a hash ring.
+//
+// It is safe for concurrent use.
+type Store struct {
+	ring     *hashring.HashRing
+	connect  func(node string) Node
+	replicas int
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	version uint64
+	nodes   map[string]Node
+}
+
+// New creates a session store over the nodes in ring, reaching them through
+// the clients returned by connect, which is called once per node.
+//
+// Example:
+//
+//	ring := hashring.New(150, hashring.WithCircuitBreaker(3, 10*time.Second))
+//	ring.AddServer("sessions-1:6379")
+//	ring.AddServer("sessions-2:6379")
+//	ring.AddServer("sessions-3:6379")
+//
+//	store := sessionstore.New(ring, func(node string) sessionstore.Node {
+//		return newRedisNode(node)
+//	})
+//	err := store.Set(ctx, sessionID, data, 30*time.Minute)
+func New(ring *hashring.HashRing, connect func(node string) Node, opts ...Option) *Store {
+	s := &Store{
+		ring:     ring,
+		connect:  connect,
+		replicas: DefaultReplicas,
+		timeout:  DefaultTimeout,
+		nodes:    make(map[string]Node),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Get returns a session's data, trying its owner first and then its
+// replicas.
+//
+// Returns ErrNotFound if no node has the session, or an error if every node
+// that might have it failed.
+func (s *Store) Get(ctx context.Context, id string) ([]byte, error) {
+	nodes, err := s.ring.GetReplicas(id, s.replicas)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, node := range nodes {
+		var data []byte
+		err := s.do(ctx, node, func(ctx context.Context, n Node) (err error) {
+			data, err = n.Get(ctx, id)
+			return err
+		})
+
+		switch {
+		case err == nil:
+			return data, nil
+		case !errors.Is(err, ErrNotFound):
+			errs = append(errs, fmt.Errorf("node %s: %w", node, err))
+		}
+	}
+
+	if len(errs) == len(nodes) {
+		return nil, errors.Join(errs...)
+	}
+
+	return nil, ErrNotFound
+}
+
+// Set writes a session to its owner and replicas. A zero ttl means the
+// session doesn't expire.
+//
+// It succeeds if any node accepted the write, since a session that's missing
+// on some nodes is still found by Get; otherwise it returns every node's error.
+func (s *Store) Set(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	nodes, err := s.ring.GetReplicas(id, s.replicas)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, node := range nodes {
+		err := s.do(ctx, node, func(ctx context.Context, n Node) error {
+			return n.Set(ctx, id, data, ttl)
+		})
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("node %s: %w", node, err))
+		}
+	}
+
+	if len(errs) == len(nodes) {
+		return errors.Join(errs...)
+	}
+
+	return nil
+}
+
+// Delete removes a session from its owner and replicas.
+//
+// Unlike Set, it fails if any of the nodes fails, since the session could
+// otherwise still be read from that node.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	nodes, err := s.ring.GetReplicas(id, s.replicas)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, node := range nodes {
+		err := s.do(ctx, node, func(ctx context.Context, n Node) error {
+			return n.Delete(ctx, id)
+		})
+
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, fmt.Errorf("node %s: %w", node, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// do runs fn against node, bounded by the store's timeout, and reports the
+// outcome to the ring's circuit breaker.
+func (s *Store) do(ctx context.Context, node string, fn func(context.Context, Node) error) error {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	err := fn(ctx, s.node(node))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		s.ring.ReportFailure(node)
+	} else {
+		s.ring.ReportSuccess(node)
+	}
+
+	return err
+}
+
+// node returns the (cached) client for node. When the ring has changed since
+// the last call, clients for nodes that left the ring are dropped.
+func (s *Store) node(node string) Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if version := s.ring.Version(); version != s.version {
+		s.version = version
+		for name := range s.nodes {
+			if _, ok := s.ring.GetServerInfo(name); !ok {
+				delete(s.nodes, name)
+			}
+		}
+	}
+
+	n, ok := s.nodes[node]
+	if !ok {
+		n = s.connect(node)
+		s.nodes[node] = n
+	}
+
+	return n
+}
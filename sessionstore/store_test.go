@@ -0,0 +1,173 @@
+package sessionstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryNode is an in-memory Node that can be taken down.
+type memoryNode struct {
+	mu       sync.Mutex
+	sessions map[string][]byte
+	down     bool
+}
+
+func (m *memoryNode) Get(_ context.Context, id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.down {
+		return nil, errors.New("connection refused")
+	}
+
+	data, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return data, nil
+}
+
+func (m *memoryNode) Set(_ context.Context, id string, data []byte, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.down {
+		return errors.New("connection refused")
+	}
+
+	m.sessions[id] = data
+	return nil
+}
+
+func (m *memoryNode) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.down {
+		return errors.New("connection refused")
+	}
+
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *memoryNode) setDown(down bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.down = down
+}
+
+// cluster creates a ring of n memory nodes.
+func cluster(t *testing.T, n int, opts ...hashring.Option) (*hashring.HashRing, map[string]*memoryNode) {
+	t.Helper()
+
+	ring := hashring.New(50, opts...)
+	nodes := make(map[string]*memoryNode)
+	for i := range n {
+		name := fmt.Sprintf("node%d", i)
+		nodes[name] = &memoryNode{sessions: make(map[string][]byte)}
+		require.NoError(t, ring.AddServer(name))
+	}
+
+	return ring, nodes
+}
+
+func TestStore(t *testing.T) {
+	ring, nodes := cluster(t, 4)
+	store := New(ring, func(node string) Node { return nodes[node] })
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "session1", []byte("data"), time.Minute))
+
+	// written to the owner and one replica
+	replicas, err := ring.GetReplicas("session1", 2)
+	require.NoError(t, err)
+	for name, node := range nodes {
+		_, ok := node.sessions["session1"]
+		require.Equal(t, name == replicas[0] || name == replicas[1], ok, name)
+	}
+
+	data, err := store.Get(ctx, "session1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), data)
+
+	_, err = store.Get(ctx, "session2")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Delete(ctx, "session1"))
+	_, err = store.Get(ctx, "session1")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStoreNodeLoss(t *testing.T) {
+	ring, nodes := cluster(t, 4, hashring.WithCircuitBreaker(1, time.Hour))
+	store := New(ring, func(node string) Node { return nodes[node] })
+	ctx := context.Background()
+
+	require.NoError(t, store.Set(ctx, "session1", []byte("data"), time.Minute))
+	owner, err := ring.GetServer("session1")
+	require.NoError(t, err)
+
+	// reads fall back to the replica while the owner is down
+	nodes[owner].setDown(true)
+	data, err := store.Get(ctx, "session1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("data"), data)
+
+	// and the failure tripped the owner's breaker, so writes go elsewhere
+	require.NoError(t, store.Set(ctx, "session1", []byte("new"), time.Minute))
+	data, err = store.Get(ctx, "session1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), data)
+
+	// deletes must reach every replica
+	require.NoError(t, store.Delete(ctx, "session1"))
+	_, err = store.Get(ctx, "session1")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	replicas, err := ring.GetReplicas("session1", 2)
+	require.NoError(t, err)
+	nodes[replicas[0]].setDown(true)
+	require.Error(t, store.Delete(ctx, "session1"))
+
+	// only failing when every node does
+	for _, node := range nodes {
+		node.setDown(true)
+	}
+	_, err = store.Get(ctx, "session1")
+	require.ErrorContains(t, err, "connection refused")
+	require.Error(t, store.Set(ctx, "session1", []byte("data"), time.Minute))
+
+	_, err = New(hashring.New(50), func(string) Node { return nil }).Get(ctx, "session1")
+	require.Error(t, err)
+}
+
+func TestStoreNewOwner(t *testing.T) {
+	ring, nodes := cluster(t, 3)
+	store := New(ring, func(node string) Node { return nodes[node] }, WithReplicas(3))
+	ctx := context.Background()
+
+	var ids []string
+	for i := range 50 {
+		id := fmt.Sprintf("session%d", i)
+		ids = append(ids, id)
+		require.NoError(t, store.Set(ctx, id, []byte(id), time.Minute))
+	}
+
+	// sessions the new node now owns are read from the replicas
+	nodes["node3"] = &memoryNode{sessions: make(map[string][]byte)}
+	require.NoError(t, ring.AddServer("node3"))
+	for _, id := range ids {
+		data, err := store.Get(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, []byte(id), data)
+	}
+}
@@ -0,0 +1,128 @@
+package shardedmap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// ShardedCounter is a counter whose increments are spread across shards by a
+// hash ring and summed on read, for hot counters that would otherwise have
+// every goroutine contending on one value.
+//
+// Each increment takes a key that picks its shard, such as a worker or request
+// ID; keys that vary across the contending goroutines spread the increments
+// best. Increments take no locks.
+//
+// It is safe for concurrent use.
+type ShardedCounter struct {
+	mu   sync.Mutex // serializes Resize
+	ring *hashring.HashRing
+
+	layout atomic.Pointer[counterLayout]
+}
+
+// counterLayout is an immutable view of the counter's shards, replaced as a
+// whole by Resize.
+type counterLayout struct {
+	ring   *hashring.FrozenRing
+	active map[string]*counterShard
+
+	// all holds every shard ever created, including ones resized away, which
+	// keep their counts (and any increments that raced the resize)
+	all []*counterShard
+}
+
+// counterShard is one part of the count, padded to its own cache line so
+// shards don't contend through false sharing.
+type counterShard struct {
+	n atomic.Int64
+	_ [56]byte
+}
+
+// NewShardedCounter creates a counter with the given number of shards.
+//
+// Example:
+//
+//	requests, err := shardedmap.NewShardedCounter(runtime.GOMAXPROCS(0))
+//	if err != nil {
+//		return err
+//	}
+//
+//	// in each handler
+//	requests.Inc(requestID)
+func NewShardedCounter(shards int) (*ShardedCounter, error) {
+	c := &ShardedCounter{ring: hashring.New(DefaultVirtualNodes)}
+	c.layout.Store(&counterLayout{ring: c.ring.Freeze()})
+
+	if _, err := c.Resize(shards); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Add adds delta to the counter, on the shard key picks.
+func (c *ShardedCounter) Add(key string, delta int64) {
+	l := c.layout.Load()
+	name, _ := l.ring.GetServer(key)
+	l.active[name].n.Add(delta)
+}
+
+// Inc adds one to the counter, on the shard key picks.
+func (c *ShardedCounter) Inc(key string) {
+	c.Add(key, 1)
+}
+
+// Value returns the sum of the shards. Increments made while it runs may or
+// may not be counted.
+func (c *ShardedCounter) Value() int64 {
+	var sum int64
+	for _, s := range c.layout.Load().all {
+		sum += s.n.Load()
+	}
+
+	return sum
+}
+
+// Shards returns the number of shards increments are spread across.
+func (c *ShardedCounter) Shards() int {
+	return len(c.layout.Load().active)
+}
+
+// Resize changes the number of shards increments are spread across, without
+// changing the counter's value, and returns the previous number.
+func (c *ShardedCounter) Resize(shards int) (int, error) {
+	if shards <= 0 {
+		return 0, fmt.Errorf("shard count must be positive, got %d", shards)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prev := c.layout.Load()
+	next := &counterLayout{
+		active: make(map[string]*counterShard, shards),
+		all:    prev.all,
+	}
+
+	for i := len(next.all); i < shards; i++ {
+		next.all = append(next.all, &counterShard{})
+	}
+
+	for i := range max(len(prev.active), shards) {
+		name := shardName(i)
+		if i < shards {
+			next.active[name] = next.all[i]
+			_ = c.ring.AddServer(name)
+		} else {
+			_ = c.ring.RemoveServer(name)
+		}
+	}
+
+	next.ring = c.ring.Freeze()
+	c.layout.Store(next)
+	return len(prev.active), nil
+}
@@ -0,0 +1,73 @@
+package shardedmap
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedCounter(t *testing.T) {
+	_, err := NewShardedCounter(0)
+	require.Error(t, err)
+
+	c, err := NewShardedCounter(4)
+	require.NoError(t, err)
+	require.Equal(t, 4, c.Shards())
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				c.Inc(fmt.Sprintf("worker%d", w))
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.Add("worker0", -500)
+	require.Equal(t, int64(7500), c.Value())
+
+	// increments are spread across the shards
+	used := 0
+	for _, s := range c.layout.Load().all {
+		if s.n.Load() != 0 {
+			used++
+		}
+	}
+	require.Greater(t, used, 1)
+}
+
+func TestShardedCounterResize(t *testing.T) {
+	c, err := NewShardedCounter(8)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				c.Inc(fmt.Sprintf("worker%d-%d", w, i))
+			}
+		}()
+	}
+
+	for _, shards := range []int{2, 16, 1, 4} {
+		prev := c.Shards()
+		got, err := c.Resize(shards)
+		require.NoError(t, err)
+		require.Equal(t, prev, got)
+		require.Equal(t, shards, c.Shards())
+	}
+	wg.Wait()
+
+	// no increments are lost to shards resized away
+	require.Equal(t, int64(4000), c.Value())
+
+	_, err = c.Resize(-1)
+	require.Error(t, err)
+}
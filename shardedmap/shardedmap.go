@@ -0,0 +1,305 @@
+// Package shardedmap provides a concurrent map split into shards, each with
+// its own lock, that uses a consistent hash ring to pick a key's shard.
+//
+// Splitting a map into shards lets goroutines working on different keys
+// proceed without contending on one lock. Because shards are placed on a ring,
+// the number of shards can be changed while the map is in use and only about
+// 1/n of the entries move, where rehashing by modulo would move nearly all of
+// them.
+//
+// ShardedCounter applies the same idea to a single hot counter.
+package shardedmap
+
+import (
+	"fmt"
+	"iter"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultVirtualNodes is the number of virtual nodes placed for each shard.
+const DefaultVirtualNodes = 150
+
+// Option configures a ShardedMap.
+type Option[K comparable] func(*config[K])
+
+type config[K comparable] struct {
+	vnodes int
+	key    func(K) string
+}
+
+// WithVirtualNodes sets the number of virtual nodes placed for each shard.
+func WithVirtualNodes[K comparable](n int) Option[K] {
+	return func(c *config[K]) {
+		c.vnodes = n
+	}
+}
+
+// WithKeyFunc sets the function that turns keys into the strings hashed onto
+// the ring. The default uses strings as they are and fmt.Sprint for other
+// types, so a faster function is worth providing for non-string keys.
+func WithKeyFunc[K comparable](fn func(K) string) Option[K] {
+	return func(c *config[K]) {
+		c.key = fn
+	}
+}
+
+// ShardedMap is a concurrent map whose entries are spread across shards by a
+// hash ring.
+//
+// It is safe for concurrent use. Operations on a key lock only its shard and
+// look it up on an immutable copy of the ring, so they share no locks or
+// counters with operations on other shards.
+type ShardedMap[K comparable, V any] struct {
+	key func(K) string
+
+	mu   sync.RWMutex // held for writing while resizing
+	ring *hashring.HashRing
+
+	layout atomic.Pointer[mapLayout[K, V]]
+}
+
+// mapLayout is an immutable view of the map's shards, replaced as a whole by
+// Resize.
+type mapLayout[K comparable, V any] struct {
+	ring   *hashring.FrozenRing
+	shards map[string]*shard[K, V]
+}
+
+// shard is a part of the map with its own lock.
+type shard[K comparable, V any] struct {
+	mu      sync.RWMutex
+	entries map[K]V
+}
+
+// New creates a map with the given number of shards.
+//
+// Example:
+//
+//	m, err := shardedmap.New[string, *Session](16)
+//	if err != nil {
+//		return err
+//	}
+//
+//	m.Set("session:42", session)
+//	s, ok := m.Get("session:42")
+func New[K comparable, V any](shards int, opts ...Option[K]) (*ShardedMap[K, V], error) {
+	cfg := config[K]{vnodes: DefaultVirtualNodes, key: defaultKey[K]}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	m := &ShardedMap[K, V]{
+		key:  cfg.key,
+		ring: hashring.New(cfg.vnodes),
+	}
+	m.layout.Store(&mapLayout[K, V]{ring: m.ring.Freeze(), shards: make(map[string]*shard[K, V])})
+
+	if _, err := m.Resize(shards); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Get returns the value stored under key and whether it was found.
+func (m *ShardedMap[K, V]) Get(key K) (V, bool) {
+	s := m.lockShard(key, false)
+	defer s.mu.RUnlock()
+
+	v, ok := s.entries[key]
+	return v, ok
+}
+
+// Set stores value under key.
+func (m *ShardedMap[K, V]) Set(key K, value V) {
+	s := m.lockShard(key, true)
+	defer s.mu.Unlock()
+
+	s.entries[key] = value
+}
+
+// Update sets key to the result of fn, which is given the current value and
+// whether it exists, atomically with respect to other operations on the key.
+// fn must not use the map.
+//
+// Example:
+//
+//	m.Update("hits", func(n int, _ bool) int { return n + 1 })
+func (m *ShardedMap[K, V]) Update(key K, fn func(value V, ok bool) V) V {
+	s := m.lockShard(key, true)
+	defer s.mu.Unlock()
+
+	v, ok := s.entries[key]
+	v = fn(v, ok)
+	s.entries[key] = v
+	return v
+}
+
+// Delete removes key, reporting whether it was present.
+func (m *ShardedMap[K, V]) Delete(key K) bool {
+	s := m.lockShard(key, true)
+	defer s.mu.Unlock()
+
+	_, ok := s.entries[key]
+	delete(s.entries, key)
+	return ok
+}
+
+// Len returns the number of entries. Concurrent changes may or may not be
+// counted.
+func (m *ShardedMap[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n := 0
+	for _, s := range m.layout.Load().shards {
+		s.mu.RLock()
+		n += len(s.entries)
+		s.mu.RUnlock()
+	}
+
+	return n
+}
+
+// Shards returns the number of shards.
+func (m *ShardedMap[K, V]) Shards() int {
+	return len(m.layout.Load().shards)
+}
+
+// All returns an iterator over the map's entries, a shard at a time, in no
+// particular order. Each shard is locked while it's iterated, so the loop body
+// must not change the map.
+func (m *ShardedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		for _, s := range m.layout.Load().shards {
+			if !s.each(yield) {
+				return
+			}
+		}
+	}
+}
+
+// Resize changes the number of shards while the map is in use, moving only the
+// entries whose shard changed, and returns how many moved. Operations on the
+// shards involved wait while the entries move.
+func (m *ShardedMap[K, V]) Resize(shards int) (int, error) {
+	if shards <= 0 {
+		return 0, fmt.Errorf("shard count must be positive, got %d", shards)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Shards are numbered, so growing adds the highest numbers and shrinking
+	// removes them
+	prev := m.layout.Load()
+	next := &mapLayout[K, V]{shards: make(map[string]*shard[K, V], shards)}
+	var all []*shard[K, V]
+	for i := range max(len(prev.shards), shards) {
+		name := shardName(i)
+		s, ok := prev.shards[name]
+		if !ok {
+			s = &shard[K, V]{entries: make(map[K]V)}
+		}
+
+		if i < shards {
+			next.shards[name] = s
+			_ = m.ring.AddServer(name)
+		} else {
+			_ = m.ring.RemoveServer(name)
+		}
+
+		all = append(all, s)
+	}
+	next.ring = m.ring.Freeze()
+
+	// Operations hold one shard lock at a time, so taking them all in order
+	// can't deadlock. Any that locked a shard under the old layout see it's
+	// been replaced and look their key up again.
+	for _, s := range all {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+	m.layout.Store(next)
+
+	moved := 0
+	for _, s := range all {
+		for k, v := range s.entries {
+			if target := next.shardFor(m.key(k)); target != s {
+				target.entries[k] = v
+				delete(s.entries, k)
+				moved++
+			}
+		}
+	}
+
+	return moved, nil
+}
+
+// lockShard locks the shard key belongs to, for writing if write is set, and
+// returns it.
+func (m *ShardedMap[K, V]) lockShard(key K, write bool) *shard[K, V] {
+	hashed := m.key(key)
+	for {
+		l := m.layout.Load()
+		s := l.shardFor(hashed)
+		if write {
+			s.mu.Lock()
+		} else {
+			s.mu.RLock()
+		}
+
+		// A resize that finished while we waited may have moved the key
+		if m.layout.Load() == l {
+			return s
+		}
+
+		if write {
+			s.mu.Unlock()
+		} else {
+			s.mu.RUnlock()
+		}
+	}
+}
+
+// shardFor returns the shard the hashed key belongs to.
+func (l *mapLayout[K, V]) shardFor(key string) *shard[K, V] {
+	name, _ := l.ring.GetServer(key)
+	return l.shards[name]
+}
+
+// each calls yield for each of the shard's entries until it returns false,
+// reporting whether it never did.
+func (s *shard[K, V]) each(yield func(K, V) bool) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for k, v := range s.entries {
+		if !yield(k, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// shardName returns the name of shard i on the ring.
+func shardName(i int) string {
+	return "shard-" + strconv.Itoa(i)
+}
+
+// defaultKey returns key as a string for hashing.
+func defaultKey[K comparable](key K) string {
+	if s, ok := any(key).(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,166 @@
+package shardedmap
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedMap(t *testing.T) {
+	_, err := New[string, int](0)
+	require.Error(t, err)
+
+	m, err := New[string, int](4)
+	require.NoError(t, err)
+	require.Equal(t, 4, m.Shards())
+
+	m.Set("a", 1)
+	m.Set("b", 2)
+
+	v, ok := m.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+
+	_, ok = m.Get("c")
+	require.False(t, ok)
+
+	require.Equal(t, 3, m.Update("b", func(v int, ok bool) int {
+		require.True(t, ok)
+		return v + 1
+	}))
+	require.Equal(t, 1, m.Update("c", func(v int, ok bool) int {
+		require.False(t, ok)
+		return v + 1
+	}))
+	require.Equal(t, 3, m.Len())
+
+	require.True(t, m.Delete("c"))
+	require.False(t, m.Delete("c"))
+
+	entries := make(map[string]int)
+	for k, v := range m.All() {
+		entries[k] = v
+	}
+	require.Equal(t, map[string]int{"a": 1, "b": 3}, entries)
+
+	for range m.All() {
+		break
+	}
+}
+
+func TestShardedMapKeyFunc(t *testing.T) {
+	m, err := New[int, string](8, WithKeyFunc(strconv.Itoa), WithVirtualNodes[int](50))
+	require.NoError(t, err)
+
+	for i := range 100 {
+		m.Set(i, strconv.Itoa(i))
+	}
+
+	// keys are spread across the shards
+	for _, s := range m.layout.Load().shards {
+		require.NotEmpty(t, s.entries)
+	}
+
+	v, ok := m.Get(42)
+	require.True(t, ok)
+	require.Equal(t, "42", v)
+}
+
+func TestResize(t *testing.T) {
+	m, err := New[string, int](4)
+	require.NoError(t, err)
+
+	const n = 10000
+	for i := range n {
+		m.Set(fmt.Sprintf("key%d", i), i)
+	}
+
+	// growing from 4 to 5 shards moves roughly a fifth of the entries
+	moved, err := m.Resize(5)
+	require.NoError(t, err)
+	require.Equal(t, 5, m.Shards())
+	require.Equal(t, n, m.Len())
+	require.Positive(t, moved)
+	require.Less(t, moved, n/3)
+
+	// shrinking moves the removed shard's entries and nothing else
+	removed := len(m.layout.Load().shards[shardName(4)].entries)
+	moved, err = m.Resize(4)
+	require.NoError(t, err)
+	require.Equal(t, removed, moved)
+	require.Equal(t, n, m.Len())
+
+	for i := range n {
+		v, ok := m.Get(fmt.Sprintf("key%d", i))
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+
+	_, err = m.Resize(0)
+	require.Error(t, err)
+}
+
+func TestResizeConcurrent(t *testing.T) {
+	m, err := New[string, int](2)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				m.Set(fmt.Sprintf("w%d-key%d", w, i), i)
+			}
+		}()
+	}
+
+	for shards := 3; shards <= 8; shards++ {
+		_, err := m.Resize(shards)
+		require.NoError(t, err)
+	}
+	wg.Wait()
+
+	require.Equal(t, 4000, m.Len())
+	for w := range 4 {
+		for i := range 1000 {
+			v, ok := m.Get(fmt.Sprintf("w%d-key%d", w, i))
+			require.True(t, ok)
+			require.Equal(t, i, v)
+		}
+	}
+}
+
+func TestUpdateDuringResize(t *testing.T) {
+	m, err := New[string, int](4)
+	require.NoError(t, err)
+
+	// Updates racing a resize are neither lost nor applied to a shard the key
+	// has moved away from
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 2000 {
+				m.Update(fmt.Sprintf("key%d", i%50), func(n int, _ bool) int { return n + 1 })
+			}
+		}()
+	}
+
+	for _, shards := range []int{8, 2, 16, 3, 5, 1, 4} {
+		_, err := m.Resize(shards)
+		require.NoError(t, err)
+	}
+	wg.Wait()
+
+	require.Equal(t, 50, m.Len())
+	for i := range 50 {
+		v, ok := m.Get(fmt.Sprintf("key%d", i))
+		require.True(t, ok)
+		require.Equal(t, 4*2000/50, v)
+	}
+}
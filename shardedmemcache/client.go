@@ -0,0 +1,155 @@
+package shardedmemcache
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// Client is a memcache.Client that ejects servers failing requests.
+//
+// Get, Set, Delete, and GetMulti mark the server that handled the request as
+// dead when it fails with a connection or I/O error; subsequent requests for
+// its keys go to the next server on the ring until the cooldown expires. Other
+// memcache.Client methods are available but don't eject servers.
+type Client struct {
+	*memcache.Client
+
+	selector *Selector
+}
+
+// New creates a client that shards keys across the servers in ring.
+//
+// Example:
+//
+//	ring := hashring.New(150)
+//	ring.AddServer("10.0.0.1:11211")
+//	ring.AddServer("10.0.0.2:11211")
+//
+//	mc := shardedmemcache.New(ring)
+//	mc.Set(&memcache.Item{Key: "user:42", Value: []byte("alice")})
+//	items, err := mc.GetMulti([]string{"user:42", "user:43"})
+func New(ring *hashring.HashRing, opts ...Option) *Client {
+	selector := NewSelector(ring, opts...)
+	return &Client{
+		Client:   memcache.NewFromSelector(selector),
+		selector: selector,
+	}
+}
+
+// Selector returns the selector used to pick servers.
+func (c *Client) Selector() *Selector {
+	return c.selector
+}
+
+// Get gets the item for key from the server that owns it.
+func (c *Client) Get(key string) (*memcache.Item, error) {
+	var item *memcache.Item
+	err := c.withServer(key, func() (err error) {
+		item, err = c.Client.Get(key)
+		return err
+	})
+
+	return item, err
+}
+
+// Set writes item to the server that owns its key.
+func (c *Client) Set(item *memcache.Item) error {
+	return c.withServer(item.Key, func() error {
+		return c.Client.Set(item)
+	})
+}
+
+// Delete removes key from the server that owns it.
+func (c *Client) Delete(key string) error {
+	return c.withServer(key, func() error {
+		return c.Client.Delete(key)
+	})
+}
+
+// GetMulti fetches keys with one request per server, in parallel.
+//
+// The returned map may have fewer items than keys due to cache misses. If any
+// server fails, its error is returned along with the items that were fetched.
+func (c *Client) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	batches := make(map[string][]string)
+	for _, key := range keys {
+		server, err := c.selector.Server(key)
+		if err != nil {
+			return nil, err
+		}
+
+		batches[server] = append(batches[server], key)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		items = make(map[string]*memcache.Item, len(keys))
+		errs  []error
+	)
+
+	for server, batch := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			found, err := c.Client.GetMulti(batch)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			for key, item := range found {
+				items[key] = item
+			}
+
+			if err != nil {
+				c.eject(server, err)
+				errs = append(errs, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return items, errors.Join(errs...)
+}
+
+// withServer calls fn and ejects the server owning key if it fails.
+func (c *Client) withServer(key string, fn func() error) error {
+	server, err := c.selector.Server(key)
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	c.eject(server, err)
+	return err
+}
+
+// eject marks server as dead when err indicates the server itself failed
+// rather than the request.
+func (c *Client) eject(server string, err error) {
+	if serverFailed(err) {
+		c.selector.MarkDead(server)
+	}
+}
+
+// serverFailed reports whether err is a connection or I/O failure, as opposed to
+// a cache-level result like a miss.
+func serverFailed(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, memcache.ErrCacheMiss),
+		errors.Is(err, memcache.ErrCASConflict),
+		errors.Is(err, memcache.ErrNotStored),
+		errors.Is(err, memcache.ErrServerError),
+		errors.Is(err, memcache.ErrNoStats),
+		errors.Is(err, memcache.ErrMalformedKey),
+		errors.Is(err, memcache.ErrNoServers):
+		return false
+	}
+
+	return true
+}
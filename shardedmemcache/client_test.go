@@ -0,0 +1,87 @@
+package shardedmemcache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T, n int) (*hashring.HashRing, map[string]*fakeServer) {
+	t.Helper()
+
+	ring := hashring.New(50)
+	servers := make(map[string]*fakeServer)
+	for range n {
+		srv := newFakeServer(t)
+		servers[srv.Addr()] = srv
+		require.NoError(t, ring.AddServer(srv.Addr()))
+	}
+
+	return ring, servers
+}
+
+func TestClient(t *testing.T) {
+	ring, servers := setup(t, 3)
+	mc := New(ring)
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%d", i)
+		require.NoError(t, mc.Set(&memcache.Item{Key: keys[i], Value: []byte(keys[i])}))
+
+		// The item lives only on the owning server
+		owner, err := ketama(t, ring).GetServer(keys[i])
+		require.NoError(t, err)
+
+		for addr, srv := range servers {
+			require.Equal(t, addr == owner, srv.Has(keys[i]), "key %s on %s", keys[i], addr)
+		}
+
+		item, err := mc.Get(keys[i])
+		require.NoError(t, err)
+		require.Equal(t, keys[i], string(item.Value))
+	}
+
+	items, err := mc.GetMulti(append(keys, "missing"))
+	require.NoError(t, err)
+	require.Len(t, items, len(keys))
+	for _, key := range keys {
+		require.Equal(t, key, string(items[key].Value))
+	}
+
+	require.NoError(t, mc.Delete(keys[0]))
+	_, err = mc.Get(keys[0])
+	require.ErrorIs(t, err, memcache.ErrCacheMiss)
+
+	// Cache misses don't eject servers
+	for server := range servers {
+		require.True(t, mc.Selector().Alive(server))
+	}
+}
+
+func TestClientEjectsDeadServers(t *testing.T) {
+	ring, servers := setup(t, 2)
+	mc := New(ring)
+
+	owner, err := ketama(t, ring).GetServer("key")
+	require.NoError(t, err)
+	servers[owner].Close()
+
+	// The failed request ejects the server
+	require.Error(t, mc.Set(&memcache.Item{Key: "key", Value: []byte("value")}))
+	require.False(t, mc.Selector().Alive(owner))
+
+	// Subsequent requests are served by the next server
+	require.NoError(t, mc.Set(&memcache.Item{Key: "key", Value: []byte("value")}))
+
+	item, err := mc.Get("key")
+	require.NoError(t, err)
+	require.Equal(t, "value", string(item.Value))
+
+	items, err := mc.GetMulti([]string{"key"})
+	require.NoError(t, err)
+	require.Contains(t, items, "key")
+}
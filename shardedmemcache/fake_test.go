@@ -0,0 +1,113 @@
+package shardedmemcache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer is a minimal memcached speaking enough of the text protocol for
+// gets, set, and delete.
+type fakeServer struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fakeServer{ln: ln, items: make(map[string][]byte)}
+	t.Cleanup(s.Close)
+
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) Close() {
+	_ = s.ln.Close()
+}
+
+func (s *fakeServer) Has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.items[key]
+	return ok
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		s.mu.Lock()
+		switch fields[0] {
+		case "get", "gets":
+			for _, key := range fields[1:] {
+				if v, ok := s.items[key]; ok {
+					_, _ = fmt.Fprintf(rw, "VALUE %s 0 %d 1\r\n%s\r\n", key, len(v), v)
+				}
+			}
+			_, _ = rw.WriteString("END\r\n")
+		case "set":
+			n, _ := strconv.Atoi(fields[4])
+			data := make([]byte, n+2)
+			if _, err := io.ReadFull(rw, data); err != nil {
+				s.mu.Unlock()
+				return
+			}
+			s.items[fields[1]] = data[:n]
+			_, _ = rw.WriteString("STORED\r\n")
+		case "delete":
+			if _, ok := s.items[fields[1]]; ok {
+				delete(s.items, fields[1])
+				_, _ = rw.WriteString("DELETED\r\n")
+			} else {
+				_, _ = rw.WriteString("NOT_FOUND\r\n")
+			}
+		default:
+			_, _ = rw.WriteString("ERROR\r\n")
+		}
+		s.mu.Unlock()
+
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
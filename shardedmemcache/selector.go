@@ -0,0 +1,230 @@
+// Package shardedmemcache shards memcached keys across a set of servers using
+// a consistent hash ring.
+//
+// Selector is a gomemcache ServerSelector that places keys the way ketama
+// clients such as libmemcached do, with servers taken from the ring, so a
+// service can share a memcached pool with clients in other languages. Client wraps
+// a gomemcache client so that servers failing requests are ejected from
+// selection for a cooldown, with their keys served by the next server clockwise
+// around the ring.
+package shardedmemcache
+
+import (
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultCooldown is how long an ejected server is excluded from selection.
+const DefaultCooldown = 10 * time.Second
+
+// Option configures a Selector.
+type Option func(*Selector)
+
+// WithCooldown sets how long a dead server is excluded from selection before
+// it's tried again.
+func WithCooldown(d time.Duration) Option {
+	return func(s *Selector) {
+		s.cooldown = d
+	}
+}
+
+// Selector is a memcache.ServerSelector that picks servers from a hash ring.
+//
+// Server names in the ring are memcached addresses: host:port for TCP, or a
+// path (anything containing "/") for unix sockets. Keys are placed on a
+// hashring.KetamaRing built from the ring's servers and weights, rebuilt when
+// the ring changes. Servers that are down or joining aren't selected.
+//
+// It is safe for concurrent use.
+type Selector struct {
+	ring     *hashring.HashRing
+	cooldown time.Duration
+	now      func() time.Time
+
+	continuum atomic.Pointer[continuum]
+	rebuild   sync.Mutex // serializes rebuilding the continuum
+
+	mu    sync.RWMutex
+	addrs map[string]net.Addr
+	dead  map[string]time.Time // server -> excluded until
+}
+
+// continuum is the ketama continuum built from a version of the ring.
+type continuum struct {
+	ketama  *hashring.KetamaRing // nil when no server can be selected
+	version uint64
+}
+
+var _ memcache.ServerSelector = (*Selector)(nil)
+
+// NewSelector creates a selector that picks servers from ring.
+//
+// Example:
+//
+//	mc := memcache.NewFromSelector(shardedmemcache.NewSelector(ring))
+func NewSelector(ring *hashring.HashRing, opts ...Option) *Selector {
+	s := &Selector{
+		ring:     ring,
+		cooldown: DefaultCooldown,
+		now:      time.Now,
+		addrs:    make(map[string]net.Addr),
+		dead:     make(map[string]time.Time),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// PickServer returns the address of the first live server for key, walking
+// clockwise from the key's position on the ketama continuum.
+//
+// Returns memcache.ErrNoServers when no live servers exist.
+func (s *Selector) PickServer(key string) (net.Addr, error) {
+	server, err := s.Server(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.addr(server)
+}
+
+// Server returns the name of the first live server for key.
+func (s *Selector) Server(key string) (string, error) {
+	ketama := s.ketama()
+	if ketama == nil {
+		return "", memcache.ErrNoServers
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for server := range ketama.Successors(key) {
+		if s.alive(server) {
+			return server, nil
+		}
+	}
+
+	return "", memcache.ErrNoServers
+}
+
+// Each calls fn with the address of every live server.
+func (s *Selector) Each(fn func(net.Addr) error) error {
+	ketama := s.ketama()
+	if ketama == nil {
+		return nil
+	}
+
+	for _, server := range ketama.GetServers() {
+		if !s.Alive(server) {
+			continue
+		}
+
+		addr, err := s.addr(server)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarkDead ejects a server from selection for the configured cooldown.
+func (s *Selector) MarkDead(server string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget servers whose cooldown expired, so the map doesn't grow
+	now := s.now()
+	maps.DeleteFunc(s.dead, func(_ string, until time.Time) bool { return !now.Before(until) })
+	s.dead[server] = now.Add(s.cooldown)
+}
+
+// MarkAlive makes a server eligible for selection again immediately.
+func (s *Selector) MarkAlive(server string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.dead, server)
+}
+
+// Alive reports whether a server is currently eligible for selection.
+func (s *Selector) Alive(server string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.alive(server)
+}
+
+// alive reports whether server is eligible for selection. The caller must hold
+// s.mu for reading.
+func (s *Selector) alive(server string) bool {
+	until, ok := s.dead[server]
+	return !ok || !s.now().Before(until)
+}
+
+// ketama returns the ketama continuum for the ring's current servers,
+// rebuilding it if the ring has changed, or nil if no server can be selected.
+func (s *Selector) ketama() *hashring.KetamaRing {
+	version := s.ring.Version()
+	if c := s.continuum.Load(); c != nil && c.version == version {
+		return c.ketama
+	}
+
+	s.rebuild.Lock()
+	defer s.rebuild.Unlock()
+
+	// Another lookup may have rebuilt it while this one waited
+	if c := s.continuum.Load(); c != nil && c.version == s.ring.Version() {
+		return c.ketama
+	}
+
+	snapshot := s.ring.Snapshot()
+	servers := slices.DeleteFunc(snapshot.Servers, func(info hashring.ServerInfo) bool {
+		return info.State == hashring.StateDown || info.State == hashring.StateJoining
+	})
+
+	// Only fails, returning nil, when there are no servers
+	ketama, _ := hashring.NewKetamaRing(servers)
+	s.continuum.Store(&continuum{ketama: ketama, version: snapshot.Version})
+	return ketama
+}
+
+// addr returns the (cached) resolved address for server.
+func (s *Selector) addr(server string) (net.Addr, error) {
+	s.mu.RLock()
+	addr, ok := s.addrs[server]
+	s.mu.RUnlock()
+	if ok {
+		return addr, nil
+	}
+
+	var err error
+	if strings.Contains(server, "/") {
+		addr, err = net.ResolveUnixAddr("unix", server)
+	} else {
+		addr, err = net.ResolveTCPAddr("tcp", server)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addrs[server] = addr
+	return addr, nil
+}
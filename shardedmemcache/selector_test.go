@@ -0,0 +1,177 @@
+package shardedmemcache
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// ketama builds the continuum ketama clients would for ring's servers.
+func ketama(t *testing.T, ring *hashring.HashRing) *hashring.KetamaRing {
+	t.Helper()
+
+	k, err := hashring.NewKetamaRing(ring.Snapshot().Servers)
+	require.NoError(t, err)
+	return k
+}
+
+func TestSelectorPickServer(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("127.0.0.1:11211"))
+	require.NoError(t, ring.AddServer("127.0.0.1:11212"))
+	require.NoError(t, ring.AddServer("/tmp/memcached.sock"))
+
+	s := NewSelector(ring)
+	for i := range 50 {
+		key := fmt.Sprintf("key:%d", i)
+
+		expected, err := ketama(t, ring).GetServer(key)
+		require.NoError(t, err)
+
+		addr, err := s.PickServer(key)
+		require.NoError(t, err)
+		require.Equal(t, expected, addr.String())
+
+		if expected == "/tmp/memcached.sock" {
+			require.Equal(t, "unix", addr.Network())
+		} else {
+			require.Equal(t, "tcp", addr.Network())
+		}
+	}
+
+	var addrs []string
+	require.NoError(t, s.Each(func(addr net.Addr) error {
+		addrs = append(addrs, addr.String())
+		return nil
+	}))
+	require.ElementsMatch(t, ring.GetServers(), addrs)
+}
+
+func TestSelectorDeadServers(t *testing.T) {
+	now := time.Now()
+
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("127.0.0.1:11211"))
+	require.NoError(t, ring.AddServer("127.0.0.1:11212"))
+
+	s := NewSelector(ring, WithCooldown(time.Minute))
+	s.now = func() time.Time { return now }
+
+	owner, err := s.Server("key")
+	require.NoError(t, err)
+
+	replicas := ketama(t, ring).GetReplicas("key", 2)
+
+	// Keys owned by a dead server move to the next server clockwise
+	s.MarkDead(owner)
+	require.False(t, s.Alive(owner))
+
+	server, err := s.Server("key")
+	require.NoError(t, err)
+	require.Equal(t, replicas[1], server)
+
+	// Servers come back once the cooldown expires
+	now = now.Add(time.Minute)
+	server, err = s.Server("key")
+	require.NoError(t, err)
+	require.Equal(t, owner, server)
+
+	// No live servers
+	s.MarkDead(replicas[0])
+	s.MarkDead(replicas[1])
+	_, err = s.PickServer("key")
+	require.ErrorIs(t, err, memcache.ErrNoServers)
+
+	s.MarkAlive(replicas[1])
+	server, err = s.Server("key")
+	require.NoError(t, err)
+	require.Equal(t, replicas[1], server)
+}
+
+func TestSelectorKetama(t *testing.T) {
+	ring := hashring.New(50)
+	for _, server := range []string{"127.0.0.1:11211", "127.0.0.1:11212", "127.0.0.1:11213"} {
+		require.NoError(t, ring.AddServer(server))
+	}
+
+	// Keys go where libketama sends them, not where the ring would
+	s := NewSelector(ring)
+	tests := map[string]string{
+		"foo":     "127.0.0.1:11213",
+		"bar":     "127.0.0.1:11212",
+		"baz":     "127.0.0.1:11211",
+		"user:42": "127.0.0.1:11212",
+	}
+	for key, want := range tests {
+		server, err := s.Server(key)
+		require.NoError(t, err)
+		require.Equal(t, want, server, key)
+	}
+
+	// Changes to the ring are picked up, including weights and states
+	require.NoError(t, ring.AddServerWithInfo(hashring.ServerInfo{Name: "127.0.0.1:11214", Weight: 2}))
+	require.NoError(t, ring.SetState("127.0.0.1:11211", hashring.StateDown))
+
+	want, err := hashring.NewKetamaRing([]hashring.ServerInfo{
+		{Name: "127.0.0.1:11212"}, {Name: "127.0.0.1:11213"}, {Name: "127.0.0.1:11214", Weight: 2},
+	})
+	require.NoError(t, err)
+
+	for i := range 100 {
+		key := fmt.Sprintf("key:%d", i)
+		expected, err := want.GetServer(key)
+		require.NoError(t, err)
+
+		server, err := s.Server(key)
+		require.NoError(t, err)
+		require.Equal(t, expected, server, key)
+	}
+}
+
+func TestSelectorConcurrent(t *testing.T) {
+	ring := hashring.New(50)
+	require.NoError(t, ring.AddServer("127.0.0.1:11211"))
+	require.NoError(t, ring.AddServer("127.0.0.1:11212"))
+	s := NewSelector(ring)
+
+	// Lookups run alongside ejections and ring changes
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				_, err := s.PickServer(fmt.Sprintf("w%d-key%d", w, i))
+				require.NoError(t, err)
+			}
+		}()
+	}
+
+	for i := range 20 {
+		s.MarkDead("127.0.0.1:11211")
+		s.MarkAlive("127.0.0.1:11211")
+		require.NoError(t, ring.AddServer(fmt.Sprintf("127.0.0.1:%d", 12000+i)))
+	}
+	wg.Wait()
+
+	for i := range 100 {
+		key := fmt.Sprintf("key:%d", i)
+		expected, err := ketama(t, ring).GetServer(key)
+		require.NoError(t, err)
+
+		server, err := s.Server(key)
+		require.NoError(t, err)
+		require.Equal(t, expected, server)
+	}
+}
+
+func TestSelectorEmptyRing(t *testing.T) {
+	_, err := NewSelector(hashring.New(10)).PickServer("key")
+	require.ErrorIs(t, err, memcache.ErrNoServers)
+}
@@ -0,0 +1,263 @@
+// Package shardedredis shards Redis commands across a set of servers using a
+// consistent hash ring.
+//
+// Client keeps one go-redis client per server in the ring, sends single-key
+// commands to the server owning the key, and splits multi-key commands (MGET,
+// DEL, etc.) into one command per shard. When the ring's topology changes,
+// keys are remapped on the next command and clients for removed servers are
+// closed.
+package shardedredis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/redis/go-redis/v9"
+)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithOptions sets the template used to create per-server clients. Addr is
+// replaced by the server name from the ring.
+func WithOptions(opts *redis.Options) Option {
+	return func(c *Client) {
+		c.newClient = func(server string) *redis.Client {
+			o := *opts
+			o.Addr = server
+			return redis.NewClient(&o)
+		}
+	}
+}
+
+// WithClientFactory sets how the client for a server is created. Use it when
+// server names in the ring aren't Redis addresses.
+func WithClientFactory(fn func(server string) *redis.Client) Option {
+	return func(c *Client) {
+		c.newClient = fn
+	}
+}
+
+// Client routes Redis commands to the servers in a hash ring.
+//
+// It is safe for concurrent use.
+type Client struct {
+	ring      *hashring.HashRing
+	newClient func(string) *redis.Client
+
+	mu      sync.Mutex
+	version uint64
+	clients map[string]*redis.Client
+}
+
+// New creates a client that shards commands across the servers in ring. Server
+// names are used as Redis addresses unless WithClientFactory is given.
+//
+// Example:
+//
+//	ring := hashring.New(150)
+//	ring.AddServer("10.0.0.1:6379")
+//	ring.AddServer("10.0.0.2:6379")
+//
+//	rdb := shardedredis.New(ring)
+//	defer rdb.Close()
+//
+//	rdb.Set(ctx, "user:42", "alice", time.Hour)
+//	name, err := rdb.Get(ctx, "user:42").Result()
+func New(ring *hashring.HashRing, opts ...Option) *Client {
+	c := &Client{
+		ring:    ring,
+		clients: make(map[string]*redis.Client),
+	}
+
+	WithOptions(&redis.Options{})(c)
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Shard returns the client for the server that owns key. Use it to run commands
+// that Client doesn't wrap.
+func (c *Client) Shard(key string) (*redis.Client, error) {
+	server, version, err := c.ring.GetServerVersioned(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.client(server, version), nil
+}
+
+// Shards returns the clients for every server in the ring, keyed by server.
+func (c *Client) Shards() map[string]*redis.Client {
+	version := c.ring.Version()
+
+	shards := make(map[string]*redis.Client)
+	for _, server := range c.ring.GetServers() {
+		shards[server] = c.client(server, version)
+	}
+
+	return shards
+}
+
+// Get returns the value of key from the shard that owns it.
+func (c *Client) Get(ctx context.Context, key string) *redis.StringCmd {
+	rdb, err := c.Shard(key)
+	if err != nil {
+		cmd := redis.NewStringCmd(ctx, "get", key)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	return rdb.Get(ctx, key)
+}
+
+// Set stores value under key on the shard that owns it.
+func (c *Client) Set(ctx context.Context, key string, value any, expiration time.Duration) *redis.StatusCmd {
+	rdb, err := c.Shard(key)
+	if err != nil {
+		cmd := redis.NewStatusCmd(ctx, "set", key, value)
+		cmd.SetErr(err)
+		return cmd
+	}
+
+	return rdb.Set(ctx, key, value, expiration)
+}
+
+// MGet returns the values of keys, fetching them with one MGET per shard.
+//
+// Values are returned in the same order as keys, with nil for keys that don't
+// exist. If any shard fails, the error is returned along with the values that
+// were fetched.
+func (c *Client) MGet(ctx context.Context, keys ...string) ([]any, error) {
+	values := make([]any, len(keys))
+	err := c.split(keys, func(rdb *redis.Client, idx []int, shardKeys []string) error {
+		vals, err := rdb.MGet(ctx, shardKeys...).Result()
+		if err != nil {
+			return err
+		}
+
+		for i, v := range vals {
+			values[idx[i]] = v
+		}
+		return nil
+	})
+
+	return values, err
+}
+
+// Del removes keys from their shards and returns the number of keys removed.
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	var (
+		mu      sync.Mutex
+		removed int64
+	)
+
+	err := c.split(keys, func(rdb *redis.Client, _ []int, shardKeys []string) error {
+		n, err := rdb.Del(ctx, shardKeys...).Result()
+
+		mu.Lock()
+		removed += n
+		mu.Unlock()
+		return err
+	})
+
+	return removed, err
+}
+
+// Close closes the clients for every shard.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for server, rdb := range c.clients {
+		errs = append(errs, rdb.Close())
+		delete(c.clients, server)
+	}
+
+	return errors.Join(errs...)
+}
+
+// split groups keys by shard and calls fn concurrently for each shard with the
+// shard's keys and their positions in keys.
+func (c *Client) split(keys []string, fn func(rdb *redis.Client, idx []int, keys []string) error) error {
+	type batch struct {
+		idx  []int
+		keys []string
+	}
+
+	batches := make(map[*redis.Client]*batch)
+	for i, key := range keys {
+		rdb, err := c.Shard(key)
+		if err != nil {
+			return err
+		}
+
+		b, ok := batches[rdb]
+		if !ok {
+			b = &batch{}
+			batches[rdb] = b
+		}
+
+		b.idx = append(b.idx, i)
+		b.keys = append(b.keys, key)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for rdb, b := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := fn(rdb, b.idx, b.keys); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// client returns the (cached) client for server. When the ring has changed
+// since the last call, clients for servers that left the ring are closed.
+func (c *Client) client(server string, version uint64) *redis.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version != c.version {
+		c.version = version
+		c.prune()
+	}
+
+	rdb, ok := c.clients[server]
+	if !ok {
+		rdb = c.newClient(server)
+		c.clients[server] = rdb
+	}
+
+	return rdb
+}
+
+// prune closes clients for servers no longer in the ring. The caller must hold
+// c.mu.
+func (c *Client) prune() {
+	for server, rdb := range c.clients {
+		if _, ok := c.ring.GetServerInfo(server); !ok {
+			_ = rdb.Close()
+			delete(c.clients, server)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package shardedredis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+func setup(t *testing.T, n int) (*hashring.HashRing, map[string]*miniredis.Miniredis) {
+	t.Helper()
+
+	ring := hashring.New(50)
+	servers := make(map[string]*miniredis.Miniredis)
+	for range n {
+		srv := miniredis.RunT(t)
+		servers[srv.Addr()] = srv
+		require.NoError(t, ring.AddServer(srv.Addr()))
+	}
+
+	return ring, servers
+}
+
+func TestGetSet(t *testing.T) {
+	ctx := context.Background()
+	ring, servers := setup(t, 3)
+
+	rdb := New(ring)
+	defer func() { require.NoError(t, rdb.Close()) }()
+
+	for i := range 20 {
+		key := fmt.Sprintf("user:%d", i)
+		require.NoError(t, rdb.Set(ctx, key, i, 0).Err())
+
+		// The value lives only on the owning server
+		owner, err := ring.GetServer(key)
+		require.NoError(t, err)
+
+		for addr, srv := range servers {
+			require.Equal(t, addr == owner, srv.Exists(key), "key %s on %s", key, addr)
+		}
+
+		got, err := rdb.Get(ctx, key).Int()
+		require.NoError(t, err)
+		require.Equal(t, i, got)
+	}
+}
+
+func TestMGetDel(t *testing.T) {
+	ctx := context.Background()
+	ring, _ := setup(t, 3)
+
+	rdb := New(ring)
+	defer func() { require.NoError(t, rdb.Close()) }()
+
+	keys := make([]string, 10)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+		require.NoError(t, rdb.Set(ctx, keys[i], fmt.Sprintf("value:%d", i), 0).Err())
+	}
+
+	values, err := rdb.MGet(ctx, append(keys, "missing")...)
+	require.NoError(t, err)
+	require.Len(t, values, len(keys)+1)
+	for i := range keys {
+		require.Equal(t, fmt.Sprintf("value:%d", i), values[i])
+	}
+	require.Nil(t, values[len(keys)])
+
+	n, err := rdb.Del(ctx, append(keys, "missing")...)
+	require.NoError(t, err)
+	require.Equal(t, int64(len(keys)), n)
+}
+
+func TestTopologyChange(t *testing.T) {
+	ctx := context.Background()
+	ring, servers := setup(t, 2)
+
+	rdb := New(ring)
+	defer func() { require.NoError(t, rdb.Close()) }()
+
+	require.Len(t, rdb.Shards(), 2)
+
+	// Keys owned by a removed server remap to the remaining one
+	var removed string
+	for addr := range servers {
+		removed = addr
+		break
+	}
+	require.NoError(t, ring.RemoveServer(removed))
+
+	for i := range 10 {
+		key := fmt.Sprintf("key:%d", i)
+		require.NoError(t, rdb.Set(ctx, key, i, 0).Err())
+		require.False(t, servers[removed].Exists(key))
+	}
+
+	rdb.mu.Lock()
+	require.NotContains(t, rdb.clients, removed)
+	rdb.mu.Unlock()
+}
+
+func TestEmptyRing(t *testing.T) {
+	ctx := context.Background()
+	rdb := New(hashring.New(10))
+
+	require.Error(t, rdb.Get(ctx, "key").Err())
+	require.Error(t, rdb.Set(ctx, "key", "value", 0).Err())
+
+	_, err := rdb.MGet(ctx, "a", "b")
+	require.Error(t, err)
+}
@@ -0,0 +1,294 @@
+// Package shardrouter routes SQL queries to database shards using a
+// consistent hash ring.
+//
+// Router maps each shard key to a *sql.DB for the shard that owns it. When
+// shards are added or removed, the router emits migration tasks describing the
+// ranges of the ring that changed owner, per table, so the data can be copied
+// between shards. With WithMigrationGate, keys in a range that's being migrated
+// keep routing to the source shard until the migration is acknowledged with
+// Complete, making resharding safe for reads and writes.
+package shardrouter
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// Migration is a task to move the rows of a table in a range of the ring from
+// one shard to another.
+type Migration struct {
+	ID          uint64             `json:"id"`
+	Table       string             `json:"table,omitempty"`
+	Range       hashring.HashRange `json:"range"`
+	Source      string             `json:"source"`
+	Destination string             `json:"destination"`
+}
+
+// Option configures a Router.
+type Option func(*Router)
+
+// WithOpener sets how the *sql.DB for a shard is opened. The default treats
+// shard names as data source names for the driver given to New.
+func WithOpener(open func(shard string) (*sql.DB, error)) Option {
+	return func(r *Router) {
+		r.open = open
+	}
+}
+
+// WithTables sets the tables that are sharded. A migration is emitted for each
+// table whenever a range moves. Without tables, a single migration with an
+// empty Table is emitted per range.
+func WithTables(tables ...string) Option {
+	return func(r *Router) {
+		r.tables = slices.Clone(tables)
+	}
+}
+
+// WithMigrationGate keeps keys in ranges with pending migrations routed to the
+// source shard until the migration is completed.
+func WithMigrationGate() Option {
+	return func(r *Router) {
+		r.gated = true
+	}
+}
+
+// WithMigrationHandler sets a function called with the migrations emitted by
+// each topology change. It's called without the router's lock held, so it may
+// call back into the router (e.g. to Complete migrations).
+func WithMigrationHandler(fn func([]Migration)) Option {
+	return func(r *Router) {
+		r.onMigrate = fn
+	}
+}
+
+// Router routes shard keys to databases.
+//
+// It is safe for concurrent use.
+type Router struct {
+	ring      *hashring.HashRing
+	open      func(string) (*sql.DB, error)
+	tables    []string
+	gated     bool
+	onMigrate func([]Migration)
+
+	mu      sync.Mutex
+	dbs     map[string]*sql.DB
+	pending []Migration // oldest first
+	nextID  uint64
+}
+
+// New creates a router over the shards in ring, opening each shard with the
+// given database/sql driver.
+//
+// Topology changes must be made through the router (AddShard and RemoveShard)
+// so it can emit migrations.
+//
+// Example:
+//
+//	ring := hashring.New(150)
+//	router := shardrouter.New(ring, "pgx",
+//		shardrouter.WithTables("users", "orders"),
+//		shardrouter.WithMigrationGate(),
+//	)
+//	router.AddShard("postgres://db-1/app")
+//	router.AddShard("postgres://db-2/app")
+//
+//	db, err := router.DB(userID)
+//	row := db.QueryRowContext(ctx, "SELECT name FROM users WHERE id = $1", userID)
+func New(ring *hashring.HashRing, driver string, opts ...Option) *Router {
+	r := &Router{
+		ring: ring,
+		open: func(shard string) (*sql.DB, error) {
+			return sql.Open(driver, shard)
+		},
+		dbs: make(map[string]*sql.DB),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Shard returns the shard that queries for key should be sent to.
+//
+// With WithMigrationGate, keys in a range with a pending migration route to the
+// source of the oldest such migration.
+func (r *Router) Shard(key string) (string, error) {
+	shard, err := r.ring.GetServer(key)
+	if err != nil {
+		return "", err
+	}
+
+	if !r.gated {
+		return shard, nil
+	}
+
+	hash := r.ring.Hash(key)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, m := range r.pending {
+		if m.Range.Contains(hash) {
+			return m.Source, nil
+		}
+	}
+
+	return shard, nil
+}
+
+// DB returns the database that queries for key should be sent to.
+func (r *Router) DB(key string) (*sql.DB, error) {
+	shard, err := r.Shard(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.db(shard)
+}
+
+// AddShard adds a shard to the ring and returns the migrations needed to move
+// its data onto it.
+func (r *Router) AddShard(shard string) ([]Migration, error) {
+	return r.change(func() error {
+		return r.ring.AddServer(shard)
+	})
+}
+
+// RemoveShard removes a shard from the ring and returns the migrations needed
+// to move its data off it. The shard's database stays open until its
+// migrations are completed.
+func (r *Router) RemoveShard(shard string) ([]Migration, error) {
+	return r.change(func() error {
+		return r.ring.RemoveServer(shard)
+	})
+}
+
+// Migrations returns the pending migrations, oldest first.
+func (r *Router) Migrations() []Migration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.pending)
+}
+
+// Complete acknowledges that a migration has finished. Keys in its range stop
+// being routed to the source shard once no other pending migration covers them.
+func (r *Router) Complete(id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx := slices.IndexFunc(r.pending, func(m Migration) bool { return m.ID == id })
+	if idx < 0 {
+		return fmt.Errorf("migration %d not found", id)
+	}
+
+	r.pending = slices.Delete(r.pending, idx, idx+1)
+	r.release()
+	return nil
+}
+
+// Close closes the databases of every shard.
+func (r *Router) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for shard, db := range r.dbs {
+		errs = append(errs, db.Close())
+		delete(r.dbs, shard)
+	}
+
+	return errors.Join(errs...)
+}
+
+// change applies fn to the ring and records the migrations it requires.
+func (r *Router) change(fn func() error) ([]Migration, error) {
+	before, err := hashring.Restore(r.ring.Snapshot())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fn(); err != nil {
+		return nil, err
+	}
+
+	moves := hashring.DiffRanges(before, r.ring)
+
+	tables := r.tables
+	if len(tables) == 0 {
+		tables = []string{""}
+	}
+
+	r.mu.Lock()
+
+	migrations := make([]Migration, 0, len(moves)*len(tables))
+	for _, move := range moves {
+		// Ranges moving to or from an empty ring have nothing to migrate
+		if move.From == "" || move.To == "" {
+			continue
+		}
+
+		for _, table := range tables {
+			r.nextID++
+			migrations = append(migrations, Migration{
+				ID:          r.nextID,
+				Table:       table,
+				Range:       move.Range,
+				Source:      move.From,
+				Destination: move.To,
+			})
+		}
+	}
+
+	r.pending = append(r.pending, migrations...)
+	r.release()
+	r.mu.Unlock()
+
+	if r.onMigrate != nil && len(migrations) > 0 {
+		r.onMigrate(slices.Clone(migrations))
+	}
+
+	return migrations, nil
+}
+
+// db returns the (cached) database for shard.
+func (r *Router) db(shard string) (*sql.DB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if db, ok := r.dbs[shard]; ok {
+		return db, nil
+	}
+
+	db, err := r.open(shard)
+	if err != nil {
+		return nil, fmt.Errorf("opening shard %s: %w", shard, err)
+	}
+
+	r.dbs[shard] = db
+	return db, nil
+}
+
+// release closes the databases of shards that left the ring and are no longer
+// the source of a pending migration. The caller must hold r.mu.
+func (r *Router) release() {
+	for shard, db := range r.dbs {
+		if _, ok := r.ring.GetServerInfo(shard); ok {
+			continue
+		}
+
+		if slices.ContainsFunc(r.pending, func(m Migration) bool { return m.Source == shard }) {
+			continue
+		}
+
+		_ = db.Close()
+		delete(r.dbs, shard)
+	}
+}
@@ -0,0 +1,156 @@
+package shardrouter
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// nopDriver satisfies database/sql without ever being connected to.
+type nopDriver struct{}
+
+func (nopDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+
+func init() {
+	sql.Register("shardrouter-test", nopDriver{})
+}
+
+func newRouter(t *testing.T, opts ...Option) *Router {
+	t.Helper()
+
+	r := New(hashring.New(50), "shardrouter-test", opts...)
+	t.Cleanup(func() { require.NoError(t, r.Close()) })
+
+	for _, shard := range []string{"db-1", "db-2", "db-3"} {
+		migrations, err := r.AddShard(shard)
+		require.NoError(t, err)
+
+		if shard == "db-1" {
+			require.Empty(t, migrations, "the first shard has nothing to migrate")
+		}
+	}
+
+	// Start with no pending migrations
+	for _, m := range r.Migrations() {
+		require.NoError(t, r.Complete(m.ID))
+	}
+
+	return r
+}
+
+func TestRouterDB(t *testing.T) {
+	r := newRouter(t)
+
+	db1, err := r.DB("user:1")
+	require.NoError(t, err)
+
+	db2, err := r.DB("user:1")
+	require.NoError(t, err)
+	require.Same(t, db1, db2, "databases should be cached per shard")
+
+	_, err = New(hashring.New(10), "shardrouter-test").DB("user:1")
+	require.Error(t, err)
+}
+
+func TestRouterMigrations(t *testing.T) {
+	var handled []Migration
+	r := newRouter(t,
+		WithTables("users", "orders"),
+		WithMigrationHandler(func(m []Migration) { handled = append(handled, m...) }),
+	)
+	handled = nil
+
+	migrations, err := r.AddShard("db-4")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	require.Equal(t, migrations, handled)
+	require.Equal(t, migrations, r.Migrations())
+
+	tables := make(map[string]int)
+	for _, m := range migrations {
+		require.Equal(t, "db-4", m.Destination)
+		require.NotEqual(t, "db-4", m.Source)
+		tables[m.Table]++
+	}
+	require.Equal(t, tables["users"], tables["orders"], "each range should be migrated for every table")
+
+	_, err = r.AddShard("db-4")
+	require.Error(t, err)
+
+	require.Error(t, r.Complete(0))
+}
+
+func TestRouterMigrationGate(t *testing.T) {
+	r := newRouter(t, WithMigrationGate())
+
+	before := make(map[string]string)
+	for i := range 1000 {
+		key := fmt.Sprintf("user:%d", i)
+		before[key], _ = r.Shard(key)
+	}
+
+	migrations, err := r.AddShard("db-4")
+	require.NoError(t, err)
+
+	// Until migrations complete, keys keep routing to their source shard
+	for key, shard := range before {
+		got, err := r.Shard(key)
+		require.NoError(t, err)
+		require.Equal(t, shard, got)
+	}
+
+	for _, m := range migrations {
+		require.NoError(t, r.Complete(m.ID))
+	}
+
+	moved := 0
+	for key, shard := range before {
+		got, err := r.Shard(key)
+		require.NoError(t, err)
+
+		if got != shard {
+			require.Equal(t, "db-4", got)
+			moved++
+		}
+	}
+	require.NotZero(t, moved)
+}
+
+func TestRouterRemoveShard(t *testing.T) {
+	r := newRouter(t, WithMigrationGate())
+
+	db, err := r.DB("user:1")
+	require.NoError(t, err)
+
+	shard, err := r.Shard("user:1")
+	require.NoError(t, err)
+
+	migrations, err := r.RemoveShard(shard)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	// The removed shard still serves its keys until the migrations complete
+	got, err := r.DB("user:1")
+	require.NoError(t, err)
+	require.Same(t, db, got)
+
+	for _, m := range migrations {
+		require.Equal(t, shard, m.Source)
+		require.NoError(t, r.Complete(m.ID))
+	}
+
+	got, err = r.DB("user:1")
+	require.NoError(t, err)
+	require.NotSame(t, db, got)
+
+	r.mu.Lock()
+	require.NotContains(t, r.dbs, shard, "the removed shard's database should be closed")
+	r.mu.Unlock()
+}
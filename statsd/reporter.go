@@ -0,0 +1,112 @@
+package statsd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultInterval is how often a Reporter samples the ring.
+const DefaultInterval = 10 * time.Second
+
+// ReporterOption configures a Reporter.
+type ReporterOption func(*Reporter)
+
+// WithInterval sets how often Run samples the ring.
+func WithInterval(d time.Duration) ReporterOption {
+	return func(r *Reporter) {
+		r.interval = d
+	}
+}
+
+// WithGaugesOnly stops the reporter sending the lookups and topology_changes
+// counters. Use it when the ring already pushes them to the sink (see
+// hashring.WithMetricsSink), so they aren't counted twice.
+func WithGaugesOnly() ReporterOption {
+	return func(r *Reporter) {
+		r.counters = false
+	}
+}
+
+// Reporter samples a ring and sends its metrics to a Sink:
+//
+//   - lookups (counter): keys resolved since the last report
+//   - topology_changes (counter): version increments since the last report
+//   - servers (gauge): number of servers in the ring
+//   - ownership_cv (gauge): CV of ownership shares, in percent
+//   - ownership_share.<server> (gauge): each server's share of the hash
+//     space, in percent
+//   - collisions (gauge): virtual nodes displaced by hash collisions
+type Reporter struct {
+	ring     *hashring.HashRing
+	sink     *Sink
+	interval time.Duration
+	counters bool
+
+	mu      sync.Mutex
+	lookups uint64 // ring.Lookups() at the last report
+	version uint64 // ring.Version() at the last report
+}
+
+// NewReporter creates a reporter for ring that sends metrics to sink.
+//
+// Example:
+//
+//	reporter := statsd.NewReporter(ring, sink, statsd.WithInterval(time.Minute))
+//	go reporter.Run(ctx)
+func NewReporter(ring *hashring.HashRing, sink *Sink, opts ...ReporterOption) *Reporter {
+	r := &Reporter{
+		ring:     ring,
+		sink:     sink,
+		interval: DefaultInterval,
+		counters: true,
+		lookups:  ring.Lookups(),
+		version:  ring.Version(),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run reports every interval until ctx is done, returning ctx's error.
+func (r *Reporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.Report()
+		}
+	}
+}
+
+// Report samples the ring and sends its metrics once. Errors are passed to
+// the sink's error handler (see WithErrorHandler).
+func (r *Reporter) Report() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lookups, version := r.ring.Lookups(), r.ring.Version()
+	if r.counters {
+		r.sink.Counter("lookups", int64(lookups-r.lookups))
+		r.sink.Counter("topology_changes", int64(version-r.version))
+	}
+	r.lookups, r.version = lookups, version
+
+	shares := r.ring.OwnershipShare()
+	r.sink.Gauge("servers", float64(len(shares)))
+	r.sink.Gauge("ownership_cv", r.ring.OwnershipCV())
+	for server, share := range shares {
+		r.sink.Gauge("ownership_share", share*100, hashring.Label{Name: "server", Value: server})
+	}
+
+	r.sink.Gauge("collisions", float64(r.ring.Collisions()))
+}
@@ -0,0 +1,97 @@
+package statsd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReporter(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	sink, err := NewSink(StatsD, addr)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ring := hashring.New(10, hashring.WithPlacement(hashring.PlacementEvenlySpaced))
+	require.NoError(t, ring.AddServer("cache-1"))
+
+	reporter := NewReporter(ring, sink)
+	require.NoError(t, ring.AddServer("cache.2"))
+	_, err = ring.GetServer("key")
+	require.NoError(t, err)
+	_, err = ring.GetServer("key")
+	require.NoError(t, err)
+
+	readAll := func() []string {
+		var packets []string
+		for range 7 {
+			packets = append(packets, read())
+		}
+
+		return packets
+	}
+
+	// Counts are relative to when the reporter was created
+	reporter.Report()
+	require.ElementsMatch(t, []string{
+		"hashlab.lookups:2|c",
+		"hashlab.topology_changes:1|c",
+		"hashlab.servers:2|g",
+		"hashlab.ownership_cv:0|g",
+		"hashlab.ownership_share.cache-1:50|g",
+		"hashlab.ownership_share.cache_2:50|g",
+		"hashlab.collisions:0|g",
+	}, readAll())
+
+	// ...and then to the last report
+	reporter.Report()
+	packets := readAll()
+	require.Contains(t, packets, "hashlab.lookups:0|c")
+	require.Contains(t, packets, "hashlab.topology_changes:0|c")
+}
+
+func TestReporterGaugesOnly(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	sink, err := NewSink(StatsD, addr)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	// The ring pushes its own counters, so the reporter only sends gauges
+	ring := hashring.New(10, hashring.WithMetricsSink(sink))
+	reporter := NewReporter(ring, sink, WithGaugesOnly())
+
+	require.NoError(t, ring.AddServer("cache-1"))
+	require.Equal(t, "hashlab.topology_changes.add:1|c", read())
+	require.Equal(t, "hashlab.servers:1|g", read())
+	require.Equal(t, "hashlab.collisions:0|g", read())
+
+	reporter.Report()
+	require.Equal(t, "hashlab.servers:1|g", read())
+	require.Equal(t, "hashlab.ownership_cv:0|g", read())
+	require.Equal(t, "hashlab.ownership_share.cache-1:100|g", read())
+	require.Equal(t, "hashlab.collisions:0|g", read())
+}
+
+func TestReporterRun(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	sink, err := NewSink(StatsD, addr)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ring := hashring.New(10)
+	reporter := NewReporter(ring, sink, WithInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- reporter.Run(ctx) }()
+
+	require.Equal(t, "hashlab.lookups:0|c", read())
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,228 @@
+// Package statsd reports hash ring metrics to StatsD or Graphite, for
+// deployments that aren't scraped by Prometheus.
+//
+// A Sink sends metrics using either protocol and implements
+// hashring.MetricsSink, so a ring can push lookups, latencies, topology
+// changes, and breaker trips as they happen:
+//
+//	ring := hashring.New(150, hashring.WithMetricsSink(sink))
+//
+// A Reporter periodically samples a ring and sends balance metrics the ring
+// doesn't push, such as each server's ownership share, along with lookup and
+// topology change counts for rings that aren't configured with the sink.
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// Protocol is the wire format a Sink writes.
+type Protocol string
+
+const (
+	// StatsD sends metrics over UDP in the StatsD line format, e.g.
+	// "hashlab.lookups:42|c". StatsD aggregates and forwards them (usually to
+	// Graphite).
+	StatsD Protocol = "statsd"
+
+	// Graphite sends metrics over TCP in the Graphite plaintext format, e.g.
+	// "hashlab.lookups 42 1700000000". Graphite has no counter type, so counts
+	// are sent as the value for the reporting interval.
+	Graphite Protocol = "graphite"
+
+	// DefaultPrefix is prepended to every metric name.
+	DefaultPrefix = "hashlab"
+)
+
+// SinkOption configures a Sink.
+type SinkOption func(*Sink)
+
+// WithPrefix sets the prefix prepended to every metric name. An empty prefix
+// sends names as is.
+func WithPrefix(prefix string) SinkOption {
+	return func(s *Sink) {
+		s.prefix = prefix
+	}
+}
+
+// WithErrorHandler sets a function called with errors sending metrics. By
+// default they're ignored, since a metrics outage shouldn't affect the
+// application.
+func WithErrorHandler(fn func(error)) SinkOption {
+	return func(s *Sink) {
+		s.onError = fn
+	}
+}
+
+// Sink writes metrics to a StatsD or Graphite server. It's safe for concurrent
+// use.
+//
+// Label values are appended to the metric name as path components, since
+// neither protocol supports tags, e.g. "breaker_trips.cache-1".
+type Sink struct {
+	mu       sync.Mutex
+	protocol Protocol
+	addr     string
+	prefix   string
+	conn     net.Conn
+	now      func() time.Time
+	onError  func(error)
+}
+
+var _ hashring.MetricsSink = (*Sink)(nil)
+
+// NewSink creates a sink that writes metrics to addr using protocol.
+//
+// Graphite connections are re-established on the next write after a failure,
+// so a restarted Graphite server doesn't require restarting the sink.
+//
+// Example:
+//
+//	sink, err := statsd.NewSink(statsd.StatsD, "127.0.0.1:8125", statsd.WithPrefix("myapp.ring"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer sink.Close()
+func NewSink(protocol Protocol, addr string, opts ...SinkOption) (*Sink, error) {
+	if protocol != StatsD && protocol != Graphite {
+		return nil, fmt.Errorf("unknown protocol %q", protocol)
+	}
+
+	s := &Sink{
+		protocol: protocol,
+		addr:     addr,
+		prefix:   DefaultPrefix,
+		now:      time.Now,
+		onError:  func(error) {},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	conn, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	s.conn = conn
+	return s, nil
+}
+
+// Counter sends a counter increment.
+func (s *Sink) Counter(name string, delta int64, labels ...hashring.Label) {
+	s.send(name, labels, strconv.FormatInt(delta, 10), "c")
+}
+
+// Gauge sends the current value of a gauge.
+func (s *Sink) Gauge(name string, value float64, labels ...hashring.Label) {
+	s.send(name, labels, strconv.FormatFloat(value, 'f', -1, 64), "g")
+}
+
+// Histogram sends a sampled value, such as a latency in seconds.
+func (s *Sink) Histogram(name string, value float64, labels ...hashring.Label) {
+	s.send(name, labels, strconv.FormatFloat(value, 'f', -1, 64), "h")
+}
+
+// Close closes the connection to the server.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// send writes a single metric, reporting any error to the error handler. kind
+// is the StatsD metric type.
+func (s *Sink) send(name string, labels []hashring.Label, value, kind string) {
+	if err := s.write(s.path(name, labels), value, kind); err != nil {
+		s.onError(err)
+	}
+}
+
+// path returns the full metric path for name and labels.
+func (s *Sink) path(name string, labels []hashring.Label) string {
+	var b strings.Builder
+	if s.prefix != "" {
+		b.WriteString(s.prefix)
+		b.WriteByte('.')
+	}
+
+	b.WriteString(name)
+	for _, label := range labels {
+		b.WriteByte('.')
+		b.WriteString(metricName(label.Value))
+	}
+
+	return b.String()
+}
+
+// write sends a single line, reconnecting if necessary.
+func (s *Sink) write(path, value, kind string) error {
+	var line string
+	switch s.protocol {
+	case StatsD:
+		line = fmt.Sprintf("%s:%s|%s", path, value, kind)
+	case Graphite:
+		line = fmt.Sprintf("%s %s %d\n", path, value, s.now().Unix())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial()
+		if err != nil {
+			return err
+		}
+
+		s.conn = conn
+	}
+
+	if _, err := s.conn.Write([]byte(line)); err != nil {
+		if s.protocol == Graphite {
+			s.conn.Close()
+			s.conn = nil
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// dial connects to the server.
+func (s *Sink) dial() (net.Conn, error) {
+	network := "udp"
+	if s.protocol == Graphite {
+		network = "tcp"
+	}
+
+	return net.DialTimeout(network, s.addr, 5*time.Second)
+}
+
+// metricName makes name safe to use as a metric path component. Dots separate
+// components in both protocols, and StatsD reserves ':' and '|'.
+func metricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ':', '|', ' ', '/', '@':
+			return '_'
+		}
+
+		return r
+	}, name)
+}
@@ -0,0 +1,136 @@
+package statsd
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+// listenUDP returns a StatsD server address and a function that reads the
+// next packet.
+func listenUDP(t *testing.T) (string, func() string) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().String(), func() string {
+		t.Helper()
+
+		buf := make([]byte, 1024)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+}
+
+// listenTCP returns a Graphite server address and a channel of received lines.
+func listenTCP(t *testing.T) (net.Listener, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+
+	return ln, lines
+}
+
+func TestSinkStatsD(t *testing.T) {
+	addr, read := listenUDP(t)
+
+	sink, err := NewSink(StatsD, addr)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	sink.Counter("lookups", 42)
+	require.Equal(t, "hashlab.lookups:42|c", read())
+
+	sink.Gauge("ownership_cv", 3.5)
+	require.Equal(t, "hashlab.ownership_cv:3.5|g", read())
+
+	sink.Histogram("lookup_duration_seconds", 0.0002)
+	require.Equal(t, "hashlab.lookup_duration_seconds:0.0002|h", read())
+
+	sink.Counter("breaker_trips", 1, hashring.Label{Name: "server", Value: "cache-1.example.com:11211"})
+	require.Equal(t, "hashlab.breaker_trips.cache-1_example_com_11211:1|c", read())
+
+	sink, err = NewSink(StatsD, addr, WithPrefix(""))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	sink.Gauge("servers", 3)
+	require.Equal(t, "servers:3|g", read())
+}
+
+func TestSinkGraphite(t *testing.T) {
+	ln, lines := listenTCP(t)
+
+	sink, err := NewSink(Graphite, ln.Addr().String(), WithPrefix("app.ring"))
+	require.NoError(t, err)
+	defer sink.Close()
+
+	sink.now = func() time.Time { return time.Unix(1700000000, 0) }
+	sink.Counter("lookups", 42)
+	sink.Gauge("servers", 3)
+	require.Equal(t, "app.ring.lookups 42 1700000000", <-lines)
+	require.Equal(t, "app.ring.servers 3 1700000000", <-lines)
+}
+
+func TestSinkErrors(t *testing.T) {
+	ln, _ := listenTCP(t)
+
+	var errs []error
+	sink, err := NewSink(Graphite, ln.Addr().String(), WithErrorHandler(func(err error) {
+		errs = append(errs, err)
+	}))
+	require.NoError(t, err)
+
+	// Writes fail after the server goes away, and then fail to reconnect
+	ln.Close()
+	require.NoError(t, sink.Close())
+	sink.Gauge("servers", 3)
+	require.Len(t, errs, 1)
+}
+
+func TestNewSinkErrors(t *testing.T) {
+	_, err := NewSink("carrier-pigeon", "127.0.0.1:0")
+	require.ErrorContains(t, err, "unknown protocol")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	_, err = NewSink(Graphite, addr)
+	require.Error(t, err)
+}
+
+func TestMetricName(t *testing.T) {
+	require.Equal(t, "cache-1_example_com_11211", metricName("cache-1.example.com:11211"))
+	require.Equal(t, "a_b_c", metricName("a|b@c"))
+}
@@ -0,0 +1,156 @@
+// Package subjects maps keys to partitioned stream subjects (e.g. NATS
+// JetStream subjects like "orders.3") using a consistent hash ring.
+//
+// Publishers use a Partitioner to pick the subject for a key, and fan-out
+// workers each consume a subset of the subjects. Because partitions are placed
+// on a ring, adding or removing a partition only remaps the keys adjacent to
+// it rather than reshuffling every key.
+package subjects
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pseudomuto/hashlab/hashring"
+)
+
+// DefaultVirtualNodes is the number of virtual nodes placed for each partition.
+const DefaultVirtualNodes = 150
+
+// Option configures a Partitioner.
+type Option func(*config)
+
+// WithVirtualNodes sets the number of virtual nodes placed for each partition.
+func WithVirtualNodes(n int) Option {
+	return func(c *config) {
+		c.vnodes = n
+	}
+}
+
+type config struct {
+	vnodes int
+}
+
+// Partitioner maps keys to one of a set of numbered subjects.
+//
+// It is safe for concurrent use.
+type Partitioner struct {
+	ring     *hashring.HashRing
+	template string
+}
+
+// NewPartitioner creates a partitioner over partitions 0 through n-1. The
+// template is a fmt format containing a single %d verb for the partition
+// number.
+//
+// Example:
+//
+//	p, err := subjects.NewPartitioner("orders.%d", 8)
+//	subject, err := p.Subject(order.CustomerID)
+//	js.Publish(ctx, subject, data)
+func NewPartitioner(template string, n int, opts ...Option) (*Partitioner, error) {
+	if strings.Count(template, "%d") != 1 {
+		return nil, fmt.Errorf("subject template %q must contain exactly one %%d", template)
+	}
+
+	cfg := config{vnodes: DefaultVirtualNodes}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	p := &Partitioner{
+		ring:     hashring.New(cfg.vnodes),
+		template: template,
+	}
+
+	for i := range n {
+		if err := p.Add(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Partition returns the partition number key maps to.
+func (p *Partitioner) Partition(key string) (int, error) {
+	server, err := p.ring.GetServer(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(server)
+}
+
+// Subject returns the subject key should be published to.
+func (p *Partitioner) Subject(key string) (string, error) {
+	partition, err := p.Partition(key)
+	if err != nil {
+		return "", err
+	}
+
+	return p.SubjectFor(partition), nil
+}
+
+// SubjectFor returns the subject for a partition number.
+func (p *Partitioner) SubjectFor(partition int) string {
+	return fmt.Sprintf(p.template, partition)
+}
+
+// Add adds a partition. Only keys adjacent to the new partition on the ring
+// move to it.
+func (p *Partitioner) Add(partition int) error {
+	if partition < 0 {
+		return fmt.Errorf("invalid partition %d", partition)
+	}
+
+	return p.ring.AddServer(strconv.Itoa(partition))
+}
+
+// Remove removes a partition. Its keys move to the neighbouring partitions;
+// every other key stays where it is.
+func (p *Partitioner) Remove(partition int) error {
+	return p.ring.RemoveServer(strconv.Itoa(partition))
+}
+
+// Partitions returns the partition numbers in ascending order.
+func (p *Partitioner) Partitions() []int {
+	servers := p.ring.GetServers()
+
+	partitions := make([]int, 0, len(servers))
+	for _, server := range servers {
+		partition, _ := strconv.Atoi(server)
+		partitions = append(partitions, partition)
+	}
+
+	slices.Sort(partitions)
+	return partitions
+}
+
+// Subjects returns the subject of every partition, ordered by partition
+// number.
+func (p *Partitioner) Subjects() []string {
+	partitions := p.Partitions()
+
+	subjects := make([]string, len(partitions))
+	for i, partition := range partitions {
+		subjects[i] = p.SubjectFor(partition)
+	}
+
+	return subjects
+}
+
+// Assign splits the subjects across workers, returning the subjects consumed
+// by worker i of n. Each subject is assigned to exactly one worker.
+func (p *Partitioner) Assign(worker, workers int) []string {
+	var assigned []string
+	for i, subject := range p.Subjects() {
+		if workers > 0 && i%workers == worker {
+			assigned = append(assigned, subject)
+		}
+	}
+
+	return assigned
+}
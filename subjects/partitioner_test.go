@@ -0,0 +1,94 @@
+package subjects
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPartitioner(t *testing.T) {
+	p, err := NewPartitioner("orders.%d", 4)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1, 2, 3}, p.Partitions())
+	require.Equal(t, []string{"orders.0", "orders.1", "orders.2", "orders.3"}, p.Subjects())
+
+	_, err = NewPartitioner("orders", 4)
+	require.Error(t, err)
+
+	_, err = NewPartitioner("orders.%d.%d", 4)
+	require.Error(t, err)
+}
+
+func TestSubject(t *testing.T) {
+	p, err := NewPartitioner("orders.%d", 4)
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for i := range 1000 {
+		key := fmt.Sprintf("customer:%d", i)
+
+		subject, err := p.Subject(key)
+		require.NoError(t, err)
+
+		partition, err := p.Partition(key)
+		require.NoError(t, err)
+		require.Equal(t, p.SubjectFor(partition), subject)
+
+		seen[subject] = true
+	}
+
+	require.Len(t, seen, 4, "keys should be spread across every subject")
+
+	empty, err := NewPartitioner("orders.%d", 0)
+	require.NoError(t, err)
+
+	_, err = empty.Subject("key")
+	require.Error(t, err)
+}
+
+func TestAddRemove(t *testing.T) {
+	p, err := NewPartitioner("orders.%d", 4)
+	require.NoError(t, err)
+
+	const keys = 1000
+
+	before := make(map[string]int, keys)
+	for i := range keys {
+		key := fmt.Sprintf("customer:%d", i)
+		before[key], err = p.Partition(key)
+		require.NoError(t, err)
+	}
+
+	// Keys only move to the new partition
+	require.NoError(t, p.Add(4))
+	require.Error(t, p.Add(4))
+	require.Error(t, p.Add(-1))
+
+	for key, partition := range before {
+		got, err := p.Partition(key)
+		require.NoError(t, err)
+		if got != partition {
+			require.Equal(t, 4, got)
+		}
+	}
+
+	// Removing it restores the original mapping
+	require.NoError(t, p.Remove(4))
+	require.Error(t, p.Remove(4))
+
+	for key, partition := range before {
+		got, err := p.Partition(key)
+		require.NoError(t, err)
+		require.Equal(t, partition, got)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	p, err := NewPartitioner("orders.%d", 5)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"orders.0", "orders.2", "orders.4"}, p.Assign(0, 2))
+	require.Equal(t, []string{"orders.1", "orders.3"}, p.Assign(1, 2))
+	require.Empty(t, p.Assign(0, 0))
+}
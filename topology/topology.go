@@ -0,0 +1,191 @@
+// Package topology reads ring topologies from YAML files, so a ring's
+// servers and settings can be reviewed, versioned, and shared between tools
+// rather than rebuilt in code.
+//
+// A topology file looks like this; only servers is required:
+//
+//	vnodes: 150
+//	hasher: xxhash64
+//	placement: hashed
+//	servers:
+//	  - name: cache-1
+//	    zone: us-east-1a
+//	    tags: [ssd]
+//	  - name: cache-2
+//	    zone: us-east-1b
+//	    weight: 2
+//	  - name: cache-3
+//	    state: joining
+//	pins:
+//	  "tenant42:": cache-1
+package topology
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultVNodes is the number of virtual nodes per server when a topology
+// doesn't set one.
+const DefaultVNodes = 150
+
+// Topology describes a ring: its settings and servers.
+type Topology struct {
+	VNodes    int               `yaml:"vnodes,omitempty"`    // virtual nodes per server, DefaultVNodes if unset
+	Hasher    string            `yaml:"hasher,omitempty"`    // a built-in hasher's name (see hashring.HasherByName), CRC32 if unset
+	Placement string            `yaml:"placement,omitempty"` // a hashring.Placement, hashed if unset
+	Servers   []Server          `yaml:"servers"`
+	Pins      map[string]string `yaml:"pins,omitempty"` // key or prefix -> server (see hashring.HashRing.Pin)
+}
+
+// Server describes a server in a topology. Its fields mean the same as
+// hashring.ServerInfo's.
+type Server struct {
+	Name    string               `yaml:"name"`
+	Zone    string               `yaml:"zone,omitempty"`
+	Tags    []string             `yaml:"tags,omitempty"`
+	Weight  float64              `yaml:"weight,omitempty"`
+	VNodes  int                  `yaml:"vnodes,omitempty"`
+	Tokens  []uint64             `yaml:"tokens,omitempty"`
+	Tenants []string             `yaml:"tenants,omitempty"`
+	State   hashring.ServerState `yaml:"state,omitempty"`
+}
+
+// Info returns the server's hashring.ServerInfo.
+func (s Server) Info() hashring.ServerInfo {
+	return hashring.ServerInfo{
+		Name:    s.Name,
+		Zone:    s.Zone,
+		Tags:    s.Tags,
+		Weight:  s.Weight,
+		VNodes:  s.VNodes,
+		Tokens:  s.Tokens,
+		Tenants: s.Tenants,
+		State:   s.State,
+	}
+}
+
+// Load reads the topology file at path.
+//
+// Example:
+//
+//	topo, err := topology.Load("ring.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	ring, err := topo.Ring()
+func Load(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	topo, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return topo, nil
+}
+
+// Parse parses a topology from YAML. Unknown fields are rejected, so typos
+// don't silently fall back to defaults.
+func Parse(data []byte) (*Topology, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	var topo Topology
+	if err := dec.Decode(&topo); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("topology is empty")
+		}
+
+		return nil, err
+	}
+
+	if err := topo.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &topo, nil
+}
+
+// Validate reports whether the topology's settings are valid. Server-level
+// problems, such as duplicate names, are reported by Ring.
+func (t *Topology) Validate() error {
+	if t.VNodes < 0 {
+		return fmt.Errorf("vnodes must be positive, got %d", t.VNodes)
+	}
+
+	if _, err := t.hasher(); err != nil {
+		return err
+	}
+
+	switch hashring.Placement(t.Placement) {
+	case "", hashring.PlacementHashed, hashring.PlacementEvenlySpaced, hashring.PlacementIterated:
+	default:
+		return fmt.Errorf("unknown placement %q", t.Placement)
+	}
+
+	return nil
+}
+
+// Ring builds a ring with the topology's settings, servers, and pins. opts are
+// applied after the topology's settings, so they can add to or override
+// them.
+//
+// Returns an error if the topology is invalid or any server can't be added.
+func (t *Topology) Ring(opts ...hashring.Option) (*hashring.HashRing, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	hasher, _ := t.hasher()
+	vnodes := t.VNodes
+	if vnodes == 0 {
+		vnodes = DefaultVNodes
+	}
+
+	settings := []hashring.Option{hashring.WithHasher(hasher)}
+	if t.Placement != "" {
+		settings = append(settings, hashring.WithPlacement(hashring.Placement(t.Placement)))
+	}
+
+	infos := make([]hashring.ServerInfo, len(t.Servers))
+	for i, server := range t.Servers {
+		infos[i] = server.Info()
+	}
+
+	ring := hashring.New(vnodes, append(settings, opts...)...)
+	if err := ring.SetServers(infos); err != nil {
+		return nil, err
+	}
+
+	for keyOrPrefix, server := range t.Pins {
+		if err := ring.Pin(keyOrPrefix, server); err != nil {
+			return nil, fmt.Errorf("pin %q: %w", keyOrPrefix, err)
+		}
+	}
+
+	return ring, nil
+}
+
+// hasher returns the topology's hasher.
+func (t *Topology) hasher() (hashring.Hasher, error) {
+	if t.Hasher == "" {
+		return hashring.CRC32, nil
+	}
+
+	hasher, ok := hashring.HasherByName(t.Hasher)
+	if !ok {
+		return nil, fmt.Errorf("unknown hasher %q", t.Hasher)
+	}
+
+	return hasher, nil
+}
@@ -0,0 +1,92 @@
+package topology
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pseudomuto/hashlab/hashring"
+	"github.com/stretchr/testify/require"
+)
+
+const example = `
+vnodes: 100
+hasher: xxhash64
+servers:
+  - name: cache-1
+    zone: us-east-1a
+    tags: [ssd]
+  - name: cache-2
+    weight: 2
+  - name: cache-3
+    state: joining
+pins:
+  "tenant42:": cache-1
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(example), 0o600))
+
+	topo, err := Load(path)
+	require.NoError(t, err)
+	require.Equal(t, 100, topo.VNodes)
+	require.Len(t, topo.Servers, 3)
+	require.Equal(t, []string{"ssd"}, topo.Servers[0].Tags)
+
+	ring, err := topo.Ring()
+	require.NoError(t, err)
+	require.Equal(t, 3, ring.Size())
+
+	// it's the ring the topology describes
+	want := hashring.New(100, hashring.WithHasher(hashring.XXHash64))
+	require.NoError(t, want.AddServerWithInfo(hashring.ServerInfo{Name: "cache-1", Zone: "us-east-1a", Tags: []string{"ssd"}}))
+	require.NoError(t, want.AddServerWithInfo(hashring.ServerInfo{Name: "cache-2", Weight: 2}))
+	require.NoError(t, want.AddServerWithInfo(hashring.ServerInfo{Name: "cache-3", State: hashring.StateJoining}))
+	require.NoError(t, want.Pin("tenant42:", "cache-1"))
+	require.Equal(t, want.Checksum(), ring.Checksum())
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestParseDefaults(t *testing.T) {
+	topo, err := Parse([]byte("servers: [{name: a}, {name: b}]"))
+	require.NoError(t, err)
+
+	ring, err := topo.Ring()
+	require.NoError(t, err)
+
+	want := hashring.New(DefaultVNodes)
+	require.NoError(t, want.AddServer("a"))
+	require.NoError(t, want.AddServer("b"))
+	require.Equal(t, want.Checksum(), ring.Checksum())
+}
+
+func TestParseInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":             "",
+		"unknown field":     "servers: [{name: a, wieght: 2}]",
+		"unknown hasher":    "hasher: md5\nservers: [{name: a}]",
+		"unknown placement": "placement: random\nservers: [{name: a}]",
+		"negative vnodes":   "vnodes: -1\nservers: [{name: a}]",
+		"not yaml":          "servers: [",
+	} {
+		_, err := Parse([]byte(data))
+		require.Error(t, err, name)
+	}
+}
+
+func TestRingInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"duplicate server": "servers: [{name: a}, {name: a}]",
+		"invalid state":    "servers: [{name: a, state: paused}]",
+		"pin to unknown":   "servers: [{name: a}]\npins: {\"user:\": b}",
+	} {
+		topo, err := Parse([]byte(data))
+		require.NoError(t, err, name)
+
+		_, err = topo.Ring()
+		require.Error(t, err, name)
+	}
+}